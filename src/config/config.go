@@ -1,54 +1,502 @@
+// Package config loads the configuration of the service from its config
+// file, the environment and the command line, each overriding the one
+// before.
 package config
 
 import (
+	"account-service/src/model"
+	"account-service/src/pii"
+	"account-service/src/publisher"
+	"account-service/src/tracing"
 	"account-service/src/utils"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // The runtime image has no zoneinfo database
 
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"golang.org/x/crypto/bcrypt"
 )
 
-var (
-	IsProd     bool
-	AppName    string
-	AppHost    string
-	AppPort    int
+// Config is the configuration of the service.
+type Config struct {
+	IsProd  bool
+	AppName string
+	AppHost string
+	AppPort int
+
+	DBDriver   string
+	DBPath     string // Only used by the sqlite driver
 	DBHost     string
 	DBUser     string
 	DBPassword string
 	DBName     string
 	DBPort     int
-)
 
-func loadConfig() {
-	configPaths := []string{
-		"./",     // For app
-		"../../", // For test folder
+	DBAutoMigrate        bool
+	DBSlowQueryThreshold time.Duration
+
+	LogFormat string
+	LogLevel  logrus.Level
+
+	IdempotencyKeyTTL           time.Duration
+	IdempotencyKeyPurgeInterval time.Duration
+
+	JWTSecret string
+	JWTTTL    time.Duration
+
+	PIIKeys          map[int][]byte // Encryption keys by version
+	PIIBlindIndexKey []byte
+	PIIKeyring       *pii.Keyring
+
+	BusinessLocation *time.Location
+	WithdrawalLimits model.WithdrawalLimits
+	PINPolicy        model.PINPolicy
+
+	OutboxPublisher      string
+	OutboxFile           string
+	OutboxWebhookURL     string
+	OutboxWebhookTimeout time.Duration
+	OutboxPolicy         model.OutboxPolicy
+
+	WebhookPolicy model.WebhookPolicy
+
+	TracingExporter     string
+	TracingOTLPEndpoint string
+	TracingSampleRatio  float64
+}
+
+// defaults holds the keys of the configuration with their default values, nil
+// for those that have none.
+var defaults = []struct {
+	key   string
+	value any
+}{
+	{"APP_ENV", "dev"},
+	{"APP_NAME", "account-service"},
+	{"APP_HOST", "localhost"},
+	{"APP_PORT", 3000},
+
+	{"LOG_FORMAT", nil}, // json in prod, text otherwise
+	{"LOG_LEVEL", "info"},
+
+	{"DB_DRIVER", "postgres"},
+	{"DB_PATH", "account.db"},
+	{"DB_HOST", nil},
+	{"DB_USER", nil},
+	{"DB_PASSWORD", nil},
+	{"DB_NAME", nil},
+	{"DB_PORT", 5432},
+	{"DB_AUTO_MIGRATE", false},
+	{"DB_SLOW_QUERY_THRESHOLD", "200ms"},
+
+	{"IDEMPOTENCY_KEY_TTL", "24h"},
+	{"IDEMPOTENCY_KEY_PURGE_INTERVAL", "1m"},
+
+	{"JWT_SECRET", nil},
+	{"JWT_TTL", "24h"},
+
+	{"PII_KEYS", nil},
+	{"PII_ACTIVE_KEY_VERSION", 0}, // The highest version of PII_KEYS
+	{"PII_BLIND_INDEX_KEY", nil},
+
+	{"BUSINESS_TIMEZONE", "Asia/Jakarta"},
+
+	{"WITHDRAWAL_MAX_PER_TRANSACTION", "10000000"},
+	{"WITHDRAWAL_MAX_DAILY_TOTAL", "50000000"},
+	{"WITHDRAWAL_MAX_DAILY_COUNT", 20},
+
+	{"PIN_MAX_ATTEMPTS", 3},
+	{"PIN_HASH_COST", bcrypt.DefaultCost},
+
+	{"OUTBOX_PUBLISHER", publisher.KindStdout},
+	{"OUTBOX_FILE", "outbox.jsonl"},
+	{"OUTBOX_WEBHOOK_URL", nil},
+	{"OUTBOX_WEBHOOK_TIMEOUT", "10s"},
+	{"OUTBOX_POLL_INTERVAL", "1s"},
+	{"OUTBOX_RETRY_DELAY", "1s"},
+	{"OUTBOX_MAX_RETRY_DELAY", "5m"},
+	{"OUTBOX_BATCH_SIZE", 100},
+	{"OUTBOX_CLAIM_TIMEOUT", "5m"},
+
+	{"WEBHOOK_POLL_INTERVAL", "1s"},
+	{"WEBHOOK_TIMEOUT", "10s"},
+	{"WEBHOOK_RETRY_DELAY", "10s"},
+	{"WEBHOOK_MAX_RETRY_DELAY", "1h"},
+	{"WEBHOOK_MAX_ATTEMPTS", 10},
+	{"WEBHOOK_BATCH_SIZE", 50},
+	{"WEBHOOK_SECRET_GRACE_PERIOD", "24h"},
+
+	{"TRACING_EXPORTER", tracing.KindNone},
+	{"TRACING_OTLP_ENDPOINT", "http://localhost:4318"},
+	{"TRACING_SAMPLE_RATIO", 1},
+}
+
+// defaultConfigFile is the config file read when none is given. Unlike a
+// file given with -config, it may be missing.
+const defaultConfigFile = ".env"
+
+// fileSuffix ends the name of a key holding the path of a file to read the
+// value of the key from, as in DB_PASSWORD_FILE=/run/secrets/db_password.
+const fileSuffix = "_FILE"
+
+// Load loads the configuration from the config file, the environment and the
+// command line flags in args, each overriding the one before, and validates
+// it. It returns the arguments left after the flags, and an error listing
+// every invalid setting.
+//
+// Every key can be set by a flag named after it, such as -app-port for
+// APP_PORT, and read from a file named by the same key suffixed with _FILE.
+func Load(args []string) (*Config, []string, error) {
+	v := viper.New()
+	for _, d := range defaults {
+		if d.value != nil {
+			v.SetDefault(d.key, d.value)
+		}
+	}
+
+	flags := flag.NewFlagSet("account-service", flag.ContinueOnError)
+	configFile := flags.String("config", "", "config file in the .env format (default ./"+defaultConfigFile+" if present)")
+	set := map[string]string{}
+	for _, d := range defaults {
+		key := d.key
+		flags.Func(flagName(key), "overrides "+key, func(value string) error {
+			set[key] = value
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if err := readConfigFile(v, *configFile); err != nil {
+		return nil, nil, err
+	}
+	v.AutomaticEnv()
+	if err := readFiles(v, set); err != nil {
+		return nil, nil, err
+	}
+	for key, value := range set {
+		v.Set(key, value)
+	}
+
+	cfg, err := newConfig(&reader{v: v})
+	return cfg, flags.Args(), err
+}
+
+// flagName returns the name of the flag of key: APP_PORT is set by -app-port.
+func flagName(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "_", "-")
+}
+
+// readConfigFile reads path, or the default config file if path is empty.
+func readConfigFile(v *viper.Viper, path string) error {
+	if path == "" {
+		if _, err := os.Stat(defaultConfigFile); errors.Is(err, os.ErrNotExist) {
+			utils.Log.Info("No config file, reading the environment only")
+			return nil
+		}
+		path = defaultConfigFile
+	}
+
+	v.SetConfigFile(path)
+	v.SetConfigType("env")
+	if err := v.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	utils.Log.Infof("Config file loaded from %s", path)
+	return nil
+}
+
+// readFiles sets every key whose _FILE key is set to the content of the file
+// it names, without its trailing newline. A _FILE key ranks with the key
+// itself: set in the environment it overrides the config file, and setting
+// both where one would override the other is an error. Keys set by a flag are
+// left alone.
+func readFiles(v *viper.Viper, set map[string]string) error {
+	var errs []error
+	for _, d := range defaults {
+		fileKey := d.key + fileSuffix
+		if _, ok := set[d.key]; ok || !v.IsSet(fileKey) {
+			continue
+		}
+
+		inEnv, fileKeyInEnv := os.Getenv(d.key) != "", os.Getenv(fileKey) != ""
+		switch {
+		case inEnv && fileKeyInEnv:
+			errs = append(errs, fmt.Errorf("%s and %s are both set in the environment", d.key, fileKey))
+			continue
+		case inEnv:
+			continue // The environment overrides the config file
+		case !fileKeyInEnv && v.InConfig(d.key):
+			errs = append(errs, fmt.Errorf("%s and %s are both set in the config file", d.key, fileKey))
+			continue
+		}
+
+		content, err := os.ReadFile(v.GetString(fileKey))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", fileKey, err))
+			continue
+		}
+		v.Set(d.key, strings.TrimRight(string(content), "\r\n"))
+	}
+	return errors.Join(errs...)
+}
+
+// newConfig reads the configuration through r and validates it.
+func newConfig(r *reader) (*Config, error) {
+	cfg := &Config{}
+
+	// server config
+	appEnv := r.string("APP_ENV")
+	if appEnv != "dev" && appEnv != "prod" {
+		r.invalid("APP_ENV", "must be dev or prod, not %q", appEnv)
+	}
+	cfg.IsProd = appEnv == "prod"
+	cfg.AppName = r.string("APP_NAME")
+	cfg.AppHost = r.string("APP_HOST")
+	cfg.AppPort = r.port("APP_PORT")
+
+	// log config, JSON by default in prod
+	cfg.LogFormat = r.string("LOG_FORMAT")
+	if cfg.LogFormat == "" {
+		cfg.LogFormat = utils.LogFormatText
+		if cfg.IsProd {
+			cfg.LogFormat = utils.LogFormatJSON
+		}
+	}
+	if cfg.LogFormat != utils.LogFormatText && cfg.LogFormat != utils.LogFormatJSON {
+		r.invalid("LOG_FORMAT", "must be %s or %s, not %q", utils.LogFormatText, utils.LogFormatJSON, cfg.LogFormat)
+	}
+	level, err := logrus.ParseLevel(r.string("LOG_LEVEL"))
+	if err != nil {
+		r.invalid("LOG_LEVEL", "%v", err)
+	}
+	cfg.LogLevel = level
+
+	// db config, DB_PATH is only used by the sqlite driver and the others only
+	// by the postgres driver
+	cfg.DBDriver = r.string("DB_DRIVER")
+	switch cfg.DBDriver {
+	case "sqlite":
+		cfg.DBPath = r.required("DB_PATH")
+	case "postgres":
+		cfg.DBHost = r.required("DB_HOST")
+		cfg.DBUser = r.required("DB_USER")
+		cfg.DBPassword = r.string("DB_PASSWORD")
+		cfg.DBName = r.required("DB_NAME")
+		cfg.DBPort = r.port("DB_PORT")
+	default:
+		r.invalid("DB_DRIVER", "must be postgres or sqlite, not %q", cfg.DBDriver)
 	}
+	cfg.DBAutoMigrate = r.bool("DB_AUTO_MIGRATE")
+	cfg.DBSlowQueryThreshold = r.duration("DB_SLOW_QUERY_THRESHOLD", 0)
+
+	// idempotency config
+	cfg.IdempotencyKeyTTL = r.duration("IDEMPOTENCY_KEY_TTL", time.Nanosecond)
+	cfg.IdempotencyKeyPurgeInterval = r.duration("IDEMPOTENCY_KEY_PURGE_INTERVAL", time.Nanosecond)
 
-	for _, path := range configPaths {
-		viper.SetConfigFile(path + ".env")
+	// jwt config
+	cfg.JWTSecret = r.required("JWT_SECRET")
+	cfg.JWTTTL = r.duration("JWT_TTL", time.Nanosecond)
 
-		if err := viper.ReadInConfig(); err == nil {
-			utils.Log.Infof("Config file loaded from %s", path)
-			return
+	// pii config
+	if keys := r.required("PII_KEYS"); keys != "" {
+		if cfg.PIIKeys, err = pii.ParseKeys(keys); err != nil {
+			r.invalid("PII_KEYS", "%v", err)
+		}
+	}
+	if indexKey := r.required("PII_BLIND_INDEX_KEY"); indexKey != "" {
+		if cfg.PIIBlindIndexKey, err = base64.StdEncoding.DecodeString(indexKey); err != nil {
+			r.invalid("PII_BLIND_INDEX_KEY", "must be base64: %v", err)
 		}
 	}
+	if activeVersion := r.int("PII_ACTIVE_KEY_VERSION"); cfg.PIIKeys != nil && cfg.PIIBlindIndexKey != nil {
+		if cfg.PIIKeyring, err = pii.NewKeyring(cfg.PIIKeys, activeVersion, cfg.PIIBlindIndexKey); err != nil {
+			r.invalid("PII_KEYS", "%v", err)
+		}
+	}
+
+	// business config
+	location, err := time.LoadLocation(r.string("BUSINESS_TIMEZONE"))
+	if err != nil {
+		r.invalid("BUSINESS_TIMEZONE", "%v", err)
+	}
+	cfg.BusinessLocation = location
+
+	// withdrawal limit config, 0 disables a limit
+	cfg.WithdrawalLimits = model.WithdrawalLimits{
+		MaxPerTransaction: r.money("WITHDRAWAL_MAX_PER_TRANSACTION"),
+		MaxDailyTotal:     r.money("WITHDRAWAL_MAX_DAILY_TOTAL"),
+		MaxDailyCount:     r.atLeast("WITHDRAWAL_MAX_DAILY_COUNT", 0),
+	}
+
+	// pin config, 0 attempts never locks a PIN
+	cfg.PINPolicy = model.PINPolicy{
+		MaxAttempts: r.atLeast("PIN_MAX_ATTEMPTS", 0),
+		HashCost:    r.int("PIN_HASH_COST"),
+	}
+	if cfg.PINPolicy.HashCost < bcrypt.MinCost || cfg.PINPolicy.HashCost > bcrypt.MaxCost {
+		r.invalid("PIN_HASH_COST", "must be between %d and %d, not %d", bcrypt.MinCost, bcrypt.MaxCost, cfg.PINPolicy.HashCost)
+	}
+
+	// outbox config, "none" publishes events to webhook subscriptions only
+	cfg.OutboxPublisher = r.string("OUTBOX_PUBLISHER")
+	switch cfg.OutboxPublisher {
+	case "none", publisher.KindStdout:
+	case publisher.KindFile:
+		cfg.OutboxFile = r.required("OUTBOX_FILE")
+	case publisher.KindWebhook:
+		cfg.OutboxWebhookURL = r.required("OUTBOX_WEBHOOK_URL")
+		cfg.OutboxWebhookTimeout = r.duration("OUTBOX_WEBHOOK_TIMEOUT", time.Nanosecond)
+	default:
+		r.invalid("OUTBOX_PUBLISHER", "must be %s, %s, %s or none, not %q", publisher.KindStdout, publisher.KindFile, publisher.KindWebhook, cfg.OutboxPublisher)
+	}
+	cfg.OutboxPolicy = model.OutboxPolicy{
+		PollInterval:  r.duration("OUTBOX_POLL_INTERVAL", time.Nanosecond),
+		RetryDelay:    r.duration("OUTBOX_RETRY_DELAY", time.Nanosecond),
+		MaxRetryDelay: r.duration("OUTBOX_MAX_RETRY_DELAY", time.Nanosecond),
+		BatchSize:     r.atLeast("OUTBOX_BATCH_SIZE", 1),
+		ClaimTimeout:  r.duration("OUTBOX_CLAIM_TIMEOUT", time.Nanosecond),
+	}
+	if cfg.OutboxPolicy.MaxRetryDelay < cfg.OutboxPolicy.RetryDelay {
+		r.invalid("OUTBOX_MAX_RETRY_DELAY", "must not be under OUTBOX_RETRY_DELAY")
+	}
+
+	// webhook subscription config
+	cfg.WebhookPolicy = model.WebhookPolicy{
+		PollInterval:      r.duration("WEBHOOK_POLL_INTERVAL", time.Nanosecond),
+		Timeout:           r.duration("WEBHOOK_TIMEOUT", time.Nanosecond),
+		RetryDelay:        r.duration("WEBHOOK_RETRY_DELAY", time.Nanosecond),
+		MaxRetryDelay:     r.duration("WEBHOOK_MAX_RETRY_DELAY", time.Nanosecond),
+		MaxAttempts:       r.atLeast("WEBHOOK_MAX_ATTEMPTS", 1),
+		BatchSize:         r.atLeast("WEBHOOK_BATCH_SIZE", 1),
+		SecretGracePeriod: r.duration("WEBHOOK_SECRET_GRACE_PERIOD", 0),
+	}
+	if cfg.WebhookPolicy.MaxRetryDelay < cfg.WebhookPolicy.RetryDelay {
+		r.invalid("WEBHOOK_MAX_RETRY_DELAY", "must not be under WEBHOOK_RETRY_DELAY")
+	}
+
+	// tracing config, "none" still continues the traces of callers
+	cfg.TracingExporter = r.string("TRACING_EXPORTER")
+	switch cfg.TracingExporter {
+	case tracing.KindNone, tracing.KindStdout:
+	case tracing.KindOTLP:
+		cfg.TracingOTLPEndpoint = r.required("TRACING_OTLP_ENDPOINT")
+	default:
+		r.invalid("TRACING_EXPORTER", "must be %s, %s or %s, not %q", tracing.KindOTLP, tracing.KindStdout, tracing.KindNone, cfg.TracingExporter)
+	}
+	cfg.TracingSampleRatio = r.float("TRACING_SAMPLE_RATIO")
+	if cfg.TracingSampleRatio < 0 || cfg.TracingSampleRatio > 1 {
+		r.invalid("TRACING_SAMPLE_RATIO", "must be between 0 and 1, not %v", cfg.TracingSampleRatio)
+	}
+
+	if len(r.errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(r.errs...))
+	}
+	return cfg, nil
+}
+
+// reader reads typed values of the configuration, collecting an error for
+// each invalid one rather than stopping at the first.
+type reader struct {
+	v    *viper.Viper
+	errs []error
+}
+
+func (r *reader) invalid(key, format string, args ...any) {
+	r.errs = append(r.errs, fmt.Errorf("%s %s", key, fmt.Sprintf(format, args...)))
+}
 
-	utils.Log.Error("Failed to load any config file")
+// string returns the value of key without surrounding spaces, which a
+// Makefile including the config file keeps before a trailing comment.
+func (r *reader) string(key string) string {
+	return strings.TrimSpace(r.v.GetString(key))
 }
 
-func init() {
-	loadConfig()
+func (r *reader) required(key string) string {
+	value := r.string(key)
+	if value == "" {
+		r.invalid(key, "must be set")
+	}
+	return value
+}
 
-	// server confi
-	IsProd = viper.GetString("APP_ENV") == "prod"
-	AppName = viper.GetString("APP_NAME")
-	AppHost = viper.GetString("APP_HOST")
-	AppPort = viper.GetInt("APP_PORT")
+func (r *reader) int(key string) int {
+	value, _ := r.parseInt(key)
+	return value
+}
 
-	// db config
-	DBHost = viper.GetString("DB_HOST")
-	DBUser = viper.GetString("DB_USER")
-	DBPassword = viper.GetString("DB_PASSWORD")
-	DBName = viper.GetString("DB_NAME")
-	DBPort = viper.GetInt("DB_PORT")
+// parseInt returns the integer of key, and whether it is one.
+func (r *reader) parseInt(key string) (int, bool) {
+	value, err := strconv.Atoi(r.string(key))
+	if err != nil {
+		r.invalid(key, "must be an integer, not %q", r.string(key))
+		return 0, false
+	}
+	return value, true
+}
+
+func (r *reader) atLeast(key string, min int) int {
+	value, ok := r.parseInt(key)
+	if ok && value < min {
+		r.invalid(key, "must be at least %d, not %d", min, value)
+	}
+	return value
+}
+
+func (r *reader) port(key string) int {
+	value, ok := r.parseInt(key)
+	if ok && (value < 1 || value > 65535) {
+		r.invalid(key, "must be a port between 1 and 65535, not %d", value)
+	}
+	return value
+}
+
+func (r *reader) bool(key string) bool {
+	value, err := strconv.ParseBool(r.string(key))
+	if err != nil {
+		r.invalid(key, "must be true or false, not %q", r.string(key))
+	}
+	return value
+}
+
+func (r *reader) float(key string) float64 {
+	value, err := strconv.ParseFloat(r.string(key), 64)
+	if err != nil {
+		r.invalid(key, "must be a number, not %q", r.string(key))
+	}
+	return value
+}
+
+// duration returns the duration of key, such as 10s or 24h, which must be at
+// least min.
+func (r *reader) duration(key string, min time.Duration) time.Duration {
+	value, err := time.ParseDuration(r.string(key))
+	if err != nil {
+		r.invalid(key, "must be a duration such as 10s, not %q", r.string(key))
+		return 0
+	}
+	if value < min {
+		if min == time.Nanosecond {
+			r.invalid(key, "must be positive, not %s", value)
+		} else {
+			r.invalid(key, "must be at least %s, not %s", min, value)
+		}
+	}
+	return value
+}
+
+func (r *reader) money(key string) model.Money {
+	amount, err := model.ParseMoney(r.string(key))
+	if err != nil || amount.IsNegative() {
+		r.invalid(key, "must be an amount of at least 0, not %q", r.string(key))
+	}
+	return amount
 }
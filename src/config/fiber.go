@@ -7,12 +7,13 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func FiberConfig() fiber.Config {
+// FiberConfig returns the config of the Fiber app, which preforks in prod.
+func (cfg *Config) FiberConfig() fiber.Config {
 	return fiber.Config{
-		Prefork:       IsProd,
+		Prefork:       cfg.IsProd,
 		CaseSensitive: true,
 		ServerHeader:  "Fiber",
-		AppName:       AppName,
+		AppName:       cfg.AppName,
 		ErrorHandler:  utils.ErrorHandler,
 		JSONEncoder:   sonic.Marshal,
 		JSONDecoder:   sonic.Unmarshal,
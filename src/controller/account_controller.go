@@ -1,9 +1,11 @@
 package controller
 
 import (
+	"account-service/src/middleware"
 	"account-service/src/model"
 	"account-service/src/response"
 	"account-service/src/service"
+	"context"
 	"strconv"
 
 	"github.com/go-playground/validator/v10"
@@ -13,19 +15,34 @@ import (
 
 type AccountController struct {
 	AccountService service.AccountServices
+	JWT            *middleware.JWT
 	Validator      *validator.Validate
 }
 
-func NewAccountController(accountService service.AccountServices, validator *validator.Validate) *AccountController {
+func NewAccountController(accountService service.AccountServices, jwt *middleware.JWT, validator *validator.Validate) *AccountController {
 	return &AccountController{
 		AccountService: accountService,
+		JWT:            jwt,
 		Validator:      validator,
 	}
 }
 
+// authorizeAccount rejects a request acting on an account the caller may not
+// touch. Customers may only act on their own account.
+func authorizeAccount(c *fiber.Ctx, accountNumber string) *fiber.Error {
+	claims := middleware.CurrentClaims(c)
+	if claims == nil {
+		return fiber.NewError(fiber.StatusUnauthorized, middleware.ErrUnauthenticated.Error())
+	}
+	if !claims.CanAccessAccount(accountNumber) {
+		return fiber.NewError(fiber.StatusForbidden, middleware.ErrForbidden.Error())
+	}
+	return nil
+}
+
 // @Tags         Accounts
 // @Summary      Register a new customer (Nasabah)
-// @Description  API for registering a new customer.
+// @Description  API for registering a new customer. The response carries a customer token for the new account, and the ID number and phone number masked.
 // @Accept       json
 // @Produce      json
 // @Param        request  body  model.CreateAccount  true  "Request body"
@@ -36,19 +53,25 @@ func NewAccountController(accountService service.AccountServices, validator *val
 func (accountController *AccountController) Register(c *fiber.Ctx) error {
 	req := new(model.CreateAccount)
 	if err := c.BodyParser(req); err != nil {
-		return response.ErrorCustom(c, fiber.StatusBadRequest, err.Error(), "Invalid request body")
+		// The parser error may quote the body, which carries the PIN.
+		return response.ErrorCustom(c, fiber.StatusBadRequest, "Invalid request body", nil)
 	}
 
-	account, err := accountController.AccountService.CreateAccount(c.Context(), req)
+	account, err := accountController.AccountService.CreateAccount(c.UserContext(), req)
 	if err != nil {
 		return response.Error(c, err, nil)
 	}
 
+	token, tokenErr := accountController.JWT.Issue(account.AccountNumber, middleware.RoleCustomer)
+	if tokenErr != nil {
+		return response.ErrorCustom(c, fiber.StatusInternalServerError, "Failed to issue token", nil)
+	}
+
 	return c.Status(fiber.StatusCreated).JSON(response.SuccessWithData{
 		Code:    fiber.StatusCreated,
 		Status:  "success",
 		Message: "Account registration successful",
-		Data:    account,
+		Data:    model.RegisterResponse{Account: account.Masked(), Token: token},
 	})
 }
 
@@ -57,9 +80,13 @@ func (accountController *AccountController) Register(c *fiber.Ctx) error {
 // @Description  API for depositing money into an account.
 // @Accept       json
 // @Produce      json
+// @Security     BearerAuth
 // @Param        request  body  model.DepositRequest  true  "Request body"
+// @Param        Idempotency-Key  header  string  false  "Key that makes retries of this request safe"
 // @Success      200  {object}  response.SuccessWithData
 // @Failure      400  {object}  response.ErrorDetails
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
 // @Failure      404  {object}  response.ErrorDetails
 // @Router       /tabung [post]
 func (accountController *AccountController) Deposit(c *fiber.Ctx) error {
@@ -68,12 +95,16 @@ func (accountController *AccountController) Deposit(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	err := accountController.AccountService.Deposit(c.Context(), req)
+	if err := authorizeAccount(c, req.AccountNumber); err != nil {
+		return response.Error(c, err, nil)
+	}
+
+	err := accountController.AccountService.Deposit(c.UserContext(), req)
 	if err != nil {
 		return response.Error(c, err, nil)
 	}
 
-	account, err := accountController.AccountService.GetBalance(c.Context(), req.AccountNumber)
+	account, err := accountController.AccountService.GetBalance(c.UserContext(), req.AccountNumber)
 	if err != nil {
 		return response.Error(c, err, nil)
 	}
@@ -88,13 +119,19 @@ func (accountController *AccountController) Deposit(c *fiber.Ctx) error {
 
 // @Tags         Accounts
 // @Summary      Withdraw from an account (Tarik)
-// @Description  API for withdrawing money from an account.
+// @Description  API for withdrawing money from an account. The account's transaction PIN is required; after too many wrong PINs it locks until reset.
 // @Accept       json
 // @Produce      json
+// @Security     BearerAuth
 // @Param        request  body  model.Withdrawal  true  "Request body"
+// @Param        Idempotency-Key  header  string  false  "Key that makes retries of this request safe"
 // @Success      200  {object}  response.SuccessWithData
 // @Failure      400  {object}  response.ErrorDetails
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
 // @Failure      404  {object}  response.ErrorDetails
+// @Failure      422  {object}  response.ErrorDetails
+// @Failure      423  {object}  response.ErrorDetails
 // @Router       /tarik [post]
 func (accountController *AccountController) Withdrawal(c *fiber.Ctx) error {
 	req := new(model.Withdrawal)
@@ -102,12 +139,16 @@ func (accountController *AccountController) Withdrawal(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	err := accountController.AccountService.Withdraw(c.Context(), req)
+	if err := authorizeAccount(c, req.AccountNumber); err != nil {
+		return response.Error(c, err, nil)
+	}
+
+	err := accountController.AccountService.Withdraw(c.UserContext(), req)
 	if err != nil {
 		return response.Error(c, err, nil)
 	}
 
-	account, err := accountController.AccountService.GetBalance(c.Context(), req.AccountNumber)
+	account, err := accountController.AccountService.GetBalance(c.UserContext(), req.AccountNumber)
 	if err != nil {
 		return response.Error(c, err, nil)
 	}
@@ -124,9 +165,12 @@ func (accountController *AccountController) Withdrawal(c *fiber.Ctx) error {
 // @Summary      Get account balance (Saldo)
 // @Description  API for checking the balance of an account.
 // @Produce      json
+// @Security     BearerAuth
 // @Param        accountNumber  path  string  true  "Account number"
 // @Success      200  {object}  response.SuccessWithData
 // @Failure      400  {object}  response.ErrorDetails
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
 // @Failure      404  {object}  response.ErrorDetails
 // @Router       /saldo/{accountNumber} [get]
 func (accountController *AccountController) GetBalance(c *fiber.Ctx) error {
@@ -137,7 +181,11 @@ func (accountController *AccountController) GetBalance(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid account number")
 	}
 
-	account, err := accountController.AccountService.GetBalance(c.Context(), accountNumber)
+	if err := authorizeAccount(c, accountNumber); err != nil {
+		return response.Error(c, err, nil)
+	}
+
+	account, err := accountController.AccountService.GetBalance(c.UserContext(), accountNumber)
 	if err != nil {
 		return response.Error(c, err, nil)
 	}
@@ -149,3 +197,366 @@ func (accountController *AccountController) GetBalance(c *fiber.Ctx) error {
 		Data:    map[string]interface{}{"saldo": account.Balance},
 	})
 }
+
+// @Tags         Accounts
+// @Summary      Get account transaction history (Mutasi)
+// @Description  API for listing the cash activities of an account for a month or a date range, oldest first.
+// @Produce      json
+// @Security     BearerAuth
+// @Param        no_rekening  query  string  true   "Account number"
+// @Param        bulan        query  int     false  "Month (1-12), required when dari is empty"
+// @Param        tahun        query  int     false  "Year of the month, defaults to the current year"
+// @Param        dari         query  string  false  "Start date (YYYY-MM-DD), required when bulan is empty"
+// @Param        sampai       query  string  false  "End date (YYYY-MM-DD), inclusive"
+// @Param        page         query  int     false  "Page number"     default(1)
+// @Param        limit        query  int     false  "Items per page"  default(10)
+// @Success      200  {object}  response.SuccessWithPaginate
+// @Failure      400  {object}  response.ErrorDetails
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
+// @Failure      404  {object}  response.ErrorDetails
+// @Router       /mutasi [get]
+func (accountController *AccountController) GetMutations(c *fiber.Ctx) error {
+	req := new(model.Mutation)
+	if err := c.QueryParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid query parameters")
+	}
+
+	if err := authorizeAccount(c, req.AccountNumber); err != nil {
+		return response.Error(c, err, nil)
+	}
+
+	activities, total, err := accountController.AccountService.GetMutations(c.UserContext(), req)
+	if err != nil {
+		return response.Error(c, err, nil)
+	}
+
+	data := make([]any, len(activities))
+	for i := range activities {
+		data[i] = activities[i]
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.SuccessWithPaginate{
+		Code:       fiber.StatusOK,
+		Status:     "success",
+		Message:    "Get mutations successful",
+		Data:       data,
+		Page:       req.Page,
+		Limit:      req.Limit,
+		TotalPages: (total + int64(req.Limit) - 1) / int64(req.Limit),
+		Count:      total,
+	})
+}
+
+// @Tags         Accounts
+// @Summary      Transfer between accounts (Transfer)
+// @Description  API for moving money from one account to another in a single transaction. The transfer counts towards the withdrawal limits of the source account.
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body  model.TransferRequest  true  "Request body"
+// @Param        Idempotency-Key  header  string  false  "Key that makes retries of this request safe"
+// @Success      200  {object}  response.SuccessWithData
+// @Failure      400  {object}  response.ErrorDetails
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
+// @Failure      404  {object}  response.ErrorDetails
+// @Failure      422  {object}  response.ErrorDetails
+// @Failure      423  {object}  response.ErrorDetails
+// @Router       /transfer [post]
+func (accountController *AccountController) Transfer(c *fiber.Ctx) error {
+	req := new(model.TransferRequest)
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	// Only the source account has to belong to the caller.
+	if err := authorizeAccount(c, req.FromAccountNumber); err != nil {
+		return response.Error(c, err, nil)
+	}
+
+	transfer, err := accountController.AccountService.Transfer(c.UserContext(), req)
+	if err != nil {
+		return response.Error(c, err, nil)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.SuccessWithData{
+		Code:    fiber.StatusOK,
+		Status:  "success",
+		Message: "Transfer successful",
+		Data:    transfer,
+	})
+}
+
+// @Tags         Accounts
+// @Summary      Reverse a transaction (Reversal)
+// @Description  API for undoing a deposit or withdrawal by posting a compensating cash activity of the opposite type.
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path  int                    true  "Cash activity ID"
+// @Param        request  body  model.ReversalRequest  true  "Request body"
+// @Param        Idempotency-Key  header  string  false  "Key that makes retries of this request safe"
+// @Success      200  {object}  response.SuccessWithData
+// @Failure      400  {object}  response.ErrorDetails
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
+// @Failure      404  {object}  response.ErrorDetails
+// @Failure      409  {object}  response.ErrorDetails
+// @Failure      422  {object}  response.ErrorDetails
+// @Router       /transaksi/{id}/reversal [post]
+func (accountController *AccountController) Reversal(c *fiber.Ctx) error {
+	activityID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid transaction ID")
+	}
+
+	req := new(model.ReversalRequest)
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	reversal, fiberErr := accountController.AccountService.Reverse(c.UserContext(), uint(activityID), req)
+	if fiberErr != nil {
+		return response.Error(c, fiberErr, nil)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.SuccessWithData{
+		Code:    fiber.StatusOK,
+		Status:  "success",
+		Message: "Reversal successful",
+		Data:    reversal,
+	})
+}
+
+// @Tags         Accounts
+// @Summary      Change or reset the transaction PIN
+// @Description  API for replacing the PIN of an account and lifting its lockout. Customers must give their current PIN; tellers and admins reset a forgotten or locked PIN without it.
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body  model.PINResetRequest  true  "Request body"
+// @Success      200  {object}  response.Common
+// @Failure      400  {object}  response.ErrorDetails
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
+// @Failure      404  {object}  response.ErrorDetails
+// @Failure      410  {object}  response.ErrorDetails
+// @Failure      423  {object}  response.ErrorDetails
+// @Router       /pin [put]
+func (accountController *AccountController) ResetPIN(c *fiber.Ctx) error {
+	req := new(model.PINResetRequest)
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := authorizeAccount(c, req.AccountNumber); err != nil {
+		return response.Error(c, err, nil)
+	}
+	req.RequireOldPIN = middleware.CurrentClaims(c).Role == middleware.RoleCustomer
+
+	if err := accountController.AccountService.ResetPIN(c.UserContext(), req); err != nil {
+		return response.Error(c, err, nil)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.Common{
+		Code:    fiber.StatusOK,
+		Status:  "success",
+		Message: "PIN reset successful",
+	})
+}
+
+// @Tags         Admin
+// @Summary      Verify an account ledger
+// @Description  API for walking the hash chain of an account's cash activities and reporting the first broken link or balance discontinuity.
+// @Produce      json
+// @Security     BearerAuth
+// @Param        accountNumber  path  string  true  "Account number"
+// @Success      200  {object}  response.SuccessWithData
+// @Failure      400  {object}  response.ErrorDetails
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
+// @Failure      404  {object}  response.ErrorDetails
+// @Router       /admin/ledger/{accountNumber}/verify [get]
+func (accountController *AccountController) VerifyLedger(c *fiber.Ctx) error {
+	accountNumber := c.Params("accountNumber")
+
+	if _, err := strconv.ParseUint(accountNumber, 10, 64); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid account number")
+	}
+
+	verification, err := accountController.AccountService.VerifyLedger(c.UserContext(), accountNumber)
+	if err != nil {
+		return response.Error(c, err, nil)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.SuccessWithData{
+		Code:    fiber.StatusOK,
+		Status:  "success",
+		Message: "Ledger verification completed",
+		Data:    verification,
+	})
+}
+
+// @Tags         Admin
+// @Summary      Freeze an account
+// @Description  API for stopping all money movement on an active or dormant account.
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        accountNumber  path  string                      true  "Account number"
+// @Param        request        body  model.AccountStatusRequest  true  "Request body"
+// @Success      200  {object}  response.SuccessWithData
+// @Failure      400  {object}  response.ErrorDetails
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
+// @Failure      404  {object}  response.ErrorDetails
+// @Failure      409  {object}  response.ErrorDetails
+// @Router       /admin/rekening/{accountNumber}/freeze [post]
+func (accountController *AccountController) Freeze(c *fiber.Ctx) error {
+	return accountController.changeStatus(c, accountController.AccountService.Freeze, "Account frozen")
+}
+
+// @Tags         Admin
+// @Summary      Unfreeze an account
+// @Description  API for returning a frozen or dormant account to active.
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        accountNumber  path  string                      true  "Account number"
+// @Param        request        body  model.AccountStatusRequest  true  "Request body"
+// @Success      200  {object}  response.SuccessWithData
+// @Failure      400  {object}  response.ErrorDetails
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
+// @Failure      404  {object}  response.ErrorDetails
+// @Failure      409  {object}  response.ErrorDetails
+// @Router       /admin/rekening/{accountNumber}/unfreeze [post]
+func (accountController *AccountController) Unfreeze(c *fiber.Ctx) error {
+	return accountController.changeStatus(c, accountController.AccountService.Unfreeze, "Account unfrozen")
+}
+
+// @Tags         Admin
+// @Summary      Close an account
+// @Description  API for permanently closing an account whose balance is zero.
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        accountNumber  path  string                      true  "Account number"
+// @Param        request        body  model.AccountStatusRequest  true  "Request body"
+// @Success      200  {object}  response.SuccessWithData
+// @Failure      400  {object}  response.ErrorDetails
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
+// @Failure      404  {object}  response.ErrorDetails
+// @Failure      409  {object}  response.ErrorDetails
+// @Failure      422  {object}  response.ErrorDetails
+// @Router       /admin/rekening/{accountNumber}/close [post]
+func (accountController *AccountController) Close(c *fiber.Ctx) error {
+	return accountController.changeStatus(c, accountController.AccountService.Close, "Account closed")
+}
+
+// changeStatus handles the account status endpoints, which differ only in
+// the service method they call.
+func (accountController *AccountController) changeStatus(c *fiber.Ctx, change func(context.Context, string, *model.AccountStatusRequest) (*model.Account, *fiber.Error), message string) error {
+	accountNumber := c.Params("accountNumber")
+
+	if _, err := strconv.ParseUint(accountNumber, 10, 64); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid account number")
+	}
+
+	req := new(model.AccountStatusRequest)
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	// The transition is recorded against the authenticated caller.
+	claims := middleware.CurrentClaims(c)
+	if claims == nil {
+		return response.ErrorCustom(c, fiber.StatusUnauthorized, middleware.ErrUnauthenticated.Error(), nil)
+	}
+	req.Actor = claims.Subject
+
+	account, err := change(c.UserContext(), accountNumber, req)
+	if err != nil {
+		return response.Error(c, err, nil)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.SuccessWithData{
+		Code:    fiber.StatusOK,
+		Status:  "success",
+		Message: message,
+		Data:    model.AccountStatusResponse{AccountNumber: account.AccountNumber, Status: account.Status},
+	})
+}
+
+// @Tags         Admin
+// @Summary      Get the withdrawal limits of an account
+// @Description  API for showing the withdrawal limits in force for an account, with its overrides applied to the global defaults. A limit of 0 is disabled.
+// @Produce      json
+// @Security     BearerAuth
+// @Param        accountNumber  path  string  true  "Account number"
+// @Success      200  {object}  response.SuccessWithData
+// @Failure      400  {object}  response.ErrorDetails
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
+// @Failure      404  {object}  response.ErrorDetails
+// @Router       /admin/rekening/{accountNumber}/limit [get]
+func (accountController *AccountController) GetWithdrawalLimits(c *fiber.Ctx) error {
+	accountNumber := c.Params("accountNumber")
+
+	if _, err := strconv.ParseUint(accountNumber, 10, 64); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid account number")
+	}
+
+	limits, err := accountController.AccountService.GetWithdrawalLimits(c.UserContext(), accountNumber)
+	if err != nil {
+		return response.Error(c, err, nil)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.SuccessWithData{
+		Code:    fiber.StatusOK,
+		Status:  "success",
+		Message: "Get withdrawal limits successful",
+		Data:    limits,
+	})
+}
+
+// @Tags         Admin
+// @Summary      Override the withdrawal limits of an account
+// @Description  API for replacing the withdrawal limit overrides of an account. A limit left empty falls back to the global default.
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        accountNumber  path  string                        true  "Account number"
+// @Param        request        body  model.WithdrawalLimitRequest  true  "Request body"
+// @Success      200  {object}  response.SuccessWithData
+// @Failure      400  {object}  response.ErrorDetails
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
+// @Failure      404  {object}  response.ErrorDetails
+// @Router       /admin/rekening/{accountNumber}/limit [put]
+func (accountController *AccountController) SetWithdrawalLimits(c *fiber.Ctx) error {
+	accountNumber := c.Params("accountNumber")
+
+	if _, err := strconv.ParseUint(accountNumber, 10, 64); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid account number")
+	}
+
+	req := new(model.WithdrawalLimitRequest)
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	limits, err := accountController.AccountService.SetWithdrawalLimits(c.UserContext(), accountNumber, req)
+	if err != nil {
+		return response.Error(c, err, nil)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.SuccessWithData{
+		Code:    fiber.StatusOK,
+		Status:  "success",
+		Message: "Withdrawal limits updated",
+		Data:    limits,
+	})
+}
@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"account-service/src/middleware"
+	"account-service/src/model"
+	"account-service/src/response"
+	"account-service/src/service"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type AuthController struct {
+	AccountService service.AccountServices
+	JWT            *middleware.JWT
+	Validator      *validator.Validate
+}
+
+func NewAuthController(accountService service.AccountServices, jwt *middleware.JWT, validator *validator.Validate) *AuthController {
+	return &AuthController{
+		AccountService: accountService,
+		JWT:            jwt,
+		Validator:      validator,
+	}
+}
+
+// @Tags         Admin
+// @Summary      Issue an access token
+// @Description  API for issuing a token to a teller, an admin, or the customer owning an account. A customer token's subject is the account number.
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body  model.TokenRequest  true  "Request body"
+// @Success      201  {object}  response.SuccessWithData
+// @Failure      400  {object}  response.ErrorDetails
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
+// @Failure      404  {object}  response.ErrorDetails
+// @Router       /admin/token [post]
+func (authController *AuthController) IssueToken(c *fiber.Ctx) error {
+	req := new(model.TokenRequest)
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := authController.Validator.Struct(req); err != nil {
+		return response.ErrorCustom(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
+
+	// A customer token is only issued for an open account.
+	if req.Role == middleware.RoleCustomer {
+		if _, err := authController.AccountService.GetBalance(c.UserContext(), req.Subject); err != nil {
+			return response.Error(c, err, nil)
+		}
+	}
+
+	token, err := authController.JWT.Issue(req.Subject, req.Role)
+	if err != nil {
+		return response.ErrorCustom(c, fiber.StatusInternalServerError, "Failed to issue token", nil)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response.SuccessWithData{
+		Code:    fiber.StatusCreated,
+		Status:  "success",
+		Message: "Token issued",
+		Data:    token,
+	})
+}
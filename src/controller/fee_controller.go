@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"account-service/src/model"
+	"account-service/src/response"
+	"account-service/src/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type FeeController struct {
+	FeeService service.FeeService
+}
+
+func NewFeeController(feeService service.FeeService) *FeeController {
+	return &FeeController{
+		FeeService: feeService,
+	}
+}
+
+// @Tags         Admin
+// @Summary      List the fee schedules of a product
+// @Description  API for listing every version of the fee rules of a product, oldest first. The version in force on a day is the latest one effective on or before it.
+// @Produce      json
+// @Security     BearerAuth
+// @Param        code  path  string  true  "Product code"
+// @Success      200  {object}  response.SuccessWithData
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
+// @Failure      404  {object}  response.ErrorDetails
+// @Router       /admin/produk/{code}/biaya [get]
+func (feeController *FeeController) ListFeeSchedules(c *fiber.Ctx) error {
+	schedules, err := feeController.FeeService.ListFeeSchedules(c.UserContext(), c.Params("code"))
+	if err != nil {
+		return response.Error(c, err, nil)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.SuccessWithData{
+		Code:    fiber.StatusOK,
+		Status:  "success",
+		Message: "Get fee schedules successful",
+		Data:    schedules,
+	})
+}
+
+// @Tags         Admin
+// @Summary      Add a fee schedule version to a product
+// @Description  API for changing a fee rule of a product from a date on, which must not be before today. Earlier versions and the fees charged under them are kept.
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        code     path  string                    true  "Product code"
+// @Param        request  body  model.FeeScheduleRequest  true  "Request body"
+// @Success      201  {object}  response.SuccessWithData
+// @Failure      400  {object}  response.ErrorDetails
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
+// @Failure      404  {object}  response.ErrorDetails
+// @Failure      409  {object}  response.ErrorDetails
+// @Failure      422  {object}  response.ErrorDetails
+// @Router       /admin/produk/{code}/biaya [post]
+func (feeController *FeeController) CreateFeeSchedule(c *fiber.Ctx) error {
+	req := new(model.FeeScheduleRequest)
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	schedule, err := feeController.FeeService.CreateFeeSchedule(c.UserContext(), c.Params("code"), req)
+	if err != nil {
+		return response.Error(c, err, nil)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response.SuccessWithData{
+		Code:    fiber.StatusCreated,
+		Status:  "success",
+		Message: "Fee schedule created",
+		Data:    schedule,
+	})
+}
@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"account-service/src/model"
+	"account-service/src/response"
+	"account-service/src/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type GeneralLedgerController struct {
+	GeneralLedgerService service.GeneralLedgerService
+}
+
+func NewGeneralLedgerController(generalLedgerService service.GeneralLedgerService) *GeneralLedgerController {
+	return &GeneralLedgerController{
+		GeneralLedgerService: generalLedgerService,
+	}
+}
+
+// @Tags         Admin
+// @Summary      Get the trial balance
+// @Description  API for listing the balance of every general ledger account at the end of a business day. Debit and credit totals are equal while every journal balances.
+// @Produce      json
+// @Security     BearerAuth
+// @Param        tanggal  query  string  false  "Business day (YYYY-MM-DD), defaults to today"
+// @Success      200  {object}  response.SuccessWithData
+// @Failure      400  {object}  response.ErrorDetails
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
+// @Router       /admin/gl/trial-balance [get]
+func (generalLedgerController *GeneralLedgerController) TrialBalance(c *fiber.Ctx) error {
+	req := new(model.TrialBalanceRequest)
+	if err := c.QueryParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid query parameters")
+	}
+
+	trialBalance, err := generalLedgerController.GeneralLedgerService.TrialBalance(c.UserContext(), req)
+	if err != nil {
+		return response.Error(c, err, nil)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.SuccessWithData{
+		Code:    fiber.StatusOK,
+		Status:  "success",
+		Message: "Get trial balance successful",
+		Data:    trialBalance,
+	})
+}
+
+// @Tags         Admin
+// @Summary      Reconcile customer balances with the general ledger
+// @Description  API for checking that the balances of all customer accounts add up to the customer deposits liability account.
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  response.SuccessWithData
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
+// @Router       /admin/gl/reconcile [get]
+func (generalLedgerController *GeneralLedgerController) Reconcile(c *fiber.Ctx) error {
+	reconciliation, err := generalLedgerController.GeneralLedgerService.Reconcile(c.UserContext())
+	if err != nil {
+		return response.Error(c, err, nil)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.SuccessWithData{
+		Code:    fiber.StatusOK,
+		Status:  "success",
+		Message: "General ledger reconciliation complete",
+		Data:    reconciliation,
+	})
+}
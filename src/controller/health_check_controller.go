@@ -55,6 +55,17 @@ func (h *HealthCheckController) Check(c *fiber.Ctx) error {
 		h.addServiceStatus(&serviceList, "Postgre", true, nil)
 	}
 
+	// Report the schema version, which is down when unknown or dirty
+	var schemaVersion *uint
+	if version, err := h.HealthCheckService.SchemaVersion(); err != nil {
+		isHealthy = false
+		errMsg := err.Error()
+		h.addServiceStatus(&serviceList, "Schema", false, &errMsg)
+	} else {
+		schemaVersion = &version
+		h.addServiceStatus(&serviceList, "Schema", true, nil)
+	}
+
 	if err := h.HealthCheckService.MemoryHeapCheck(); err != nil {
 		isHealthy = false
 		errMsg := err.Error()
@@ -73,10 +84,11 @@ func (h *HealthCheckController) Check(c *fiber.Ctx) error {
 	}
 
 	return c.Status(statusCode).JSON(response.HealthCheckResponse{
-		Status:    status,
-		Message:   "Health check completed",
-		Code:      statusCode,
-		IsHealthy: isHealthy,
-		Result:    serviceList,
+		Status:        status,
+		Message:       "Health check completed",
+		Code:          statusCode,
+		IsHealthy:     isHealthy,
+		SchemaVersion: schemaVersion,
+		Result:        serviceList,
 	})
 }
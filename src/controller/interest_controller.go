@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"account-service/src/model"
+	"account-service/src/response"
+	"account-service/src/service"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type InterestController struct {
+	InterestService service.InterestService
+}
+
+func NewInterestController(interestService service.InterestService) *InterestController {
+	return &InterestController{
+		InterestService: interestService,
+	}
+}
+
+// @Tags         Accounts
+// @Summary      Get pending interest (Bunga)
+// @Description  API for listing the interest an account has accrued day by day that has not yet been credited. Pending interest is credited on the last day of each month.
+// @Produce      json
+// @Security     BearerAuth
+// @Param        accountNumber  path  string  true  "Account number"
+// @Success      200  {object}  response.SuccessWithData
+// @Failure      400  {object}  response.ErrorDetails
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
+// @Failure      404  {object}  response.ErrorDetails
+// @Failure      410  {object}  response.ErrorDetails
+// @Router       /bunga/{accountNumber} [get]
+func (interestController *InterestController) PendingInterest(c *fiber.Ctx) error {
+	accountNumber := c.Params("accountNumber")
+
+	if _, err := strconv.ParseUint(accountNumber, 10, 64); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid account number")
+	}
+
+	if err := authorizeAccount(c, accountNumber); err != nil {
+		return response.Error(c, err, nil)
+	}
+
+	pending, err := interestController.InterestService.PendingInterest(c.UserContext(), accountNumber)
+	if err != nil {
+		return response.Error(c, err, nil)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.SuccessWithData{
+		Code:    fiber.StatusOK,
+		Status:  "success",
+		Message: "Get pending interest successful",
+		Data:    pending,
+	})
+}
+
+// @Tags         Admin
+// @Summary      Set the interest rate of a product
+// @Description  API for changing the annual interest rate of a savings product, in basis points. Days already accrued keep their rate.
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        code     path  string                    true  "Product code"
+// @Param        request  body  model.ProductRateRequest  true  "Request body"
+// @Success      200  {object}  response.SuccessWithData
+// @Failure      400  {object}  response.ErrorDetails
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
+// @Failure      404  {object}  response.ErrorDetails
+// @Router       /admin/produk/{code} [put]
+func (interestController *InterestController) SetProductRate(c *fiber.Ctx) error {
+	req := new(model.ProductRateRequest)
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	product, err := interestController.InterestService.SetProductRate(c.UserContext(), c.Params("code"), req)
+	if err != nil {
+		return response.Error(c, err, nil)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.SuccessWithData{
+		Code:    fiber.StatusOK,
+		Status:  "success",
+		Message: "Product rate updated",
+		Data:    product,
+	})
+}
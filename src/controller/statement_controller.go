@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"account-service/src/model"
+	"account-service/src/response"
+	"account-service/src/service"
+	"account-service/src/utils"
+	"bufio"
+	"context"
+	"io"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type StatementController struct {
+	StatementService service.StatementService
+}
+
+func NewStatementController(statementService service.StatementService) *StatementController {
+	return &StatementController{
+		StatementService: statementService,
+	}
+}
+
+// @Tags         Accounts
+// @Summary      Export an account statement
+// @Description  API for downloading the statement of an account over a range of business days as CSV, PDF or ISO 20022 camt.053.001.02 XML. Every format carries the opening and closing balances and the debit and credit totals; each PDF page also ends with the totals of its own lines. The statement is streamed as it is read, so ranges of several years are supported.
+// @Produce      text/csv
+// @Produce      application/pdf
+// @Produce      application/xml
+// @Security     BearerAuth
+// @Param        accountNumber  path   string  true   "Account number"
+// @Param        from           query  string  true   "Start date (YYYY-MM-DD)"
+// @Param        to             query  string  true   "End date (YYYY-MM-DD), inclusive"
+// @Param        format         query  string  false  "Output format"  Enums(csv, pdf, camt053)  default(csv)
+// @Success      200  {file}    file
+// @Failure      400  {object}  response.ErrorDetails
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
+// @Failure      404  {object}  response.ErrorDetails
+// @Router       /rekening/{accountNumber}/statement [get]
+func (statementController *StatementController) Statement(c *fiber.Ctx) error {
+	accountNumber := c.Params("accountNumber")
+
+	if _, err := strconv.ParseUint(accountNumber, 10, 64); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid account number")
+	}
+
+	if err := authorizeAccount(c, accountNumber); err != nil {
+		return response.Error(c, err, nil)
+	}
+
+	req := new(model.StatementRequest)
+	if err := c.QueryParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid query parameters")
+	}
+
+	statement, err := statementController.StatementService.Statement(c.UserContext(), accountNumber, req)
+	if err != nil {
+		return response.Error(c, err, nil)
+	}
+
+	c.Set(fiber.HeaderContentType, response.StatementContentType(req.Format))
+	c.Attachment(response.StatementFileName(statement, req.Format))
+
+	// The body is written after the handler returns, when c and its user
+	// context may no longer be used, so the stream gets a context of its own
+	// carrying the request ID and the trace of the request. A failure past
+	// this point can only cut the statement short.
+	ctx := utils.WithRequestID(context.Background(), utils.RequestID(c.UserContext()))
+	ctx = trace.ContextWithSpanContext(ctx, trace.SpanContextFromContext(c.UserContext()))
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := statementController.writeStatement(ctx, w, statement, req.Format); err != nil {
+			utils.Log.WithContext(ctx).Errorf("Failed to write statement of account %s: %+v", statement.AccountNumber, err)
+		}
+	})
+	return nil
+}
+
+func (statementController *StatementController) writeStatement(ctx context.Context, w *bufio.Writer, statement *model.Statement, format string) error {
+	body := &bodyWriter{w: w}
+	writer, err := response.NewStatementWriter(format, body, statement)
+	if err != nil {
+		return err
+	}
+	if err := writer.WriteHeader(); err != nil {
+		return err
+	}
+	// The statement writers buffer, so a line may be accepted after the body
+	// has already failed; the failure is checked after every line instead.
+	writeLine := func(line *model.StatementLine) error {
+		if err := writer.WriteLine(line); err != nil {
+			return err
+		}
+		return body.err
+	}
+	if err := statementController.StatementService.StreamLines(ctx, statement, writeLine); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// bodyWriter remembers the first failure to write the body of a response, once
+// the client is gone for instance, and refuses every write after it.
+type bodyWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (b *bodyWriter) Write(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	n, err := b.w.Write(p)
+	if err != nil {
+		b.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,283 @@
+package controller
+
+import (
+	"account-service/src/model"
+	"account-service/src/response"
+	"account-service/src/service"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type WebhookController struct {
+	WebhookService service.WebhookService
+}
+
+func NewWebhookController(webhookService service.WebhookService) *WebhookController {
+	return &WebhookController{
+		WebhookService: webhookService,
+	}
+}
+
+// @Tags         Admin
+// @Summary      List the webhook subscriptions
+// @Description  API for listing every webhook subscription, oldest first. Secrets are not shown.
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  response.SuccessWithData
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
+// @Router       /admin/webhooks [get]
+func (webhookController *WebhookController) ListSubscriptions(c *fiber.Ctx) error {
+	subscriptions, err := webhookController.WebhookService.ListSubscriptions(c.UserContext())
+	if err != nil {
+		return response.Error(c, err, nil)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.SuccessWithData{
+		Code:    fiber.StatusOK,
+		Status:  "success",
+		Message: "Get webhook subscriptions successful",
+		Data:    subscriptions,
+	})
+}
+
+// @Tags         Admin
+// @Summary      Register a webhook subscription
+// @Description  API for registering a URL that the account events of the chosen types are posted to. The response carries the secret the posts are signed with, which is not shown again.
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body  model.WebhookSubscriptionRequest  true  "Request body"
+// @Success      201  {object}  response.SuccessWithData
+// @Failure      400  {object}  response.ErrorDetails
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
+// @Router       /admin/webhooks [post]
+func (webhookController *WebhookController) CreateSubscription(c *fiber.Ctx) error {
+	req := new(model.WebhookSubscriptionRequest)
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	subscription, err := webhookController.WebhookService.CreateSubscription(c.UserContext(), req)
+	if err != nil {
+		return response.Error(c, err, nil)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response.SuccessWithData{
+		Code:    fiber.StatusCreated,
+		Status:  "success",
+		Message: "Webhook subscription created",
+		Data:    subscription,
+	})
+}
+
+// @Tags         Admin
+// @Summary      Change a webhook subscription
+// @Description  API for changing the URL and event types of a subscription, and pausing or resuming it with active. Deliveries of a paused subscription are held until it is resumed.
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path  int                               true  "Subscription ID"
+// @Param        request  body  model.WebhookSubscriptionRequest  true  "Request body"
+// @Success      200  {object}  response.SuccessWithData
+// @Failure      400  {object}  response.ErrorDetails
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
+// @Failure      404  {object}  response.ErrorDetails
+// @Router       /admin/webhooks/{id} [put]
+func (webhookController *WebhookController) UpdateSubscription(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid subscription ID")
+	}
+
+	req := new(model.WebhookSubscriptionRequest)
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	subscription, fiberErr := webhookController.WebhookService.UpdateSubscription(c.UserContext(), uint(id), req)
+	if fiberErr != nil {
+		return response.Error(c, fiberErr, nil)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.SuccessWithData{
+		Code:    fiber.StatusOK,
+		Status:  "success",
+		Message: "Webhook subscription updated",
+		Data:    subscription,
+	})
+}
+
+// @Tags         Admin
+// @Summary      Rotate the secret of a webhook subscription
+// @Description  API for giving a subscription a new signing secret, returned in the response. Posts also carry a signature with the old secret until previous_secret_expires_at.
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path  int  true  "Subscription ID"
+// @Success      200  {object}  response.SuccessWithData
+// @Failure      400  {object}  response.ErrorDetails
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
+// @Failure      404  {object}  response.ErrorDetails
+// @Router       /admin/webhooks/{id}/rotate-secret [post]
+func (webhookController *WebhookController) RotateSecret(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid subscription ID")
+	}
+
+	subscription, fiberErr := webhookController.WebhookService.RotateSecret(c.UserContext(), uint(id))
+	if fiberErr != nil {
+		return response.Error(c, fiberErr, nil)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.SuccessWithData{
+		Code:    fiber.StatusOK,
+		Status:  "success",
+		Message: "Webhook secret rotated",
+		Data:    subscription,
+	})
+}
+
+// @Tags         Admin
+// @Summary      Delete a webhook subscription
+// @Description  API for deleting a subscription together with its deliveries.
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path  int  true  "Subscription ID"
+// @Success      200  {object}  response.Common
+// @Failure      400  {object}  response.ErrorDetails
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
+// @Failure      404  {object}  response.ErrorDetails
+// @Router       /admin/webhooks/{id} [delete]
+func (webhookController *WebhookController) DeleteSubscription(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid subscription ID")
+	}
+
+	if err := webhookController.WebhookService.DeleteSubscription(c.UserContext(), uint(id)); err != nil {
+		return response.Error(c, err, nil)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.Common{
+		Code:    fiber.StatusOK,
+		Status:  "success",
+		Message: "Webhook subscription deleted",
+	})
+}
+
+// @Tags         Admin
+// @Summary      List the deliveries of a webhook subscription
+// @Description  API for inspecting the deliveries of a subscription, newest first, optionally only those with a status. Dead deliveries failed every attempt and wait for a redelivery.
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path   int     true   "Subscription ID"
+// @Param        status  query  string  false  "pending, delivered or dead"
+// @Param        page    query  int     false  "Page number (default 1)"
+// @Param        limit   query  int     false  "Deliveries per page (default 10, max 100)"
+// @Success      200  {object}  response.SuccessWithPaginate
+// @Failure      400  {object}  response.ErrorDetails
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
+// @Failure      404  {object}  response.ErrorDetails
+// @Router       /admin/webhooks/{id}/deliveries [get]
+func (webhookController *WebhookController) ListDeliveries(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid subscription ID")
+	}
+
+	query := new(model.WebhookDeliveryQuery)
+	if err := c.QueryParser(query); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid query parameters")
+	}
+
+	deliveries, total, fiberErr := webhookController.WebhookService.ListDeliveries(c.UserContext(), uint(id), query)
+	if fiberErr != nil {
+		return response.Error(c, fiberErr, nil)
+	}
+
+	data := make([]any, len(deliveries))
+	for i := range deliveries {
+		data[i] = deliveries[i]
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.SuccessWithPaginate{
+		Code:       fiber.StatusOK,
+		Status:     "success",
+		Message:    "Get webhook deliveries successful",
+		Data:       data,
+		Page:       query.Page,
+		Limit:      query.Limit,
+		TotalPages: (total + int64(query.Limit) - 1) / int64(query.Limit),
+		Count:      total,
+	})
+}
+
+// @Tags         Admin
+// @Summary      Get a webhook delivery
+// @Description  API for inspecting a delivery with the log of its attempts: when each was made, how long it took and the status code or error it ended with.
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path  int  true  "Delivery ID"
+// @Success      200  {object}  response.SuccessWithData
+// @Failure      400  {object}  response.ErrorDetails
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
+// @Failure      404  {object}  response.ErrorDetails
+// @Router       /admin/webhooks/deliveries/{id} [get]
+func (webhookController *WebhookController) GetDelivery(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid delivery ID")
+	}
+
+	delivery, fiberErr := webhookController.WebhookService.GetDelivery(c.UserContext(), uint(id))
+	if fiberErr != nil {
+		return response.Error(c, fiberErr, nil)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.SuccessWithData{
+		Code:    fiber.StatusOK,
+		Status:  "success",
+		Message: "Get webhook delivery successful",
+		Data:    delivery,
+	})
+}
+
+// @Tags         Admin
+// @Summary      Redeliver a webhook delivery
+// @Description  API for posting a delivery again as soon as possible with a fresh set of attempts, whether it is dead, pending or already delivered.
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path  int  true  "Delivery ID"
+// @Success      202  {object}  response.SuccessWithData
+// @Failure      400  {object}  response.ErrorDetails
+// @Failure      401  {object}  response.ErrorDetails
+// @Failure      403  {object}  response.ErrorDetails
+// @Failure      404  {object}  response.ErrorDetails
+// @Failure      409  {object}  response.ErrorDetails
+// @Router       /admin/webhooks/deliveries/{id}/redeliver [post]
+func (webhookController *WebhookController) Redeliver(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid delivery ID")
+	}
+
+	delivery, fiberErr := webhookController.WebhookService.Redeliver(c.UserContext(), uint(id))
+	if fiberErr != nil {
+		return response.Error(c, fiberErr, nil)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(response.SuccessWithData{
+		Code:    fiber.StatusAccepted,
+		Status:  "success",
+		Message: "Webhook delivery scheduled",
+		Data:    delivery,
+	})
+}
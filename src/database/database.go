@@ -2,36 +2,40 @@ package database
 
 import (
 	"account-service/src/config"
+	"account-service/src/pii"
+	"account-service/src/tracing"
 	"account-service/src/utils"
 	"fmt"
 	"time"
 
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
-func Connect() *gorm.DB {
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%d sslmode=disable TimeZone=Asia/Shanghai",
-		config.DBHost, config.DBUser, config.DBPassword, config.DBName, config.DBPort,
-	)
-
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger:                 logger.Default.LogMode(logger.Info),
+// Connect opens the connection pool of the database of cfg, through which
+// the columns tagged with the pii serializer are encrypted with its keyring.
+func Connect(cfg *config.Config) (*gorm.DB, error) {
+	db, err := gorm.Open(dialector(cfg), &gorm.Config{
+		Logger:                 utils.NewGormLogger(utils.Log, cfg.DBSlowQueryThreshold),
 		SkipDefaultTransaction: true,
 		PrepareStmt:            true,
 		TranslateError:         true,
 	})
 	if err != nil {
-		utils.Log.Errorf("Failed to connect to database: %+v", err)
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	sqlDB, errDB := db.DB()
-	if errDB != nil {
-		utils.Log.Errorf("Failed to connect to database: %+v", errDB)
+	if err := db.Use(pii.GORM(cfg.PIIKeyring)); err != nil {
+		return nil, fmt.Errorf("failed to encrypt personal data: %w", err)
+	}
+	if err := db.Use(tracing.GORM()); err != nil {
+		utils.Log.Errorf("Failed to trace database queries: %+v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
 	// Config connection pooling
@@ -39,5 +43,18 @@ func Connect() *gorm.DB {
 	sqlDB.SetMaxOpenConns(100)
 	sqlDB.SetConnMaxLifetime(60 * time.Minute)
 
-	return db
+	return db, nil
+}
+
+// dialector returns the GORM dialector of the DB_DRIVER of cfg.
+func dialector(cfg *config.Config) gorm.Dialector {
+	if cfg.DBDriver == "sqlite" {
+		return &sqlite.Dialector{DriverName: sqliteDriverName, DSN: sqliteDSN(cfg.DBPath)}
+	}
+
+	dsn := fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s port=%d sslmode=disable TimeZone=Asia/Shanghai",
+		cfg.DBHost, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBPort,
+	)
+	return postgres.Open(dsn)
 }
@@ -0,0 +1,99 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"gorm.io/gorm"
+)
+
+// The migrations of each dialect are in the directory named after it, with
+// the same versions in each.
+//
+//go:embed migrations/postgres/*.sql migrations/sqlite/*.sql
+var migrations embed.FS
+
+// newMigrate returns a migrate instance reading the embedded migrations of the
+// dialect of db and running them on a dedicated connection of db. Closing it
+// releases the connection but leaves the pool of db open.
+func newMigrate(db *gorm.DB) (*migrate.Migrate, error) {
+	dialect := db.Dialector.Name()
+	source, err := iofs.New(migrations, "migrations/"+dialect)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded migrations: %w", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to access the database connection pool: %w", err)
+	}
+	conn, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a database connection: %w", err)
+	}
+
+	var driver database.Driver
+	if dialect == "sqlite" {
+		driver, err = newSQLiteMigrations(conn)
+	} else {
+		driver, err = postgres.WithConnection(context.Background(), conn, &postgres.Config{})
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open the migration driver: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, dialect, driver)
+	if err != nil {
+		driver.Close()
+		return nil, fmt.Errorf("failed to prepare migrations: %w", err)
+	}
+	return m, nil
+}
+
+// MigrateUp applies every embedded migration that has not been applied yet.
+func MigrateUp(db *gorm.DB) error {
+	m, err := newMigrate(db)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+	return nil
+}
+
+// MigrateDown rolls back the last steps applied migrations.
+func MigrateDown(db *gorm.DB, steps int) error {
+	m, err := newMigrate(db)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if err := m.Steps(-steps); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to roll back migrations: %w", err)
+	}
+	return nil
+}
+
+// MigrationVersion returns the version of the last applied migration and
+// whether it failed halfway. It returns migrate.ErrNilVersion when no
+// migration has been applied.
+func MigrationVersion(db *gorm.DB) (uint, bool, error) {
+	m, err := newMigrate(db)
+	if err != nil {
+		return 0, false, err
+	}
+	defer m.Close()
+
+	return m.Version()
+}
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/golang-migrate/migrate/v4/database"
+)
+
+// sqliteMigrations is the golang-migrate driver of SQLite databases. The
+// driver golang-migrate ships links another SQLite driver registering the same
+// name as the one GORM uses, so this one runs the migrations on a connection
+// of the pool of GORM instead. Like the Postgres driver it keeps the version
+// in schema_migrations.
+type sqliteMigrations struct {
+	conn   *sql.Conn
+	locked atomic.Bool
+}
+
+func newSQLiteMigrations(conn *sql.Conn) (*sqliteMigrations, error) {
+	m := &sqliteMigrations{conn: conn}
+	query := `CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)`
+	if _, err := conn.ExecContext(context.Background(), query); err != nil {
+		return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return m, nil
+}
+
+func (m *sqliteMigrations) Open(string) (database.Driver, error) {
+	return nil, errors.New("the SQLite migrations only run on an open connection")
+}
+
+func (m *sqliteMigrations) Close() error {
+	return m.conn.Close()
+}
+
+// Lock only guards against concurrent use of m: whoever migrates a SQLite
+// database is its only user.
+func (m *sqliteMigrations) Lock() error {
+	if !m.locked.CompareAndSwap(false, true) {
+		return database.ErrLocked
+	}
+	return nil
+}
+
+func (m *sqliteMigrations) Unlock() error {
+	if !m.locked.CompareAndSwap(true, false) {
+		return database.ErrNotLocked
+	}
+	return nil
+}
+
+// Run applies a migration in a transaction, so that a failed one leaves
+// nothing behind.
+func (m *sqliteMigrations) Run(migration io.Reader) error {
+	query, err := io.ReadAll(migration)
+	if err != nil {
+		return err
+	}
+	return m.transaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(string(query)); err != nil {
+			return &database.Error{OrigErr: err, Query: query}
+		}
+		return nil
+	})
+}
+
+func (m *sqliteMigrations) SetVersion(version int, dirty bool) error {
+	return m.transaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM schema_migrations`); err != nil {
+			return err
+		}
+		// A nil version is kept only while dirty, to remember a failed down
+		// migration of the first version.
+		if version >= 0 || (version == database.NilVersion && dirty) {
+			if _, err := tx.Exec(`INSERT INTO schema_migrations (version, dirty) VALUES (?, ?)`, version, dirty); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (m *sqliteMigrations) Version() (int, bool, error) {
+	var version int
+	var dirty bool
+	err := m.conn.QueryRowContext(context.Background(), `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return database.NilVersion, false, nil
+	case err != nil:
+		return 0, false, err
+	}
+	return version, dirty, nil
+}
+
+// Drop drops every table.
+func (m *sqliteMigrations) Drop() error {
+	rows, err := m.conn.QueryContext(context.Background(), `SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'`)
+	if err != nil {
+		return err
+	}
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			rows.Close()
+			return err
+		}
+		tables = append(tables, table)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Tables are dropped in any order, so references are not enforced meanwhile
+	ctx := context.Background()
+	if _, err := m.conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+		return err
+	}
+	defer m.conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`)
+	for _, table := range tables {
+		if _, err := m.conn.ExecContext(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS "%s"`, table)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *sqliteMigrations) transaction(fn func(tx *sql.Tx) error) error {
+	tx, err := m.conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,5 @@
+-- Drop the index
+DROP INDEX IF EXISTS idx_cash_activities_transfer_reference;
+
+-- Drop the transfer reference column
+ALTER TABLE cash_activities DROP COLUMN IF EXISTS transfer_reference;
@@ -0,0 +1,5 @@
+-- Link the debit and credit legs of an account-to-account transfer
+ALTER TABLE cash_activities ADD COLUMN transfer_reference VARCHAR(36);
+
+-- Add indexes for optimization
+CREATE INDEX idx_cash_activities_transfer_reference ON cash_activities(transfer_reference);
@@ -0,0 +1,14 @@
+-- Create the idempotency_keys table
+CREATE TABLE idempotency_keys (
+    id SERIAL PRIMARY KEY,
+    key VARCHAR(255) UNIQUE NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    status_code INT NOT NULL DEFAULT 0, -- 0 while the first request is still in progress
+    content_type VARCHAR(100),
+    response_body BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Add indexes for optimization
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
@@ -0,0 +1,2 @@
+-- Drop the hash column
+ALTER TABLE cash_activities DROP COLUMN IF EXISTS hash;
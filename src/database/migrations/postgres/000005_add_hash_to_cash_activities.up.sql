@@ -0,0 +1,43 @@
+-- Each activity carries a hash of its own fields and of the previous activity
+-- of the same account, making the per-account chain tamper-evident
+ALTER TABLE cash_activities ADD COLUMN hash VARCHAR(64);
+
+-- Seal the existing history. The encoding must match CashActivity.ComputeHash:
+-- every field is written as '<byte length>:<value>;'
+DO $$
+DECLARE
+    r RECORD;
+    v_account_id BIGINT;
+    v_previous_hash TEXT := '';
+    v_payload TEXT;
+    v_field TEXT;
+    v_fields TEXT[];
+BEGIN
+    FOR r IN SELECT * FROM cash_activities ORDER BY account_id, id LOOP
+        IF v_account_id IS DISTINCT FROM r.account_id THEN
+            v_account_id := r.account_id;
+            v_previous_hash := '';
+        END IF;
+
+        v_fields := ARRAY[
+            r.account_id::TEXT,
+            COALESCE(r.reference_id::TEXT, ''),
+            r.type,
+            r.nominal::TEXT,
+            r.balance_before::TEXT,
+            r.balance_after::TEXT,
+            COALESCE(r.description, ''),
+            COALESCE(r.transfer_reference, ''),
+            COALESCE(to_char(r.created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'), ''),
+            v_previous_hash
+        ];
+
+        v_payload := '';
+        FOREACH v_field IN ARRAY v_fields LOOP
+            v_payload := v_payload || octet_length(v_field) || ':' || v_field || ';';
+        END LOOP;
+
+        v_previous_hash := encode(sha256(convert_to(v_payload, 'UTF8')), 'hex');
+        UPDATE cash_activities SET hash = v_previous_hash WHERE id = r.id;
+    END LOOP;
+END $$;
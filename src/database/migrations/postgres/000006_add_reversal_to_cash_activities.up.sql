@@ -0,0 +1,8 @@
+-- Link a compensating activity to the activity it reverses
+ALTER TABLE cash_activities ADD COLUMN reversal_of_id INT REFERENCES cash_activities(id);
+
+-- Mark activities that have been reversed
+ALTER TABLE cash_activities ADD COLUMN reversed_at TIMESTAMP WITH TIME ZONE;
+
+-- An activity can be reversed at most once
+CREATE UNIQUE INDEX idx_cash_activities_reversal_of_id ON cash_activities(reversal_of_id);
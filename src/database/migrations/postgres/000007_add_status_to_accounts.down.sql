@@ -0,0 +1,5 @@
+-- Drop the account_status_histories table
+DROP TABLE IF EXISTS account_status_histories;
+
+-- Drop the status column
+ALTER TABLE accounts DROP COLUMN IF EXISTS status;
@@ -0,0 +1,18 @@
+-- Track the lifecycle of an account
+ALTER TABLE accounts ADD COLUMN status VARCHAR(10) NOT NULL DEFAULT 'active'
+    CHECK (status IN ('active', 'frozen', 'dormant', 'closed'));
+
+-- Create the account_status_histories table
+CREATE TABLE account_status_histories (
+    id SERIAL PRIMARY KEY,
+    account_id bigint NOT NULL,
+    from_status VARCHAR(10) NOT NULL,
+    to_status VARCHAR(10) NOT NULL,
+    actor VARCHAR(100) NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (account_id) REFERENCES accounts(id)
+);
+
+-- Add indexes for optimization
+CREATE INDEX idx_account_status_histories_account_id ON account_status_histories(account_id);
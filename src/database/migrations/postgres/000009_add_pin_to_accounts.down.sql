@@ -0,0 +1,5 @@
+-- Drop the transaction PIN columns
+ALTER TABLE accounts
+    DROP COLUMN IF EXISTS pin_locked_at,
+    DROP COLUMN IF EXISTS pin_failed_attempts,
+    DROP COLUMN IF EXISTS pin_hash;
@@ -0,0 +1,5 @@
+-- Add the transaction PIN to accounts. Existing accounts have no PIN until a teller resets it.
+ALTER TABLE accounts
+    ADD COLUMN pin_hash VARCHAR(60) NOT NULL DEFAULT '', -- bcrypt hash, empty until a PIN is set
+    ADD COLUMN pin_failed_attempts INT NOT NULL DEFAULT 0,
+    ADD COLUMN pin_locked_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,5 @@
+-- Drop the general ledger tables
+DROP TABLE IF EXISTS journal_lines;
+DROP FUNCTION IF EXISTS check_journal_entry_balanced();
+DROP TABLE IF EXISTS journal_entries;
+DROP TABLE IF EXISTS gl_accounts;
@@ -0,0 +1,68 @@
+-- Create the gl_accounts table, the chart of accounts
+CREATE TABLE gl_accounts (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(10) UNIQUE NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('asset', 'liability', 'equity', 'income', 'expense')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO gl_accounts (code, name, type) VALUES
+    ('1000', 'Cash', 'asset'),
+    ('2000', 'Customer Deposits', 'liability'),
+    ('2900', 'Suspense', 'liability'),
+    ('4000', 'Fee Income', 'income');
+
+-- Create the journal_entries table
+CREATE TABLE journal_entries (
+    id SERIAL PRIMARY KEY,
+    description TEXT NOT NULL,
+    cash_activity_id INT REFERENCES cash_activities(id), -- The customer activity the journal accounts for
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create the journal_lines table. Debits are positive, credits negative.
+CREATE TABLE journal_lines (
+    id SERIAL PRIMARY KEY,
+    journal_entry_id INT NOT NULL REFERENCES journal_entries(id),
+    gl_account_code VARCHAR(10) NOT NULL REFERENCES gl_accounts(code),
+    amount NUMERIC(15, 2) NOT NULL CHECK (amount <> 0)
+);
+
+-- Add indexes for optimization
+CREATE INDEX idx_journal_entries_cash_activity_id ON journal_entries(cash_activity_id);
+CREATE INDEX idx_journal_entries_created_at ON journal_entries(created_at);
+CREATE INDEX idx_journal_lines_journal_entry_id ON journal_lines(journal_entry_id);
+CREATE INDEX idx_journal_lines_gl_account_code ON journal_lines(gl_account_code);
+
+-- Refuse to commit a journal entry whose lines do not sum to zero. The check
+-- is deferred to commit, so the lines of an entry can be inserted one by one.
+CREATE FUNCTION check_journal_entry_balanced() RETURNS trigger AS $$
+BEGIN
+    IF (SELECT SUM(amount) FROM journal_lines WHERE journal_entry_id = NEW.journal_entry_id) <> 0 THEN
+        RAISE EXCEPTION 'journal entry % does not balance', NEW.journal_entry_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER journal_lines_balanced
+    AFTER INSERT OR UPDATE ON journal_lines
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION check_journal_entry_balanced();
+
+-- Open the ledger with the customer balances held before it existed, as cash
+-- owed to the customers.
+WITH opening AS (
+    INSERT INTO journal_entries (description)
+    SELECT 'Opening balance' FROM accounts HAVING SUM(balance) <> 0
+    RETURNING id
+)
+INSERT INTO journal_lines (journal_entry_id, gl_account_code, amount)
+SELECT opening.id, lines.code, lines.amount
+FROM opening
+CROSS JOIN (
+    SELECT '1000' AS code, SUM(balance) AS amount FROM accounts
+    UNION ALL
+    SELECT '2000', -SUM(balance) FROM accounts
+) AS lines;
@@ -0,0 +1,8 @@
+-- Drop the fee schedules and the fee columns of cash_activities
+DROP INDEX IF EXISTS idx_cash_activities_fee_period;
+DROP INDEX IF EXISTS idx_cash_activities_fee_of_id;
+ALTER TABLE cash_activities
+    DROP COLUMN IF EXISTS fee_period,
+    DROP COLUMN IF EXISTS fee_of_id,
+    DROP COLUMN IF EXISTS fee_schedule_id;
+DROP TABLE IF EXISTS fee_schedules;
@@ -0,0 +1,44 @@
+-- Create the webhook tables. The outbox dispatcher copies each event into a
+-- delivery per subscription to its type, and the webhook deliverer posts the
+-- deliveries signed with the secret of their subscription.
+CREATE TABLE webhook_subscriptions (
+    id SERIAL PRIMARY KEY,
+    url VARCHAR(500) NOT NULL,
+    event_types VARCHAR(500) NOT NULL, -- Comma separated event types
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    secret VARCHAR(100) NOT NULL,
+    previous_secret VARCHAR(100), -- Still signs deliveries until previous_secret_expires_at
+    previous_secret_expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id INT NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    outbox_id BIGINT NOT NULL REFERENCES outbox(id),
+    event_type VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'dead')),
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_status_code INT,
+    last_error TEXT,
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (subscription_id, outbox_id) -- An event published twice is delivered once
+);
+
+-- The deliverer only reads deliveries still to make
+CREATE INDEX idx_webhook_deliveries_pending ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+
+CREATE TABLE webhook_delivery_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    delivery_id BIGINT NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+    status_code INT, -- NULL when no response was received
+    error TEXT,
+    duration_ms BIGINT NOT NULL,
+    attempted_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_webhook_delivery_attempts_delivery_id ON webhook_delivery_attempts(delivery_id);
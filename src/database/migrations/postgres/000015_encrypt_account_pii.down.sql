@@ -0,0 +1,19 @@
+-- Drop the blind indexes. Values encrypted with a random nonce cannot be kept
+-- unique, nor decrypted here, so the rollback is refused while any account
+-- holds an encrypted value.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM accounts WHERE id_number LIKE 'v%:%' OR phone_number LIKE 'v%:%') THEN
+        RAISE EXCEPTION 'accounts hold encrypted ID numbers or phone numbers, which cannot be kept unique without their blind indexes';
+    END IF;
+END;
+$$;
+
+DROP INDEX IF EXISTS idx_accounts_phone_number_hash;
+DROP INDEX IF EXISTS idx_accounts_id_number_hash;
+
+ALTER TABLE accounts
+    DROP COLUMN IF EXISTS phone_number_hash,
+    DROP COLUMN IF EXISTS id_number_hash,
+    ADD CONSTRAINT accounts_id_number_key UNIQUE (id_number),
+    ADD CONSTRAINT accounts_phone_number_key UNIQUE (phone_number);
@@ -0,0 +1,14 @@
+-- Encrypt the ID numbers and phone numbers of accounts. The application
+-- stores them encrypted with a random nonce, so they are kept unique and
+-- looked up by their blind index instead. Existing accounts are encrypted and
+-- indexed when migrating, right after this migration is applied.
+ALTER TABLE accounts
+    DROP CONSTRAINT IF EXISTS accounts_id_number_key,
+    DROP CONSTRAINT IF EXISTS accounts_phone_number_key,
+    ALTER COLUMN id_number TYPE TEXT,
+    ALTER COLUMN phone_number TYPE TEXT,
+    ADD COLUMN id_number_hash VARCHAR(64), -- HMAC-SHA256 of the ID number, in hex
+    ADD COLUMN phone_number_hash VARCHAR(64); -- HMAC-SHA256 of the phone number, in hex
+
+CREATE UNIQUE INDEX idx_accounts_id_number_hash ON accounts(id_number_hash);
+CREATE UNIQUE INDEX idx_accounts_phone_number_hash ON accounts(phone_number_hash);
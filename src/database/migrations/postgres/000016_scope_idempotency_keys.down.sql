@@ -0,0 +1,9 @@
+-- Make idempotency keys global again. Keys chosen by several callers cannot
+-- be kept unique, so only the latest of each is kept.
+DELETE FROM idempotency_keys WHERE id NOT IN (SELECT MAX(id) FROM idempotency_keys GROUP BY key);
+
+DROP INDEX IF EXISTS idx_idempotency_keys_principal_key;
+
+ALTER TABLE idempotency_keys
+    DROP COLUMN IF EXISTS principal,
+    ADD CONSTRAINT idempotency_keys_key_key UNIQUE (key);
@@ -0,0 +1,8 @@
+-- Scope idempotency keys to the caller that chose them, so two callers using
+-- the same key never collide. Keys stored before have no caller and are never
+-- replayed again; they expire as usual.
+ALTER TABLE idempotency_keys
+    DROP CONSTRAINT IF EXISTS idempotency_keys_key_key,
+    ADD COLUMN principal VARCHAR(255) NOT NULL DEFAULT ''; -- The role and subject of the caller
+
+CREATE UNIQUE INDEX idx_idempotency_keys_principal_key ON idempotency_keys(principal, key);
@@ -0,0 +1,12 @@
+-- The previous encoding does not cover the reversal and fee links, so the
+-- hashes sealed with them no longer verify once the service is rolled back.
+-- Resealing without them would sign whatever the history holds by then.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM cash_activities
+        WHERE reversal_of_id IS NOT NULL OR fee_schedule_id IS NOT NULL OR fee_of_id IS NOT NULL OR fee_period IS NOT NULL
+    ) THEN
+        RAISE EXCEPTION 'cash activities were sealed with their reversal or fee links, which the previous version does not verify';
+    END IF;
+END $$;
@@ -0,0 +1,75 @@
+-- The hash of a cash activity now also covers its reversal and fee links,
+-- appended as '<column>=<value>' fields before the previous hash when set.
+-- Reseal the existing history: the hash of every activity carrying a link
+-- changes, and so does that of every later activity of its account. The
+-- chain is verified under the previous encoding first, so history that was
+-- tampered with is never sealed over. The encoding must match
+-- CashActivity.ComputeHash: every field is written as '<byte length>:<value>;'
+CREATE FUNCTION pg_temp.cash_activity_hash(v_fields TEXT[]) RETURNS TEXT AS $$
+DECLARE
+    v_payload TEXT := '';
+    v_field TEXT;
+BEGIN
+    FOREACH v_field IN ARRAY v_fields LOOP
+        v_payload := v_payload || octet_length(v_field) || ':' || v_field || ';';
+    END LOOP;
+    RETURN encode(sha256(convert_to(v_payload, 'UTF8')), 'hex');
+END;
+$$ LANGUAGE plpgsql;
+
+DO $$
+DECLARE
+    r RECORD;
+    v_account_id BIGINT;
+    v_sealed_hash TEXT := '';
+    v_previous_hash TEXT := '';
+    v_fields TEXT[];
+    v_links TEXT[];
+    v_hash TEXT;
+BEGIN
+    FOR r IN SELECT * FROM cash_activities ORDER BY account_id, id LOOP
+        IF v_account_id IS DISTINCT FROM r.account_id THEN
+            v_account_id := r.account_id;
+            v_sealed_hash := '';
+            v_previous_hash := '';
+        END IF;
+
+        v_fields := ARRAY[
+            r.account_id::TEXT,
+            COALESCE(r.reference_id::TEXT, ''),
+            r.type,
+            r.nominal::TEXT,
+            r.balance_before::TEXT,
+            r.balance_after::TEXT,
+            COALESCE(r.description, ''),
+            COALESCE(r.transfer_reference, ''),
+            COALESCE(to_char(r.created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'), '')
+        ];
+
+        v_sealed_hash := pg_temp.cash_activity_hash(v_fields || v_sealed_hash);
+        IF r.hash IS DISTINCT FROM v_sealed_hash THEN
+            RAISE EXCEPTION 'cash activity % breaks the hash chain of account %, refusing to reseal it', r.id, r.account_id;
+        END IF;
+
+        v_links := ARRAY[]::TEXT[];
+        IF r.reversal_of_id IS NOT NULL THEN
+            v_links := v_links || ('reversal_of_id=' || r.reversal_of_id);
+        END IF;
+        IF r.fee_schedule_id IS NOT NULL THEN
+            v_links := v_links || ('fee_schedule_id=' || r.fee_schedule_id);
+        END IF;
+        IF r.fee_of_id IS NOT NULL THEN
+            v_links := v_links || ('fee_of_id=' || r.fee_of_id);
+        END IF;
+        IF r.fee_period IS NOT NULL THEN
+            v_links := v_links || ('fee_period=' || to_char(r.fee_period, 'YYYY-MM-DD'));
+        END IF;
+
+        v_previous_hash := pg_temp.cash_activity_hash(v_fields || v_links || v_previous_hash);
+        IF v_previous_hash <> r.hash THEN
+            UPDATE cash_activities SET hash = v_previous_hash WHERE id = r.id;
+        END IF;
+    END LOOP;
+END $$;
+
+DROP FUNCTION pg_temp.cash_activity_hash(TEXT[]);
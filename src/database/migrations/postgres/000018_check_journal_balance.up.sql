@@ -0,0 +1,2 @@
+-- Postgres has refused journal entries that do not balance since the general
+-- ledger was created; only SQLite is changed.
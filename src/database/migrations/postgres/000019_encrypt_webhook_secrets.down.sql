@@ -0,0 +1,14 @@
+-- Bring back the length of the secret columns. An encrypted secret does not
+-- fit it, nor can it be decrypted here, so the rollback is refused while any
+-- subscription holds an encrypted secret.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM webhook_subscriptions WHERE secret LIKE 'v%:%' OR previous_secret LIKE 'v%:%') THEN
+        RAISE EXCEPTION 'webhook subscriptions hold encrypted secrets, which do not fit the length of their columns';
+    END IF;
+END;
+$$;
+
+ALTER TABLE webhook_subscriptions
+    ALTER COLUMN secret TYPE VARCHAR(100),
+    ALTER COLUMN previous_secret TYPE VARCHAR(100);
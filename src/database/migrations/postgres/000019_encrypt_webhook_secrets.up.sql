@@ -0,0 +1,7 @@
+-- Encrypt the secrets of webhook subscriptions, which sign their deliveries,
+-- with the keys of the personal data of accounts. An encrypted secret does not
+-- fit the length of the column. Existing secrets are encrypted when
+-- migrating, right after this migration is applied.
+ALTER TABLE webhook_subscriptions
+    ALTER COLUMN secret TYPE TEXT,
+    ALTER COLUMN previous_secret TYPE TEXT;
@@ -0,0 +1,2 @@
+-- Drop the account table
+DROP TABLE IF EXISTS accounts;
@@ -0,0 +1,15 @@
+-- Create the account table. updated_at is kept by GORM, SQLite has no
+-- counterpart of the Postgres trigger function.
+CREATE TABLE accounts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    account_number VARCHAR(20) UNIQUE NOT NULL,
+    full_name VARCHAR(50) NOT NULL,
+    id_number VARCHAR(16) UNIQUE NOT NULL,
+    phone_number VARCHAR(15) UNIQUE NOT NULL,
+    balance NUMERIC(15, 2) NOT NULL DEFAULT 0.00,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Add indexes for optimization (optional but recommended)
+CREATE INDEX idx_accounts_account_number ON accounts(account_number);
@@ -0,0 +1,2 @@
+-- Drop the cash_activity table
+DROP TABLE IF EXISTS cash_activities;
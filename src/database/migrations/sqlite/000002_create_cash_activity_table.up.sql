@@ -0,0 +1,18 @@
+-- Create the cash_activity table
+CREATE TABLE cash_activities (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    account_id BIGINT NOT NULL,
+    reference_id INT, -- New column for chained transactions
+    type VARCHAR(10) NOT NULL CHECK (type IN ('debit', 'credit')),
+    nominal NUMERIC(15, 2) NOT NULL,
+    balance_before NUMERIC(15, 2) NOT NULL,
+    balance_after NUMERIC(15, 2) NOT NULL,
+    description TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (account_id) REFERENCES accounts(id)
+);
+
+-- Add indexes for optimization
+CREATE INDEX idx_cash_activities_account_id ON cash_activities(account_id);
+CREATE INDEX idx_cash_activities_reference_id ON cash_activities(reference_id); -- Index on reference_id
+CREATE INDEX idx_cash_activities_created_at ON cash_activities(created_at);
@@ -0,0 +1,14 @@
+-- Create the idempotency_keys table
+CREATE TABLE idempotency_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    key VARCHAR(255) UNIQUE NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    status_code INT NOT NULL DEFAULT 0, -- 0 while the first request is still in progress
+    content_type VARCHAR(100),
+    response_body BLOB,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL
+);
+
+-- Add indexes for optimization
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
@@ -0,0 +1,4 @@
+-- Each activity carries a hash of its own fields and of the previous activity
+-- of the same account, making the per-account chain tamper-evident. A SQLite
+-- database starts empty, so there is no existing history to seal.
+ALTER TABLE cash_activities ADD COLUMN hash VARCHAR(64);
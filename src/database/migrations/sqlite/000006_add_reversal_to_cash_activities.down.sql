@@ -0,0 +1,6 @@
+-- Drop the index
+DROP INDEX IF EXISTS idx_cash_activities_reversal_of_id;
+
+-- Drop the reversal columns
+ALTER TABLE cash_activities DROP COLUMN reversed_at;
+ALTER TABLE cash_activities DROP COLUMN reversal_of_id;
@@ -0,0 +1,9 @@
+-- Link a compensating activity to the activity it reverses. SQLite cannot
+-- drop a column that references another row, so the link is not a foreign key.
+ALTER TABLE cash_activities ADD COLUMN reversal_of_id INT;
+
+-- Mark activities that have been reversed
+ALTER TABLE cash_activities ADD COLUMN reversed_at DATETIME;
+
+-- An activity can be reversed at most once
+CREATE UNIQUE INDEX idx_cash_activities_reversal_of_id ON cash_activities(reversal_of_id);
@@ -0,0 +1,2 @@
+-- Drop the account_withdrawal_limits table
+DROP TABLE IF EXISTS account_withdrawal_limits;
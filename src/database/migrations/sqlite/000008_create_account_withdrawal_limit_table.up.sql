@@ -0,0 +1,11 @@
+-- Create the account_withdrawal_limits table, overriding the global defaults per account
+CREATE TABLE account_withdrawal_limits (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    account_id BIGINT UNIQUE NOT NULL,
+    max_per_transaction NUMERIC(15, 2), -- NULL falls back to the global default
+    max_daily_total NUMERIC(15, 2),
+    max_daily_count INT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (account_id) REFERENCES accounts(id)
+);
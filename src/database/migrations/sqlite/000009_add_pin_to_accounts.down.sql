@@ -0,0 +1,4 @@
+-- Drop the transaction PIN columns
+ALTER TABLE accounts DROP COLUMN pin_locked_at;
+ALTER TABLE accounts DROP COLUMN pin_failed_attempts;
+ALTER TABLE accounts DROP COLUMN pin_hash;
@@ -0,0 +1,4 @@
+-- Add the transaction PIN to accounts. Existing accounts have no PIN until a teller resets it.
+ALTER TABLE accounts ADD COLUMN pin_hash VARCHAR(60) NOT NULL DEFAULT ''; -- bcrypt hash, empty until a PIN is set
+ALTER TABLE accounts ADD COLUMN pin_failed_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE accounts ADD COLUMN pin_locked_at DATETIME;
@@ -0,0 +1,4 @@
+-- Drop the general ledger tables
+DROP TABLE IF EXISTS journal_lines;
+DROP TABLE IF EXISTS journal_entries;
+DROP TABLE IF EXISTS gl_accounts;
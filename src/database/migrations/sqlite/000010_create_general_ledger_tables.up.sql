@@ -0,0 +1,39 @@
+-- Create the gl_accounts table, the chart of accounts
+CREATE TABLE gl_accounts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    code VARCHAR(10) UNIQUE NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('asset', 'liability', 'equity', 'income', 'expense')),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO gl_accounts (code, name, type) VALUES
+    ('1000', 'Cash', 'asset'),
+    ('2000', 'Customer Deposits', 'liability'),
+    ('2900', 'Suspense', 'liability'),
+    ('4000', 'Fee Income', 'income');
+
+-- Create the journal_entries table
+CREATE TABLE journal_entries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    description TEXT NOT NULL,
+    cash_activity_id INT REFERENCES cash_activities(id), -- The customer activity the journal accounts for
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create the journal_lines table. Debits are positive, credits negative.
+-- SQLite has no deferred triggers, so unlike on Postgres the database does
+-- not refuse an entry that does not balance; the service checks it before
+-- writing the entry.
+CREATE TABLE journal_lines (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    journal_entry_id INT NOT NULL REFERENCES journal_entries(id),
+    gl_account_code VARCHAR(10) NOT NULL REFERENCES gl_accounts(code),
+    amount NUMERIC(15, 2) NOT NULL CHECK (amount <> 0)
+);
+
+-- Add indexes for optimization
+CREATE INDEX idx_journal_entries_cash_activity_id ON journal_entries(cash_activity_id);
+CREATE INDEX idx_journal_entries_created_at ON journal_entries(created_at);
+CREATE INDEX idx_journal_lines_journal_entry_id ON journal_lines(journal_entry_id);
+CREATE INDEX idx_journal_lines_gl_account_code ON journal_lines(gl_account_code);
@@ -0,0 +1,6 @@
+-- Drop the interest tables
+DELETE FROM gl_accounts WHERE code = '5000' AND NOT EXISTS (SELECT 1 FROM journal_lines WHERE gl_account_code = '5000');
+DROP TABLE IF EXISTS interest_runs;
+DROP TABLE IF EXISTS interest_accruals;
+ALTER TABLE accounts DROP COLUMN product_code;
+DROP TABLE IF EXISTS products;
@@ -0,0 +1,46 @@
+-- Create the products table. Every account belongs to a savings product,
+-- which sets the annual interest rate it earns.
+CREATE TABLE products (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    code VARCHAR(20) UNIQUE NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    annual_rate_bps INT NOT NULL DEFAULT 0 CHECK (annual_rate_bps BETWEEN 0 AND 10000), -- Basis points, 100 is 1% a year
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO products (code, name, annual_rate_bps) VALUES ('tabungan', 'Tabungan', 100);
+
+-- SQLite only adds a column referencing another table if it defaults to
+-- NULL, so the product of an account is not a foreign key.
+ALTER TABLE accounts ADD COLUMN product_code VARCHAR(20) NOT NULL DEFAULT 'tabungan';
+
+-- Create the interest_accruals table, the interest earned by an account on
+-- each business day, pending until it is capitalized
+CREATE TABLE interest_accruals (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    account_id BIGINT NOT NULL REFERENCES accounts(id),
+    business_date DATE NOT NULL,
+    balance NUMERIC(15, 2) NOT NULL, -- End-of-day balance the interest is earned on
+    annual_rate_bps INT NOT NULL,
+    amount NUMERIC(15, 2) NOT NULL CHECK (amount > 0),
+    cash_activity_id INT REFERENCES cash_activities(id), -- The credit that capitalized the interest, NULL while pending
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (account_id, business_date)
+);
+
+CREATE INDEX idx_interest_accruals_pending ON interest_accruals(account_id, business_date) WHERE cash_activity_id IS NULL;
+
+-- Create the interest_runs table, one row per business date the interest job
+-- has completed
+CREATE TABLE interest_runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    business_date DATE UNIQUE NOT NULL,
+    accrued_accounts INT NOT NULL DEFAULT 0,
+    total_accrued NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    capitalized_accounts INT NOT NULL DEFAULT 0,
+    total_capitalized NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    completed_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO gl_accounts (code, name, type) VALUES ('5000', 'Interest Expense', 'expense');
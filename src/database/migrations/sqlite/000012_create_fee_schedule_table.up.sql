@@ -0,0 +1,24 @@
+-- Create the fee_schedules table. A rule is changed by adding a version with
+-- a later effective date, so fees already charged keep the rule they were
+-- charged under.
+CREATE TABLE fee_schedules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_code VARCHAR(20) NOT NULL REFERENCES products(code),
+    type VARCHAR(20) NOT NULL CHECK (type IN ('withdrawal', 'monthly_admin')),
+    amount NUMERIC(15, 2) NOT NULL CHECK (amount >= 0), -- 0 waives the fee
+    free_per_month INT NOT NULL DEFAULT 0 CHECK (free_per_month >= 0), -- Withdrawals a month before the fee applies
+    effective_from DATE NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (product_code, type, effective_from)
+);
+
+-- Link a fee to its schedule and to the activity that triggered it. As with
+-- reversal_of_id, the links are not foreign keys so that they can be dropped.
+ALTER TABLE cash_activities ADD COLUMN fee_schedule_id INT;
+ALTER TABLE cash_activities ADD COLUMN fee_of_id INT;
+ALTER TABLE cash_activities ADD COLUMN fee_period DATE; -- First day of the month a monthly fee is charged for
+
+CREATE INDEX idx_cash_activities_fee_of_id ON cash_activities(fee_of_id);
+
+-- A monthly fee is charged at most once per account and month
+CREATE UNIQUE INDEX idx_cash_activities_fee_period ON cash_activities(account_id, fee_period) WHERE fee_period IS NOT NULL;
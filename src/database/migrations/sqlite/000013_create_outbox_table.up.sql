@@ -0,0 +1,16 @@
+-- Create the outbox table. Events are written in the same transaction as the
+-- change they describe and published afterwards by the outbox dispatcher.
+CREATE TABLE outbox (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    account_number VARCHAR(20) NOT NULL, -- Events of an account are published in id order
+    type VARCHAR(50) NOT NULL,
+    payload TEXT NOT NULL, -- JSON
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT,
+    published_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The dispatcher only reads events still to publish
+CREATE INDEX idx_outbox_pending ON outbox(account_number, id) WHERE published_at IS NULL;
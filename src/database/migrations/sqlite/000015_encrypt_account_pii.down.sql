@@ -0,0 +1,19 @@
+-- Drop the blind indexes. Values encrypted with a random nonce cannot be kept
+-- unique, nor decrypted here, so the rollback is refused while any account
+-- holds an encrypted value.
+CREATE TEMP TABLE encrypted_pii (accounts INTEGER NOT NULL);
+
+CREATE TEMP TRIGGER encrypted_pii_refused BEFORE INSERT ON encrypted_pii WHEN NEW.accounts > 0
+BEGIN
+    SELECT RAISE(ABORT, 'accounts hold encrypted ID numbers or phone numbers, which cannot be kept unique without their blind indexes');
+END;
+
+INSERT INTO encrypted_pii
+SELECT COUNT(*) FROM accounts WHERE id_number LIKE 'v%:%' OR phone_number LIKE 'v%:%';
+
+DROP TABLE encrypted_pii;
+
+DROP INDEX IF EXISTS idx_accounts_phone_number_hash;
+DROP INDEX IF EXISTS idx_accounts_id_number_hash;
+ALTER TABLE accounts DROP COLUMN phone_number_hash;
+ALTER TABLE accounts DROP COLUMN id_number_hash;
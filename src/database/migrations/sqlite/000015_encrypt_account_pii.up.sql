@@ -0,0 +1,11 @@
+-- Encrypt the ID numbers and phone numbers of accounts. The application
+-- stores them encrypted with a random nonce, so they are kept unique and
+-- looked up by their blind index instead. Existing accounts are encrypted and
+-- indexed when migrating, right after this migration is applied. SQLite cannot drop the UNIQUE
+-- constraints of the encrypted columns short of rebuilding the table, and
+-- they do no harm, nor does it enforce the length of their type.
+ALTER TABLE accounts ADD COLUMN id_number_hash VARCHAR(64); -- HMAC-SHA256 of the ID number, in hex
+ALTER TABLE accounts ADD COLUMN phone_number_hash VARCHAR(64); -- HMAC-SHA256 of the phone number, in hex
+
+CREATE UNIQUE INDEX idx_accounts_id_number_hash ON accounts(id_number_hash);
+CREATE UNIQUE INDEX idx_accounts_phone_number_hash ON accounts(phone_number_hash);
@@ -0,0 +1,22 @@
+-- Make idempotency keys global again. Keys chosen by several callers cannot
+-- be kept unique, so only the latest of each is kept.
+CREATE TABLE idempotency_keys_global (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    key VARCHAR(255) UNIQUE NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    status_code INT NOT NULL DEFAULT 0, -- 0 while the first request is still in progress
+    content_type VARCHAR(100),
+    response_body BLOB,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL
+);
+
+INSERT INTO idempotency_keys_global (id, key, request_hash, status_code, content_type, response_body, created_at, expires_at)
+SELECT id, key, request_hash, status_code, content_type, response_body, created_at, expires_at FROM idempotency_keys
+WHERE id IN (SELECT MAX(id) FROM idempotency_keys GROUP BY key);
+
+DROP TABLE idempotency_keys;
+ALTER TABLE idempotency_keys_global RENAME TO idempotency_keys;
+
+-- Add indexes for optimization
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
@@ -0,0 +1,25 @@
+-- Scope idempotency keys to the caller that chose them, so two callers using
+-- the same key never collide. Keys stored before have no caller and are never
+-- replayed again; they expire as usual. SQLite cannot drop the UNIQUE
+-- constraint of the key, so the table is rebuilt.
+CREATE TABLE idempotency_keys_scoped (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    principal VARCHAR(255) NOT NULL DEFAULT '', -- The role and subject of the caller
+    key VARCHAR(255) NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    status_code INT NOT NULL DEFAULT 0, -- 0 while the first request is still in progress
+    content_type VARCHAR(100),
+    response_body BLOB,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL
+);
+
+INSERT INTO idempotency_keys_scoped (id, key, request_hash, status_code, content_type, response_body, created_at, expires_at)
+SELECT id, key, request_hash, status_code, content_type, response_body, created_at, expires_at FROM idempotency_keys;
+
+DROP TABLE idempotency_keys;
+ALTER TABLE idempotency_keys_scoped RENAME TO idempotency_keys;
+
+-- Add indexes for optimization
+CREATE UNIQUE INDEX idx_idempotency_keys_principal_key ON idempotency_keys(principal, key);
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
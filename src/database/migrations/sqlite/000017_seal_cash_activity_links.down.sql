@@ -0,0 +1,15 @@
+-- The previous encoding does not cover the reversal and fee links, so the
+-- hashes sealed with them no longer verify once the service is rolled back:
+-- refuse a database holding any.
+CREATE TEMP TABLE sealed_links (activities INTEGER NOT NULL);
+
+CREATE TEMP TRIGGER sealed_links_refused BEFORE INSERT ON sealed_links WHEN NEW.activities > 0
+BEGIN
+    SELECT RAISE(ABORT, 'cash activities were sealed with their reversal or fee links, which the previous version does not verify');
+END;
+
+INSERT INTO sealed_links
+SELECT COUNT(*) FROM cash_activities
+WHERE reversal_of_id IS NOT NULL OR fee_schedule_id IS NOT NULL OR fee_of_id IS NOT NULL OR fee_period IS NOT NULL;
+
+DROP TABLE sealed_links;
@@ -0,0 +1,16 @@
+-- The hash of a cash activity now also covers its reversal and fee links.
+-- SQLite has no SHA-256 to reseal the existing history with, so a database
+-- holding activities sealed without their links is refused rather than left
+-- failing verification: recreate it.
+CREATE TEMP TABLE sealed_links (activities INTEGER NOT NULL);
+
+CREATE TEMP TRIGGER sealed_links_refused BEFORE INSERT ON sealed_links WHEN NEW.activities > 0
+BEGIN
+    SELECT RAISE(ABORT, 'cash activities were sealed without their reversal or fee links, recreate the SQLite database');
+END;
+
+INSERT INTO sealed_links
+SELECT COUNT(*) FROM cash_activities
+WHERE reversal_of_id IS NOT NULL OR fee_schedule_id IS NOT NULL OR fee_of_id IS NOT NULL OR fee_period IS NOT NULL;
+
+DROP TABLE sealed_links;
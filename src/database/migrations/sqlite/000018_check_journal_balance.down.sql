@@ -0,0 +1,5 @@
+-- Stop checking that journal entries balance
+DROP TRIGGER IF EXISTS journal_lines_balanced_update;
+DROP TRIGGER IF EXISTS journal_lines_balanced_insert;
+DROP TABLE IF EXISTS unbalanced_journal_entries;
+DROP TABLE IF EXISTS journal_balance_checks;
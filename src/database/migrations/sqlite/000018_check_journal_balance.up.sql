@@ -0,0 +1,29 @@
+-- Refuse to commit a journal entry whose lines do not sum to zero, as
+-- Postgres does. SQLite has no deferred triggers but checks deferred foreign
+-- keys at commit: an entry is listed in unbalanced_journal_entries while its
+-- lines do not balance, and a listed entry references journal_balance_checks,
+-- which never has rows, so a transaction that leaves an entry listed fails to
+-- commit. The lines of an entry can still be inserted one by one.
+CREATE TABLE journal_balance_checks (
+    id INTEGER PRIMARY KEY
+);
+
+CREATE TABLE unbalanced_journal_entries (
+    journal_entry_id INTEGER PRIMARY KEY REFERENCES journal_balance_checks(id) DEFERRABLE INITIALLY DEFERRED
+);
+
+CREATE TRIGGER journal_lines_balanced_insert AFTER INSERT ON journal_lines
+BEGIN
+    INSERT OR IGNORE INTO unbalanced_journal_entries (journal_entry_id) VALUES (NEW.journal_entry_id);
+    DELETE FROM unbalanced_journal_entries
+    WHERE journal_entry_id = NEW.journal_entry_id
+        AND (SELECT ROUND(SUM(amount), 2) FROM journal_lines WHERE journal_entry_id = NEW.journal_entry_id) = 0;
+END;
+
+CREATE TRIGGER journal_lines_balanced_update AFTER UPDATE ON journal_lines
+BEGIN
+    INSERT OR IGNORE INTO unbalanced_journal_entries (journal_entry_id) VALUES (NEW.journal_entry_id);
+    DELETE FROM unbalanced_journal_entries
+    WHERE journal_entry_id = NEW.journal_entry_id
+        AND (SELECT ROUND(SUM(amount), 2) FROM journal_lines WHERE journal_entry_id = NEW.journal_entry_id) = 0;
+END;
@@ -0,0 +1,13 @@
+-- Decrypting the secrets is left to the application, so the rollback is
+-- refused while any subscription holds an encrypted secret.
+CREATE TEMP TABLE encrypted_secrets (subscriptions INTEGER NOT NULL);
+
+CREATE TEMP TRIGGER encrypted_secrets_refused BEFORE INSERT ON encrypted_secrets WHEN NEW.subscriptions > 0
+BEGIN
+    SELECT RAISE(ABORT, 'webhook subscriptions hold encrypted secrets, which cannot be decrypted by the database');
+END;
+
+INSERT INTO encrypted_secrets
+SELECT COUNT(*) FROM webhook_subscriptions WHERE secret LIKE 'v%:%' OR previous_secret LIKE 'v%:%';
+
+DROP TABLE encrypted_secrets;
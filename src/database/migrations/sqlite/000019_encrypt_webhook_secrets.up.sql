@@ -0,0 +1,6 @@
+-- Encrypt the secrets of webhook subscriptions, which sign their deliveries,
+-- with the keys of the personal data of accounts. Existing secrets are
+-- encrypted when migrating, right after this migration is applied. SQLite
+-- does not enforce the length of the type of the columns, so they are left as
+-- they are.
+SELECT 1;
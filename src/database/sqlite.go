@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"time"
+
+	_ "github.com/glebarez/go-sqlite" // Registers the pure-Go "sqlite" driver
+)
+
+// sqliteDriverName is the name the SQLite driver is registered under with its
+// arguments normalized by utcConn.
+const sqliteDriverName = "sqlite-utc"
+
+func init() {
+	db, err := sql.Open("sqlite", "")
+	if err != nil {
+		panic(err)
+	}
+	sql.Register(sqliteDriverName, utcDriver{db.Driver()})
+	db.Close()
+}
+
+// sqliteDSN returns the DSN of the SQLite database in the file at path.
+//
+// Every transaction begins immediately, taking the write lock of the database
+// for its whole duration: SQLite has no row locks, so this is what keeps a
+// transaction that has read a balance from racing another one that updates it.
+// The others wait for the lock for up to the busy timeout.
+func sqliteDSN(path string) string {
+	query := url.Values{}
+	query.Add("_pragma", "foreign_keys(1)")
+	query.Add("_pragma", "journal_mode(WAL)")
+	query.Add("_pragma", "busy_timeout(30000)")
+	query.Set("_txlock", "immediate")
+	return fmt.Sprintf("file:%s?%s", path, query.Encode())
+}
+
+// utcDriver opens connections binding times in UTC. SQLite stores times as
+// text, which compares correctly only between times written with the same
+// offset, and the service compares times of the business time zone with
+// times of the local one.
+type utcDriver struct {
+	driver.Driver
+}
+
+// sqliteConn is what the connections of the SQLite driver implement.
+type sqliteConn interface {
+	driver.Conn
+	driver.Pinger
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+}
+
+func (d utcDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	sqlite, ok := conn.(sqliteConn)
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected SQLite connection %T", conn)
+	}
+	return utcConn{sqlite}, nil
+}
+
+type utcConn struct {
+	sqliteConn
+}
+
+// CheckNamedValue converts times to UTC and leaves every other argument to the
+// default conversion.
+func (utcConn) CheckNamedValue(value *driver.NamedValue) error {
+	switch t := value.Value.(type) {
+	case time.Time:
+		value.Value = t.UTC()
+		return nil
+	case *time.Time:
+		if t != nil {
+			value.Value = t.UTC()
+			return nil
+		}
+	}
+	return driver.ErrSkip
+}
+
+// BeginTx begins a transaction that is rolled back when its commit fails.
+// SQLite checks deferred foreign keys at commit, as it does the balance of
+// journal entries, and leaves the transaction open when one fails, where
+// Postgres ends it; the connection would then refuse every later transaction.
+func (c utcConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	tx, err := c.sqliteConn.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return sqliteTx{tx}, nil
+}
+
+type sqliteTx struct {
+	driver.Tx
+}
+
+func (tx sqliteTx) Commit() error {
+	if err := tx.Tx.Commit(); err != nil {
+		tx.Tx.Rollback()
+		return err
+	}
+	return nil
+}
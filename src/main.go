@@ -3,20 +3,30 @@ package main
 import (
 	"account-service/src/config"
 	"account-service/src/database"
+	"account-service/src/metrics"
 	"account-service/src/middleware"
+	"account-service/src/publisher"
+	"account-service/src/repository"
 	"account-service/src/router"
+	"account-service/src/service"
+	"account-service/src/tracing"
 	"account-service/src/utils"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/compress"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/helmet"
+	"github.com/golang-migrate/migrate/v4"
 	"gorm.io/gorm"
 )
 
@@ -24,28 +34,76 @@ import (
 // @version 1.0.0
 // @host localhost:3000
 // @BasePath /v1
+// @securityDefinitions.apikey BearerAuth
 // @in header
+// @name Authorization
+// @description Type "Bearer" followed by a space and the access token.
 func main() {
+	// Flags come before the command: account-service -app-port 8080
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		utils.Log.Fatalf("%v", err)
+	}
+	utils.ConfigureLog(cfg.LogFormat, cfg.LogLevel)
+
+	if len(args) > 0 && args[0] == "migrate" {
+		os.Exit(runMigrateCommand(cfg, args[1:]))
+	}
+	if len(args) > 0 && args[0] == "token" {
+		os.Exit(runTokenCommand(cfg, args[1:]))
+	}
+	if len(args) > 0 && args[0] == "interest" {
+		os.Exit(runInterestCommand(cfg, args[1:]))
+	}
+	if len(args) > 0 && args[0] == "fees" {
+		os.Exit(runFeesCommand(cfg, args[1:]))
+	}
+	if len(args) > 0 && args[0] == "pii" {
+		os.Exit(runPIICommand(cfg, args[1:]))
+	}
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n", args[0])
+		os.Exit(2)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	app := setupFiberApp()
-	db := setupDatabase()
+	shutdownTracing := setupTracing(ctx, cfg)
+	defer shutdownTracing()
+	app := setupFiberApp(cfg)
+	db := setupDatabase(cfg)
 	defer closeDatabase(db)
-	setupRoutes(app, db)
-	appHost := flag.String("host", "localhost", "Application host")
-	appPort := flag.Int("port", 3000, "Application port")
-	address := fmt.Sprintf("%s:%d", *appHost, *appPort)
+	setupRoutes(app, db, cfg)
+	store := repository.NewGorm(db, cfg.PIIKeyring)
+	deliverer := service.NewWebhookDeliverer(store, cfg.WebhookPolicy)
+	dispatcherDone := startOutboxDispatcher(ctx, cfg, store, deliverer)
+	delivererDone := startWebhookDeliverer(ctx, deliverer)
+	purgerDone := startIdempotencyKeyPurger(ctx, cfg, store)
+	metricsDone := startMetricsSharing(ctx, app)
+	address := fmt.Sprintf("%s:%d", cfg.AppHost, cfg.AppPort)
 
 	// Start server and handle graceful shutdown
 	serverErrors := make(chan error, 1)
 	go startServer(app, address, serverErrors)
 	handleGracefulShutdown(ctx, app, serverErrors)
+
+	cancel()
+	<-dispatcherDone
+	<-delivererDone
+	<-purgerDone
+	<-metricsDone
 }
 
-func setupFiberApp() *fiber.App {
-	app := fiber.New(config.FiberConfig())
+func setupFiberApp(cfg *config.Config) *fiber.App {
+	app := fiber.New(cfg.FiberConfig())
 	// Middleware setup
+	app.Use(middleware.RequestIDConfig())
+	app.Use(middleware.TracingConfig())
+	app.Use(middleware.MetricsConfig())
 	app.Use("/v1", middleware.LimiterConfig())
 	app.Use(middleware.LoggerConfig())
 	app.Use(helmet.New())
@@ -56,16 +114,352 @@ func setupFiberApp() *fiber.App {
 	return app
 }
 
-func setupDatabase() *gorm.DB {
-	db := database.Connect()
+// setupTracing installs the tracer provider of the configured exporter,
+// returning a function flushing the spans still buffered.
+func setupTracing(ctx context.Context, cfg *config.Config) func() {
+	serviceName := cfg.AppName
+	if serviceName == "" {
+		serviceName = tracing.ScopeName
+	}
+	shutdown, err := tracing.Setup(ctx, cfg.TracingExporter, cfg.TracingOTLPEndpoint, serviceName, cfg.TracingSampleRatio)
+	if err != nil {
+		utils.Log.Fatalf("Failed to set up tracing: %v", err)
+	}
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			utils.Log.Errorf("Failed to flush traces: %v", err)
+		}
+	}
+}
+
+func setupDatabase(cfg *config.Config) *gorm.DB {
+	db := connectDatabase(cfg)
+	if sqlDB, err := db.DB(); err == nil {
+		if err := metrics.RegisterDB(sqlDB); err != nil {
+			utils.Log.Errorf("Failed to export database pool metrics: %v", err)
+		}
+	}
+
+	// With prefork only the parent process migrates.
+	if cfg.DBAutoMigrate && !fiber.IsChild() {
+		if err := migrateUp(cfg, db); err != nil {
+			utils.Log.Fatalf("Failed to migrate database: %v", err)
+		}
+		utils.Log.Info("Database migrations applied")
+	}
+
+	// Duplicate NIKs and phone numbers are only found through their blind
+	// index, so the service does not start while an account lacks one.
+	unindexed, fiberErr := service.NewPIIService(db, cfg.PIIKeyring).Unindexed(context.Background())
+	if fiberErr != nil {
+		utils.Log.Fatalf("Failed to check the account PII: %s", fiberErr.Message)
+	}
+	if unindexed > 0 {
+		utils.Log.Fatalf("%d accounts have no blind index of their PII yet, run \"account-service migrate up\"", unindexed)
+	}
+
 	return db
 }
 
-func setupRoutes(app *fiber.App, db *gorm.DB) {
-	router.Routes(app, db)
+// migrateUp applies the migrations that have not been applied yet, then
+// encrypts and indexes the PII of the accounts written before the migration
+// to encrypted columns, which SQL alone cannot do.
+func migrateUp(cfg *config.Config, db *gorm.DB) error {
+	if err := database.MigrateUp(db); err != nil {
+		return err
+	}
+	result, err := service.NewPIIService(db, cfg.PIIKeyring).Backfill(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to encrypt the account PII and webhook secrets: %s", err.Message)
+	}
+	if result.Rewritten > 0 {
+		utils.Log.Infof("Encrypted and indexed the PII of %d accounts", result.Rewritten)
+	}
+	if result.SubscriptionsRewritten > 0 {
+		utils.Log.Infof("Encrypted the secrets of %d webhook subscriptions", result.SubscriptionsRewritten)
+	}
+	return nil
+}
+
+// startOutboxDispatcher publishes the events of the outbox in the background
+// to the webhook subscriptions through deliverer and to the configured
+// publisher until ctx is cancelled, returning a channel closed once it has
+// stopped. With prefork only the parent process publishes.
+func startOutboxDispatcher(ctx context.Context, cfg *config.Config, store repository.UnitOfWork, deliverer *service.WebhookDeliverer) <-chan struct{} {
+	done := make(chan struct{})
+	if fiber.IsChild() {
+		close(done)
+		return done
+	}
+
+	pubs := []publisher.Publisher{deliverer}
+	var closer io.Closer
+	switch cfg.OutboxPublisher {
+	case publisher.KindStdout:
+		pubs = append(pubs, publisher.NewWriter(os.Stdout))
+	case publisher.KindFile:
+		filePublisher, file, err := publisher.NewFile(cfg.OutboxFile)
+		if err != nil {
+			utils.Log.Fatalf("Failed to open outbox file: %v", err)
+		}
+		pubs, closer = append(pubs, filePublisher), file
+	case publisher.KindWebhook:
+		pubs = append(pubs, publisher.NewWebhook(cfg.OutboxWebhookURL, cfg.OutboxWebhookTimeout))
+	}
+
+	go func() {
+		defer close(done)
+		service.NewOutboxDispatcher(store, publisher.NewMulti(pubs...), cfg.OutboxPolicy).Run(ctx)
+		if closer != nil {
+			if err := closer.Close(); err != nil {
+				utils.Log.Errorf("Failed to close outbox file: %v", err)
+			}
+		}
+	}()
+	return done
+}
+
+// startWebhookDeliverer posts the webhook deliveries in the background until
+// ctx is cancelled, returning a channel closed once it has stopped. With
+// prefork only the parent process delivers.
+func startWebhookDeliverer(ctx context.Context, deliverer *service.WebhookDeliverer) <-chan struct{} {
+	done := make(chan struct{})
+	if fiber.IsChild() {
+		close(done)
+		return done
+	}
+
+	go func() {
+		defer close(done)
+		deliverer.Run(ctx)
+	}()
+	return done
+}
+
+// startIdempotencyKeyPurger deletes expired idempotency keys in the
+// background until ctx is cancelled, returning a channel closed once it has
+// stopped. With prefork only the parent process purges.
+func startIdempotencyKeyPurger(ctx context.Context, cfg *config.Config, store repository.UnitOfWork) <-chan struct{} {
+	done := make(chan struct{})
+	if fiber.IsChild() {
+		close(done)
+		return done
+	}
+
+	go func() {
+		defer close(done)
+		service.NewIdempotencyKeyPurger(store, cfg.IdempotencyKeyPurgeInterval).Run(ctx)
+	}()
+	return done
+}
+
+// startMetricsSharing shares the metrics of this process with the other
+// processes of a prefork service until ctx is cancelled, so that scraping any
+// of them gives those of all, returning a channel closed once it has stopped.
+func startMetricsSharing(ctx context.Context, app *fiber.App) <-chan struct{} {
+	done := make(chan struct{})
+	dir := metrics.Dir(app)
+	if dir == "" {
+		close(done)
+		return done
+	}
+
+	go func() {
+		defer close(done)
+		if err := metrics.Serve(ctx, dir); err != nil {
+			utils.Log.Errorf("Failed to share metrics: %v", err)
+		}
+	}()
+	return done
+}
+
+// runMigrateCommand handles "migrate up", "migrate down [steps]" and
+// "migrate version", returning the process exit code.
+func runMigrateCommand(cfg *config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: account-service migrate up|down [steps]|version")
+		return 2
+	}
+
+	db := connectDatabase(cfg)
+	defer closeDatabase(db)
+
+	switch args[0] {
+	case "up":
+		if err := migrateUp(cfg, db); err != nil {
+			utils.Log.Errorf("%v", err)
+			return 1
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				fmt.Fprintf(os.Stderr, "invalid number of steps: %s\n", args[1])
+				return 2
+			}
+			steps = n
+		}
+		if err := database.MigrateDown(db, steps); err != nil {
+			utils.Log.Errorf("%v", err)
+			return 1
+		}
+	case "version":
+		version, dirty, err := database.MigrationVersion(db)
+		if errors.Is(err, migrate.ErrNilVersion) {
+			fmt.Println("no migration applied")
+			return 0
+		}
+		if err != nil {
+			utils.Log.Errorf("%v", err)
+			return 1
+		}
+		if dirty {
+			fmt.Printf("%d (dirty)\n", version)
+		} else {
+			fmt.Println(version)
+		}
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate command: %s\n", args[0])
+		return 2
+	}
+
+	version, dirty, err := database.MigrationVersion(db)
+	if err == nil {
+		utils.Log.Infof("Schema version %d (dirty: %t)", version, dirty)
+	}
+	return 0
+}
+
+func setupRoutes(app *fiber.App, db *gorm.DB, cfg *config.Config) {
+	router.Routes(app, db, cfg)
 	app.Use(utils.NotFoundHandler)
 }
 
+// runTokenCommand handles "token <role> <subject>", printing a signed access
+// token. It bootstraps the first admin, who then issues tokens over the API.
+func runTokenCommand(cfg *config.Config, args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: account-service token customer|teller|admin <subject>")
+		return 2
+	}
+
+	token, err := middleware.NewJWT(cfg.JWTSecret, cfg.JWTTTL).Issue(args[1], args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to issue token: %v\n", err)
+		return 2
+	}
+
+	fmt.Println(token.Token)
+	return 0
+}
+
+// runInterestCommand handles "interest run [YYYY-MM-DD]", accruing the
+// interest of a business day, yesterday by default, and crediting it at month
+// end. It is meant to be scheduled daily after midnight; running a date again
+// does nothing.
+func runInterestCommand(cfg *config.Config, args []string) int {
+	if len(args) == 0 || args[0] != "run" || len(args) > 2 {
+		fmt.Fprintln(os.Stderr, "usage: account-service interest run [YYYY-MM-DD]")
+		return 2
+	}
+
+	date := time.Now().In(cfg.BusinessLocation).AddDate(0, 0, -1)
+	if len(args) == 2 {
+		parsed, err := time.ParseInLocation(time.DateOnly, args[1], cfg.BusinessLocation)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid business date: %s\n", args[1])
+			return 2
+		}
+		date = parsed
+	}
+
+	db := connectDatabase(cfg)
+	defer closeDatabase(db)
+
+	run, err := service.NewInterestService(repository.NewGorm(db, cfg.PIIKeyring), service.NewLedger(), utils.Validator(), cfg.BusinessLocation).Run(context.Background(), date)
+	if err != nil {
+		utils.Log.Errorf("%s", err.Message)
+		return 1
+	}
+	if run.AlreadyCompleted {
+		fmt.Printf("interest for %s was already run at %s\n", run.BusinessDate.Format(time.DateOnly), run.CompletedAt.Format(time.RFC3339))
+		return 0
+	}
+	fmt.Printf("interest for %s: %d accounts accrued %s, %d accounts credited %s\n",
+		run.BusinessDate.Format(time.DateOnly), run.AccruedAccounts, run.TotalAccrued, run.CapitalizedAccounts, run.TotalCapitalized)
+	return 0
+}
+
+// runFeesCommand handles "fees run [YYYY-MM]", charging the monthly admin fees
+// of a month, last month by default. It is meant to be scheduled after the
+// interest of the month has been credited; running a month again only charges
+// the accounts it skipped.
+func runFeesCommand(cfg *config.Config, args []string) int {
+	if len(args) == 0 || args[0] != "run" || len(args) > 2 {
+		fmt.Fprintln(os.Stderr, "usage: account-service fees run [YYYY-MM]")
+		return 2
+	}
+
+	now := time.Now().In(cfg.BusinessLocation)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, cfg.BusinessLocation).AddDate(0, -1, 0)
+	if len(args) == 2 {
+		parsed, err := time.ParseInLocation("2006-01", args[1], cfg.BusinessLocation)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid month: %s\n", args[1])
+			return 2
+		}
+		month = parsed
+	}
+
+	db := connectDatabase(cfg)
+	defer closeDatabase(db)
+
+	result, err := service.NewFeeService(repository.NewGorm(db, cfg.PIIKeyring), service.NewLedger(), utils.Validator(), cfg.BusinessLocation).ChargeMonthlyFees(context.Background(), month)
+	if err != nil {
+		utils.Log.Errorf("%s", err.Message)
+		return 1
+	}
+	fmt.Printf("monthly fees for %s: %d accounts charged %s, %d skipped\n", result.Month, result.Charged, result.Total, result.Skipped)
+	return 0
+}
+
+// runPIICommand handles "pii rotate", encrypting the ID numbers and phone
+// numbers of the accounts and the webhook secrets with the active key of
+// PII_KEYS. It is run after a new key is made active, before the previous one
+// is removed from PII_KEYS.
+func runPIICommand(cfg *config.Config, args []string) int {
+	if len(args) != 1 || args[0] != "rotate" {
+		fmt.Fprintln(os.Stderr, "usage: account-service pii rotate")
+		return 2
+	}
+
+	db := connectDatabase(cfg)
+	defer closeDatabase(db)
+
+	result, err := service.NewPIIService(db, cfg.PIIKeyring).Rotate(context.Background())
+	if err != nil {
+		utils.Log.Errorf("%s", err.Message)
+		return 1
+	}
+	fmt.Printf("account PII under key %d: %d accounts read, %d rewritten\n", cfg.PIIKeyring.ActiveVersion(), result.Accounts, result.Rewritten)
+	fmt.Printf("webhook secrets under key %d: %d subscriptions read, %d rewritten\n", cfg.PIIKeyring.ActiveVersion(), result.Subscriptions, result.SubscriptionsRewritten)
+	return 0
+}
+
+// connectDatabase connects to the database of cfg, exiting if it cannot.
+func connectDatabase(cfg *config.Config) *gorm.DB {
+	db, err := database.Connect(cfg)
+	if err != nil {
+		utils.Log.Fatalf("%v", err)
+	}
+	return db
+}
+
 func startServer(app *fiber.App, address string, errs chan<- error) {
 	if err := app.Listen(address); err != nil {
 		errs <- fmt.Errorf("error starting server: %w", err)
@@ -0,0 +1,96 @@
+// Package metrics keeps the metrics of the service and serves them in the
+// Prometheus text exposition format.
+package metrics
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// Outcomes of a deposit or a withdrawal.
+const (
+	OutcomeSuccess             = "success"
+	OutcomeInsufficientBalance = "insufficient_balance"
+	OutcomeNotFound            = "not_found"
+	OutcomeRejected            = "rejected" // Any other client error
+	OutcomeError               = "error"
+)
+
+// Registry holds the metrics of this process.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Number of HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of HTTP requests by method and route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	deposits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "account_deposits_total",
+		Help: "Number of deposits by outcome.",
+	}, []string{"outcome"})
+	depositedAmount = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "account_deposited_amount_total",
+		Help: "Amount of the successful deposits.",
+	})
+	withdrawals = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "account_withdrawals_total",
+		Help: "Number of withdrawals by outcome.",
+	}, []string{"outcome"})
+	withdrawnAmount = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "account_withdrawn_amount_total",
+		Help: "Amount of the successful withdrawals, fees excluded.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpRequestDuration,
+		deposits, depositedAmount,
+		withdrawals, withdrawnAmount,
+	)
+	// Every outcome is exported from the start, so that rates can be taken
+	// before the first failure.
+	for _, outcome := range []string{OutcomeSuccess, OutcomeInsufficientBalance, OutcomeNotFound, OutcomeRejected, OutcomeError} {
+		deposits.WithLabelValues(outcome)
+		withdrawals.WithLabelValues(outcome)
+	}
+}
+
+// RegisterDB exports the connection pool statistics of db.
+func RegisterDB(db *sql.DB) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, "account"))
+}
+
+// ObserveRequest records an HTTP request to route, the path it was routed by.
+func ObserveRequest(method, route string, status int, duration time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpRequestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// ObserveDeposit records a deposit of amount with its outcome.
+func ObserveDeposit(outcome string, amount float64) {
+	deposits.WithLabelValues(outcome).Inc()
+	if outcome == OutcomeSuccess {
+		depositedAmount.Add(amount)
+	}
+}
+
+// ObserveWithdrawal records a withdrawal of amount with its outcome.
+func ObserveWithdrawal(outcome string, amount float64) {
+	withdrawals.WithLabelValues(outcome).Inc()
+	if outcome == OutcomeSuccess {
+		withdrawnAmount.Add(amount)
+	}
+}
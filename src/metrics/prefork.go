@@ -0,0 +1,196 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"account-service/src/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// With prefork every process counts what it serves in a registry of its own,
+// and a scrape reaches only one of them. So each process also serves its
+// metrics, labelled with its pid as the worker, on a unix socket in a
+// directory shared by the processes of the service, and the one scraped
+// gathers those of the others from there.
+
+// workerLabel is the label telling apart the metrics of the processes of a
+// prefork service.
+const workerLabel = "worker"
+
+// siblingTimeout bounds how long a scrape waits for another process.
+const siblingTimeout = 2 * time.Second
+
+// Dir returns the directory where the processes of app share their metrics,
+// or "" without prefork.
+func Dir(app *fiber.App) string {
+	if !app.Config().Prefork {
+		return ""
+	}
+	master := os.Getpid()
+	if fiber.IsChild() {
+		master = os.Getppid()
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("account-service-metrics-%d", master))
+}
+
+// Handler serves the metrics of this process and of the other processes
+// sharing dir in the Prometheus text format. A process that cannot be reached
+// is left out of the scrape.
+func Handler(dir string) fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(Gatherer(dir), promhttp.HandlerOpts{
+		ErrorLog:      utils.Log,
+		ErrorHandling: promhttp.ContinueOnError,
+	}))
+}
+
+// Gatherer returns the gatherer of the metrics of this process and, unless
+// dir is empty, of the other processes sharing dir.
+func Gatherer(dir string) prometheus.Gatherer {
+	if dir == "" {
+		return Registry
+	}
+	return prometheus.Gatherers{worker(os.Getpid()), siblings{dir: dir, self: socketPath(dir, os.Getpid())}}
+}
+
+// Serve shares the metrics of this process in dir until ctx is done. The
+// master process removes dir on return.
+func Serve(ctx context.Context, dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	if !fiber.IsChild() {
+		defer os.RemoveAll(dir)
+	}
+
+	path := socketPath(dir, os.Getpid())
+	os.Remove(path) // Left behind by a killed process with the same pid
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	server := &http.Server{
+		Handler:           promhttp.HandlerFor(worker(os.Getpid()), promhttp.HandlerOpts{}),
+		ReadHeaderTimeout: siblingTimeout,
+	}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func socketPath(dir string, pid int) string {
+	return filepath.Join(dir, strconv.Itoa(pid)+".sock")
+}
+
+// workerGatherer gathers the metrics of this process with the worker label.
+type workerGatherer string
+
+func worker(pid int) workerGatherer {
+	return workerGatherer(strconv.Itoa(pid))
+}
+
+func (w workerGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := Registry.Gather()
+	name, value := workerLabel, string(w)
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			metric.Label = append(metric.Label, &dto.LabelPair{Name: &name, Value: &value})
+			sort.Slice(metric.Label, func(i, j int) bool {
+				return metric.Label[i].GetName() < metric.Label[j].GetName()
+			})
+		}
+	}
+	return families, err
+}
+
+// siblings gathers the metrics the other processes share in dir.
+type siblings struct {
+	dir  string
+	self string
+}
+
+func (s siblings) Gather() ([]*dto.MetricFamily, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.sock"))
+	if err != nil {
+		return nil, err
+	}
+
+	var families []*dto.MetricFamily
+	var errs prometheus.MultiError
+	for _, path := range paths {
+		if path == s.self {
+			continue
+		}
+		gathered, err := gatherSocket(path)
+		if err != nil {
+			errs.Append(fmt.Errorf("gathering the metrics of %s: %w", filepath.Base(path), err))
+			continue
+		}
+		families = append(families, gathered...)
+	}
+	return families, errs.MaybeUnwrap()
+}
+
+// gatherSocket fetches the metrics served on the unix socket at path.
+func gatherSocket(path string) ([]*dto.MetricFamily, error) {
+	client := http.Client{
+		Timeout: siblingTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", path)
+			},
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	req, err := http.NewRequest(http.MethodGet, "http://metrics/", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", string(expfmt.NewFormat(expfmt.TypeProtoDelim)))
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var families []*dto.MetricFamily
+	decoder := expfmt.NewDecoder(resp.Body, expfmt.ResponseFormat(resp.Header))
+	for {
+		family := &dto.MetricFamily{}
+		err := decoder.Decode(family)
+		if errors.Is(err, io.EOF) {
+			return families, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		families = append(families, family)
+	}
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"account-service/src/model"
+	"account-service/src/response"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Roles carried by access tokens. A customer token is bound to one account,
+// whose number is the token subject; tellers and admins act on any account.
+const (
+	RoleCustomer = "customer"
+	RoleTeller   = "teller"
+	RoleAdmin    = "admin"
+)
+
+const claimsLocalKey = "claims"
+
+var (
+	ErrUnauthenticated = errors.New("Please authenticate")
+	ErrForbidden       = errors.New("You don't have permission to access this resource")
+	errUnknownRole     = errors.New("unknown role")
+)
+
+// Claims of an access token.
+type Claims struct {
+	Role string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// CanAccessAccount reports whether the holder may act on accountNumber.
+func (claims *Claims) CanAccessAccount(accountNumber string) bool {
+	if claims.Role == RoleCustomer {
+		return claims.Subject == accountNumber
+	}
+	return claims.Role == RoleTeller || claims.Role == RoleAdmin
+}
+
+// JWT issues and verifies HS256 signed access tokens.
+type JWT struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+func NewJWT(secret string, ttl time.Duration) *JWT {
+	return &JWT{
+		secret: []byte(secret),
+		ttl:    ttl,
+	}
+}
+
+// Issue signs a token for subject with role, valid for the configured TTL.
+func (j *JWT) Issue(subject, role string) (*model.Token, error) {
+	if role != RoleCustomer && role != RoleTeller && role != RoleAdmin {
+		return nil, errUnknownRole
+	}
+
+	now := time.Now()
+	expires := now.Add(j.ttl)
+	claims := Claims{
+		Role: role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expires),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(j.secret)
+	if err != nil {
+		return nil, err
+	}
+	return &model.Token{Token: token, Expires: expires}, nil
+}
+
+// Verify parses token, checking its signature and expiry.
+func (j *JWT) Verify(token string) (*Claims, error) {
+	claims := new(Claims)
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return j.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, jwt.ErrTokenInvalidSubject
+	}
+	return claims, nil
+}
+
+// Auth requires a valid bearer token whose role is one of roles, and makes
+// its claims available through CurrentClaims.
+func Auth(j *JWT, roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token, found := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if !found || token == "" {
+			return response.ErrorCustom(c, fiber.StatusUnauthorized, ErrUnauthenticated.Error(), nil)
+		}
+
+		claims, err := j.Verify(token)
+		if err != nil {
+			return response.ErrorCustom(c, fiber.StatusUnauthorized, ErrUnauthenticated.Error(), nil)
+		}
+		if !slices.Contains(roles, claims.Role) {
+			return response.ErrorCustom(c, fiber.StatusForbidden, ErrForbidden.Error(), nil)
+		}
+
+		c.Locals(claimsLocalKey, claims)
+		return c.Next()
+	}
+}
+
+// CurrentClaims returns the claims stored by Auth, or nil on routes without
+// authentication.
+func CurrentClaims(c *fiber.Ctx) *Claims {
+	claims, _ := c.Locals(claimsLocalKey).(*Claims)
+	return claims
+}
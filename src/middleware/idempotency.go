@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"account-service/src/response"
+	"account-service/src/service"
+	"account-service/src/utils"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	idempotencyKeyMaxLength  = 255
+)
+
+// Idempotency makes retries of a request carrying an Idempotency-Key header
+// safe: the first response is stored and replayed for every retry with the
+// same key, while reusing the key for a different request is rejected.
+func Idempotency(idempotencyService service.IdempotencyService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(IdempotencyKeyHeader)
+		if key == "" {
+			return c.Next()
+		}
+		if len(key) > idempotencyKeyMaxLength {
+			return response.ErrorCustom(c, fiber.StatusBadRequest, "Idempotency-Key header is too long", nil)
+		}
+
+		principal := principalOf(c)
+		stored, err := idempotencyService.Begin(c.UserContext(), principal, key, requestHash(c))
+		if err != nil {
+			return response.Error(c, err, nil)
+		}
+		if stored != nil {
+			c.Set(IdempotentReplayedHeader, "true")
+			c.Set(fiber.HeaderContentType, stored.ContentType)
+			return c.Status(stored.StatusCode).Send(stored.ResponseBody)
+		}
+
+		if err := c.Next(); err != nil {
+			// The error handler writes the response later, so there is
+			// nothing to store; let the client retry with the same key.
+			release(c, idempotencyService, principal, key)
+			return err
+		}
+
+		statusCode := c.Response().StatusCode()
+		if statusCode >= fiber.StatusInternalServerError {
+			release(c, idempotencyService, principal, key)
+			return nil
+		}
+
+		body := append([]byte(nil), c.Response().Body()...)
+		contentType := string(c.Response().Header.ContentType())
+		if err := idempotencyService.Complete(c.UserContext(), principal, key, statusCode, contentType, body); err != nil {
+			utils.Log.WithContext(c.UserContext()).Errorf("Failed to store idempotent response: %+v", err)
+		}
+		return nil
+	}
+}
+
+func release(c *fiber.Ctx, idempotencyService service.IdempotencyService, principal, key string) {
+	if err := idempotencyService.Release(c.UserContext(), principal, key); err != nil {
+		utils.Log.WithContext(c.UserContext()).Errorf("Failed to release idempotency key: %+v", err)
+	}
+}
+
+// principalOf identifies the caller of a request by the role and subject of its
+// token. Idempotency keys are scoped to it, so a key replays only to the
+// caller that chose it.
+func principalOf(c *fiber.Ctx) string {
+	if claims := CurrentClaims(c); claims != nil {
+		return claims.Role + ":" + claims.Subject
+	}
+	return ""
+}
+
+// requestHash fingerprints the method, path and body of a request.
+func requestHash(c *fiber.Ctx) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.Method()))
+	hash.Write([]byte{0})
+	hash.Write([]byte(c.Path()))
+	hash.Write([]byte{0})
+	hash.Write(c.Body())
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -1,13 +1,39 @@
 package middleware
 
 import (
+	"account-service/src/utils"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/sirupsen/logrus"
 )
 
+// LoggerConfig logs every request through utils.Log once it has been served,
+// with its request ID.
 func LoggerConfig() fiber.Handler {
-	return logger.New(logger.Config{
-		Format:     "${time} ${method} ${status} ${path} in ${latency}\n",
-		TimeFormat: "15:04:05.00",
-	})
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		if err := c.Next(); err != nil {
+			// Render the error now, so that its status is the one logged
+			if err := c.App().ErrorHandler(c, err); err != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		status := c.Response().StatusCode()
+		entry := utils.Log.WithContext(c.UserContext()).WithFields(logrus.Fields{
+			"method":      c.Method(),
+			"path":        c.Path(),
+			"status":      status,
+			"duration_ms": float64(time.Since(start).Microseconds()) / 1000,
+			"ip":          c.IP(),
+		})
+		switch {
+		case status >= fiber.StatusInternalServerError:
+			entry.Error("Request failed")
+		default:
+			entry.Info("Request served")
+		}
+		return nil
+	}
 }
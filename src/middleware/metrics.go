@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"account-service/src/metrics"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// MetricsConfig counts the requests and their latency by the path of the route
+// that served them, never by the raw path, which would give every account its
+// own series. Requests no route matched are counted under the path of the
+// last middleware they went through.
+func MetricsConfig() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		if err := c.Next(); err != nil {
+			// Render the error now, so that its status is the one counted
+			if err := c.App().ErrorHandler(c, err); err != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+		metrics.ObserveRequest(c.Method(), c.Route().Path, c.Response().StatusCode(), time.Since(start))
+		return nil
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"account-service/src/utils"
+	"regexp"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the ID of a request, from the caller or generated,
+// and is echoed in the response.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern is what a request ID from a caller must look like to be
+// kept. Anything else is replaced, so that it cannot forge log lines.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestIDConfig gives every request an ID, the one of its X-Request-ID header
+// or a new one, and hands it to the handlers in the user context, where the
+// logs of the request pick it up.
+func RequestIDConfig() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Get(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = uuid.NewString()
+		}
+		c.Set(RequestIDHeader, id)
+		c.SetUserContext(utils.WithRequestID(c.UserContext(), id))
+		return c.Next()
+	}
+}
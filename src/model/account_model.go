@@ -1,20 +1,84 @@
 package model
 
 import (
+	"account-service/src/pii"
 	"time"
+
+	"gorm.io/gorm"
+)
+
+// Account statuses. An active account accepts every operation; a frozen or
+// dormant account keeps its balance but moves no money until it is
+// reactivated; a closed account is final.
+const (
+	AccountStatusActive  = "active"
+	AccountStatusFrozen  = "frozen"
+	AccountStatusDormant = "dormant"
+	AccountStatusClosed  = "closed"
 )
 
+// accountStatusTransitions lists the statuses each status may move to.
+var accountStatusTransitions = map[string][]string{
+	AccountStatusActive:  {AccountStatusFrozen, AccountStatusDormant, AccountStatusClosed},
+	AccountStatusFrozen:  {AccountStatusActive, AccountStatusClosed},
+	AccountStatusDormant: {AccountStatusActive, AccountStatusFrozen, AccountStatusClosed},
+	AccountStatusClosed:  {},
+}
+
 // Account Model
 type Account struct {
 	ID            uint           `gorm:"primaryKey" json:"id"`
 	AccountNumber string         `gorm:"uniqueIndex;not null" json:"account_number"`
 	FullName      string         `gorm:"not null" json:"full_name"`
-	IDNumber      string         `gorm:"uniqueIndex;not null" json:"id_number"`
-	PhoneNumber   string         `gorm:"uniqueIndex;not null" json:"phone_number"`
-	Balance       float64        `gorm:"not null;default:0.00" json:"balance"`
+	IDNumber      string         `gorm:"serializer:pii;not null" json:"id_number"`    // Encrypted at rest
+	PhoneNumber   string         `gorm:"serializer:pii;not null" json:"phone_number"` // Encrypted at rest
+	Balance       Money          `gorm:"not null;default:0.00" json:"balance"`
+	Status        string         `gorm:"not null;default:active" json:"status"` // 'active', 'frozen', 'dormant' or 'closed'
+	ProductCode   string         `gorm:"not null;default:tabungan" json:"product_code"`
 	CreatedAt     time.Time      `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt     time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
 	CashActivity  []CashActivity `gorm:"foreignKey:AccountID;references:ID" json:"-"`
+
+	PINHash           string     `gorm:"column:pin_hash;not null;default:''" json:"-"` // bcrypt hash, empty until a PIN is set
+	PINFailedAttempts int        `gorm:"column:pin_failed_attempts;not null;default:0" json:"-"`
+	PINLockedAt       *time.Time `gorm:"column:pin_locked_at" json:"-"`
+
+	// Blind indexes of IDNumber and PhoneNumber, by which they are looked up
+	// and kept unique
+	IDNumberHash    string `gorm:"column:id_number_hash;uniqueIndex" json:"-"`
+	PhoneNumberHash string `gorm:"column:phone_number_hash;uniqueIndex" json:"-"`
+}
+
+// BeforeCreate indexes the ID number and the phone number of a new account
+// with the keyring of the statement.
+func (account *Account) BeforeCreate(tx *gorm.DB) error {
+	keyring, err := pii.FromContext(tx.Statement.Context)
+	if err != nil {
+		return err
+	}
+	account.IDNumberHash = keyring.BlindIndex(account.IDNumber)
+	account.PhoneNumberHash = keyring.BlindIndex(account.PhoneNumber)
+	return nil
+}
+
+// Masked returns a copy of the account with its ID number and phone number
+// masked, as shown in responses.
+func (account *Account) Masked() *Account {
+	masked := *account
+	masked.IDNumber = pii.Mask(account.IDNumber)
+	masked.PhoneNumber = pii.Mask(account.PhoneNumber)
+	return &masked
+}
+
+// CanTransitionTo reports whether the account may move from its current
+// status to status.
+func (account *Account) CanTransitionTo(status string) bool {
+	for _, next := range accountStatusTransitions[account.Status] {
+		if next == status {
+			return true
+		}
+	}
+	return false
 }
 
 // CreateAccount struct for account registration (daftar)
@@ -22,6 +86,8 @@ type CreateAccount struct {
 	FullName    string `json:"nama" validate:"required,max=50" example:"John Doe"`
 	IDNumber    string `json:"nik" validate:"required,len=16,numeric" example:"1234567890123456"`
 	PhoneNumber string `json:"no_hp" validate:"required,max=15,numeric" example:"081234567890"`
+	PIN         PIN    `json:"pin" validate:"required,len=6,numeric" swaggertype:"string" example:"123456"`
+	ProductCode string `json:"produk" validate:"omitempty,max=20" example:"tabungan"` // Defaults to DefaultProductCode
 }
 
 // CreateAccountResponse struct for account of user registration
@@ -29,7 +95,28 @@ type CreateAccountResponse struct {
 	AccountNumber string `json:"no_rekening" example:"9876543210"`
 }
 
+// AccountStatusRequest struct for freezing, unfreezing or closing an account
+type AccountStatusRequest struct {
+	Actor  string `json:"-" validate:"required,max=100"` // Set from the authenticated caller
+	Reason string `json:"alasan" validate:"required,max=255" example:"Suspicious activity reported by the customer"`
+}
+
+// AccountStatusResponse struct for account status change response
+type AccountStatusResponse struct {
+	AccountNumber string `json:"no_rekening" example:"9876543210"`
+	Status        string `json:"status" example:"frozen"`
+}
+
 // ErrorResponse struct for error responses
 type ErrorResponse struct {
 	Remark string `json:"remark" example:"Invalid input data"`
 }
+
+// PIIRotationResult struct for the result of re-encrypting the ID numbers and
+// phone numbers of the accounts and the secrets of the webhook subscriptions
+type PIIRotationResult struct {
+	Accounts               int // Accounts read
+	Rewritten              int // Accounts encrypted with the active key or indexed again
+	Subscriptions          int // Webhook subscriptions read
+	SubscriptionsRewritten int // Webhook subscriptions whose secrets were encrypted with the active key
+}
@@ -0,0 +1,17 @@
+package model
+
+import (
+	"time"
+)
+
+// AccountStatusHistory Model
+type AccountStatusHistory struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	AccountID  uint      `gorm:"not null" json:"account_id"`
+	FromStatus string    `gorm:"not null" json:"from_status"`
+	ToStatus   string    `gorm:"not null" json:"to_status"`
+	Actor      string    `gorm:"not null" json:"actor"`
+	Reason     string    `gorm:"type:text;not null" json:"reason"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+	Account    Account   `gorm:"foreignKey:AccountID;references:ID" json:"-"` // Belongs to Account
+}
@@ -1,53 +1,175 @@
 package model
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
 	"time"
 )
 
+// cashActivityHashTimeFormat renders created_at at the microsecond precision
+// the database stores it with.
+const cashActivityHashTimeFormat = "2006-01-02T15:04:05.000000Z"
+
 // CashActivity Model
 type CashActivity struct {
-	ID            uint          `gorm:"primaryKey" json:"id"`
-	AccountID     uint          `gorm:"not null" json:"account_id"`
-	ReferenceID   *uint         `gorm:"null" json:"reference_id"` // Allow null for the first transaction
-	Type          string        `gorm:"not null" json:"type"`     // 'debit' or 'credit'
-	Nominal       float64       `gorm:"not null" json:"nominal"`
-	BalanceBefore float64       `gorm:"not null" json:"balance_before"`
-	BalanceAfter  float64       `gorm:"not null" json:"balance_after"`
-	Description   string        `gorm:"type:text" json:"description"`
-	CreatedAt     time.Time     `gorm:"autoCreateTime" json:"created_at"`
-	Account       Account       `gorm:"foreignKey:AccountID;references:ID" json:"-"`   // Belongs to Account
-	Reference     *CashActivity `gorm:"foreignKey:ReferenceID;references:ID" json:"-"` // Belongs to another CashActivity (previous transaction)
+	ID                uint          `gorm:"primaryKey" json:"id"`
+	AccountID         uint          `gorm:"not null" json:"account_id"`
+	ReferenceID       *uint         `gorm:"null" json:"reference_id"` // Allow null for the first transaction
+	Type              string        `gorm:"not null" json:"type"`     // 'debit' or 'credit'
+	Nominal           Money         `gorm:"not null" json:"nominal"`
+	BalanceBefore     Money         `gorm:"not null" json:"balance_before"`
+	BalanceAfter      Money         `gorm:"not null" json:"balance_after"`
+	Description       string        `gorm:"type:text" json:"description"`
+	TransferReference *string       `gorm:"null" json:"transfer_reference"`   // Shared by both legs of a transfer
+	Hash              string        `gorm:"null" json:"hash"`                 // Hash of this activity chained to the previous one
+	ReversalOfID      *uint         `gorm:"null" json:"reversal_of_id"`       // Set on the compensating activity of a reversal
+	ReversedAt        *time.Time    `gorm:"null" json:"reversed_at"`          // Set on an activity once it has been reversed
+	FeeScheduleID     *uint         `gorm:"null" json:"fee_schedule_id"`      // Set on a fee to the schedule it was charged under
+	FeeOfID           *uint         `gorm:"null" json:"fee_of_id"`            // Set on a fee to the activity that triggered it
+	FeePeriod         *time.Time    `gorm:"type:date;null" json:"fee_period"` // Set on a monthly fee to the first day of its month
+	CreatedAt         time.Time     `gorm:"autoCreateTime" json:"created_at"`
+	Account           Account       `gorm:"foreignKey:AccountID;references:ID" json:"-"`   // Belongs to Account
+	Reference         *CashActivity `gorm:"foreignKey:ReferenceID;references:ID" json:"-"` // Belongs to another CashActivity (previous transaction)
+}
+
+// ComputeHash returns the SHA-256 of the activity's fields chained to the hash
+// of the previous activity of the same account, so altering or removing any
+// earlier activity breaks every later hash. ReversedAt is not covered, as it
+// is set after the activity is posted. The reversal and fee links are fixed
+// when it is posted, and covered as "<column>=<value>" fields only when set,
+// which leaves the hash of every activity without them unchanged. Each field
+// is encoded as "<byte length>:<value>;", which the sealing migrations
+// reproduce in SQL.
+func (activity *CashActivity) ComputeHash(previousHash string) string {
+	referenceID := ""
+	if activity.ReferenceID != nil {
+		referenceID = strconv.FormatUint(uint64(*activity.ReferenceID), 10)
+	}
+	transferReference := ""
+	if activity.TransferReference != nil {
+		transferReference = *activity.TransferReference
+	}
+	createdAt := ""
+	if !activity.CreatedAt.IsZero() {
+		createdAt = activity.CreatedAt.UTC().Format(cashActivityHashTimeFormat)
+	}
+
+	fields := []string{
+		strconv.FormatUint(uint64(activity.AccountID), 10),
+		referenceID,
+		activity.Type,
+		activity.Nominal.String(),
+		activity.BalanceBefore.String(),
+		activity.BalanceAfter.String(),
+		activity.Description,
+		transferReference,
+		createdAt,
+	}
+	for _, link := range []struct {
+		column string
+		id     *uint
+	}{
+		{"reversal_of_id", activity.ReversalOfID},
+		{"fee_schedule_id", activity.FeeScheduleID},
+		{"fee_of_id", activity.FeeOfID},
+	} {
+		if link.id != nil {
+			fields = append(fields, link.column+"="+strconv.FormatUint(uint64(*link.id), 10))
+		}
+	}
+	if activity.FeePeriod != nil {
+		fields = append(fields, "fee_period="+activity.FeePeriod.Format(time.DateOnly))
+	}
+	fields = append(fields, previousHash)
+
+	hash := sha256.New()
+	for _, field := range fields {
+		fmt.Fprintf(hash, "%d:%s;", len(field), field)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// LedgerVerification struct for the result of walking an account's activity chain
+type LedgerVerification struct {
+	AccountNumber  string `json:"no_rekening" example:"9876543210"`
+	Valid          bool   `json:"valid" example:"false"`
+	CheckedEntries int    `json:"checked_entries" example:"42"`
+	BrokenAt       *uint  `json:"broken_at,omitempty" example:"17"` // ID of the first activity that breaks the chain
+	Reason         string `json:"reason,omitempty" example:"hash mismatch"`
 }
 
 // DepositRequest struct for deposit operation (tabung)
 type DepositRequest struct {
-	AccountNumber string  `json:"no_rekening" validate:"required,numeric" example:"9876543210"`
-	Nominal       float64 `json:"nominal" validate:"required,gt=0" example:"100000"`
+	AccountNumber string `json:"no_rekening" validate:"required,numeric" example:"9876543210"`
+	Nominal       Money  `json:"nominal" validate:"required,gt=0" swaggertype:"number" example:"100000"`
 }
 
 // DepositResponse struct for deposit operation response
 type DepositResponse struct {
-	Balance float64 `json:"saldo" example:"500000"`
+	Balance Money `json:"saldo" swaggertype:"number" example:"500000"`
 }
 
 // Withdrawal struct for withdrawal operation (tarik)
 type Withdrawal struct {
-	AccountNumber string  `json:"no_rekening" validate:"required,numeric" example:"9876543210"`
-	Nominal       float64 `json:"nominal" validate:"required,gt=0" example:"50000"`
+	AccountNumber string `json:"no_rekening" validate:"required,numeric" example:"9876543210"`
+	Nominal       Money  `json:"nominal" validate:"required,gt=0" swaggertype:"number" example:"50000"`
+	PIN           PIN    `json:"pin" validate:"required,len=6,numeric" swaggertype:"string" example:"123456"`
 }
 
 // WithdrawalResponse struct for withdrawal operation response
 type WithdrawalResponse struct {
-	Balance float64 `json:"saldo" example:"450000"`
+	Balance Money `json:"saldo" swaggertype:"number" example:"450000"`
+}
+
+// TransferRequest struct for transfer operation between two accounts (transfer)
+type TransferRequest struct {
+	FromAccountNumber string `json:"no_rekening_asal" validate:"required,numeric" example:"9876543210"`
+	ToAccountNumber   string `json:"no_rekening_tujuan" validate:"required,numeric,nefield=FromAccountNumber" example:"1234567890"`
+	Nominal           Money  `json:"nominal" validate:"required,gt=0" swaggertype:"number" example:"50000"`
+	PIN               PIN    `json:"pin" validate:"required,len=6,numeric" swaggertype:"string" example:"123456"`
+}
+
+// TransferBalance struct for the balance of one side of a transfer
+type TransferBalance struct {
+	AccountNumber string `json:"no_rekening" example:"9876543210"`
+	Balance       Money  `json:"saldo" swaggertype:"number" example:"450000"`
+}
+
+// TransferResponse struct for transfer operation response
+type TransferResponse struct {
+	Reference string          `json:"referensi" example:"0b6f3c52-8a51-4c1e-9f0e-3f9b7d6a2c11"`
+	From      TransferBalance `json:"asal"`
+	To        TransferBalance `json:"tujuan"`
+}
+
+// ReversalRequest struct for reversing a deposit or withdrawal (reversal)
+type ReversalRequest struct {
+	Reason string `json:"alasan" validate:"required,max=255" example:"Deposit posted to the wrong account"`
+}
+
+// ReversalResponse struct for reversal operation response
+type ReversalResponse struct {
+	TransactionID uint   `json:"id_transaksi" example:"41"`
+	ReversalID    uint   `json:"id_reversal" example:"42"`
+	FeeReversalID *uint  `json:"id_reversal_biaya,omitempty" example:"43"` // Set when the fee of a withdrawal was refunded with it
+	AccountNumber string `json:"no_rekening" example:"9876543210"`
+	Balance       Money  `json:"saldo" swaggertype:"number" example:"450000"`
 }
 
 // BalanceResponse struct for checking balance (saldo) response
 type BalanceResponse struct {
-	Balance float64 `json:"saldo" example:"450000"`
+	Balance Money `json:"saldo" swaggertype:"number" example:"450000"`
 }
 
-// Mutation struct for withdrawal operation (tarik)
+// Mutation struct for transaction history query (mutasi)
 type Mutation struct {
-	AccountNumber string `json:"no_rekening" validate:"required,numeric" example:"9876543210"`
-	Month         int    `json:"bulan" validate:"required,numeric,lt=13,gt=0" example:"1"`
+	AccountNumber string `query:"no_rekening" json:"no_rekening" validate:"required,numeric" example:"9876543210"`
+	Month         int    `query:"bulan" json:"bulan" validate:"required_without=From,excluded_with=From,omitempty,lt=13,gt=0" example:"1"`
+	Year          int    `query:"tahun" json:"tahun" validate:"omitempty,gte=1970" example:"2025"`
+	From          string `query:"dari" json:"dari" validate:"required_without=Month,omitempty,datetime=2006-01-02" example:"2025-01-01"`
+	To            string `query:"sampai" json:"sampai" validate:"required_with=From,omitempty,datetime=2006-01-02" example:"2025-01-31"`
+	Page          int    `query:"page" json:"page" validate:"omitempty,gt=0" example:"1"`
+	Limit         int    `query:"limit" json:"limit" validate:"omitempty,gt=0,lte=100" example:"10"`
 }
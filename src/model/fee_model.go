@@ -0,0 +1,48 @@
+package model
+
+import (
+	"time"
+)
+
+// Fee types
+const (
+	FeeTypeWithdrawal   = "withdrawal"    // Charged on each withdrawal after the free ones of the month
+	FeeTypeMonthlyAdmin = "monthly_admin" // Charged once a month for the month past
+)
+
+// FeeSchedule Model, one version of the fee rule of a product. The version in
+// force on a day is the one with the latest EffectiveFrom on or before it.
+type FeeSchedule struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	ProductCode   string    `gorm:"not null" json:"product_code"`
+	Type          string    `gorm:"not null" json:"type"` // 'withdrawal' or 'monthly_admin'
+	Amount        Money     `gorm:"not null" json:"amount"`
+	FreePerMonth  int       `gorm:"not null;default:0" json:"free_per_month"` // Withdrawals a month before the fee applies
+	EffectiveFrom time.Time `gorm:"type:date;not null" json:"effective_from"` // Midnight UTC of the business day
+	CreatedAt     time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// WithdrawalFee returns the fee of a withdrawal when the account has already
+// made withdrawalsThisMonth withdrawals this month.
+func (schedule *FeeSchedule) WithdrawalFee(withdrawalsThisMonth int) Money {
+	if schedule == nil || withdrawalsThisMonth < schedule.FreePerMonth {
+		return NewMoney(0)
+	}
+	return schedule.Amount
+}
+
+// FeeScheduleRequest struct for adding a version of a fee rule
+type FeeScheduleRequest struct {
+	Type          string `json:"jenis" validate:"required,oneof=withdrawal monthly_admin" example:"withdrawal"`
+	Amount        Money  `json:"nominal" validate:"gte=0" swaggertype:"number" example:"2500"` // 0 waives the fee
+	FreePerMonth  int    `json:"gratis_per_bulan" validate:"gte=0" example:"5"`                // Only for withdrawal fees
+	EffectiveFrom string `json:"berlaku_mulai" validate:"required,datetime=2006-01-02" example:"2025-02-01"`
+}
+
+// MonthlyFeeResult struct for the result of charging the monthly fees
+type MonthlyFeeResult struct {
+	Month   string `json:"bulan" example:"2025-01"`
+	Charged int    `json:"charged" example:"120"`
+	Total   Money  `json:"total" swaggertype:"number" example:"600000"`
+	Skipped int    `json:"skipped" example:"3"` // Accounts whose balance could not cover the fee
+}
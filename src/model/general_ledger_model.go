@@ -0,0 +1,127 @@
+package model
+
+import (
+	"time"
+)
+
+// GL account types. Assets and expenses carry debit balances; liabilities,
+// equity and income carry credit balances.
+const (
+	GLAccountTypeAsset     = "asset"
+	GLAccountTypeLiability = "liability"
+	GLAccountTypeEquity    = "equity"
+	GLAccountTypeIncome    = "income"
+	GLAccountTypeExpense   = "expense"
+)
+
+// Codes of the GL accounts the service posts to, seeded by the migration that
+// creates the chart of accounts.
+const (
+	GLCashCode             = "1000" // Cash held by the bank
+	GLCustomerDepositsCode = "2000" // What the bank owes its customers, the sum of all account balances
+	GLSuspenseCode         = "2900" // Amounts whose counterpart is still to be investigated
+	GLFeeIncomeCode        = "4000" // Fees charged to customers
+	GLInterestExpenseCode  = "5000" // Interest credited to customers
+)
+
+// GLAccount Model, an entry of the chart of accounts
+type GLAccount struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Code      string    `gorm:"uniqueIndex;not null" json:"code"`
+	Name      string    `gorm:"not null" json:"name"`
+	Type      string    `gorm:"not null" json:"type"` // 'asset', 'liability', 'equity', 'income' or 'expense'
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// JournalEntry Model
+type JournalEntry struct {
+	ID             uint          `gorm:"primaryKey" json:"id"`
+	Description    string        `gorm:"type:text;not null" json:"description"`
+	CashActivityID *uint         `gorm:"null" json:"cash_activity_id"` // The customer activity the journal accounts for
+	CreatedAt      time.Time     `gorm:"autoCreateTime" json:"created_at"`
+	Lines          []JournalLine `gorm:"foreignKey:JournalEntryID;references:ID" json:"lines"`
+}
+
+// JournalLine Model
+type JournalLine struct {
+	ID             uint   `gorm:"primaryKey" json:"id"`
+	JournalEntryID uint   `gorm:"not null" json:"journal_entry_id"`
+	GLAccountCode  string `gorm:"column:gl_account_code;not null" json:"gl_account_code"`
+	Amount         Money  `gorm:"not null" json:"amount"` // Debits are positive, credits negative
+}
+
+// NewActivityJournal returns the journal of a cash activity posted against
+// the customer deposits liability, with contraCode on the other side: a
+// credit to the customer debits contraCode, a debit credits it.
+func NewActivityJournal(activity *CashActivity, contraCode string, description string) *JournalEntry {
+	amount := activity.Nominal
+	if activity.Type == "debit" {
+		amount = amount.Neg()
+	}
+	return &JournalEntry{
+		Description:    description,
+		CashActivityID: &activity.ID,
+		Lines: []JournalLine{
+			{GLAccountCode: contraCode, Amount: amount},
+			{GLAccountCode: GLCustomerDepositsCode, Amount: amount.Neg()},
+		},
+	}
+}
+
+// Reverse returns a journal undoing entry, for the compensating activity.
+func (entry *JournalEntry) Reverse(activity *CashActivity, description string) *JournalEntry {
+	lines := make([]JournalLine, len(entry.Lines))
+	for i, line := range entry.Lines {
+		lines[i] = JournalLine{GLAccountCode: line.GLAccountCode, Amount: line.Amount.Neg()}
+	}
+	return &JournalEntry{
+		Description:    description,
+		CashActivityID: &activity.ID,
+		Lines:          lines,
+	}
+}
+
+// IsBalanced reports whether the entry has at least two lines and its debits
+// equal its credits.
+func (entry *JournalEntry) IsBalanced() bool {
+	if len(entry.Lines) < 2 {
+		return false
+	}
+	sum := NewMoney(0)
+	for _, line := range entry.Lines {
+		sum = sum.Add(line.Amount)
+	}
+	return sum.IsZero()
+}
+
+// TrialBalanceRequest struct for the trial balance report
+type TrialBalanceRequest struct {
+	Date string `query:"tanggal" json:"tanggal" validate:"omitempty,datetime=2006-01-02" example:"2025-01-31"` // Defaults to today
+}
+
+// TrialBalanceLine struct for the balance of one GL account
+type TrialBalanceLine struct {
+	Code   string `json:"code" example:"1000"`
+	Name   string `json:"name" example:"Cash"`
+	Type   string `json:"type" example:"asset"`
+	Debit  Money  `json:"debit" swaggertype:"number" example:"150000"`
+	Credit Money  `json:"credit" swaggertype:"number" example:"0"`
+}
+
+// TrialBalance struct for the trial balance report
+type TrialBalance struct {
+	Date        string             `json:"tanggal" example:"2025-01-31"`
+	Accounts    []TrialBalanceLine `json:"accounts"`
+	TotalDebit  Money              `json:"total_debit" swaggertype:"number" example:"150000"`
+	TotalCredit Money              `json:"total_credit" swaggertype:"number" example:"150000"`
+	Balanced    bool               `json:"balanced" example:"true"`
+}
+
+// GLReconciliation struct for the check that the customer balances equal the
+// customer deposits liability
+type GLReconciliation struct {
+	CustomerBalances Money `json:"customer_balances" swaggertype:"number" example:"150000"`
+	LiabilityBalance Money `json:"liability_balance" swaggertype:"number" example:"150000"`
+	Difference       Money `json:"difference" swaggertype:"number" example:"0"`
+	Balanced         bool  `json:"balanced" example:"true"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+)
+
+// IdempotencyKey Model
+type IdempotencyKey struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Principal    string    `gorm:"uniqueIndex:idx_idempotency_keys_principal_key;not null" json:"principal"` // The role and subject of the caller
+	Key          string    `gorm:"uniqueIndex:idx_idempotency_keys_principal_key;not null" json:"key"`
+	RequestHash  string    `gorm:"not null" json:"request_hash"`
+	StatusCode   int       `gorm:"not null;default:0" json:"status_code"` // 0 while the first request is still in progress
+	ContentType  string    `json:"content_type"`
+	ResponseBody []byte    `json:"-"`
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
+	ExpiresAt    time.Time `gorm:"not null" json:"expires_at"`
+}
+
+// IsCompleted reports whether the response of the first request was stored.
+func (key *IdempotencyKey) IsCompleted() bool {
+	return key.StatusCode != 0
+}
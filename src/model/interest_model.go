@@ -0,0 +1,93 @@
+package model
+
+import (
+	"math/big"
+	"time"
+)
+
+const (
+	// DefaultProductCode is the product of accounts opened without one.
+	DefaultProductCode = "tabungan"
+	// InterestDaysPerYear is the day count basis of daily interest.
+	InterestDaysPerYear = 365
+
+	basisPointsPerUnit = 10000
+)
+
+// Product Model, a savings product setting the interest its accounts earn
+type Product struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	Code          string    `gorm:"uniqueIndex;not null" json:"code"`
+	Name          string    `gorm:"not null" json:"name"`
+	AnnualRateBPS int       `gorm:"column:annual_rate_bps;not null;default:0" json:"annual_rate_bps"` // Basis points, 100 is 1% a year
+	CreatedAt     time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// InterestAccrual Model, the interest earned by an account on one business day
+type InterestAccrual struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	AccountID      uint      `gorm:"not null" json:"account_id"`
+	BusinessDate   time.Time `gorm:"type:date;not null" json:"business_date"` // Midnight UTC of the business day
+	Balance        Money     `gorm:"not null" json:"balance"`                 // End-of-day balance the interest is earned on
+	AnnualRateBPS  int       `gorm:"column:annual_rate_bps;not null" json:"annual_rate_bps"`
+	Amount         Money     `gorm:"not null" json:"amount"`
+	CashActivityID *uint     `gorm:"null" json:"cash_activity_id"` // The credit that capitalized the interest, nil while pending
+	CreatedAt      time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// InterestRun Model, a business date the interest job has completed
+type InterestRun struct {
+	ID                  uint      `gorm:"primaryKey" json:"id"`
+	BusinessDate        time.Time `gorm:"type:date;uniqueIndex;not null" json:"business_date"` // Midnight UTC of the business day
+	AccruedAccounts     int       `gorm:"not null;default:0" json:"accrued_accounts"`
+	TotalAccrued        Money     `gorm:"not null;default:0" json:"total_accrued"`
+	CapitalizedAccounts int       `gorm:"not null;default:0" json:"capitalized_accounts"` // Accounts credited with the interest of the month, on its last day
+	TotalCapitalized    Money     `gorm:"not null;default:0" json:"total_capitalized"`
+	CompletedAt         time.Time `gorm:"autoCreateTime" json:"completed_at"`
+	AlreadyCompleted    bool      `gorm:"-" json:"-"` // Set when the date had been run before and nothing was done
+}
+
+// EndOfDayBalance struct for the balance an account earns interest on at the
+// end of a business day
+type EndOfDayBalance struct {
+	AccountID     uint
+	AnnualRateBPS int // Of the product of the account
+	Balance       Money
+}
+
+// DailyInterest returns the interest earned in one day by balance at
+// annualRateBPS, rounded half up to the sen.
+func DailyInterest(balance Money, annualRateBPS int) Money {
+	// balance and the rate can overflow int64 when multiplied.
+	interest := new(big.Int).Mul(big.NewInt(balance.minor), big.NewInt(int64(annualRateBPS)))
+	denominator := big.NewInt(basisPointsPerUnit * InterestDaysPerYear)
+	quotient, remainder := new(big.Int).QuoRem(interest, denominator, new(big.Int))
+	if remainder.Lsh(remainder.Abs(remainder), 1).Cmp(denominator) >= 0 {
+		quotient.Add(quotient, big.NewInt(int64(interest.Sign())))
+	}
+	return Money{minor: quotient.Int64()}
+}
+
+// ProductRateRequest struct for setting the interest rate of a product
+type ProductRateRequest struct {
+	AnnualRateBPS *int `json:"suku_bunga_bps" validate:"required,gte=0,lte=10000" example:"150"` // Basis points, 150 is 1.5% a year
+}
+
+// InterestAccrualLine struct for one day of pending interest
+type InterestAccrualLine struct {
+	Date          string `json:"tanggal" example:"2025-01-31"`
+	Balance       Money  `json:"saldo" swaggertype:"number" example:"1000000"`
+	AnnualRateBPS int    `json:"suku_bunga_bps" example:"100"`
+	Amount        Money  `json:"bunga" swaggertype:"number" example:"27.40"`
+}
+
+// PendingInterestResponse struct for the interest accrued but not yet
+// credited to an account
+type PendingInterestResponse struct {
+	AccountNumber string                `json:"no_rekening" example:"9876543210"`
+	ProductCode   string                `json:"produk" example:"tabungan"`
+	AnnualRateBPS int                   `json:"suku_bunga_bps" example:"100"`
+	Pending       Money                 `json:"bunga_tertunda" swaggertype:"number" example:"849.40"`
+	Accruals      []InterestAccrualLine `json:"rincian"`
+}
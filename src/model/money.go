@@ -0,0 +1,237 @@
+package model
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MoneyScale is the number of minor unit digits of the currency, matching
+	// the NUMERIC(15, 2) columns of the database.
+	MoneyScale = 2
+	// MoneyMaxIntegerDigits is the number of integer digits a NUMERIC(15, 2)
+	// column can hold.
+	MoneyMaxIntegerDigits = 15 - MoneyScale
+
+	minorUnitsPerUnit = 100
+)
+
+var (
+	ErrInvalidMoney   = errors.New("invalid money amount")
+	ErrMoneyPrecision = fmt.Errorf("money amount has more than %d decimal places", MoneyScale)
+	ErrMoneyOverflow  = fmt.Errorf("money amount has more than %d integer digits", MoneyMaxIntegerDigits)
+)
+
+// Money is an exact currency amount held as a whole number of minor units
+// (sen), so repeated arithmetic never drifts the way float64 does.
+type Money struct {
+	minor int64
+}
+
+// NewMoney returns an amount of whole currency units.
+func NewMoney(units int64) Money {
+	return Money{minor: units * minorUnitsPerUnit}
+}
+
+// MoneyFromMinorUnits returns an amount of minor units (sen).
+func MoneyFromMinorUnits(minor int64) Money {
+	return Money{minor: minor}
+}
+
+// ParseMoney parses a plain decimal such as "1500", "-12.5" or "0.01". It
+// rejects amounts with more decimal places than the currency has.
+func ParseMoney(s string) (Money, error) {
+	return parseMoney(s, false)
+}
+
+// MustParseMoney is like ParseMoney but panics on error. It is meant for
+// constants and tests.
+func MustParseMoney(s string) Money {
+	m, err := ParseMoney(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// parseMoney parses a plain decimal. When round is set, extra decimal places
+// are rounded half away from zero instead of being rejected.
+func parseMoney(s string, round bool) (Money, error) {
+	s = strings.TrimSpace(s)
+	negative := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		negative = true
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	integer, fraction, _ := strings.Cut(s, ".")
+	if integer == "" && fraction == "" || !isDigits(integer) || !isDigits(fraction) {
+		return Money{}, ErrInvalidMoney
+	}
+
+	integer = strings.TrimLeft(integer, "0")
+	if len(integer) > MoneyMaxIntegerDigits {
+		return Money{}, ErrMoneyOverflow
+	}
+
+	roundUp := false
+	if len(fraction) > MoneyScale {
+		if !round {
+			if strings.Trim(fraction[MoneyScale:], "0") != "" {
+				return Money{}, ErrMoneyPrecision
+			}
+		} else {
+			roundUp = fraction[MoneyScale] >= '5'
+		}
+		fraction = fraction[:MoneyScale]
+	}
+	fraction += strings.Repeat("0", MoneyScale-len(fraction))
+
+	minor, err := strconv.ParseInt(integer+fraction, 10, 64)
+	if err != nil {
+		return Money{}, ErrInvalidMoney
+	}
+	if roundUp {
+		minor++
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{minor: minor}, nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// MinorUnits returns the amount in minor units (sen).
+func (m Money) MinorUnits() int64 {
+	return m.minor
+}
+
+// Float64 returns an approximation of the amount. It must only be used for
+// display or metrics, never for further arithmetic.
+func (m Money) Float64() float64 {
+	return float64(m.minor) / minorUnitsPerUnit
+}
+
+func (m Money) Add(other Money) Money {
+	return Money{minor: m.minor + other.minor}
+}
+
+func (m Money) Sub(other Money) Money {
+	return Money{minor: m.minor - other.minor}
+}
+
+func (m Money) Neg() Money {
+	return Money{minor: -m.minor}
+}
+
+// Cmp returns -1, 0 or +1 depending on whether m is less than, equal to or
+// greater than other.
+func (m Money) Cmp(other Money) int {
+	switch {
+	case m.minor < other.minor:
+		return -1
+	case m.minor > other.minor:
+		return 1
+	}
+	return 0
+}
+
+func (m Money) LessThan(other Money) bool {
+	return m.minor < other.minor
+}
+
+func (m Money) IsZero() bool {
+	return m.minor == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.minor < 0
+}
+
+// String formats the amount with exactly MoneyScale decimal places, e.g.
+// "1500.00".
+func (m Money) String() string {
+	sign := ""
+	minor := m.minor
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	return fmt.Sprintf("%s%d.%0*d", sign, minor/minorUnitsPerUnit, MoneyScale, minor%minorUnitsPerUnit)
+}
+
+// MarshalJSON encodes the amount as a JSON number with exactly MoneyScale
+// decimal places.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a quoted decimal string and rejects
+// amounts with more decimal places than the currency has.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if len(data) >= 2 && data[0] == '"' && data[len(data)-1] == '"' {
+		data = data[1 : len(data)-1]
+	}
+
+	parsed, err := ParseMoney(string(data))
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value implements driver.Valuer, storing the amount as an exact decimal.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan implements sql.Scanner, rounding to minor units if the database returns
+// more precision than the currency has.
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*m = Money{}
+		return nil
+	case int64:
+		*m = NewMoney(v)
+		return nil
+	case float64:
+		*m = Money{minor: int64(math.Round(v * minorUnitsPerUnit))}
+		return nil
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	}
+	return fmt.Errorf("cannot scan %T into Money", src)
+}
+
+func (m *Money) scanString(s string) error {
+	parsed, err := parseMoney(s, true)
+	if err != nil {
+		return fmt.Errorf("cannot scan %q into Money: %w", s, err)
+	}
+	*m = parsed
+	return nil
+}
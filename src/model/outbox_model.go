@@ -0,0 +1,142 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event types
+const (
+	EventAccountCreated      = "AccountCreated"
+	EventFundsDeposited      = "FundsDeposited"
+	EventFundsWithdrawn      = "FundsWithdrawn"
+	EventTransferSent        = "TransferSent"
+	EventTransferReceived    = "TransferReceived"
+	EventFeeCharged          = "FeeCharged"
+	EventInterestCredited    = "InterestCredited"
+	EventTransactionReversed = "TransactionReversed"
+)
+
+// OutboxEvent Model, an event waiting to be published or already published.
+// Events of an account are published one at a time in ID order, so a
+// consumer sees the changes of an account in the order they were made.
+type OutboxEvent struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	AccountNumber string     `gorm:"not null" json:"account_number"`
+	Type          string     `gorm:"not null" json:"type"`
+	Payload       string     `gorm:"type:jsonb;not null" json:"payload"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"` // Failed attempts to publish
+	NextAttemptAt time.Time  `gorm:"not null" json:"next_attempt_at"`
+	LastError     string     `gorm:"type:text" json:"last_error"`
+	PublishedAt   *time.Time `gorm:"null" json:"published_at"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "outbox"
+}
+
+// NewOutboxEvent returns the event of type eventType about an account, with
+// data as its payload.
+func NewOutboxEvent(accountNumber string, eventType string, data any) (*OutboxEvent, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &OutboxEvent{
+		AccountNumber: accountNumber,
+		Type:          eventType,
+		Payload:       string(payload),
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}, nil
+}
+
+// Event returns the message the event is published as.
+func (event *OutboxEvent) Event() *Event {
+	return &Event{
+		ID:            event.ID,
+		Type:          event.Type,
+		AccountNumber: event.AccountNumber,
+		OccurredAt:    event.CreatedAt,
+		Data:          json.RawMessage(event.Payload),
+	}
+}
+
+// RetryDelay returns how long to wait before publishing the event again
+// after its attempts failed: base, doubled after every further failure, up
+// to max.
+func (event *OutboxEvent) RetryDelay(base, max time.Duration) time.Duration {
+	return retryDelay(event.Attempts, base, max)
+}
+
+// retryDelay returns base doubled after every failed attempt past the first,
+// up to max.
+func retryDelay(attempts int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
+
+// OutboxPolicy struct for how the outbox dispatcher publishes events
+type OutboxPolicy struct {
+	PollInterval  time.Duration // Wait between passes that found nothing to publish
+	RetryDelay    time.Duration // Wait after a failed attempt, doubled after each further one
+	MaxRetryDelay time.Duration
+	BatchSize     int           // Events published per pass, at most one per account
+	ClaimTimeout  time.Duration // Wait before the events of a dispatcher that stopped mid-pass are published again
+}
+
+// Event is the message an outbox event is published as. Events are
+// published at least once; consumers drop the IDs they have already seen.
+type Event struct {
+	ID            uint            `json:"id"`
+	Type          string          `json:"type"`
+	AccountNumber string          `json:"account_number"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// AccountCreatedData is the payload of AccountCreated.
+type AccountCreatedData struct {
+	AccountNumber string `json:"account_number"`
+	ProductCode   string `json:"product_code"`
+	Status        string `json:"status"`
+	Balance       Money  `json:"balance"`
+}
+
+// ActivityData is the payload of the events of a balance change.
+type ActivityData struct {
+	ActivityID        uint    `json:"activity_id"`
+	AccountNumber     string  `json:"account_number"`
+	Type              string  `json:"type"` // 'debit' or 'credit'
+	Nominal           Money   `json:"nominal"`
+	BalanceBefore     Money   `json:"balance_before"`
+	BalanceAfter      Money   `json:"balance_after"`
+	Description       string  `json:"description"`
+	TransferReference *string `json:"transfer_reference,omitempty"`
+	ReversalOfID      *uint   `json:"reversal_of_id,omitempty"`
+	FeeOfID           *uint   `json:"fee_of_id,omitempty"`
+}
+
+// NewActivityData returns the payload of the event of a posted activity.
+func NewActivityData(account *Account, activity *CashActivity) ActivityData {
+	return ActivityData{
+		ActivityID:        activity.ID,
+		AccountNumber:     account.AccountNumber,
+		Type:              activity.Type,
+		Nominal:           activity.Nominal,
+		BalanceBefore:     activity.BalanceBefore,
+		BalanceAfter:      activity.BalanceAfter,
+		Description:       activity.Description,
+		TransferReference: activity.TransferReference,
+		ReversalOfID:      activity.ReversalOfID,
+		FeeOfID:           activity.FeeOfID,
+	}
+}
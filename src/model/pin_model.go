@@ -0,0 +1,30 @@
+package model
+
+// pinMask is how a PIN is printed, whatever its value.
+const pinMask = "******"
+
+// PIN is a 6-digit transaction PIN. It formats as asterisks, so a request
+// logged with fmt never shows the PIN.
+type PIN string
+
+func (pin PIN) String() string {
+	return pinMask
+}
+
+func (pin PIN) GoString() string {
+	return pinMask
+}
+
+// PINPolicy configures how PINs are hashed and when they lock.
+type PINPolicy struct {
+	MaxAttempts int // Consecutive wrong PINs before the PIN locks, 0 never locks
+	HashCost    int // bcrypt cost of stored PIN hashes
+}
+
+// PINResetRequest struct for changing or resetting the PIN of an account
+type PINResetRequest struct {
+	AccountNumber string `json:"no_rekening" validate:"required,numeric" example:"9876543210"`
+	OldPIN        PIN    `json:"pin_lama" validate:"required_if=RequireOldPIN true,omitempty,len=6,numeric" swaggertype:"string" example:"123456"`
+	NewPIN        PIN    `json:"pin_baru" validate:"required,len=6,numeric" swaggertype:"string" example:"654321"`
+	RequireOldPIN bool   `json:"-"` // Set for customers; staff reset a forgotten or locked PIN
+}
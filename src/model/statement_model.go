@@ -0,0 +1,93 @@
+package model
+
+import (
+	"time"
+)
+
+// Statement formats
+const (
+	StatementFormatCSV     = "csv"
+	StatementFormatPDF     = "pdf"
+	StatementFormatCamt053 = "camt053" // ISO 20022 BankToCustomerStatement
+)
+
+// Kinds of statement lines
+const (
+	ActivityKindDeposit    = "deposit"
+	ActivityKindWithdrawal = "withdrawal"
+	ActivityKindTransfer   = "transfer"
+	ActivityKindFee        = "fee"
+	ActivityKindInterest   = "interest"
+	ActivityKindReversal   = "reversal"
+)
+
+// StatementRequest struct for exporting the statement of an account
+type StatementRequest struct {
+	From   string `query:"from" validate:"required,datetime=2006-01-02" example:"2025-01-01"`
+	To     string `query:"to" validate:"required,datetime=2006-01-02" example:"2025-01-31"` // Inclusive
+	Format string `query:"format" validate:"omitempty,oneof=csv pdf camt053" example:"csv"` // Defaults to csv
+}
+
+// Statement is everything about a statement that precedes its lines, so it
+// can be rendered before the lines are read.
+type Statement struct {
+	AccountNumber  string
+	FullName       string
+	From           time.Time // Start of the first business day
+	To             time.Time // End of the last business day, exclusive
+	GeneratedAt    time.Time
+	OpeningBalance Money
+	ClosingBalance Money
+	CreditCount    int
+	CreditTotal    Money
+	DebitCount     int
+	DebitTotal     Money
+
+	AccountID      uint
+	LastActivityID uint // Lines are read up to this activity, so later postings cannot change the totals
+}
+
+// LastDay returns the last business day the statement covers.
+func (statement *Statement) LastDay() time.Time {
+	return statement.To.Add(-time.Nanosecond)
+}
+
+// StatementLine is a cash activity as it appears on a statement.
+type StatementLine struct {
+	CashActivity
+	Interest bool // Set when the activity credited capitalized interest
+}
+
+// Kind returns what the activity of the line was.
+func (line *StatementLine) Kind() string {
+	switch {
+	case line.ReversalOfID != nil:
+		return ActivityKindReversal
+	case line.FeeScheduleID != nil:
+		return ActivityKindFee
+	case line.TransferReference != nil:
+		return ActivityKindTransfer
+	case line.Interest:
+		return ActivityKindInterest
+	case line.Type == "credit":
+		return ActivityKindDeposit
+	default:
+		return ActivityKindWithdrawal
+	}
+}
+
+// Debit returns the amount the line took from the account, zero for credits.
+func (line *StatementLine) Debit() Money {
+	if line.Type == "debit" {
+		return line.Nominal
+	}
+	return NewMoney(0)
+}
+
+// Credit returns the amount the line added to the account, zero for debits.
+func (line *StatementLine) Credit() Money {
+	if line.Type == "credit" {
+		return line.Nominal
+	}
+	return NewMoney(0)
+}
@@ -0,0 +1,131 @@
+package model
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryDead      = "dead" // Given up after the last attempt; only redelivered on request
+)
+
+// WebhookSubscription Model, a partner URL the events of the chosen types are
+// posted to. Deliveries are signed with Secret, and also with PreviousSecret
+// until it expires, so partners can switch secrets without missing events.
+type WebhookSubscription struct {
+	ID                      uint       `gorm:"primaryKey"`
+	URL                     string     `gorm:"not null"`
+	EventTypes              string     `gorm:"not null"` // Comma separated
+	Active                  bool       `gorm:"not null;default:true"`
+	Secret                  string     `gorm:"serializer:pii;not null"` // Encrypted at rest
+	PreviousSecret          string     `gorm:"serializer:pii"`          // Encrypted at rest
+	PreviousSecretExpiresAt *time.Time `gorm:"null"`
+	CreatedAt               time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt               time.Time  `gorm:"autoUpdateTime"`
+}
+
+// Subscribes reports whether events of eventType are delivered to the
+// subscription.
+func (subscription *WebhookSubscription) Subscribes(eventType string) bool {
+	return slices.Contains(strings.Split(subscription.EventTypes, ","), eventType)
+}
+
+// Secrets returns the secrets deliveries are signed with at now, the current
+// one first.
+func (subscription *WebhookSubscription) Secrets(now time.Time) []string {
+	secrets := []string{subscription.Secret}
+	if subscription.PreviousSecret != "" && subscription.PreviousSecretExpiresAt != nil && now.Before(*subscription.PreviousSecretExpiresAt) {
+		secrets = append(secrets, subscription.PreviousSecret)
+	}
+	return secrets
+}
+
+// Response returns the subscription as shown to admins, without its secrets.
+func (subscription *WebhookSubscription) Response() WebhookSubscriptionResponse {
+	return WebhookSubscriptionResponse{
+		ID:                      subscription.ID,
+		URL:                     subscription.URL,
+		EventTypes:              strings.Split(subscription.EventTypes, ","),
+		Active:                  subscription.Active,
+		PreviousSecretExpiresAt: subscription.PreviousSecretExpiresAt,
+		CreatedAt:               subscription.CreatedAt,
+		UpdatedAt:               subscription.UpdatedAt,
+	}
+}
+
+// WebhookSubscriptionRequest struct for registering or changing a
+// subscription
+type WebhookSubscriptionRequest struct {
+	URL        string   `json:"url" validate:"required,url,startswith=http,max=500" example:"https://partner.example.com/events"`
+	EventTypes []string `json:"event_types" validate:"required,min=1,unique,dive,oneof=AccountCreated FundsDeposited FundsWithdrawn TransferSent TransferReceived FeeCharged InterestCredited TransactionReversed" example:"FundsDeposited,FundsWithdrawn"`
+	Active     *bool    `json:"active" example:"true"` // Defaults to true
+}
+
+// WebhookSubscriptionResponse struct for a subscription. The secret is only
+// shown when it is created or rotated.
+type WebhookSubscriptionResponse struct {
+	ID                      uint       `json:"id" example:"1"`
+	URL                     string     `json:"url" example:"https://partner.example.com/events"`
+	EventTypes              []string   `json:"event_types" example:"FundsDeposited,FundsWithdrawn"`
+	Active                  bool       `json:"active" example:"true"`
+	Secret                  string     `json:"secret,omitempty" example:"whsec_4f9c..."`
+	PreviousSecretExpiresAt *time.Time `json:"previous_secret_expires_at,omitempty"` // Until then deliveries also carry the old signature
+	CreatedAt               time.Time  `json:"created_at"`
+	UpdatedAt               time.Time  `json:"updated_at"`
+}
+
+// WebhookDelivery Model, an event to post to a subscription.
+type WebhookDelivery struct {
+	ID             uint                     `gorm:"primaryKey" json:"id"`
+	SubscriptionID uint                     `gorm:"not null" json:"subscription_id"`
+	OutboxID       uint                     `gorm:"not null" json:"event_id"`
+	EventType      string                   `gorm:"not null" json:"event_type"`
+	Status         string                   `gorm:"not null;default:pending" json:"status"` // 'pending', 'delivered' or 'dead'
+	Attempts       int                      `gorm:"not null;default:0" json:"attempts"`     // Failed attempts since created or redelivered
+	NextAttemptAt  time.Time                `gorm:"not null" json:"next_attempt_at"`
+	LastStatusCode *int                     `gorm:"null" json:"last_status_code"`
+	LastError      string                   `gorm:"type:text" json:"last_error"`
+	DeliveredAt    *time.Time               `gorm:"null" json:"delivered_at"`
+	CreatedAt      time.Time                `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time                `gorm:"autoUpdateTime" json:"updated_at"`
+	AttemptLog     []WebhookDeliveryAttempt `gorm:"foreignKey:DeliveryID" json:"attempt_log,omitempty"`
+}
+
+// RetryDelay returns how long to wait before posting the delivery again
+// after its attempts failed: base, doubled after every further failure, up
+// to max.
+func (delivery *WebhookDelivery) RetryDelay(base, max time.Duration) time.Duration {
+	return retryDelay(delivery.Attempts, base, max)
+}
+
+// WebhookDeliveryAttempt Model, one post of a delivery and its outcome.
+type WebhookDeliveryAttempt struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	DeliveryID  uint      `gorm:"not null" json:"delivery_id"`
+	StatusCode  *int      `gorm:"null" json:"status_code"` // nil when no response was received
+	Error       string    `gorm:"type:text" json:"error"`
+	DurationMS  int64     `gorm:"not null" json:"duration_ms"`
+	AttemptedAt time.Time `gorm:"autoCreateTime" json:"attempted_at"`
+}
+
+// WebhookDeliveryQuery struct for listing the deliveries of a subscription
+type WebhookDeliveryQuery struct {
+	Status string `query:"status" validate:"omitempty,oneof=pending delivered dead" example:"dead"`
+	Page   int    `query:"page" validate:"omitempty,gt=0" example:"1"`
+	Limit  int    `query:"limit" validate:"omitempty,gt=0,lte=100" example:"10"`
+}
+
+// WebhookPolicy struct for how webhook deliveries are made
+type WebhookPolicy struct {
+	PollInterval      time.Duration // Wait between passes that found nothing to deliver
+	Timeout           time.Duration // Of one post
+	RetryDelay        time.Duration // Wait after a failed attempt, doubled after each further one
+	MaxRetryDelay     time.Duration
+	MaxAttempts       int           // Failed attempts before a delivery is dead
+	BatchSize         int           // Deliveries made per pass
+	SecretGracePeriod time.Duration // How long a rotated secret still signs deliveries
+}
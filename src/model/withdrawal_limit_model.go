@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+)
+
+// WithdrawalLimits bounds the withdrawals of an account. A zero value
+// disables the limit.
+type WithdrawalLimits struct {
+	MaxPerTransaction Money `json:"maks_per_transaksi" swaggertype:"number" example:"10000000"`
+	MaxDailyTotal     Money `json:"maks_total_harian" swaggertype:"number" example:"50000000"`
+	MaxDailyCount     int   `json:"maks_frekuensi_harian" example:"20"`
+}
+
+// AccountWithdrawalLimit Model
+type AccountWithdrawalLimit struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	AccountID         uint      `gorm:"uniqueIndex;not null" json:"account_id"`
+	MaxPerTransaction *Money    `gorm:"null" json:"max_per_transaction"` // Null falls back to the global default
+	MaxDailyTotal     *Money    `gorm:"null" json:"max_daily_total"`     // Null falls back to the global default
+	MaxDailyCount     *int      `gorm:"null" json:"max_daily_count"`     // Null falls back to the global default
+	CreatedAt         time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt         time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	Account           Account   `gorm:"foreignKey:AccountID;references:ID" json:"-"` // Belongs to Account
+}
+
+// Apply returns the limits with the overrides of the account applied.
+func (limits WithdrawalLimits) Apply(override *AccountWithdrawalLimit) WithdrawalLimits {
+	if override == nil {
+		return limits
+	}
+	if override.MaxPerTransaction != nil {
+		limits.MaxPerTransaction = *override.MaxPerTransaction
+	}
+	if override.MaxDailyTotal != nil {
+		limits.MaxDailyTotal = *override.MaxDailyTotal
+	}
+	if override.MaxDailyCount != nil {
+		limits.MaxDailyCount = *override.MaxDailyCount
+	}
+	return limits
+}
+
+// WithdrawalLimitRequest struct for overriding the withdrawal limits of an account
+type WithdrawalLimitRequest struct {
+	MaxPerTransaction *Money `json:"maks_per_transaksi" validate:"omitempty,gt=0" swaggertype:"number" example:"5000000"`
+	MaxDailyTotal     *Money `json:"maks_total_harian" validate:"omitempty,gt=0" swaggertype:"number" example:"20000000"`
+	MaxDailyCount     *int   `json:"maks_frekuensi_harian" validate:"omitempty,gt=0" example:"10"`
+}
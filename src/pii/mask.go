@@ -0,0 +1,28 @@
+package pii
+
+import (
+	"regexp"
+	"strings"
+)
+
+// maskKept is the number of characters Mask leaves at each end of a value.
+const maskKept = 4
+
+// Mask hides all of value but its first and last four characters, as in
+// 3201********0001. A value too short to keep anything is hidden whole.
+func Mask(value string) string {
+	if len(value) <= 2*maskKept {
+		return strings.Repeat("*", len(value))
+	}
+	return value[:maskKept] + strings.Repeat("*", len(value)-2*maskKept) + value[len(value)-maskKept:]
+}
+
+// piiPattern matches what reads as an ID number, sixteen digits, or as an
+// Indonesian mobile number, 08 or (+)628 then seven to twelve digits. Account
+// numbers, ten digits never starting with 0, are too short to match.
+var piiPattern = regexp.MustCompile(`\b\d{16}\b|\b08\d{7,12}\b|(?:\+|\b)628\d{8,12}\b`)
+
+// MaskText masks every ID number and phone number in text.
+func MaskText(text string) string {
+	return piiPattern.ReplaceAllStringFunc(text, Mask)
+}
@@ -0,0 +1,176 @@
+// Package pii protects the personal data of customers: it encrypts it at
+// rest, finds it again through a blind index, and masks it wherever it is
+// shown.
+package pii
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNoKeyring  = errors.New("pii: no keyring in the context")
+	ErrUnknownKey = errors.New("pii: unknown key version")
+)
+
+// KeySize is the size of the encryption and blind index keys, in bytes.
+const KeySize = 32
+
+// Keyring encrypts values with AES-256-GCM under its active key and decrypts
+// them under whichever of its keys they were encrypted with. An encrypted
+// value reads "v<version>:<base64 of the nonce and the ciphertext>", so that
+// a key can be rotated while the values encrypted with the previous one are
+// still read.
+type Keyring struct {
+	aeads    map[int]cipher.AEAD
+	active   int
+	indexKey []byte
+}
+
+// NewKeyring returns a keyring of keys by version, encrypting with the key of
+// version active, or with the highest version if active is 0. indexKey keys
+// the blind index; unlike the encryption keys it cannot be rotated without
+// indexing every value again.
+func NewKeyring(keys map[int][]byte, active int, indexKey []byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("pii: no encryption key")
+	}
+	if len(indexKey) != KeySize {
+		return nil, fmt.Errorf("pii: the blind index key must be %d bytes", KeySize)
+	}
+
+	keyring := &Keyring{aeads: make(map[int]cipher.AEAD, len(keys)), active: active, indexKey: indexKey}
+	for version, key := range keys {
+		if version < 1 {
+			return nil, fmt.Errorf("pii: invalid key version %d", version)
+		}
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("pii: key %d must be %d bytes", version, KeySize)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		keyring.aeads[version] = aead
+		if active == 0 && version > keyring.active {
+			keyring.active = version
+		}
+	}
+	if _, ok := keyring.aeads[keyring.active]; !ok {
+		return nil, fmt.Errorf("%w: %d is not among the keys", ErrUnknownKey, keyring.active)
+	}
+	return keyring, nil
+}
+
+// ParseKeys parses keys written "<version>:<base64 key>", separated by commas.
+func ParseKeys(s string) (map[int][]byte, error) {
+	keys := map[int][]byte{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		version, encoded, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, fmt.Errorf("pii: key %q has no version", pair)
+		}
+		v, err := strconv.Atoi(version)
+		if err != nil {
+			return nil, fmt.Errorf("pii: invalid key version %q", version)
+		}
+		if _, ok := keys[v]; ok {
+			return nil, fmt.Errorf("pii: key %d given twice", v)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("pii: key %d is not base64: %v", v, err)
+		}
+		keys[v] = key
+	}
+	return keys, nil
+}
+
+// ActiveVersion returns the version of the key new values are encrypted with.
+func (k *Keyring) ActiveVersion() int {
+	return k.active
+}
+
+// Versions returns the versions of the keys of the keyring, in order.
+func (k *Keyring) Versions() []int {
+	versions := make([]int, 0, len(k.aeads))
+	for version := range k.aeads {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	return versions
+}
+
+// Encrypt encrypts plaintext with the active key.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	aead := k.aeads[k.active]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return "v" + strconv.Itoa(k.active) + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value encrypted with any key of the keyring. A value
+// written before encryption, which has no version, is returned as it is.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	version, encoded, ok := Version(value)
+	if !ok {
+		return value, nil
+	}
+	aead, ok := k.aeads[version]
+	if !ok {
+		return "", fmt.Errorf("%w: %d", ErrUnknownKey, version)
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("pii: malformed value under key %d", version)
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("pii: cannot decrypt a value under key %d: %w", version, err)
+	}
+	return string(plaintext), nil
+}
+
+// BlindIndex returns the HMAC-SHA256 of plaintext in hex. The same plaintext
+// always gives the same index, so a value can be looked up by it without
+// being decrypted, while the index tells nothing without the key.
+func (k *Keyring) BlindIndex(plaintext string) string {
+	mac := hmac.New(sha256.New, k.indexKey)
+	mac.Write([]byte(plaintext))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Version returns the version of the key value was encrypted with and the
+// rest of value, or false if value is not encrypted.
+func Version(value string) (int, string, bool) {
+	prefix, rest, ok := strings.Cut(value, ":")
+	if !ok || len(prefix) < 2 || prefix[0] != 'v' {
+		return 0, "", false
+	}
+	version, err := strconv.Atoi(prefix[1:])
+	if err != nil || version < 1 {
+		return 0, "", false
+	}
+	return version, rest, true
+}
@@ -0,0 +1,114 @@
+package pii
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// SerializerName is the name of the GORM serializer storing a string field
+// encrypted with the keyring of the statement: `gorm:"serializer:pii"`.
+const SerializerName = "pii"
+
+func init() {
+	schema.RegisterSerializer(SerializerName, serializer{})
+}
+
+// keyringKey is the context key of the keyring of a statement.
+type keyringKey struct{}
+
+// NewContext returns a copy of c carrying keyring.
+func NewContext(c context.Context, keyring *Keyring) context.Context {
+	return context.WithValue(c, keyringKey{}, keyring)
+}
+
+// FromContext returns the keyring c carries, or ErrNoKeyring if it carries
+// none.
+func FromContext(c context.Context) (*Keyring, error) {
+	if c != nil {
+		if keyring, ok := c.Value(keyringKey{}).(*Keyring); ok && keyring != nil {
+			return keyring, nil
+		}
+	}
+	return nil, ErrNoKeyring
+}
+
+// GORM returns the GORM plugin passing keyring to every statement through
+// its context, so that the serializer and the hooks of the models encrypt
+// and index with it.
+func GORM(keyring *Keyring) gorm.Plugin {
+	return gormPlugin{keyring: keyring}
+}
+
+type gormPlugin struct {
+	keyring *Keyring
+}
+
+func (gormPlugin) Name() string {
+	return "pii"
+}
+
+func (p gormPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	return errors.Join(
+		callback.Create().Before("*").Register("pii:keyring", p.withKeyring),
+		callback.Query().Before("*").Register("pii:keyring", p.withKeyring),
+		callback.Update().Before("*").Register("pii:keyring", p.withKeyring),
+		callback.Delete().Before("*").Register("pii:keyring", p.withKeyring),
+		callback.Row().Before("*").Register("pii:keyring", p.withKeyring),
+		callback.Raw().Before("*").Register("pii:keyring", p.withKeyring),
+	)
+}
+
+func (p gormPlugin) withKeyring(db *gorm.DB) {
+	c := db.Statement.Context
+	if c == nil {
+		c = context.Background()
+	}
+	db.Statement.Context = NewContext(c, p.keyring)
+}
+
+// serializer encrypts a string field on its way to the database and decrypts
+// it on its way back.
+type serializer struct{}
+
+func (serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("pii: cannot scan %T into %s", dbValue, field.Name)
+	}
+
+	if value != "" {
+		k, err := FromContext(ctx)
+		if err != nil {
+			return err
+		}
+		if value, err = k.Decrypt(value); err != nil {
+			return err
+		}
+	}
+	field.ReflectValueOf(ctx, dst).SetString(value)
+	return nil
+}
+
+func (serializer) Value(ctx context.Context, field *schema.Field, _ reflect.Value, fieldValue interface{}) (interface{}, error) {
+	plaintext, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("pii: cannot encrypt %T of %s", fieldValue, field.Name)
+	}
+	k, err := FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return k.Encrypt(plaintext)
+}
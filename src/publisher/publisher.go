@@ -0,0 +1,144 @@
+// Package publisher delivers the events of the outbox to other services.
+package publisher
+
+import (
+	"account-service/src/model"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Publisher delivers one event. An error means the event was not delivered
+// and will be published again later.
+type Publisher interface {
+	Publish(c context.Context, event *model.Event) error
+}
+
+// Publisher kinds, as configured by OUTBOX_PUBLISHER.
+const (
+	KindStdout  = "stdout"
+	KindFile    = "file"
+	KindWebhook = "webhook"
+)
+
+// writerPublisher writes each event as a line of JSON.
+type writerPublisher struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriter returns a publisher writing each event to w as a line of JSON.
+func NewWriter(w io.Writer) Publisher {
+	return &writerPublisher{w: w}
+}
+
+func (p *writerPublisher) Publish(c context.Context, event *model.Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err = p.w.Write(line)
+	return err
+}
+
+// NewFile returns a publisher appending each event to the file at path as a
+// line of JSON, and the file to close once publishing is over.
+func NewFile(path string) (Publisher, io.Closer, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+	return NewWriter(file), file, nil
+}
+
+// multiPublisher delivers each event to several publishers in turn.
+type multiPublisher []Publisher
+
+// NewMulti returns a publisher delivering each event to every one of pubs in
+// turn. The event fails at the first publisher that fails, so it is
+// delivered again to those before it when it is retried.
+func NewMulti(pubs ...Publisher) Publisher {
+	return multiPublisher(pubs)
+}
+
+func (pubs multiPublisher) Publish(c context.Context, event *model.Event) error {
+	for _, pub := range pubs {
+		if err := pub.Publish(c, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StatusError is the error of a webhook that responded other than 2xx.
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (err *StatusError) Error() string {
+	return "webhook responded " + err.Status
+}
+
+// Webhook posts each event as JSON to URL, signed with Secrets if there are
+// any.
+type Webhook struct {
+	URL     string
+	Secrets []string
+	Client  *http.Client
+}
+
+// NewWebhook returns a publisher posting each event as JSON to url. Any
+// response other than a 2xx fails the delivery.
+func NewWebhook(url string, timeout time.Duration) Publisher {
+	return &Webhook{URL: url, Client: &http.Client{Timeout: timeout}}
+}
+
+func (webhook *Webhook) Publish(c context.Context, event *model.Event) error {
+	_, err := webhook.Post(c, event)
+	return err
+}
+
+// Post posts event and returns the status code of the response, 0 when none
+// was received. A response other than a 2xx is a *StatusError.
+func (webhook *Webhook) Post(c context.Context, event *model.Event) (int, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(c, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", strconv.FormatUint(uint64(event.ID), 10))
+	req.Header.Set("X-Event-Type", event.Type)
+	if len(webhook.Secrets) > 0 {
+		timestamp := time.Now()
+		req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp.Unix(), 10))
+		req.Header.Set(HeaderSignature, Signature(webhook.Secrets, timestamp, body))
+	}
+
+	resp, err := webhook.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,65 @@
+package publisher
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers of a signed webhook post. The signature is "sha256=" followed by
+// the hex HMAC-SHA256 of the timestamp, a dot and the body. While a secret
+// is being rotated the header carries one signature per secret, separated
+// by commas.
+const (
+	HeaderTimestamp = "X-Webhook-Timestamp" // Unix seconds
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+var (
+	ErrSignatureMismatch = errors.New("webhook signature does not match")
+	ErrTimestampTooOld   = errors.New("webhook timestamp is outside the tolerance")
+)
+
+// Signature returns the signature header of body sent at timestamp, signed
+// with each of secrets.
+func Signature(secrets []string, timestamp time.Time, body []byte) string {
+	signatures := make([]string, len(secrets))
+	for i, secret := range secrets {
+		signatures[i] = "sha256=" + hex.EncodeToString(sign(secret, timestamp.Unix(), body))
+	}
+	return strings.Join(signatures, ",")
+}
+
+// VerifySignature checks, as a receiver would, that one of the signatures
+// in signatureHeader is of body with secret, and that timestampHeader is
+// within tolerance of now so old posts cannot be replayed.
+func VerifySignature(secret, timestampHeader, signatureHeader string, body []byte, now time.Time, tolerance time.Duration) error {
+	unix, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return ErrTimestampTooOld
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return ErrTimestampTooOld
+	}
+
+	expected := sign(secret, unix, body)
+	for _, signature := range strings.Split(signatureHeader, ",") {
+		mac, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(signature), "sha256="))
+		if err == nil && hmac.Equal(mac, expected) {
+			return nil
+		}
+	}
+	return ErrSignatureMismatch
+}
+
+func sign(secret string, unix int64, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(unix, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,687 @@
+package repository
+
+import (
+	"account-service/src/model"
+	"account-service/src/pii"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// gormUnitOfWork stores everything in the database through GORM.
+type gormUnitOfWork struct {
+	db      *gorm.DB
+	keyring *pii.Keyring
+}
+
+// NewGorm returns the unit of work of the database behind db, looking
+// accounts up by the blind indexes of keyring.
+func NewGorm(db *gorm.DB, keyring *pii.Keyring) UnitOfWork {
+	return &gormUnitOfWork{db: db, keyring: keyring}
+}
+
+func (u *gormUnitOfWork) Repositories(c context.Context) Repositories {
+	return NewGormRepositories(u.db.WithContext(c), u.keyring)
+}
+
+func (u *gormUnitOfWork) Transaction(c context.Context, fn func(tx Repositories) error) error {
+	return u.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		return fn(NewGormRepositories(tx, u.keyring))
+	})
+}
+
+// NewGormRepositories returns repositories working through db, which may be
+// a transaction begun elsewhere, looking accounts up by the blind indexes of
+// keyring.
+func NewGormRepositories(db *gorm.DB, keyring *pii.Keyring) Repositories {
+	return gormRepositories{db: db, keyring: keyring}
+}
+
+type gormRepositories struct {
+	db      *gorm.DB
+	keyring *pii.Keyring
+}
+
+func (r gormRepositories) Context() context.Context               { return r.db.Statement.Context }
+func (r gormRepositories) Accounts() AccountRepository            { return gormAccounts(r) }
+func (r gormRepositories) CashActivities() CashActivityRepository { return gormCashActivities(r) }
+func (r gormRepositories) Journals() JournalRepository            { return gormJournals(r) }
+func (r gormRepositories) Outbox() OutboxRepository               { return gormOutbox(r) }
+func (r gormRepositories) FeeSchedules() FeeScheduleRepository    { return gormFeeSchedules(r) }
+func (r gormRepositories) Products() ProductRepository            { return gormProducts(r) }
+func (r gormRepositories) InterestAccruals() InterestAccrualRepository {
+	return gormInterestAccruals(r)
+}
+func (r gormRepositories) InterestRuns() InterestRunRepository       { return gormInterestRuns(r) }
+func (r gormRepositories) IdempotencyKeys() IdempotencyKeyRepository { return gormIdempotencyKeys(r) }
+func (r gormRepositories) WebhookSubscriptions() WebhookSubscriptionRepository {
+	return gormWebhookSubscriptions(r)
+}
+func (r gormRepositories) WebhookDeliveries() WebhookDeliveryRepository {
+	return gormWebhookDeliveries(r)
+}
+
+// gormError converts the errors GORM reports for a missing or duplicate row
+// into ErrNotFound and ErrDuplicate.
+func gormError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return fmt.Errorf("%w: %v", ErrDuplicate, err)
+	}
+	return err
+}
+
+type gormAccounts gormRepositories
+
+func (r gormAccounts) FindByID(id uint) (*model.Account, error) {
+	return r.find(r.db, "id = ?", id)
+}
+
+func (r gormAccounts) FindByNumber(accountNumber string) (*model.Account, error) {
+	return r.find(r.db, "account_number = ?", accountNumber)
+}
+
+// The ID number and the phone number are encrypted with a random nonce, so
+// they are looked up by their blind index.
+
+func (r gormAccounts) FindByIDNumber(idNumber string) (*model.Account, error) {
+	return r.find(r.db, "id_number_hash = ?", r.keyring.BlindIndex(idNumber))
+}
+
+func (r gormAccounts) FindByPhoneNumber(phoneNumber string) (*model.Account, error) {
+	return r.find(r.db, "phone_number_hash = ?", r.keyring.BlindIndex(phoneNumber))
+}
+
+func (r gormAccounts) Lock(accountNumber string) (*model.Account, error) {
+	return r.find(r.db.Clauses(clause.Locking{Strength: "UPDATE"}), "account_number = ?", accountNumber)
+}
+
+func (r gormAccounts) LockShared(accountNumber string) (*model.Account, error) {
+	return r.find(r.db.Clauses(clause.Locking{Strength: "SHARE"}), "account_number = ?", accountNumber)
+}
+
+func (r gormAccounts) find(db *gorm.DB, query string, arg any) (*model.Account, error) {
+	var account model.Account
+	if err := db.Where(query, arg).First(&account).Error; err != nil {
+		return nil, gormError(err)
+	}
+	return &account, nil
+}
+
+func (r gormAccounts) Create(account *model.Account) error {
+	return gormError(r.db.Create(account).Error)
+}
+
+func (r gormAccounts) UpdateBalance(account *model.Account) error {
+	return r.db.Model(account).Update("balance", account.Balance).Error
+}
+
+func (r gormAccounts) UpdateStatus(account *model.Account) error {
+	return r.db.Model(account).Update("status", account.Status).Error
+}
+
+func (r gormAccounts) UpdatePIN(account *model.Account) error {
+	return r.db.Model(account).Updates(map[string]interface{}{
+		"pin_hash":            account.PINHash,
+		"pin_failed_attempts": account.PINFailedAttempts,
+		"pin_locked_at":       account.PINLockedAt,
+	}).Error
+}
+
+func (r gormAccounts) CreateStatusHistory(history *model.AccountStatusHistory) error {
+	return r.db.Create(history).Error
+}
+
+func (r gormAccounts) FindWithdrawalLimit(accountID uint) (*model.AccountWithdrawalLimit, error) {
+	var limit model.AccountWithdrawalLimit
+	if err := r.db.Where("account_id = ?", accountID).First(&limit).Error; err != nil {
+		return nil, gormError(err)
+	}
+	return &limit, nil
+}
+
+func (r gormAccounts) SaveWithdrawalLimit(limit *model.AccountWithdrawalLimit) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "account_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"max_per_transaction", "max_daily_total", "max_daily_count", "updated_at"}),
+	}).Create(limit).Error
+}
+
+func (r gormAccounts) ListMonthlyFeeDue(end time.Time, period time.Time) ([]string, error) {
+	var accountNumbers []string
+	err := r.db.Model(&model.Account{}).
+		Where("created_at < ? AND status NOT IN ?", end, []string{model.AccountStatusClosed, model.AccountStatusFrozen}).
+		Where("NOT EXISTS (SELECT 1 FROM cash_activities WHERE cash_activities.account_id = accounts.id AND cash_activities.fee_period = ?)", period).
+		Order("account_number asc").
+		Pluck("account_number", &accountNumbers).Error
+	return accountNumbers, err
+}
+
+func (r gormAccounts) EndOfDayBalances(end time.Time) ([]model.EndOfDayBalance, error) {
+	var balances []model.EndOfDayBalance
+	err := r.db.Raw(`SELECT accounts.id AS account_id, products.annual_rate_bps,
+		COALESCE(
+			(SELECT balance_after FROM cash_activities WHERE account_id = accounts.id AND created_at < ? ORDER BY id DESC LIMIT 1),
+			(SELECT balance_before FROM cash_activities WHERE account_id = accounts.id ORDER BY id ASC LIMIT 1),
+			accounts.balance
+		) AS balance
+		FROM accounts
+		JOIN products ON products.code = accounts.product_code
+		WHERE accounts.created_at < ? AND accounts.status <> ? AND products.annual_rate_bps > 0`,
+		end, end, model.AccountStatusClosed).Scan(&balances).Error
+	return balances, err
+}
+
+type gormCashActivities gormRepositories
+
+func (r gormCashActivities) Find(id uint) (*model.CashActivity, error) {
+	var activity model.CashActivity
+	if err := r.db.First(&activity, id).Error; err != nil {
+		return nil, gormError(err)
+	}
+	return &activity, nil
+}
+
+func (r gormCashActivities) Latest(accountID uint) (*model.CashActivity, error) {
+	var activity model.CashActivity
+	if err := r.db.Where("account_id = ?", accountID).Order("id desc").First(&activity).Error; err != nil {
+		return nil, gormError(err)
+	}
+	return &activity, nil
+}
+
+func (r gormCashActivities) Create(activity *model.CashActivity) error {
+	return r.db.Create(activity).Error
+}
+
+func (r gormCashActivities) MarkReversed(activity *model.CashActivity, at time.Time) error {
+	if err := r.db.Model(activity).Update("reversed_at", at).Error; err != nil {
+		return err
+	}
+	activity.ReversedAt = &at
+	return nil
+}
+
+func (r gormCashActivities) FindUnreversedFeeOf(activityID uint) (*model.CashActivity, error) {
+	var fee model.CashActivity
+	if err := r.db.Where("fee_of_id = ? AND reversed_at IS NULL", activityID).First(&fee).Error; err != nil {
+		return nil, gormError(err)
+	}
+	return &fee, nil
+}
+
+func (r gormCashActivities) List(accountID uint, start, end time.Time, offset, limit int) ([]model.CashActivity, int64, error) {
+	query := r.db.Model(&model.CashActivity{}).
+		Where("account_id = ? AND created_at >= ? AND created_at < ?", accountID, start, end).
+		Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	activities := make([]model.CashActivity, 0, limit)
+	if err := query.Order("created_at asc, id asc").
+		Offset(offset).
+		Limit(limit).
+		Find(&activities).Error; err != nil {
+		return nil, 0, err
+	}
+	return activities, total, nil
+}
+
+func (r gormCashActivities) WithdrawalUsage(accountID uint, start, end time.Time) (model.Money, int, error) {
+	return r.debitUsage(accountID, start, end, false)
+}
+
+func (r gormCashActivities) OutflowUsage(accountID uint, start, end time.Time) (model.Money, int, error) {
+	return r.debitUsage(accountID, start, end, true)
+}
+
+// debitUsage sums the debits of an account created in [start, end) that are
+// neither a reversal, reversed nor a fee, and the transfer legs among them if
+// transfers is set.
+func (r gormCashActivities) debitUsage(accountID uint, start, end time.Time, transfers bool) (model.Money, int, error) {
+	var usage struct {
+		Total model.Money
+		Count int
+	}
+	query := r.db.Model(&model.CashActivity{}).
+		Select("COALESCE(SUM(nominal), 0) AS total, COUNT(*) AS count").
+		Where("account_id = ? AND type = ? AND reversal_of_id IS NULL AND reversed_at IS NULL AND fee_schedule_id IS NULL", accountID, "debit").
+		Where("created_at >= ? AND created_at < ?", start, end)
+	if !transfers {
+		query = query.Where("transfer_reference IS NULL")
+	}
+	if err := query.Scan(&usage).Error; err != nil {
+		return model.Money{}, 0, err
+	}
+	return usage.Total, usage.Count, nil
+}
+
+func (r gormCashActivities) Walk(accountID uint, fn func(activity *model.CashActivity) bool) error {
+	rows, err := r.db.Model(&model.CashActivity{}).Where("account_id = ?", accountID).Order("id asc").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var activity model.CashActivity
+		if err := r.db.ScanRows(rows, &activity); err != nil {
+			return err
+		}
+		if !fn(&activity) {
+			return nil
+		}
+	}
+	return rows.Err()
+}
+
+func (r gormCashActivities) FindByFeePeriod(accountID uint, period time.Time) (*model.CashActivity, error) {
+	var activity model.CashActivity
+	if err := r.db.Where("account_id = ? AND fee_period = ?", accountID, period).First(&activity).Error; err != nil {
+		return nil, gormError(err)
+	}
+	return &activity, nil
+}
+
+func (r gormCashActivities) BalanceAt(accountID uint, at time.Time) (model.Money, error) {
+	var balance *model.Money
+	if err := r.db.Raw(`SELECT COALESCE(
+			(SELECT balance_after FROM cash_activities WHERE account_id = ? AND created_at < ? ORDER BY id DESC LIMIT 1),
+			(SELECT balance_before FROM cash_activities WHERE account_id = ? ORDER BY id ASC LIMIT 1),
+			(SELECT balance FROM accounts WHERE id = ?)
+		)`, accountID, at, accountID, accountID).Row().Scan(&balance); err != nil {
+		return model.Money{}, err
+	}
+	if balance == nil {
+		return model.Money{}, ErrNotFound
+	}
+	return *balance, nil
+}
+
+func (r gormCashActivities) Totals(accountID uint, start, end time.Time) (*model.Statement, error) {
+	var statement model.Statement
+	if err := r.db.Model(&model.CashActivity{}).
+		Select(`COALESCE(SUM(CASE WHEN type = 'credit' THEN 1 ELSE 0 END), 0) AS credit_count,
+			COALESCE(SUM(CASE WHEN type = 'credit' THEN nominal ELSE 0 END), 0) AS credit_total,
+			COALESCE(SUM(CASE WHEN type = 'debit' THEN 1 ELSE 0 END), 0) AS debit_count,
+			COALESCE(SUM(CASE WHEN type = 'debit' THEN nominal ELSE 0 END), 0) AS debit_total,
+			COALESCE(MAX(id), 0) AS last_activity_id`).
+		Where("account_id = ? AND created_at >= ? AND created_at < ?", accountID, start, end).
+		Scan(&statement).Error; err != nil {
+		return nil, err
+	}
+	return &statement, nil
+}
+
+func (r gormCashActivities) StatementLines(accountID uint, start time.Time, afterID, throughID uint, limit int) ([]model.StatementLine, error) {
+	lines := make([]model.StatementLine, 0, limit)
+	err := r.db.Model(&model.CashActivity{}).
+		Select("cash_activities.*, EXISTS (SELECT 1 FROM interest_accruals WHERE interest_accruals.cash_activity_id = cash_activities.id) AS interest").
+		Where("account_id = ? AND created_at >= ? AND id > ? AND id <= ?", accountID, start, afterID, throughID).
+		Order("id asc").
+		Limit(limit).
+		Find(&lines).Error
+	return lines, err
+}
+
+type gormJournals gormRepositories
+
+func (r gormJournals) Create(entry *model.JournalEntry) error {
+	return r.db.Create(entry).Error
+}
+
+func (r gormJournals) FindByCashActivity(activityID uint) (*model.JournalEntry, error) {
+	var entry model.JournalEntry
+	if err := r.db.Preload("Lines").Where("cash_activity_id = ?", activityID).First(&entry).Error; err != nil {
+		return nil, gormError(err)
+	}
+	return &entry, nil
+}
+
+func (r gormJournals) ListGLAccounts() ([]model.GLAccount, error) {
+	var accounts []model.GLAccount
+	err := r.db.Order("code asc").Find(&accounts).Error
+	return accounts, err
+}
+
+func (r gormJournals) Balances(end time.Time) (map[string]model.Money, error) {
+	var sums []struct {
+		GLAccountCode string
+		Balance       model.Money
+	}
+	if err := r.db.Model(&model.JournalLine{}).
+		Select("journal_lines.gl_account_code, SUM(journal_lines.amount) AS balance").
+		Joins("JOIN journal_entries ON journal_entries.id = journal_lines.journal_entry_id").
+		Where("journal_entries.created_at < ?", end).
+		Group("journal_lines.gl_account_code").
+		Scan(&sums).Error; err != nil {
+		return nil, err
+	}
+
+	balances := make(map[string]model.Money, len(sums))
+	for _, sum := range sums {
+		balances[sum.GLAccountCode] = sum.Balance
+	}
+	return balances, nil
+}
+
+func (r gormJournals) Reconciliation() (*model.GLReconciliation, error) {
+	// A single statement, so both sums are read from the same snapshot.
+	var result model.GLReconciliation
+	if err := r.db.Raw(`SELECT
+		(SELECT COALESCE(SUM(balance), 0) FROM accounts) AS customer_balances,
+		(SELECT COALESCE(-SUM(amount), 0) FROM journal_lines WHERE gl_account_code = ?) AS liability_balance`,
+		model.GLCustomerDepositsCode).Scan(&result).Error; err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+type gormOutbox gormRepositories
+
+func (r gormOutbox) Create(event *model.OutboxEvent) error {
+	return r.db.Create(event).Error
+}
+
+func (r gormOutbox) FindAll(ids []uint) ([]model.OutboxEvent, error) {
+	var events []model.OutboxEvent
+	err := r.db.Where("id IN ?", ids).Find(&events).Error
+	return events, err
+}
+
+func (r gormOutbox) Claim(now time.Time, limit int, until time.Time) ([]model.OutboxEvent, error) {
+	var events []model.OutboxEvent
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("published_at IS NULL AND next_attempt_at <= ?", now).
+		Where("NOT EXISTS (SELECT 1 FROM outbox earlier WHERE earlier.account_number = outbox.account_number AND earlier.published_at IS NULL AND earlier.id < outbox.id)").
+		Order("id asc").
+		Limit(limit).
+		Find(&events).Error; err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return events, nil
+	}
+
+	ids := make([]uint, len(events))
+	for i := range events {
+		ids[i] = events[i].ID
+		events[i].NextAttemptAt = until
+	}
+	if err := r.db.Model(&model.OutboxEvent{}).Where("id IN ?", ids).Update("next_attempt_at", until).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r gormOutbox) Update(event *model.OutboxEvent) error {
+	return r.db.Model(event).Select("attempts", "next_attempt_at", "last_error", "published_at").Updates(event).Error
+}
+
+type gormFeeSchedules gormRepositories
+
+func (r gormFeeSchedules) Effective(productCode string, feeType string, date time.Time) (*model.FeeSchedule, error) {
+	var schedule model.FeeSchedule
+	if err := r.db.Where("product_code = ? AND type = ? AND effective_from <= ?", productCode, feeType, date).
+		Order("effective_from desc").First(&schedule).Error; err != nil {
+		return nil, gormError(err)
+	}
+	return &schedule, nil
+}
+
+func (r gormFeeSchedules) List(productCode string) ([]model.FeeSchedule, error) {
+	schedules := []model.FeeSchedule{}
+	err := r.db.Where("product_code = ?", productCode).Order("type asc, effective_from asc").Find(&schedules).Error
+	return schedules, err
+}
+
+func (r gormFeeSchedules) Create(schedule *model.FeeSchedule) error {
+	return gormError(r.db.Create(schedule).Error)
+}
+
+type gormProducts gormRepositories
+
+func (r gormProducts) Find(code string) (*model.Product, error) {
+	var product model.Product
+	if err := r.db.Where("code = ?", code).First(&product).Error; err != nil {
+		return nil, gormError(err)
+	}
+	return &product, nil
+}
+
+func (r gormProducts) UpdateRate(product *model.Product) error {
+	return r.db.Model(product).Update("annual_rate_bps", product.AnnualRateBPS).Error
+}
+
+type gormInterestAccruals gormRepositories
+
+func (r gormInterestAccruals) CreateAll(accruals []model.InterestAccrual) error {
+	if len(accruals) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(accruals, 500).Error
+}
+
+func (r gormInterestAccruals) Pending(accountID uint) ([]model.InterestAccrual, error) {
+	var accruals []model.InterestAccrual
+	err := r.db.Where("account_id = ? AND cash_activity_id IS NULL", accountID).Order("business_date asc").Find(&accruals).Error
+	return accruals, err
+}
+
+func (r gormInterestAccruals) PendingAccounts(date time.Time) ([]string, error) {
+	var accountNumbers []string
+	err := r.db.Model(&model.InterestAccrual{}).
+		Distinct("accounts.account_number").
+		Joins("JOIN accounts ON accounts.id = interest_accruals.account_id").
+		Where("interest_accruals.cash_activity_id IS NULL AND interest_accruals.business_date <= ?", date).
+		Pluck("accounts.account_number", &accountNumbers).Error
+	return accountNumbers, err
+}
+
+func (r gormInterestAccruals) MarkCapitalized(ids []uint, activityID uint) error {
+	return r.db.Model(&model.InterestAccrual{}).Where("id IN ?", ids).Update("cash_activity_id", activityID).Error
+}
+
+// interestTotal is how many accounts accrued interest and how much in all.
+type interestTotal struct {
+	Accounts int
+	Total    model.Money
+}
+
+func (r gormInterestAccruals) Accrued(date time.Time) (int, model.Money, error) {
+	var total interestTotal
+	err := r.db.Model(&model.InterestAccrual{}).
+		Select("COUNT(*) AS accounts, COALESCE(SUM(amount), 0) AS total").
+		Where("business_date = ?", date).
+		Scan(&total).Error
+	return total.Accounts, total.Total, err
+}
+
+func (r gormInterestAccruals) Capitalized(start, end time.Time) (int, model.Money, error) {
+	var total interestTotal
+	err := r.db.Model(&model.InterestAccrual{}).
+		Select("COUNT(DISTINCT account_id) AS accounts, COALESCE(SUM(amount), 0) AS total").
+		Where("cash_activity_id IS NOT NULL AND business_date >= ? AND business_date <= ?", start, end).
+		Scan(&total).Error
+	return total.Accounts, total.Total, err
+}
+
+type gormInterestRuns gormRepositories
+
+func (r gormInterestRuns) Find(date time.Time) (*model.InterestRun, error) {
+	var run model.InterestRun
+	if err := r.db.Where("business_date = ?", date).First(&run).Error; err != nil {
+		return nil, gormError(err)
+	}
+	return &run, nil
+}
+
+func (r gormInterestRuns) Create(run *model.InterestRun) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(run).Error
+}
+
+type gormIdempotencyKeys gormRepositories
+
+func (r gormIdempotencyKeys) Reserve(key *model.IdempotencyKey) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(key)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r gormIdempotencyKeys) Find(principal string, key string) (*model.IdempotencyKey, error) {
+	var found model.IdempotencyKey
+	if err := r.db.Where("principal = ? AND key = ?", principal, key).First(&found).Error; err != nil {
+		return nil, gormError(err)
+	}
+	return &found, nil
+}
+
+func (r gormIdempotencyKeys) Complete(key *model.IdempotencyKey) error {
+	return r.db.Model(&model.IdempotencyKey{}).Where("principal = ? AND key = ?", key.Principal, key.Key).Updates(map[string]interface{}{
+		"status_code":   key.StatusCode,
+		"content_type":  key.ContentType,
+		"response_body": key.ResponseBody,
+	}).Error
+}
+
+func (r gormIdempotencyKeys) Delete(principal string, key string) error {
+	return r.db.Where("principal = ? AND key = ?", principal, key).Delete(&model.IdempotencyKey{}).Error
+}
+
+func (r gormIdempotencyKeys) DeleteExpired(id uint, now time.Time) error {
+	return r.db.Where("id = ? AND expires_at < ?", id, now).Delete(&model.IdempotencyKey{}).Error
+}
+
+func (r gormIdempotencyKeys) Purge(now time.Time, limit int) (int, error) {
+	expired := r.db.Model(&model.IdempotencyKey{}).Select("id").Where("expires_at < ?", now).Order("id").Limit(limit)
+	result := r.db.Where("id IN (?)", expired).Delete(&model.IdempotencyKey{})
+	return int(result.RowsAffected), result.Error
+}
+
+type gormWebhookSubscriptions gormRepositories
+
+func (r gormWebhookSubscriptions) Find(id uint) (*model.WebhookSubscription, error) {
+	var subscription model.WebhookSubscription
+	if err := r.db.First(&subscription, id).Error; err != nil {
+		return nil, gormError(err)
+	}
+	return &subscription, nil
+}
+
+func (r gormWebhookSubscriptions) List(activeOnly bool) ([]model.WebhookSubscription, error) {
+	query := r.db.Order("id asc")
+	if activeOnly {
+		query = query.Where("active = ?", true)
+	}
+	var subscriptions []model.WebhookSubscription
+	err := query.Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+func (r gormWebhookSubscriptions) FindAll(ids []uint) ([]model.WebhookSubscription, error) {
+	var subscriptions []model.WebhookSubscription
+	err := r.db.Where("id IN ?", ids).Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+func (r gormWebhookSubscriptions) Create(subscription *model.WebhookSubscription) error {
+	return r.db.Create(subscription).Error
+}
+
+func (r gormWebhookSubscriptions) Update(subscription *model.WebhookSubscription) error {
+	return r.db.Model(subscription).Select("url", "event_types", "active").Updates(subscription).Error
+}
+
+func (r gormWebhookSubscriptions) UpdateSecrets(subscription *model.WebhookSubscription) error {
+	return r.db.Model(subscription).Select("secret", "previous_secret", "previous_secret_expires_at").Updates(subscription).Error
+}
+
+// Delete leaves the deliveries to the foreign keys, which cascade.
+func (r gormWebhookSubscriptions) Delete(id uint) error {
+	result := r.db.Delete(&model.WebhookSubscription{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type gormWebhookDeliveries gormRepositories
+
+func (r gormWebhookDeliveries) Find(id uint) (*model.WebhookDelivery, error) {
+	return r.find(r.db.Preload("AttemptLog", func(tx *gorm.DB) *gorm.DB { return tx.Order("id asc") }), id)
+}
+
+func (r gormWebhookDeliveries) Lock(id uint) (*model.WebhookDelivery, error) {
+	return r.find(r.db.Clauses(clause.Locking{Strength: "UPDATE"}), id)
+}
+
+func (r gormWebhookDeliveries) find(db *gorm.DB, id uint) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	if err := db.First(&delivery, id).Error; err != nil {
+		return nil, gormError(err)
+	}
+	return &delivery, nil
+}
+
+func (r gormWebhookDeliveries) List(subscriptionID uint, status string, offset, limit int) ([]model.WebhookDelivery, int64, error) {
+	query := r.db.Model(&model.WebhookDelivery{}).Where("subscription_id = ?", subscriptionID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	deliveries := make([]model.WebhookDelivery, 0, limit)
+	if err := query.Order("id desc").
+		Offset(offset).
+		Limit(limit).
+		Find(&deliveries).Error; err != nil {
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
+
+func (r gormWebhookDeliveries) CreateAll(deliveries []model.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries).Error
+}
+
+func (r gormWebhookDeliveries) Claim(now time.Time, limit int) ([]model.WebhookDelivery, error) {
+	var deliveries []model.WebhookDelivery
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND next_attempt_at <= ?", model.WebhookDeliveryPending, now).
+		Where("subscription_id IN (SELECT id FROM webhook_subscriptions WHERE active)").
+		Order("next_attempt_at asc, id asc").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r gormWebhookDeliveries) Update(delivery *model.WebhookDelivery) error {
+	return r.db.Model(delivery).
+		Select("status", "attempts", "next_attempt_at", "last_status_code", "last_error", "delivered_at").
+		Updates(delivery).Error
+}
+
+func (r gormWebhookDeliveries) CreateAttempt(attempt *model.WebhookDeliveryAttempt) error {
+	return r.db.Create(attempt).Error
+}
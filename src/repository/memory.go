@@ -0,0 +1,1194 @@
+package repository
+
+import (
+	"account-service/src/model"
+	"context"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memoryUnitOfWork keeps everything in memory. A transaction holds the store
+// for as long as it runs, so transactions never interleave and a locked
+// account stays locked; its writes are undone if it fails. Records are
+// copied in and out, so callers never share them.
+type memoryUnitOfWork struct {
+	mu   sync.Mutex
+	data *memoryData
+}
+
+// memoryData holds the tables of the in-memory store, keyed by ID.
+type memoryData struct {
+	accounts         map[uint]model.Account
+	statusHistory    map[uint]model.AccountStatusHistory
+	withdrawalLimits map[uint]model.AccountWithdrawalLimit // By account ID
+	activities       map[uint]model.CashActivity
+	journals         map[uint]model.JournalEntry
+	outbox           map[uint]model.OutboxEvent
+	feeSchedules     map[uint]model.FeeSchedule
+	products         map[string]model.Product   // By code
+	glAccounts       map[string]model.GLAccount // By code
+	interestAccruals map[uint]model.InterestAccrual
+	interestRuns     map[uint]model.InterestRun
+	idempotencyKeys  map[uint]model.IdempotencyKey
+	subscriptions    map[uint]model.WebhookSubscription
+	deliveries       map[uint]model.WebhookDelivery // Without their attempt log
+	attempts         map[uint]model.WebhookDeliveryAttempt
+	lastID           map[string]uint // By table
+}
+
+// NewMemory returns an empty in-memory unit of work holding only the default
+// product and the accounts of the general ledger, as the database does once
+// migrated. It is meant for tests and local runs without a database.
+func NewMemory() UnitOfWork {
+	now := time.Now()
+	return &memoryUnitOfWork{data: &memoryData{
+		accounts:         map[uint]model.Account{},
+		statusHistory:    map[uint]model.AccountStatusHistory{},
+		withdrawalLimits: map[uint]model.AccountWithdrawalLimit{},
+		activities:       map[uint]model.CashActivity{},
+		journals:         map[uint]model.JournalEntry{},
+		outbox:           map[uint]model.OutboxEvent{},
+		feeSchedules:     map[uint]model.FeeSchedule{},
+		products: map[string]model.Product{
+			model.DefaultProductCode: {ID: 1, Code: model.DefaultProductCode, Name: "Tabungan", AnnualRateBPS: 100, CreatedAt: now, UpdatedAt: now},
+		},
+		glAccounts: map[string]model.GLAccount{
+			model.GLCashCode:             {ID: 1, Code: model.GLCashCode, Name: "Cash", Type: "asset", CreatedAt: now},
+			model.GLCustomerDepositsCode: {ID: 2, Code: model.GLCustomerDepositsCode, Name: "Customer Deposits", Type: "liability", CreatedAt: now},
+			model.GLSuspenseCode:         {ID: 3, Code: model.GLSuspenseCode, Name: "Suspense", Type: "liability", CreatedAt: now},
+			model.GLFeeIncomeCode:        {ID: 4, Code: model.GLFeeIncomeCode, Name: "Fee Income", Type: "income", CreatedAt: now},
+			model.GLInterestExpenseCode:  {ID: 5, Code: model.GLInterestExpenseCode, Name: "Interest Expense", Type: "expense", CreatedAt: now},
+		},
+		interestAccruals: map[uint]model.InterestAccrual{},
+		interestRuns:     map[uint]model.InterestRun{},
+		idempotencyKeys:  map[uint]model.IdempotencyKey{},
+		subscriptions:    map[uint]model.WebhookSubscription{},
+		deliveries:       map[uint]model.WebhookDelivery{},
+		attempts:         map[uint]model.WebhookDeliveryAttempt{},
+		lastID:           map[string]uint{"products": 1, "gl_accounts": 5},
+	}}
+}
+
+func (u *memoryUnitOfWork) Repositories(c context.Context) Repositories {
+	return memoryRepositories{c: c, mu: &u.mu, data: u.data}
+}
+
+func (u *memoryUnitOfWork) Transaction(c context.Context, fn func(tx Repositories) error) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if err := c.Err(); err != nil {
+		return err
+	}
+
+	// Restore the tables unless fn succeeds, including when it panics.
+	snapshot := u.data.clone()
+	committed := false
+	defer func() {
+		if !committed {
+			*u.data = *snapshot
+		}
+	}()
+
+	if err := fn(memoryRepositories{c: c, data: u.data}); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// clone copies the tables. Records are values, so a shallow copy of each map
+// is enough.
+func (d *memoryData) clone() *memoryData {
+	return &memoryData{
+		accounts:         maps.Clone(d.accounts),
+		statusHistory:    maps.Clone(d.statusHistory),
+		withdrawalLimits: maps.Clone(d.withdrawalLimits),
+		activities:       maps.Clone(d.activities),
+		journals:         maps.Clone(d.journals),
+		outbox:           maps.Clone(d.outbox),
+		feeSchedules:     maps.Clone(d.feeSchedules),
+		products:         maps.Clone(d.products),
+		glAccounts:       maps.Clone(d.glAccounts),
+		interestAccruals: maps.Clone(d.interestAccruals),
+		interestRuns:     maps.Clone(d.interestRuns),
+		idempotencyKeys:  maps.Clone(d.idempotencyKeys),
+		subscriptions:    maps.Clone(d.subscriptions),
+		deliveries:       maps.Clone(d.deliveries),
+		attempts:         maps.Clone(d.attempts),
+		lastID:           maps.Clone(d.lastID),
+	}
+}
+
+// nextID returns the next ID of table.
+func (d *memoryData) nextID(table string) uint {
+	d.lastID[table]++
+	return d.lastID[table]
+}
+
+// memoryRepositories work on the tables of the store. Outside a transaction
+// mu is set and every call holds it; inside one the transaction already
+// holds it and mu is nil.
+type memoryRepositories struct {
+	c    context.Context
+	mu   *sync.Mutex
+	data *memoryData
+}
+
+func (r memoryRepositories) Context() context.Context { return r.c }
+
+func (r memoryRepositories) Accounts() AccountRepository            { return memoryAccounts(r) }
+func (r memoryRepositories) CashActivities() CashActivityRepository { return memoryCashActivities(r) }
+func (r memoryRepositories) Journals() JournalRepository            { return memoryJournals(r) }
+func (r memoryRepositories) Outbox() OutboxRepository               { return memoryOutbox(r) }
+func (r memoryRepositories) FeeSchedules() FeeScheduleRepository    { return memoryFeeSchedules(r) }
+func (r memoryRepositories) Products() ProductRepository            { return memoryProducts(r) }
+func (r memoryRepositories) InterestAccruals() InterestAccrualRepository {
+	return memoryInterestAccruals(r)
+}
+func (r memoryRepositories) InterestRuns() InterestRunRepository { return memoryInterestRuns(r) }
+func (r memoryRepositories) IdempotencyKeys() IdempotencyKeyRepository {
+	return memoryIdempotencyKeys(r)
+}
+func (r memoryRepositories) WebhookSubscriptions() WebhookSubscriptionRepository {
+	return memoryWebhookSubscriptions(r)
+}
+func (r memoryRepositories) WebhookDeliveries() WebhookDeliveryRepository {
+	return memoryWebhookDeliveries(r)
+}
+
+// lock takes the store for one call and returns the function releasing it.
+func (r memoryRepositories) lock() func() {
+	if r.mu == nil {
+		return func() {}
+	}
+	r.mu.Lock()
+	return r.mu.Unlock
+}
+
+type memoryAccounts memoryRepositories
+
+func (r memoryAccounts) FindByID(id uint) (*model.Account, error) {
+	defer memoryRepositories(r).lock()()
+	account, ok := r.data.accounts[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &account, nil
+}
+
+func (r memoryAccounts) FindByNumber(accountNumber string) (*model.Account, error) {
+	return r.find(func(account *model.Account) bool { return account.AccountNumber == accountNumber })
+}
+
+func (r memoryAccounts) FindByIDNumber(idNumber string) (*model.Account, error) {
+	return r.find(func(account *model.Account) bool { return account.IDNumber == idNumber })
+}
+
+func (r memoryAccounts) FindByPhoneNumber(phoneNumber string) (*model.Account, error) {
+	return r.find(func(account *model.Account) bool { return account.PhoneNumber == phoneNumber })
+}
+
+// Lock only finds the account: a transaction already keeps every other one
+// out until it ends.
+func (r memoryAccounts) Lock(accountNumber string) (*model.Account, error) {
+	return r.FindByNumber(accountNumber)
+}
+
+func (r memoryAccounts) LockShared(accountNumber string) (*model.Account, error) {
+	return r.FindByNumber(accountNumber)
+}
+
+func (r memoryAccounts) find(match func(account *model.Account) bool) (*model.Account, error) {
+	defer memoryRepositories(r).lock()()
+	for _, account := range r.data.accounts {
+		if match(&account) {
+			return &account, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r memoryAccounts) Create(account *model.Account) error {
+	defer memoryRepositories(r).lock()()
+	for _, existing := range r.data.accounts {
+		if existing.AccountNumber == account.AccountNumber ||
+			existing.IDNumber == account.IDNumber ||
+			existing.PhoneNumber == account.PhoneNumber {
+			return ErrDuplicate
+		}
+	}
+
+	now := time.Now()
+	account.ID = r.data.nextID("accounts")
+	if account.Status == "" {
+		account.Status = model.AccountStatusActive
+	}
+	if account.ProductCode == "" {
+		account.ProductCode = model.DefaultProductCode
+	}
+	if account.CreatedAt.IsZero() {
+		account.CreatedAt = now
+	}
+	account.UpdatedAt = now
+	r.data.accounts[account.ID] = *account
+	return nil
+}
+
+func (r memoryAccounts) UpdateBalance(account *model.Account) error {
+	return r.update(account, func(stored *model.Account) { stored.Balance = account.Balance })
+}
+
+func (r memoryAccounts) UpdateStatus(account *model.Account) error {
+	return r.update(account, func(stored *model.Account) { stored.Status = account.Status })
+}
+
+func (r memoryAccounts) UpdatePIN(account *model.Account) error {
+	return r.update(account, func(stored *model.Account) {
+		stored.PINHash = account.PINHash
+		stored.PINFailedAttempts = account.PINFailedAttempts
+		stored.PINLockedAt = account.PINLockedAt
+	})
+}
+
+// update applies set to the stored copy of account.
+func (r memoryAccounts) update(account *model.Account, set func(stored *model.Account)) error {
+	defer memoryRepositories(r).lock()()
+	stored, ok := r.data.accounts[account.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	set(&stored)
+	stored.UpdatedAt = time.Now()
+	account.UpdatedAt = stored.UpdatedAt
+	r.data.accounts[account.ID] = stored
+	return nil
+}
+
+func (r memoryAccounts) CreateStatusHistory(history *model.AccountStatusHistory) error {
+	defer memoryRepositories(r).lock()()
+	if _, ok := r.data.accounts[history.AccountID]; !ok {
+		return ErrNotFound
+	}
+	history.ID = r.data.nextID("account_status_histories")
+	if history.CreatedAt.IsZero() {
+		history.CreatedAt = time.Now()
+	}
+	r.data.statusHistory[history.ID] = *history
+	return nil
+}
+
+func (r memoryAccounts) FindWithdrawalLimit(accountID uint) (*model.AccountWithdrawalLimit, error) {
+	defer memoryRepositories(r).lock()()
+	limit, ok := r.data.withdrawalLimits[accountID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &limit, nil
+}
+
+func (r memoryAccounts) SaveWithdrawalLimit(limit *model.AccountWithdrawalLimit) error {
+	defer memoryRepositories(r).lock()()
+	if _, ok := r.data.accounts[limit.AccountID]; !ok {
+		return ErrNotFound
+	}
+
+	now := time.Now()
+	if existing, ok := r.data.withdrawalLimits[limit.AccountID]; ok {
+		limit.ID = existing.ID
+		limit.CreatedAt = existing.CreatedAt
+	} else {
+		limit.ID = r.data.nextID("account_withdrawal_limits")
+		limit.CreatedAt = now
+	}
+	limit.UpdatedAt = now
+	r.data.withdrawalLimits[limit.AccountID] = *limit
+	return nil
+}
+
+func (r memoryAccounts) ListMonthlyFeeDue(end time.Time, period time.Time) ([]string, error) {
+	defer memoryRepositories(r).lock()()
+	charged := map[uint]bool{}
+	for _, activity := range r.data.activities {
+		if activity.FeePeriod != nil && activity.FeePeriod.Equal(period) {
+			charged[activity.AccountID] = true
+		}
+	}
+
+	var accountNumbers []string
+	for _, account := range r.data.accounts {
+		if account.CreatedAt.Before(end) && account.Status != model.AccountStatusClosed && account.Status != model.AccountStatusFrozen && !charged[account.ID] {
+			accountNumbers = append(accountNumbers, account.AccountNumber)
+		}
+	}
+	slices.Sort(accountNumbers)
+	return accountNumbers, nil
+}
+
+func (r memoryAccounts) EndOfDayBalances(end time.Time) ([]model.EndOfDayBalance, error) {
+	defer memoryRepositories(r).lock()()
+	var balances []model.EndOfDayBalance
+	for _, account := range r.data.accounts {
+		product := r.data.products[account.ProductCode]
+		if !account.CreatedAt.Before(end) || account.Status == model.AccountStatusClosed || product.AnnualRateBPS <= 0 {
+			continue
+		}
+		balances = append(balances, model.EndOfDayBalance{
+			AccountID:     account.ID,
+			AnnualRateBPS: product.AnnualRateBPS,
+			Balance:       r.data.balanceAt(&account, end),
+		})
+	}
+	return balances, nil
+}
+
+// balanceAt returns the balance of account at at, the way BalanceAt does.
+// The caller holds the store.
+func (d *memoryData) balanceAt(account *model.Account, at time.Time) model.Money {
+	var last, first *model.CashActivity
+	for _, activity := range d.activities {
+		if activity.AccountID != account.ID {
+			continue
+		}
+		if activity.CreatedAt.Before(at) && (last == nil || activity.ID > last.ID) {
+			last = &activity
+		}
+		if first == nil || activity.ID < first.ID {
+			first = &activity
+		}
+	}
+	switch {
+	case last != nil:
+		return last.BalanceAfter
+	case first != nil:
+		return first.BalanceBefore
+	}
+	return account.Balance
+}
+
+type memoryCashActivities memoryRepositories
+
+func (r memoryCashActivities) Find(id uint) (*model.CashActivity, error) {
+	defer memoryRepositories(r).lock()()
+	activity, ok := r.data.activities[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &activity, nil
+}
+
+func (r memoryCashActivities) Latest(accountID uint) (*model.CashActivity, error) {
+	defer memoryRepositories(r).lock()()
+	var latest *model.CashActivity
+	for _, activity := range r.data.activities {
+		if activity.AccountID == accountID && (latest == nil || activity.ID > latest.ID) {
+			latest = &activity
+		}
+	}
+	if latest == nil {
+		return nil, ErrNotFound
+	}
+	return latest, nil
+}
+
+func (r memoryCashActivities) Create(activity *model.CashActivity) error {
+	defer memoryRepositories(r).lock()()
+	if _, ok := r.data.accounts[activity.AccountID]; !ok {
+		return ErrNotFound
+	}
+	if activity.FeePeriod != nil {
+		for _, existing := range r.data.activities {
+			if existing.AccountID == activity.AccountID && existing.FeePeriod != nil && existing.FeePeriod.Equal(*activity.FeePeriod) {
+				return ErrDuplicate
+			}
+		}
+	}
+
+	activity.ID = r.data.nextID("cash_activities")
+	if activity.CreatedAt.IsZero() {
+		activity.CreatedAt = time.Now()
+	}
+	r.data.activities[activity.ID] = *activity
+	return nil
+}
+
+func (r memoryCashActivities) MarkReversed(activity *model.CashActivity, at time.Time) error {
+	defer memoryRepositories(r).lock()()
+	stored, ok := r.data.activities[activity.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.ReversedAt = &at
+	r.data.activities[activity.ID] = stored
+	activity.ReversedAt = &at
+	return nil
+}
+
+func (r memoryCashActivities) FindUnreversedFeeOf(activityID uint) (*model.CashActivity, error) {
+	defer memoryRepositories(r).lock()()
+	for _, activity := range r.data.activities {
+		if activity.FeeOfID != nil && *activity.FeeOfID == activityID && activity.ReversedAt == nil {
+			return &activity, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r memoryCashActivities) List(accountID uint, start, end time.Time, offset, limit int) ([]model.CashActivity, int64, error) {
+	defer memoryRepositories(r).lock()()
+	matched := r.filter(func(activity *model.CashActivity) bool {
+		return activity.AccountID == accountID && !activity.CreatedAt.Before(start) && activity.CreatedAt.Before(end)
+	})
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].CreatedAt.Before(matched[j].CreatedAt)
+		}
+		return matched[i].ID < matched[j].ID
+	})
+
+	total := int64(len(matched))
+	offset = min(offset, len(matched))
+	page := matched[offset:min(offset+limit, len(matched))]
+	return slices.Clone(page), total, nil
+}
+
+func (r memoryCashActivities) WithdrawalUsage(accountID uint, start, end time.Time) (model.Money, int, error) {
+	return r.debitUsage(accountID, start, end, false)
+}
+
+func (r memoryCashActivities) OutflowUsage(accountID uint, start, end time.Time) (model.Money, int, error) {
+	return r.debitUsage(accountID, start, end, true)
+}
+
+// debitUsage sums the debits of an account created in [start, end) that are
+// neither a reversal, reversed nor a fee, and the transfer legs among them if
+// transfers is set.
+func (r memoryCashActivities) debitUsage(accountID uint, start, end time.Time, transfers bool) (model.Money, int, error) {
+	defer memoryRepositories(r).lock()()
+	debits := r.filter(func(activity *model.CashActivity) bool {
+		return activity.AccountID == accountID && activity.Type == "debit" &&
+			(transfers || activity.TransferReference == nil) && activity.ReversalOfID == nil &&
+			activity.ReversedAt == nil && activity.FeeScheduleID == nil &&
+			!activity.CreatedAt.Before(start) && activity.CreatedAt.Before(end)
+	})
+
+	total := model.NewMoney(0)
+	for _, activity := range debits {
+		total = total.Add(activity.Nominal)
+	}
+	return total, len(debits), nil
+}
+
+func (r memoryCashActivities) Walk(accountID uint, fn func(activity *model.CashActivity) bool) error {
+	unlock := memoryRepositories(r).lock()
+	activities := r.filter(func(activity *model.CashActivity) bool { return activity.AccountID == accountID })
+	unlock()
+
+	sort.Slice(activities, func(i, j int) bool { return activities[i].ID < activities[j].ID })
+	for i := range activities {
+		if !fn(&activities[i]) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (r memoryCashActivities) FindByFeePeriod(accountID uint, period time.Time) (*model.CashActivity, error) {
+	defer memoryRepositories(r).lock()()
+	for _, activity := range r.data.activities {
+		if activity.AccountID == accountID && activity.FeePeriod != nil && activity.FeePeriod.Equal(period) {
+			return &activity, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r memoryCashActivities) BalanceAt(accountID uint, at time.Time) (model.Money, error) {
+	defer memoryRepositories(r).lock()()
+	account, ok := r.data.accounts[accountID]
+	if !ok {
+		return model.Money{}, ErrNotFound
+	}
+	return r.data.balanceAt(&account, at), nil
+}
+
+func (r memoryCashActivities) Totals(accountID uint, start, end time.Time) (*model.Statement, error) {
+	defer memoryRepositories(r).lock()()
+	statement := model.Statement{CreditTotal: model.NewMoney(0), DebitTotal: model.NewMoney(0)}
+	for _, activity := range r.filter(func(activity *model.CashActivity) bool {
+		return activity.AccountID == accountID && !activity.CreatedAt.Before(start) && activity.CreatedAt.Before(end)
+	}) {
+		if activity.Type == "credit" {
+			statement.CreditCount++
+			statement.CreditTotal = statement.CreditTotal.Add(activity.Nominal)
+		} else {
+			statement.DebitCount++
+			statement.DebitTotal = statement.DebitTotal.Add(activity.Nominal)
+		}
+		statement.LastActivityID = max(statement.LastActivityID, activity.ID)
+	}
+	return &statement, nil
+}
+
+func (r memoryCashActivities) StatementLines(accountID uint, start time.Time, afterID, throughID uint, limit int) ([]model.StatementLine, error) {
+	defer memoryRepositories(r).lock()()
+	activities := r.filter(func(activity *model.CashActivity) bool {
+		return activity.AccountID == accountID && !activity.CreatedAt.Before(start) && activity.ID > afterID && activity.ID <= throughID
+	})
+	sort.Slice(activities, func(i, j int) bool { return activities[i].ID < activities[j].ID })
+
+	capitalized := map[uint]bool{}
+	for _, accrual := range r.data.interestAccruals {
+		if accrual.CashActivityID != nil {
+			capitalized[*accrual.CashActivityID] = true
+		}
+	}
+	lines := make([]model.StatementLine, 0, min(limit, len(activities)))
+	for _, activity := range activities[:min(limit, len(activities))] {
+		lines = append(lines, model.StatementLine{CashActivity: activity, Interest: capitalized[activity.ID]})
+	}
+	return lines, nil
+}
+
+// filter returns copies of the activities match accepts, in no order. The
+// caller holds the store.
+func (r memoryCashActivities) filter(match func(activity *model.CashActivity) bool) []model.CashActivity {
+	var matched []model.CashActivity
+	for _, activity := range r.data.activities {
+		if match(&activity) {
+			matched = append(matched, activity)
+		}
+	}
+	return matched
+}
+
+type memoryJournals memoryRepositories
+
+func (r memoryJournals) Create(entry *model.JournalEntry) error {
+	defer memoryRepositories(r).lock()()
+	entry.ID = r.data.nextID("journal_entries")
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	for i := range entry.Lines {
+		entry.Lines[i].ID = r.data.nextID("journal_lines")
+		entry.Lines[i].JournalEntryID = entry.ID
+	}
+
+	stored := *entry
+	stored.Lines = slices.Clone(entry.Lines)
+	r.data.journals[entry.ID] = stored
+	return nil
+}
+
+func (r memoryJournals) FindByCashActivity(activityID uint) (*model.JournalEntry, error) {
+	defer memoryRepositories(r).lock()()
+	var found *model.JournalEntry
+	for _, entry := range r.data.journals {
+		if entry.CashActivityID != nil && *entry.CashActivityID == activityID && (found == nil || entry.ID < found.ID) {
+			found = &entry
+		}
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	found.Lines = slices.Clone(found.Lines)
+	return found, nil
+}
+
+func (r memoryJournals) ListGLAccounts() ([]model.GLAccount, error) {
+	defer memoryRepositories(r).lock()()
+	accounts := make([]model.GLAccount, 0, len(r.data.glAccounts))
+	for _, account := range r.data.glAccounts {
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Code < accounts[j].Code })
+	return accounts, nil
+}
+
+func (r memoryJournals) Balances(end time.Time) (map[string]model.Money, error) {
+	defer memoryRepositories(r).lock()()
+	balances := map[string]model.Money{}
+	for _, entry := range r.data.journals {
+		if !entry.CreatedAt.Before(end) {
+			continue
+		}
+		for _, line := range entry.Lines {
+			balances[line.GLAccountCode] = balances[line.GLAccountCode].Add(line.Amount)
+		}
+	}
+	return balances, nil
+}
+
+func (r memoryJournals) Reconciliation() (*model.GLReconciliation, error) {
+	defer memoryRepositories(r).lock()()
+	result := model.GLReconciliation{CustomerBalances: model.NewMoney(0), LiabilityBalance: model.NewMoney(0)}
+	for _, account := range r.data.accounts {
+		result.CustomerBalances = result.CustomerBalances.Add(account.Balance)
+	}
+	for _, entry := range r.data.journals {
+		for _, line := range entry.Lines {
+			if line.GLAccountCode == model.GLCustomerDepositsCode {
+				result.LiabilityBalance = result.LiabilityBalance.Sub(line.Amount)
+			}
+		}
+	}
+	return &result, nil
+}
+
+type memoryOutbox memoryRepositories
+
+func (r memoryOutbox) Create(event *model.OutboxEvent) error {
+	defer memoryRepositories(r).lock()()
+	event.ID = r.data.nextID("outbox")
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	if event.NextAttemptAt.IsZero() {
+		event.NextAttemptAt = event.CreatedAt
+	}
+	r.data.outbox[event.ID] = *event
+	return nil
+}
+
+func (r memoryOutbox) FindAll(ids []uint) ([]model.OutboxEvent, error) {
+	defer memoryRepositories(r).lock()()
+	var events []model.OutboxEvent
+	for _, id := range ids {
+		if event, ok := r.data.outbox[id]; ok {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// Claim leaves no event out: a transaction already keeps every other one
+// out until it ends.
+func (r memoryOutbox) Claim(now time.Time, limit int, until time.Time) ([]model.OutboxEvent, error) {
+	defer memoryRepositories(r).lock()()
+	oldest := map[string]model.OutboxEvent{} // Unpublished, by account
+	for _, event := range r.data.outbox {
+		if event.PublishedAt == nil {
+			if earlier, ok := oldest[event.AccountNumber]; !ok || event.ID < earlier.ID {
+				oldest[event.AccountNumber] = event
+			}
+		}
+	}
+
+	var events []model.OutboxEvent
+	for _, event := range oldest {
+		if !event.NextAttemptAt.After(now) {
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	events = events[:min(limit, len(events))]
+	for i := range events {
+		events[i].NextAttemptAt = until
+		r.data.outbox[events[i].ID] = events[i]
+	}
+	return events, nil
+}
+
+func (r memoryOutbox) Update(event *model.OutboxEvent) error {
+	defer memoryRepositories(r).lock()()
+	stored, ok := r.data.outbox[event.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.Attempts = event.Attempts
+	stored.NextAttemptAt = event.NextAttemptAt
+	stored.LastError = event.LastError
+	stored.PublishedAt = event.PublishedAt
+	r.data.outbox[event.ID] = stored
+	return nil
+}
+
+type memoryFeeSchedules memoryRepositories
+
+func (r memoryFeeSchedules) Effective(productCode string, feeType string, date time.Time) (*model.FeeSchedule, error) {
+	defer memoryRepositories(r).lock()()
+	var effective *model.FeeSchedule
+	for _, schedule := range r.data.feeSchedules {
+		if schedule.ProductCode == productCode && schedule.Type == feeType && !schedule.EffectiveFrom.After(date) &&
+			(effective == nil || schedule.EffectiveFrom.After(effective.EffectiveFrom)) {
+			effective = &schedule
+		}
+	}
+	if effective == nil {
+		return nil, ErrNotFound
+	}
+	return effective, nil
+}
+
+func (r memoryFeeSchedules) List(productCode string) ([]model.FeeSchedule, error) {
+	defer memoryRepositories(r).lock()()
+	schedules := []model.FeeSchedule{}
+	for _, schedule := range r.data.feeSchedules {
+		if schedule.ProductCode == productCode {
+			schedules = append(schedules, schedule)
+		}
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		if schedules[i].Type != schedules[j].Type {
+			return schedules[i].Type < schedules[j].Type
+		}
+		return schedules[i].EffectiveFrom.Before(schedules[j].EffectiveFrom)
+	})
+	return schedules, nil
+}
+
+func (r memoryFeeSchedules) Create(schedule *model.FeeSchedule) error {
+	defer memoryRepositories(r).lock()()
+	if _, ok := r.data.products[schedule.ProductCode]; !ok {
+		return ErrNotFound
+	}
+	for _, existing := range r.data.feeSchedules {
+		if existing.ProductCode == schedule.ProductCode && existing.Type == schedule.Type && existing.EffectiveFrom.Equal(schedule.EffectiveFrom) {
+			return ErrDuplicate
+		}
+	}
+
+	schedule.ID = r.data.nextID("fee_schedules")
+	if schedule.CreatedAt.IsZero() {
+		schedule.CreatedAt = time.Now()
+	}
+	r.data.feeSchedules[schedule.ID] = *schedule
+	return nil
+}
+
+type memoryProducts memoryRepositories
+
+func (r memoryProducts) Find(code string) (*model.Product, error) {
+	defer memoryRepositories(r).lock()()
+	product, ok := r.data.products[code]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &product, nil
+}
+
+func (r memoryProducts) UpdateRate(product *model.Product) error {
+	defer memoryRepositories(r).lock()()
+	stored, ok := r.data.products[product.Code]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.AnnualRateBPS = product.AnnualRateBPS
+	stored.UpdatedAt = time.Now()
+	product.UpdatedAt = stored.UpdatedAt
+	r.data.products[product.Code] = stored
+	return nil
+}
+
+type memoryInterestAccruals memoryRepositories
+
+func (r memoryInterestAccruals) CreateAll(accruals []model.InterestAccrual) error {
+	defer memoryRepositories(r).lock()()
+	accrued := map[uint]bool{} // Account IDs of the dates of accruals
+	for _, accrual := range accruals {
+		for _, existing := range r.data.interestAccruals {
+			if existing.AccountID == accrual.AccountID && existing.BusinessDate.Equal(accrual.BusinessDate) {
+				accrued[accrual.AccountID] = true
+			}
+		}
+	}
+
+	now := time.Now()
+	for _, accrual := range accruals {
+		if accrued[accrual.AccountID] {
+			continue
+		}
+		if _, ok := r.data.accounts[accrual.AccountID]; !ok {
+			return ErrNotFound
+		}
+		accrual.ID = r.data.nextID("interest_accruals")
+		accrual.CreatedAt = now
+		r.data.interestAccruals[accrual.ID] = accrual
+	}
+	return nil
+}
+
+func (r memoryInterestAccruals) Pending(accountID uint) ([]model.InterestAccrual, error) {
+	defer memoryRepositories(r).lock()()
+	var pending []model.InterestAccrual
+	for _, accrual := range r.data.interestAccruals {
+		if accrual.AccountID == accountID && accrual.CashActivityID == nil {
+			pending = append(pending, accrual)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].BusinessDate.Before(pending[j].BusinessDate) })
+	return pending, nil
+}
+
+func (r memoryInterestAccruals) PendingAccounts(date time.Time) ([]string, error) {
+	defer memoryRepositories(r).lock()()
+	pending := map[string]bool{}
+	for _, accrual := range r.data.interestAccruals {
+		if accrual.CashActivityID == nil && !accrual.BusinessDate.After(date) {
+			pending[r.data.accounts[accrual.AccountID].AccountNumber] = true
+		}
+	}
+	accountNumbers := make([]string, 0, len(pending))
+	for accountNumber := range pending {
+		accountNumbers = append(accountNumbers, accountNumber)
+	}
+	slices.Sort(accountNumbers)
+	return accountNumbers, nil
+}
+
+func (r memoryInterestAccruals) MarkCapitalized(ids []uint, activityID uint) error {
+	defer memoryRepositories(r).lock()()
+	for _, id := range ids {
+		accrual, ok := r.data.interestAccruals[id]
+		if !ok {
+			return ErrNotFound
+		}
+		accrual.CashActivityID = &activityID
+		r.data.interestAccruals[id] = accrual
+	}
+	return nil
+}
+
+func (r memoryInterestAccruals) Accrued(date time.Time) (int, model.Money, error) {
+	return r.total(func(accrual *model.InterestAccrual) bool { return accrual.BusinessDate.Equal(date) })
+}
+
+func (r memoryInterestAccruals) Capitalized(start, end time.Time) (int, model.Money, error) {
+	return r.total(func(accrual *model.InterestAccrual) bool {
+		return accrual.CashActivityID != nil && !accrual.BusinessDate.Before(start) && !accrual.BusinessDate.After(end)
+	})
+}
+
+// total returns how many accounts the accruals match accepts belong to, and
+// their sum.
+func (r memoryInterestAccruals) total(match func(accrual *model.InterestAccrual) bool) (int, model.Money, error) {
+	defer memoryRepositories(r).lock()()
+	accounts := map[uint]bool{}
+	total := model.NewMoney(0)
+	for _, accrual := range r.data.interestAccruals {
+		if match(&accrual) {
+			accounts[accrual.AccountID] = true
+			total = total.Add(accrual.Amount)
+		}
+	}
+	return len(accounts), total, nil
+}
+
+type memoryInterestRuns memoryRepositories
+
+func (r memoryInterestRuns) Find(date time.Time) (*model.InterestRun, error) {
+	defer memoryRepositories(r).lock()()
+	for _, run := range r.data.interestRuns {
+		if run.BusinessDate.Equal(date) {
+			return &run, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r memoryInterestRuns) Create(run *model.InterestRun) error {
+	defer memoryRepositories(r).lock()()
+	for _, existing := range r.data.interestRuns {
+		if existing.BusinessDate.Equal(run.BusinessDate) {
+			return nil
+		}
+	}
+	run.ID = r.data.nextID("interest_runs")
+	run.CompletedAt = time.Now()
+	r.data.interestRuns[run.ID] = *run
+	return nil
+}
+
+type memoryIdempotencyKeys memoryRepositories
+
+func (r memoryIdempotencyKeys) Reserve(key *model.IdempotencyKey) (bool, error) {
+	defer memoryRepositories(r).lock()()
+	if _, ok := r.find(key.Principal, key.Key); ok {
+		return false, nil
+	}
+	key.ID = r.data.nextID("idempotency_keys")
+	key.CreatedAt = time.Now()
+	r.data.idempotencyKeys[key.ID] = *key
+	return true, nil
+}
+
+func (r memoryIdempotencyKeys) Find(principal string, key string) (*model.IdempotencyKey, error) {
+	defer memoryRepositories(r).lock()()
+	found, ok := r.find(principal, key)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	found.ResponseBody = slices.Clone(found.ResponseBody)
+	return &found, nil
+}
+
+func (r memoryIdempotencyKeys) Complete(key *model.IdempotencyKey) error {
+	defer memoryRepositories(r).lock()()
+	if stored, ok := r.find(key.Principal, key.Key); ok {
+		stored.StatusCode = key.StatusCode
+		stored.ContentType = key.ContentType
+		stored.ResponseBody = slices.Clone(key.ResponseBody)
+		r.data.idempotencyKeys[stored.ID] = stored
+	}
+	return nil
+}
+
+func (r memoryIdempotencyKeys) Delete(principal string, key string) error {
+	defer memoryRepositories(r).lock()()
+	if stored, ok := r.find(principal, key); ok {
+		delete(r.data.idempotencyKeys, stored.ID)
+	}
+	return nil
+}
+
+func (r memoryIdempotencyKeys) DeleteExpired(id uint, now time.Time) error {
+	defer memoryRepositories(r).lock()()
+	if stored, ok := r.data.idempotencyKeys[id]; ok && stored.ExpiresAt.Before(now) {
+		delete(r.data.idempotencyKeys, id)
+	}
+	return nil
+}
+
+func (r memoryIdempotencyKeys) Purge(now time.Time, limit int) (int, error) {
+	defer memoryRepositories(r).lock()()
+	var expired []uint
+	for id, key := range r.data.idempotencyKeys {
+		if key.ExpiresAt.Before(now) {
+			expired = append(expired, id)
+		}
+	}
+	slices.Sort(expired)
+	expired = expired[:min(limit, len(expired))]
+	for _, id := range expired {
+		delete(r.data.idempotencyKeys, id)
+	}
+	return len(expired), nil
+}
+
+// find returns the stored key of principal. The caller holds the store.
+func (r memoryIdempotencyKeys) find(principal string, key string) (model.IdempotencyKey, bool) {
+	for _, stored := range r.data.idempotencyKeys {
+		if stored.Principal == principal && stored.Key == key {
+			return stored, true
+		}
+	}
+	return model.IdempotencyKey{}, false
+}
+
+type memoryWebhookSubscriptions memoryRepositories
+
+func (r memoryWebhookSubscriptions) Find(id uint) (*model.WebhookSubscription, error) {
+	defer memoryRepositories(r).lock()()
+	subscription, ok := r.data.subscriptions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &subscription, nil
+}
+
+func (r memoryWebhookSubscriptions) List(activeOnly bool) ([]model.WebhookSubscription, error) {
+	defer memoryRepositories(r).lock()()
+	var subscriptions []model.WebhookSubscription
+	for _, subscription := range r.data.subscriptions {
+		if subscription.Active || !activeOnly {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	sort.Slice(subscriptions, func(i, j int) bool { return subscriptions[i].ID < subscriptions[j].ID })
+	return subscriptions, nil
+}
+
+func (r memoryWebhookSubscriptions) FindAll(ids []uint) ([]model.WebhookSubscription, error) {
+	defer memoryRepositories(r).lock()()
+	var subscriptions []model.WebhookSubscription
+	for _, id := range ids {
+		if subscription, ok := r.data.subscriptions[id]; ok {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions, nil
+}
+
+func (r memoryWebhookSubscriptions) Create(subscription *model.WebhookSubscription) error {
+	defer memoryRepositories(r).lock()()
+	now := time.Now()
+	subscription.ID = r.data.nextID("webhook_subscriptions")
+	subscription.CreatedAt = now
+	subscription.UpdatedAt = now
+	r.data.subscriptions[subscription.ID] = *subscription
+	return nil
+}
+
+func (r memoryWebhookSubscriptions) Update(subscription *model.WebhookSubscription) error {
+	return r.update(subscription, func(stored *model.WebhookSubscription) {
+		stored.URL = subscription.URL
+		stored.EventTypes = subscription.EventTypes
+		stored.Active = subscription.Active
+	})
+}
+
+func (r memoryWebhookSubscriptions) UpdateSecrets(subscription *model.WebhookSubscription) error {
+	return r.update(subscription, func(stored *model.WebhookSubscription) {
+		stored.Secret = subscription.Secret
+		stored.PreviousSecret = subscription.PreviousSecret
+		stored.PreviousSecretExpiresAt = subscription.PreviousSecretExpiresAt
+	})
+}
+
+// update applies set to the stored copy of subscription.
+func (r memoryWebhookSubscriptions) update(subscription *model.WebhookSubscription, set func(stored *model.WebhookSubscription)) error {
+	defer memoryRepositories(r).lock()()
+	stored, ok := r.data.subscriptions[subscription.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	set(&stored)
+	stored.UpdatedAt = time.Now()
+	subscription.UpdatedAt = stored.UpdatedAt
+	r.data.subscriptions[subscription.ID] = stored
+	return nil
+}
+
+func (r memoryWebhookSubscriptions) Delete(id uint) error {
+	defer memoryRepositories(r).lock()()
+	if _, ok := r.data.subscriptions[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.data.subscriptions, id)
+	for deliveryID, delivery := range r.data.deliveries {
+		if delivery.SubscriptionID == id {
+			delete(r.data.deliveries, deliveryID)
+		}
+	}
+	for attemptID, attempt := range r.data.attempts {
+		if _, ok := r.data.deliveries[attempt.DeliveryID]; !ok {
+			delete(r.data.attempts, attemptID)
+		}
+	}
+	return nil
+}
+
+type memoryWebhookDeliveries memoryRepositories
+
+func (r memoryWebhookDeliveries) Find(id uint) (*model.WebhookDelivery, error) {
+	defer memoryRepositories(r).lock()()
+	delivery, ok := r.data.deliveries[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	for _, attempt := range r.data.attempts {
+		if attempt.DeliveryID == id {
+			delivery.AttemptLog = append(delivery.AttemptLog, attempt)
+		}
+	}
+	sort.Slice(delivery.AttemptLog, func(i, j int) bool { return delivery.AttemptLog[i].ID < delivery.AttemptLog[j].ID })
+	return &delivery, nil
+}
+
+// Lock only finds the delivery: a transaction already keeps every other one
+// out until it ends.
+func (r memoryWebhookDeliveries) Lock(id uint) (*model.WebhookDelivery, error) {
+	defer memoryRepositories(r).lock()()
+	delivery, ok := r.data.deliveries[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &delivery, nil
+}
+
+func (r memoryWebhookDeliveries) List(subscriptionID uint, status string, offset, limit int) ([]model.WebhookDelivery, int64, error) {
+	defer memoryRepositories(r).lock()()
+	var matched []model.WebhookDelivery
+	for _, delivery := range r.data.deliveries {
+		if delivery.SubscriptionID == subscriptionID && (status == "" || delivery.Status == status) {
+			matched = append(matched, delivery)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].ID > matched[j].ID })
+
+	total := int64(len(matched))
+	offset = min(offset, len(matched))
+	page := matched[offset:min(offset+limit, len(matched))]
+	return slices.Clone(page), total, nil
+}
+
+func (r memoryWebhookDeliveries) CreateAll(deliveries []model.WebhookDelivery) error {
+	defer memoryRepositories(r).lock()()
+	now := time.Now()
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		if _, ok := r.data.subscriptions[delivery.SubscriptionID]; !ok {
+			return ErrNotFound
+		}
+		if r.exists(delivery.SubscriptionID, delivery.OutboxID) {
+			continue
+		}
+		delivery.ID = r.data.nextID("webhook_deliveries")
+		delivery.CreatedAt = now
+		delivery.UpdatedAt = now
+		r.data.deliveries[delivery.ID] = *delivery
+	}
+	return nil
+}
+
+// exists reports whether an event has a delivery to a subscription. The
+// caller holds the store.
+func (r memoryWebhookDeliveries) exists(subscriptionID uint, outboxID uint) bool {
+	for _, delivery := range r.data.deliveries {
+		if delivery.SubscriptionID == subscriptionID && delivery.OutboxID == outboxID {
+			return true
+		}
+	}
+	return false
+}
+
+// Claim leaves no delivery out: a transaction already keeps every other one
+// out until it ends.
+func (r memoryWebhookDeliveries) Claim(now time.Time, limit int) ([]model.WebhookDelivery, error) {
+	defer memoryRepositories(r).lock()()
+	var due []model.WebhookDelivery
+	for _, delivery := range r.data.deliveries {
+		if delivery.Status == model.WebhookDeliveryPending && !delivery.NextAttemptAt.After(now) && r.data.subscriptions[delivery.SubscriptionID].Active {
+			due = append(due, delivery)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttemptAt.Equal(due[j].NextAttemptAt) {
+			return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+		}
+		return due[i].ID < due[j].ID
+	})
+	return due[:min(limit, len(due))], nil
+}
+
+func (r memoryWebhookDeliveries) Update(delivery *model.WebhookDelivery) error {
+	defer memoryRepositories(r).lock()()
+	stored, ok := r.data.deliveries[delivery.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.Status = delivery.Status
+	stored.Attempts = delivery.Attempts
+	stored.NextAttemptAt = delivery.NextAttemptAt
+	stored.LastStatusCode = delivery.LastStatusCode
+	stored.LastError = delivery.LastError
+	stored.DeliveredAt = delivery.DeliveredAt
+	stored.UpdatedAt = time.Now()
+	delivery.UpdatedAt = stored.UpdatedAt
+	r.data.deliveries[delivery.ID] = stored
+	return nil
+}
+
+func (r memoryWebhookDeliveries) CreateAttempt(attempt *model.WebhookDeliveryAttempt) error {
+	defer memoryRepositories(r).lock()()
+	if _, ok := r.data.deliveries[attempt.DeliveryID]; !ok {
+		return ErrNotFound
+	}
+	attempt.ID = r.data.nextID("webhook_delivery_attempts")
+	attempt.AttemptedAt = time.Now()
+	r.data.attempts[attempt.ID] = *attempt
+	return nil
+}
@@ -0,0 +1,262 @@
+// Package repository stores the accounts and everything posted to them. The
+// services reach their data only through these interfaces, so their business
+// rules run the same against the database and against the in-memory store.
+package repository
+
+import (
+	"account-service/src/model"
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFound  = errors.New("record not found")
+	ErrDuplicate = errors.New("record already exists")
+)
+
+// UnitOfWork hands out the repositories, either standing alone or bound to
+// one transaction.
+type UnitOfWork interface {
+	// Repositories returns repositories each call of which stands alone.
+	Repositories(c context.Context) Repositories
+	// Transaction runs fn with repositories bound to one transaction, which
+	// is committed if fn returns nil and rolled back otherwise. Accounts
+	// locked through them stay locked until it ends. fn must not use
+	// repositories from outside the transaction.
+	Transaction(c context.Context, fn func(tx Repositories) error) error
+}
+
+// Repositories groups the repositories of one unit of work.
+type Repositories interface {
+	// Context returns the context the repositories were handed out for.
+	Context() context.Context
+	Accounts() AccountRepository
+	CashActivities() CashActivityRepository
+	Journals() JournalRepository
+	Outbox() OutboxRepository
+	FeeSchedules() FeeScheduleRepository
+	Products() ProductRepository
+	InterestAccruals() InterestAccrualRepository
+	InterestRuns() InterestRunRepository
+	IdempotencyKeys() IdempotencyKeyRepository
+	WebhookSubscriptions() WebhookSubscriptionRepository
+	WebhookDeliveries() WebhookDeliveryRepository
+}
+
+// AccountRepository stores accounts, the history of their statuses and their
+// withdrawal limit overrides. Finders return ErrNotFound when nothing
+// matches.
+type AccountRepository interface {
+	FindByID(id uint) (*model.Account, error)
+	FindByNumber(accountNumber string) (*model.Account, error)
+	FindByIDNumber(idNumber string) (*model.Account, error)
+	FindByPhoneNumber(phoneNumber string) (*model.Account, error)
+	// Lock loads an account and keeps every other transaction from locking
+	// or writing it until this one ends.
+	Lock(accountNumber string) (*model.Account, error)
+	// LockShared loads an account and keeps every other transaction from
+	// writing it until this one ends.
+	LockShared(accountNumber string) (*model.Account, error)
+	// Create stores a new account, returning ErrDuplicate if its number, ID
+	// number or phone number is taken.
+	Create(account *model.Account) error
+	UpdateBalance(account *model.Account) error
+	UpdateStatus(account *model.Account) error
+	// UpdatePIN writes the PIN hash, failed attempts and lock of an account.
+	UpdatePIN(account *model.Account) error
+	CreateStatusHistory(history *model.AccountStatusHistory) error
+	FindWithdrawalLimit(accountID uint) (*model.AccountWithdrawalLimit, error)
+	// SaveWithdrawalLimit creates or replaces the overrides of an account.
+	SaveWithdrawalLimit(limit *model.AccountWithdrawalLimit) error
+	// ListMonthlyFeeDue returns the numbers of the accounts opened before
+	// end that are neither closed nor frozen and have not been charged the
+	// monthly fee of period, in order.
+	ListMonthlyFeeDue(end time.Time, period time.Time) ([]string, error)
+	// EndOfDayBalances returns the balance at end of every account opened
+	// before end that is not closed and whose product pays interest, with
+	// the rate of its product.
+	EndOfDayBalances(end time.Time) ([]model.EndOfDayBalance, error)
+}
+
+// CashActivityRepository stores the cash activities of accounts.
+type CashActivityRepository interface {
+	Find(id uint) (*model.CashActivity, error)
+	// Latest returns the activity of an account with the highest ID.
+	Latest(accountID uint) (*model.CashActivity, error)
+	Create(activity *model.CashActivity) error
+	MarkReversed(activity *model.CashActivity, at time.Time) error
+	// FindUnreversedFeeOf returns the fee charged for an activity, unless it
+	// has been reversed.
+	FindUnreversedFeeOf(activityID uint) (*model.CashActivity, error)
+	// List returns the activities of an account created in [start, end),
+	// oldest first, skipping offset and returning at most limit, with how
+	// many there are in all.
+	List(accountID uint, start, end time.Time, offset, limit int) ([]model.CashActivity, int64, error)
+	// WithdrawalUsage returns the total and count of the withdrawals of an
+	// account created in [start, end) that count towards its free
+	// withdrawals: debits that are neither a transfer leg, a reversal,
+	// reversed nor a fee.
+	WithdrawalUsage(accountID uint, start, end time.Time) (model.Money, int, error)
+	// OutflowUsage returns the total and count of the debits of an account
+	// created in [start, end) that count towards its withdrawal limits: the
+	// withdrawals and the outgoing transfer legs.
+	OutflowUsage(accountID uint, start, end time.Time) (model.Money, int, error)
+	// Walk calls fn with every activity of an account in ID order, until fn
+	// returns false.
+	Walk(accountID uint, fn func(activity *model.CashActivity) bool) error
+	// FindByFeePeriod returns the monthly fee of period charged to an
+	// account.
+	FindByFeePeriod(accountID uint, period time.Time) (*model.CashActivity, error)
+	// BalanceAt returns the balance of an account at at: the balance after
+	// its last earlier activity, or before its first one. An account with
+	// no activity at all has held its balance since it was opened.
+	BalanceAt(accountID uint, at time.Time) (model.Money, error)
+	// Totals returns a statement of an account over [start, end) with only
+	// its counts, totals and last activity set.
+	Totals(accountID uint, start, end time.Time) (*model.Statement, error)
+	// StatementLines returns the activities of an account created from
+	// start with an ID in (afterID, throughID], oldest first, at most
+	// limit.
+	StatementLines(accountID uint, start time.Time, afterID, throughID uint, limit int) ([]model.StatementLine, error)
+}
+
+// JournalRepository stores general ledger journal entries.
+type JournalRepository interface {
+	// Create stores an entry with its lines.
+	Create(entry *model.JournalEntry) error
+	// FindByCashActivity returns the entry, with its lines, that accounts
+	// for a cash activity.
+	FindByCashActivity(activityID uint) (*model.JournalEntry, error)
+	// ListGLAccounts returns the accounts of the general ledger by code.
+	ListGLAccounts() ([]model.GLAccount, error)
+	// Balances returns the sum of the lines of the entries created before
+	// end, by GL account code.
+	Balances(end time.Time) (map[string]model.Money, error)
+	// Reconciliation returns the sum of the balances of the customer
+	// accounts and the balance of the customer deposits liability, read at
+	// the same time.
+	Reconciliation() (*model.GLReconciliation, error)
+}
+
+// OutboxRepository stores the events waiting to be published.
+type OutboxRepository interface {
+	Create(event *model.OutboxEvent) error
+	FindAll(ids []uint) ([]model.OutboxEvent, error)
+	// Claim returns, at most limit and oldest first, the unpublished events
+	// due at now of the accounts whose earlier events are all published,
+	// leaving out those another transaction is claiming, and moves their
+	// next attempt to until.
+	Claim(now time.Time, limit int, until time.Time) ([]model.OutboxEvent, error)
+	// Update writes the attempts, next attempt, last error and publication
+	// of an event.
+	Update(event *model.OutboxEvent) error
+}
+
+// FeeScheduleRepository stores the versions of the fee rules of products.
+type FeeScheduleRepository interface {
+	// Effective returns the version of a fee rule of a product in force on
+	// date.
+	Effective(productCode string, feeType string, date time.Time) (*model.FeeSchedule, error)
+	// List returns every version of the fee rules of a product, by type
+	// and oldest first.
+	List(productCode string) ([]model.FeeSchedule, error)
+	// Create stores a new version, returning ErrDuplicate if one of its type
+	// already takes effect on that date.
+	Create(schedule *model.FeeSchedule) error
+}
+
+// ProductRepository stores savings products.
+type ProductRepository interface {
+	Find(code string) (*model.Product, error)
+	UpdateRate(product *model.Product) error
+}
+
+// InterestAccrualRepository stores the interest accrued by accounts each
+// business day.
+type InterestAccrualRepository interface {
+	// CreateAll stores accruals, leaving out those of an account and date
+	// already accrued.
+	CreateAll(accruals []model.InterestAccrual) error
+	// Pending returns the accruals of an account not yet capitalized,
+	// oldest first.
+	Pending(accountID uint) ([]model.InterestAccrual, error)
+	// PendingAccounts returns the numbers of the accounts with accruals up
+	// to date not yet capitalized.
+	PendingAccounts(date time.Time) ([]string, error)
+	// MarkCapitalized links accruals to the activity that credited them.
+	MarkCapitalized(ids []uint, activityID uint) error
+	// Accrued returns how many accounts accrued interest on date, and how
+	// much in all.
+	Accrued(date time.Time) (int, model.Money, error)
+	// Capitalized returns how many accounts were credited the interest
+	// accrued from start through end, and how much in all.
+	Capitalized(start, end time.Time) (int, model.Money, error)
+}
+
+// InterestRunRepository stores the business dates the interest job has
+// completed.
+type InterestRunRepository interface {
+	Find(date time.Time) (*model.InterestRun, error)
+	// Create stores a run, unless one of its date is already stored.
+	Create(run *model.InterestRun) error
+}
+
+// IdempotencyKeyRepository stores the idempotency keys of requests, by
+// principal and key.
+type IdempotencyKeyRepository interface {
+	// Reserve stores a new key, reporting false if the principal already
+	// holds it.
+	Reserve(key *model.IdempotencyKey) (bool, error)
+	Find(principal string, key string) (*model.IdempotencyKey, error)
+	// Complete writes the response of the request holding a key.
+	Complete(key *model.IdempotencyKey) error
+	Delete(principal string, key string) error
+	// DeleteExpired deletes a key if it expired before now.
+	DeleteExpired(id uint, now time.Time) error
+	// Purge deletes at most limit keys expired before now, returning how
+	// many it deleted.
+	Purge(now time.Time, limit int) (int, error)
+}
+
+// WebhookSubscriptionRepository stores the webhook subscriptions of
+// partners.
+type WebhookSubscriptionRepository interface {
+	Find(id uint) (*model.WebhookSubscription, error)
+	// List returns every subscription, or only the active ones, oldest
+	// first.
+	List(activeOnly bool) ([]model.WebhookSubscription, error)
+	FindAll(ids []uint) ([]model.WebhookSubscription, error)
+	Create(subscription *model.WebhookSubscription) error
+	// Update writes the URL, event types and whether a subscription is
+	// active.
+	Update(subscription *model.WebhookSubscription) error
+	UpdateSecrets(subscription *model.WebhookSubscription) error
+	// Delete deletes a subscription with its deliveries.
+	Delete(id uint) error
+}
+
+// WebhookDeliveryRepository stores the deliveries of events to webhook
+// subscriptions and the log of their attempts.
+type WebhookDeliveryRepository interface {
+	// Find returns a delivery with the log of its attempts, oldest first.
+	Find(id uint) (*model.WebhookDelivery, error)
+	// Lock loads a delivery and keeps every other transaction from locking
+	// or writing it until this one ends.
+	Lock(id uint) (*model.WebhookDelivery, error)
+	// List returns the deliveries of a subscription, of any status if
+	// status is empty, newest first, skipping offset and returning at most
+	// limit, with how many there are in all.
+	List(subscriptionID uint, status string, offset, limit int) ([]model.WebhookDelivery, int64, error)
+	// CreateAll stores deliveries, leaving out those of an event to a
+	// subscription already stored.
+	CreateAll(deliveries []model.WebhookDelivery) error
+	// Claim returns, at most limit and soonest first, the pending
+	// deliveries due at now of active subscriptions, locked like Lock,
+	// leaving out those another transaction has locked.
+	Claim(now time.Time, limit int) ([]model.WebhookDelivery, error)
+	// Update writes the status, attempts, next attempt and outcome of the
+	// last attempt of a delivery.
+	Update(delivery *model.WebhookDelivery) error
+	CreateAttempt(attempt *model.WebhookDeliveryAttempt) error
+}
@@ -7,11 +7,12 @@ type HealthCheck struct {
 }
 
 type HealthCheckResponse struct {
-	Code      int           `json:"code" example:"200"`
-	Status    string        `json:"status" example:"success"`
-	Message   string        `json:"message" example:"Health check completed"`
-	IsHealthy bool          `json:"is_healthy" example:"true"`
-	Result    []HealthCheck `json:"result"`
+	Code          int           `json:"code" example:"200"`
+	Status        string        `json:"status" example:"success"`
+	Message       string        `json:"message" example:"Health check completed"`
+	IsHealthy     bool          `json:"is_healthy" example:"true"`
+	SchemaVersion uint          `json:"schema_version" example:"7"`
+	Result        []HealthCheck `json:"result"`
 }
 
 type HealthCheckError struct {
@@ -22,9 +23,10 @@ type HealthCheckError struct {
 }
 
 type HealthCheckResponseError struct {
-	Code      int                `json:"code" example:"500"`
-	Status    string             `json:"status" example:"error"`
-	Message   string             `json:"message" example:"Health check completed"`
-	IsHealthy bool               `json:"is_healthy" example:"false"`
-	Result    []HealthCheckError `json:"result"`
+	Code          int                `json:"code" example:"500"`
+	Status        string             `json:"status" example:"error"`
+	Message       string             `json:"message" example:"Health check completed"`
+	IsHealthy     bool               `json:"is_healthy" example:"false"`
+	SchemaVersion uint               `json:"schema_version,omitempty" example:"7"`
+	Result        []HealthCheckError `json:"result"`
 }
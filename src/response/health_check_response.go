@@ -8,9 +8,10 @@ type HealthCheck struct {
 }
 
 type HealthCheckResponse struct {
-	Code      int           `json:"code"`
-	Status    string        `json:"status"`
-	Message   string        `json:"message"`
-	IsHealthy bool          `json:"is_healthy"`
-	Result    []HealthCheck `json:"result"`
+	Code          int           `json:"code"`
+	Status        string        `json:"status"`
+	Message       string        `json:"message"`
+	IsHealthy     bool          `json:"is_healthy"`
+	SchemaVersion *uint         `json:"schema_version,omitempty"`
+	Result        []HealthCheck `json:"result"`
 }
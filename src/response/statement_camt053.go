@@ -0,0 +1,234 @@
+package response
+
+import (
+	"account-service/src/model"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+	camt053Currency  = "IDR"
+
+	// camt053MaxEntryInfo is the length of a Max500Text.
+	camt053MaxEntryInfo = 500
+)
+
+// camt053Codes maps the kind and direction of a line to its ISO 20022 bank
+// transaction code: domain, family and sub-family.
+var camt053Codes = map[string][3]string{
+	model.ActivityKindDeposit + "/credit":   {"PMNT", "CNTR", "CDPT"},
+	model.ActivityKindWithdrawal + "/debit": {"PMNT", "CNTR", "CWDL"},
+	model.ActivityKindTransfer + "/credit":  {"PMNT", "RCDT", "BOOK"},
+	model.ActivityKindTransfer + "/debit":   {"PMNT", "ICDT", "BOOK"},
+	model.ActivityKindFee + "/debit":        {"ACMT", "MDOP", "CHRG"},
+	model.ActivityKindInterest + "/credit":  {"ACMT", "MCOP", "INTR"},
+	model.ActivityKindReversal + "/credit":  {"ACMT", "MCOP", "OTHR"},
+	model.ActivityKindReversal + "/debit":   {"ACMT", "MDOP", "OTHR"},
+}
+
+type camt053Amount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type camt053Period struct {
+	From string `xml:"FrDtTm"`
+	To   string `xml:"ToDtTm"`
+}
+
+type camt053Account struct {
+	ID       string `xml:"Id>Othr>Id"`
+	Currency string `xml:"Ccy"`
+	Owner    string `xml:"Ownr>Nm"`
+}
+
+type camt053Balance struct {
+	Code                 string        `xml:"Tp>CdOrPrtry>Cd"`
+	Amount               camt053Amount `xml:"Amt"`
+	CreditDebitIndicator string        `xml:"CdtDbtInd"`
+	Date                 string        `xml:"Dt>Dt"`
+}
+
+type camt053NumberAndSum struct {
+	NumberOfEntries int    `xml:"NbOfNtries"`
+	Sum             string `xml:"Sum"`
+}
+
+type camt053Summary struct {
+	Total struct {
+		NumberOfEntries      int    `xml:"NbOfNtries"`
+		Sum                  string `xml:"Sum"`
+		NetAmount            string `xml:"TtlNetNtryAmt"`
+		CreditDebitIndicator string `xml:"CdtDbtInd"`
+	} `xml:"TtlNtries"`
+	Credits camt053NumberAndSum `xml:"TtlCdtNtries"`
+	Debits  camt053NumberAndSum `xml:"TtlDbtNtries"`
+}
+
+type camt053Entry struct {
+	Reference            string        `xml:"NtryRef"`
+	Amount               camt053Amount `xml:"Amt"`
+	CreditDebitIndicator string        `xml:"CdtDbtInd"`
+	ReversalIndicator    bool          `xml:"RvslInd,omitempty"`
+	Status               string        `xml:"Sts"`
+	BookingDateTime      string        `xml:"BookgDt>DtTm"`
+	ValueDate            string        `xml:"ValDt>Dt"`
+	ServicerReference    string        `xml:"AcctSvcrRef"`
+	Domain               string        `xml:"BkTxCd>Domn>Cd"`
+	Family               string        `xml:"BkTxCd>Domn>Fmly>Cd"`
+	SubFamily            string        `xml:"BkTxCd>Domn>Fmly>SubFmlyCd"`
+	AdditionalInfo       string        `xml:"AddtlNtryInf,omitempty"`
+}
+
+// statementCamt053 writes a statement as an ISO 20022 camt.053.001.02
+// BankToCustomerStatement holding one statement, with its opening (OPBD) and
+// closing (CLBD) booked balances, its totals, and one booked entry per line.
+type statementCamt053 struct {
+	w         io.Writer
+	xml       *xml.Encoder
+	statement *model.Statement
+}
+
+func newStatementCamt053(w io.Writer, statement *model.Statement) StatementWriter {
+	return &statementCamt053{w: w, xml: xml.NewEncoder(w), statement: statement}
+}
+
+func (s *statementCamt053) WriteHeader() error {
+	statement := s.statement
+	if _, err := io.WriteString(s.w, xml.Header); err != nil {
+		return err
+	}
+
+	document := xml.StartElement{Name: xml.Name{Local: "Document"}, Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: camt053Namespace}}}
+	if err := s.xml.EncodeToken(document); err != nil {
+		return err
+	}
+	if err := s.xml.EncodeToken(xml.StartElement{Name: xml.Name{Local: "BkToCstmrStmt"}}); err != nil {
+		return err
+	}
+
+	// Max35Text: a 10-digit account number, two dates and a time.
+	id := statement.AccountNumber + statement.From.Format("20060102") + statement.LastDay().Format("20060102") + statement.GeneratedAt.Format("150405")
+	createdAt := statement.GeneratedAt.Format(time.RFC3339)
+	if err := s.element("GrpHdr", struct {
+		MessageID string `xml:"MsgId"`
+		CreatedAt string `xml:"CreDtTm"`
+	}{id, createdAt}); err != nil {
+		return err
+	}
+
+	if err := s.xml.EncodeToken(xml.StartElement{Name: xml.Name{Local: "Stmt"}}); err != nil {
+		return err
+	}
+
+	net := statement.CreditTotal.Sub(statement.DebitTotal)
+	var summary camt053Summary
+	summary.Total.NumberOfEntries = statement.CreditCount + statement.DebitCount
+	summary.Total.Sum = statement.CreditTotal.Add(statement.DebitTotal).String()
+	summary.Total.NetAmount = camt053Abs(net).String()
+	summary.Total.CreditDebitIndicator = camt053Indicator(net)
+	summary.Credits = camt053NumberAndSum{statement.CreditCount, statement.CreditTotal.String()}
+	summary.Debits = camt053NumberAndSum{statement.DebitCount, statement.DebitTotal.String()}
+
+	// The children of Stmt that precede the entries, in schema order.
+	children := []struct {
+		name  string
+		value any
+	}{
+		{"Id", id},
+		{"CreDtTm", createdAt},
+		{"FrToDt", camt053Period{statement.From.Format(time.RFC3339), statement.LastDay().Truncate(time.Second).Format(time.RFC3339)}},
+		{"Acct", camt053Account{ID: statement.AccountNumber, Currency: camt053Currency, Owner: statement.FullName}},
+		{"Bal", camt053NewBalance("OPBD", statement.OpeningBalance, statement.From)},
+		{"Bal", camt053NewBalance("CLBD", statement.ClosingBalance, statement.LastDay())},
+		{"TxsSummry", summary},
+	}
+	for _, child := range children {
+		if err := s.element(child.name, child.value); err != nil {
+			return err
+		}
+	}
+	return s.xml.Flush()
+}
+
+func (s *statementCamt053) WriteLine(line *model.StatementLine) error {
+	codes, ok := camt053Codes[line.Kind()+"/"+line.Type]
+	if !ok {
+		codes = [3]string{"ACMT", "MDOP", "OTHR"}
+	}
+	indicator := "DBIT"
+	if line.Type == "credit" {
+		indicator = "CRDT"
+	}
+
+	postedAt := line.CreatedAt.In(s.statement.From.Location())
+	reference := strconv.FormatUint(uint64(line.ID), 10)
+	entry := camt053Entry{
+		Reference:            reference,
+		Amount:               camt053Amount{Currency: camt053Currency, Value: line.Nominal.String()},
+		CreditDebitIndicator: indicator,
+		ReversalIndicator:    line.ReversalOfID != nil,
+		Status:               "BOOK",
+		BookingDateTime:      postedAt.Format(time.RFC3339),
+		ValueDate:            postedAt.Format(time.DateOnly),
+		ServicerReference:    reference,
+		Domain:               codes[0],
+		Family:               codes[1],
+		SubFamily:            codes[2],
+		AdditionalInfo:       camt053Truncate(line.Description, camt053MaxEntryInfo),
+	}
+	if err := s.element("Ntry", entry); err != nil {
+		return err
+	}
+	return s.xml.Flush()
+}
+
+func (s *statementCamt053) Close() error {
+	for _, name := range []string{"Stmt", "BkToCstmrStmt", "Document"} {
+		if err := s.xml.EncodeToken(xml.EndElement{Name: xml.Name{Local: name}}); err != nil {
+			return err
+		}
+	}
+	return s.xml.Flush()
+}
+
+func (s *statementCamt053) element(name string, value any) error {
+	return s.xml.EncodeElement(value, xml.StartElement{Name: xml.Name{Local: name}})
+}
+
+func camt053NewBalance(code string, balance model.Money, date time.Time) camt053Balance {
+	return camt053Balance{
+		Code:                 code,
+		Amount:               camt053Amount{Currency: camt053Currency, Value: camt053Abs(balance).String()},
+		CreditDebitIndicator: camt053Indicator(balance),
+		Date:                 date.Format(time.DateOnly),
+	}
+}
+
+// camt053Indicator returns whether an amount is in the account holder's
+// favour (CRDT) or not (DBIT); amounts themselves are never negative.
+func camt053Indicator(amount model.Money) string {
+	if amount.IsNegative() {
+		return "DBIT"
+	}
+	return "CRDT"
+}
+
+func camt053Abs(amount model.Money) model.Money {
+	if amount.IsNegative() {
+		return amount.Neg()
+	}
+	return amount
+}
+
+// camt053Truncate shortens text to at most max characters.
+func camt053Truncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max])
+}
@@ -0,0 +1,280 @@
+package response
+
+import (
+	"account-service/src/model"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// StatementLinesPerPage is the number of lines on each page of a PDF
+// statement. Every page ends with the totals of its own lines.
+const StatementLinesPerPage = 40
+
+// Layout of an A4 page, in points from the bottom left corner.
+const (
+	pdfPageWidth  = 595
+	pdfPageHeight = 842
+	pdfMargin     = 40
+	pdfFontSize   = 9
+	pdfLineHeight = 14
+	pdfFirstRow   = 700
+
+	pdfDateX        = pdfMargin
+	pdfDescriptionX = 140
+	pdfDebitRight   = 400
+	pdfCreditRight  = 475
+	pdfBalanceRight = pdfPageWidth - pdfMargin
+
+	pdfMaxDescription = 45 // Characters of a description that fit its column
+)
+
+// statementPDF writes a statement as a PDF of StatementLinesPerPage lines a
+// page. The opening balance heads the first page, each page ends with its
+// debit and credit totals, and the last one with the statement totals and
+// the closing balance.
+type statementPDF struct {
+	pdf       *pdfWriter
+	statement *model.Statement
+
+	page        int
+	pageLines   int
+	pageDebits  model.Money
+	pageCredits model.Money
+}
+
+func newStatementPDF(w io.Writer, statement *model.Statement) StatementWriter {
+	return &statementPDF{pdf: newPDFWriter(w), statement: statement}
+}
+
+func (s *statementPDF) WriteHeader() error {
+	if err := s.pdf.begin(); err != nil {
+		return err
+	}
+	s.startPage()
+	s.row(s.statement.From.Format(time.DateOnly), "Opening balance", "", "", s.statement.OpeningBalance.String(), true)
+	return nil
+}
+
+func (s *statementPDF) WriteLine(line *model.StatementLine) error {
+	if s.pageLines == StatementLinesPerPage {
+		if err := s.endPage(); err != nil {
+			return err
+		}
+		s.startPage()
+	}
+
+	debit, credit := "", ""
+	if line.Type == "debit" {
+		debit = line.Nominal.String()
+	} else {
+		credit = line.Nominal.String()
+	}
+	s.row(statementTime(s.statement, line.CreatedAt), pdfTruncate(line.Description, pdfMaxDescription), debit, credit, line.BalanceAfter.String(), false)
+	s.pageLines++
+	s.pageDebits = s.pageDebits.Add(line.Debit())
+	s.pageCredits = s.pageCredits.Add(line.Credit())
+	return nil
+}
+
+func (s *statementPDF) Close() error {
+	s.totals()
+	lastDay := s.statement.LastDay().Format(time.DateOnly)
+	s.row("", "Total", s.statement.DebitTotal.String(), s.statement.CreditTotal.String(), "", true)
+	s.row(lastDay, "Closing balance", "", "", s.statement.ClosingBalance.String(), true)
+	if err := s.pdf.endPage(); err != nil {
+		return err
+	}
+	return s.pdf.end()
+}
+
+func (s *statementPDF) startPage() {
+	s.page++
+	s.pageLines = 0
+	s.pageDebits = model.NewMoney(0)
+	s.pageCredits = model.NewMoney(0)
+
+	statement := s.statement
+	s.pdf.beginPage()
+	s.pdf.text(pdfMargin, pdfPageHeight-60, 14, true, "Account Statement")
+	s.pdf.text(pdfMargin, pdfPageHeight-85, pdfFontSize, false, fmt.Sprintf("Account: %s", statement.AccountNumber))
+	s.pdf.text(pdfMargin, pdfPageHeight-99, pdfFontSize, false, fmt.Sprintf("Name: %s", statement.FullName))
+	s.pdf.text(pdfMargin, pdfPageHeight-113, pdfFontSize, false, fmt.Sprintf("Period: %s to %s",
+		statement.From.Format(time.DateOnly), statement.LastDay().Format(time.DateOnly)))
+	s.pdf.textRight(pdfBalanceRight, pdfPageHeight-85, pdfFontSize, false, fmt.Sprintf("Page %d", s.page))
+	s.pdf.textRight(pdfBalanceRight, pdfPageHeight-99, pdfFontSize, false, "Generated "+statement.GeneratedAt.Format("2006-01-02 15:04"))
+
+	s.pdf.y = pdfFirstRow
+	s.row("Date", "Description", "Debit", "Credit", "Balance", true)
+}
+
+// endPage closes a full page with its totals.
+func (s *statementPDF) endPage() error {
+	s.totals()
+	return s.pdf.endPage()
+}
+
+func (s *statementPDF) totals() {
+	s.row("", "Page total", s.pageDebits.String(), s.pageCredits.String(), "", true)
+}
+
+func (s *statementPDF) row(date, description, debit, credit, balance string, bold bool) {
+	y := s.pdf.y
+	s.pdf.text(pdfDateX, y, pdfFontSize, bold, date)
+	s.pdf.text(pdfDescriptionX, y, pdfFontSize, bold, description)
+	s.pdf.textRight(pdfDebitRight, y, pdfFontSize, bold, debit)
+	s.pdf.textRight(pdfCreditRight, y, pdfFontSize, bold, credit)
+	s.pdf.textRight(pdfBalanceRight, y, pdfFontSize, bold, balance)
+	s.pdf.y -= pdfLineHeight
+}
+
+func pdfTruncate(text string, max int) string {
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	return string([]rune(text)[:max-3]) + "..."
+}
+
+// pdfWriter writes a text-only PDF page by page. Only the page being drawn
+// and the offsets of the objects written are kept in memory, which is what
+// lets a statement of any length be streamed.
+type pdfWriter struct {
+	w       io.Writer
+	written int64
+	err     error
+
+	offsets []int64 // Offset of each object, by object number - 1
+	pages   []int   // Object numbers of the pages
+	content bytes.Buffer
+	y       int // Baseline of the next row
+}
+
+// Objects written before the pages.
+const (
+	pdfCatalogObject  = 1
+	pdfPagesObject    = 2 // Written last, once every page is known
+	pdfFontObject     = 3
+	pdfBoldFontObject = 4
+)
+
+func newPDFWriter(w io.Writer) *pdfWriter {
+	return &pdfWriter{w: w, offsets: make([]int64, pdfBoldFontObject)}
+}
+
+func (p *pdfWriter) begin() error {
+	p.write("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	p.object(pdfCatalogObject, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pdfPagesObject))
+	p.object(pdfFontObject, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	p.object(pdfBoldFontObject, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	return p.err
+}
+
+func (p *pdfWriter) beginPage() {
+	p.content.Reset()
+}
+
+func (p *pdfWriter) endPage() error {
+	contentObject := p.newObject()
+	p.object(contentObject, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", p.content.Len(), p.content.String()))
+
+	pageObject := p.newObject()
+	p.object(pageObject, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>",
+		pdfPagesObject, pdfPageWidth, pdfPageHeight, pdfFontObject, pdfBoldFontObject, contentObject))
+	p.pages = append(p.pages, pageObject)
+	return p.err
+}
+
+// end writes the page tree, the cross-reference table and the trailer.
+func (p *pdfWriter) end() error {
+	kids := make([]string, len(p.pages))
+	for i, page := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", page)
+	}
+	p.object(pdfPagesObject, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages)))
+
+	xref := p.written
+	p.write(fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", len(p.offsets)+1))
+	for _, offset := range p.offsets {
+		p.write(fmt.Sprintf("%010d 00000 n \n", offset))
+	}
+	p.write(fmt.Sprintf("trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(p.offsets)+1, pdfCatalogObject, xref))
+	return p.err
+}
+
+func (p *pdfWriter) newObject() int {
+	p.offsets = append(p.offsets, 0)
+	return len(p.offsets)
+}
+
+func (p *pdfWriter) object(number int, body string) {
+	p.offsets[number-1] = p.written
+	p.write(fmt.Sprintf("%d 0 obj\n%s\nendobj\n", number, body))
+}
+
+func (p *pdfWriter) write(s string) {
+	if p.err != nil {
+		return
+	}
+	n, err := io.WriteString(p.w, s)
+	p.written += int64(n)
+	p.err = err
+}
+
+// text draws s with its baseline starting at (x, y).
+func (p *pdfWriter) text(x, y, size int, bold bool, s string) {
+	if s == "" {
+		return
+	}
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(&p.content, "BT /%s %d Tf %d %d Td (%s) Tj ET\n", font, size, x, y, pdfEscape(s))
+}
+
+// textRight draws s with its baseline ending at (x, y).
+func (p *pdfWriter) textRight(x, y, size int, bold bool, s string) {
+	p.text(x-pdfTextWidth(s, size), y, size, bold, s)
+}
+
+// pdfTextWidth approximates the width of s in Helvetica, exact for the digits
+// and separators amounts are made of.
+func pdfTextWidth(s string, size int) int {
+	width := 0
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			width += 556
+		case r == '.' || r == ',' || r == ' ':
+			width += 278
+		case r == '-':
+			width += 333
+		default:
+			width += 556
+		}
+	}
+	return width * size / 1000
+}
+
+// pdfEscape encodes s for a PDF string in WinAnsiEncoding, replacing the
+// characters that encoding lacks.
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
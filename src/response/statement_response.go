@@ -0,0 +1,115 @@
+package response
+
+import (
+	"account-service/src/model"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StatementWriter renders a statement one line at a time, so a statement of
+// any length can be written to a stream. WriteHeader must be called first and
+// Close last; Close writes the totals and closing balance but does not close
+// the underlying writer.
+type StatementWriter interface {
+	WriteHeader() error
+	WriteLine(line *model.StatementLine) error
+	Close() error
+}
+
+type statementFormat struct {
+	contentType string
+	extension   string
+	newWriter   func(w io.Writer, statement *model.Statement) StatementWriter
+}
+
+var statementFormats = map[string]statementFormat{
+	model.StatementFormatCSV:     {"text/csv; charset=utf-8", "csv", newStatementCSV},
+	model.StatementFormatPDF:     {"application/pdf", "pdf", newStatementPDF},
+	model.StatementFormatCamt053: {"application/xml; charset=utf-8", "xml", newStatementCamt053},
+}
+
+// NewStatementWriter returns the writer of statement in format, one of the
+// model.StatementFormat constants.
+func NewStatementWriter(format string, w io.Writer, statement *model.Statement) (StatementWriter, error) {
+	f, ok := statementFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown statement format %q", format)
+	}
+	return f.newWriter(w, statement), nil
+}
+
+// StatementContentType returns the Content-Type of a statement in format.
+func StatementContentType(format string) string {
+	return statementFormats[format].contentType
+}
+
+// StatementFileName returns the name a statement in format is downloaded as.
+func StatementFileName(statement *model.Statement, format string) string {
+	return fmt.Sprintf("statement-%s-%s-%s.%s", statement.AccountNumber,
+		statement.From.Format("20060102"), statement.LastDay().Format("20060102"), statementFormats[format].extension)
+}
+
+// statementTime formats when a line was posted, in the time zone of the
+// business day.
+func statementTime(statement *model.Statement, t time.Time) string {
+	return t.In(statement.From.Location()).Format("2006-01-02 15:04:05")
+}
+
+type statementCSV struct {
+	csv       *csv.Writer
+	statement *model.Statement
+}
+
+func newStatementCSV(w io.Writer, statement *model.Statement) StatementWriter {
+	return &statementCSV{csv: csv.NewWriter(w), statement: statement}
+}
+
+func (s *statementCSV) WriteHeader() error {
+	if err := s.csv.Write([]string{"date", "id", "kind", "description", "debit", "credit", "balance"}); err != nil {
+		return err
+	}
+	return s.csv.Write([]string{s.statement.From.Format(time.DateOnly), "", "", "Opening balance", "", "", s.statement.OpeningBalance.String()})
+}
+
+func (s *statementCSV) WriteLine(line *model.StatementLine) error {
+	debit, credit := "", ""
+	if line.Type == "debit" {
+		debit = line.Nominal.String()
+	} else {
+		credit = line.Nominal.String()
+	}
+	return s.csv.Write([]string{
+		statementTime(s.statement, line.CreatedAt),
+		strconv.FormatUint(uint64(line.ID), 10),
+		line.Kind(),
+		csvText(line.Description),
+		debit,
+		credit,
+		line.BalanceAfter.String(),
+	})
+}
+
+func (s *statementCSV) Close() error {
+	lastDay := s.statement.LastDay().Format(time.DateOnly)
+	if err := s.csv.Write([]string{lastDay, "", "", "Total", s.statement.DebitTotal.String(), s.statement.CreditTotal.String(), ""}); err != nil {
+		return err
+	}
+	if err := s.csv.Write([]string{lastDay, "", "", "Closing balance", "", "", s.statement.ClosingBalance.String()}); err != nil {
+		return err
+	}
+	s.csv.Flush()
+	return s.csv.Error()
+}
+
+// csvText keeps free text, such as a reversal reason, from being read as a
+// formula by spreadsheet software.
+func csvText(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
@@ -2,17 +2,27 @@ package router
 
 import (
 	"account-service/src/controller"
+	"account-service/src/middleware"
 	"account-service/src/service"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 )
 
-func AccountRoutes(v1 fiber.Router, a service.AccountServices, v *validator.Validate) {
-	accountController := controller.NewAccountController(a, v)
+func AccountRoutes(v1 fiber.Router, a service.AccountServices, i service.IdempotencyService, j *middleware.JWT, v *validator.Validate) {
+	accountController := controller.NewAccountController(a, j, v)
+	idempotency := middleware.Idempotency(i)
 
-	v1.Post("/tabung", accountController.Deposit)
-	v1.Post("/tarik", accountController.Withdrawal)
+	// Customers are limited to their own account by the controller.
+	anyRole := middleware.Auth(j, middleware.RoleCustomer, middleware.RoleTeller, middleware.RoleAdmin)
+	staff := middleware.Auth(j, middleware.RoleTeller, middleware.RoleAdmin)
+
+	v1.Post("/tabung", anyRole, idempotency, accountController.Deposit)
+	v1.Post("/tarik", anyRole, idempotency, accountController.Withdrawal)
+	v1.Post("/transfer", anyRole, idempotency, accountController.Transfer)
+	v1.Post("/transaksi/:id/reversal", staff, idempotency, accountController.Reversal)
 	v1.Post("/daftar", accountController.Register)
-	v1.Get("/saldo/:accountNumber", accountController.GetBalance)
+	v1.Get("/saldo/:accountNumber", anyRole, accountController.GetBalance)
+	v1.Get("/mutasi", anyRole, accountController.GetMutations)
+	v1.Put("/pin", anyRole, accountController.ResetPIN)
 }
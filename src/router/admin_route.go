@@ -0,0 +1,43 @@
+package router
+
+import (
+	"account-service/src/controller"
+	"account-service/src/middleware"
+	"account-service/src/service"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminRoutes registers the back-office routes, all of which require an
+// admin token.
+func AdminRoutes(v1 fiber.Router, a service.AccountServices, g service.GeneralLedgerService, i service.InterestService, f service.FeeService, w service.WebhookService, j *middleware.JWT, v *validator.Validate) {
+	accountController := controller.NewAccountController(a, j, v)
+	authController := controller.NewAuthController(a, j, v)
+	generalLedgerController := controller.NewGeneralLedgerController(g)
+	interestController := controller.NewInterestController(i)
+	feeController := controller.NewFeeController(f)
+	webhookController := controller.NewWebhookController(w)
+
+	admin := v1.Group("/admin", middleware.Auth(j, middleware.RoleAdmin))
+	admin.Post("/token", authController.IssueToken)
+	admin.Get("/ledger/:accountNumber/verify", accountController.VerifyLedger)
+	admin.Post("/rekening/:accountNumber/freeze", accountController.Freeze)
+	admin.Post("/rekening/:accountNumber/unfreeze", accountController.Unfreeze)
+	admin.Post("/rekening/:accountNumber/close", accountController.Close)
+	admin.Get("/rekening/:accountNumber/limit", accountController.GetWithdrawalLimits)
+	admin.Put("/rekening/:accountNumber/limit", accountController.SetWithdrawalLimits)
+	admin.Get("/gl/trial-balance", generalLedgerController.TrialBalance)
+	admin.Get("/gl/reconcile", generalLedgerController.Reconcile)
+	admin.Put("/produk/:code", interestController.SetProductRate)
+	admin.Get("/produk/:code/biaya", feeController.ListFeeSchedules)
+	admin.Post("/produk/:code/biaya", feeController.CreateFeeSchedule)
+	admin.Get("/webhooks", webhookController.ListSubscriptions)
+	admin.Post("/webhooks", webhookController.CreateSubscription)
+	admin.Get("/webhooks/deliveries/:id", webhookController.GetDelivery)
+	admin.Post("/webhooks/deliveries/:id/redeliver", webhookController.Redeliver)
+	admin.Put("/webhooks/:id", webhookController.UpdateSubscription)
+	admin.Delete("/webhooks/:id", webhookController.DeleteSubscription)
+	admin.Post("/webhooks/:id/rotate-secret", webhookController.RotateSecret)
+	admin.Get("/webhooks/:id/deliveries", webhookController.ListDeliveries)
+}
@@ -0,0 +1,18 @@
+package router
+
+import (
+	"account-service/src/controller"
+	"account-service/src/middleware"
+	"account-service/src/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func InterestRoutes(v1 fiber.Router, i service.InterestService, j *middleware.JWT) {
+	interestController := controller.NewInterestController(i)
+
+	// Customers are limited to their own account by the controller.
+	anyRole := middleware.Auth(j, middleware.RoleCustomer, middleware.RoleTeller, middleware.RoleAdmin)
+
+	v1.Get("/bunga/:accountNumber", anyRole, interestController.PendingInterest)
+}
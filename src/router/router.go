@@ -2,6 +2,9 @@ package router
 
 import (
 	"account-service/src/config"
+	"account-service/src/metrics"
+	"account-service/src/middleware"
+	"account-service/src/repository"
 	"account-service/src/service"
 	"account-service/src/utils"
 
@@ -9,19 +12,35 @@ import (
 	"gorm.io/gorm"
 )
 
-func Routes(app *fiber.App, db *gorm.DB) {
+func Routes(app *fiber.App, db *gorm.DB, cfg *config.Config) {
 	validate := utils.Validator()
+	ledger := service.NewLedger()
+
+	store := repository.NewGorm(db, cfg.PIIKeyring)
 
 	healthCheckService := service.NewHealthCheckService(db)
-	accountService := service.NewAccountService(db, validate)
+	accountService := service.NewAccountService(store, ledger, validate, cfg.WithdrawalLimits, cfg.PINPolicy, cfg.BusinessLocation)
+	accountService = service.NewAccountTracing(service.NewAccountMetrics(accountService))
+	idempotencyService := service.NewIdempotencyService(store, cfg.IdempotencyKeyTTL)
+	generalLedgerService := service.NewGeneralLedgerService(store, validate, cfg.BusinessLocation)
+	interestService := service.NewInterestService(store, ledger, validate, cfg.BusinessLocation)
+	feeService := service.NewFeeService(store, ledger, validate, cfg.BusinessLocation)
+	statementService := service.NewStatementService(store, ledger, validate, cfg.BusinessLocation)
+	webhookService := service.NewWebhookService(store, validate, cfg.WebhookPolicy)
+	jwt := middleware.NewJWT(cfg.JWTSecret, cfg.JWTTTL)
+
+	app.Get("/metrics", middleware.Auth(jwt, middleware.RoleAdmin), metrics.Handler(metrics.Dir(app)))
 
 	v1 := app.Group("/v1")
 
 	HealthCheckRoutes(v1, healthCheckService)
-	AccountRoutes(v1, accountService, validate)
+	AccountRoutes(v1, accountService, idempotencyService, jwt, validate)
+	InterestRoutes(v1, interestService, jwt)
+	StatementRoutes(v1, statementService, jwt)
+	AdminRoutes(v1, accountService, generalLedgerService, interestService, feeService, webhookService, jwt, validate)
 	// add another routes here...
 
-	if !config.IsProd {
+	if !cfg.IsProd {
 		DocsRoutes(v1)
 	}
 }
@@ -0,0 +1,18 @@
+package router
+
+import (
+	"account-service/src/controller"
+	"account-service/src/middleware"
+	"account-service/src/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func StatementRoutes(v1 fiber.Router, s service.StatementService, j *middleware.JWT) {
+	statementController := controller.NewStatementController(s)
+
+	// Customers are limited to their own account by the controller.
+	anyRole := middleware.Auth(j, middleware.RoleCustomer, middleware.RoleTeller, middleware.RoleAdmin)
+
+	v1.Get("/rekening/:accountNumber/statement", anyRole, statementController.Statement)
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"account-service/src/metrics"
+	"account-service/src/model"
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// accountMetrics counts the deposits and withdrawals of the account service it
+// wraps by outcome, with the amounts they moved.
+type accountMetrics struct {
+	AccountServices
+}
+
+func NewAccountMetrics(accountService AccountServices) AccountServices {
+	return accountMetrics{accountService}
+}
+
+func (m accountMetrics) Deposit(c context.Context, req *model.DepositRequest) *fiber.Error {
+	err := m.AccountServices.Deposit(c, req)
+	metrics.ObserveDeposit(transactionOutcome(err), req.Nominal.Float64())
+	return err
+}
+
+func (m accountMetrics) Withdraw(c context.Context, req *model.Withdrawal) *fiber.Error {
+	err := m.AccountServices.Withdraw(c, req)
+	metrics.ObserveWithdrawal(transactionOutcome(err), req.Nominal.Float64())
+	return err
+}
+
+func transactionOutcome(err *fiber.Error) string {
+	switch {
+	case err == nil:
+		return metrics.OutcomeSuccess
+	case err.Message == ErrInsufficientBalance.Error():
+		return metrics.OutcomeInsufficientBalance
+	case err.Message == ErrAccountNotFound.Error():
+		return metrics.OutcomeNotFound
+	case err.Code < fiber.StatusInternalServerError:
+		return metrics.OutcomeRejected
+	}
+	return metrics.OutcomeError
+}
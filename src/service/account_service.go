@@ -2,14 +2,19 @@ package service
 
 import (
 	"account-service/src/model"
+	"account-service/src/repository"
 	"account-service/src/utils"
 	"context"
 	"errors"
+	"fmt"
+	"sort"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
-	"gorm.io/gorm"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type AccountServices interface {
@@ -17,19 +22,37 @@ type AccountServices interface {
 	Deposit(c context.Context, req *model.DepositRequest) *fiber.Error
 	Withdraw(c context.Context, req *model.Withdrawal) *fiber.Error
 	GetBalance(c context.Context, id string) (*model.Account, *fiber.Error)
+	GetMutations(c context.Context, req *model.Mutation) ([]model.CashActivity, int64, *fiber.Error)
+	Transfer(c context.Context, req *model.TransferRequest) (*model.TransferResponse, *fiber.Error)
+	VerifyLedger(c context.Context, accountNumber string) (*model.LedgerVerification, *fiber.Error)
+	Reverse(c context.Context, activityID uint, req *model.ReversalRequest) (*model.ReversalResponse, *fiber.Error)
+	Freeze(c context.Context, accountNumber string, req *model.AccountStatusRequest) (*model.Account, *fiber.Error)
+	Unfreeze(c context.Context, accountNumber string, req *model.AccountStatusRequest) (*model.Account, *fiber.Error)
+	Close(c context.Context, accountNumber string, req *model.AccountStatusRequest) (*model.Account, *fiber.Error)
+	GetWithdrawalLimits(c context.Context, accountNumber string) (*model.WithdrawalLimits, *fiber.Error)
+	SetWithdrawalLimits(c context.Context, accountNumber string, req *model.WithdrawalLimitRequest) (*model.WithdrawalLimits, *fiber.Error)
+	ResetPIN(c context.Context, req *model.PINResetRequest) *fiber.Error
 }
 
 type AccountService struct {
 	Log      *logrus.Logger
-	DB       *gorm.DB
+	Store    repository.UnitOfWork
+	Ledger   *Ledger
 	Validate *validator.Validate
+	Limits   model.WithdrawalLimits // Global defaults, overridable per account
+	PIN      model.PINPolicy
+	Location *time.Location // Time zone of the business day
 }
 
-func NewAccountService(db *gorm.DB, validate *validator.Validate) AccountServices {
+func NewAccountService(store repository.UnitOfWork, ledger *Ledger, validate *validator.Validate, limits model.WithdrawalLimits, pin model.PINPolicy, location *time.Location) AccountServices {
 	return &AccountService{
 		Log:      utils.Log,
-		DB:       db,
+		Store:    store,
+		Ledger:   ledger,
 		Validate: validate,
+		Limits:   limits,
+		PIN:      pin,
+		Location: location,
 	}
 }
 
@@ -38,6 +61,40 @@ var (
 	ErrDuplicatePhoneNumber = errors.New("phone number already registered")
 	ErrAccountNotFound      = errors.New("account not found")
 	ErrInsufficientBalance  = errors.New("insufficient balance")
+	ErrInvalidMutationRange = errors.New("start date must not be after end date")
+	ErrTransactionNotFound  = errors.New("transaction not found")
+	ErrAlreadyReversed      = errors.New("transaction has already been reversed")
+	ErrReversalOfReversal   = errors.New("a reversal cannot be reversed")
+	ErrTransferReversal     = errors.New("transfer legs cannot be reversed individually")
+	ErrAccountFrozen        = errors.New("account is frozen")
+	ErrAccountDormant       = errors.New("account is dormant")
+	ErrAccountClosed        = errors.New("account is closed")
+	ErrInvalidStatusChange  = errors.New("account status cannot be changed this way")
+	ErrNonZeroBalance       = errors.New("account balance must be zero to close the account")
+
+	ErrPerTransactionLimit = errors.New("withdrawal exceeds the per-transaction limit")
+	ErrDailyTotalLimit     = errors.New("withdrawal exceeds the daily total limit")
+	ErrDailyCountLimit     = errors.New("withdrawal exceeds the daily count limit")
+
+	ErrPINNotSet    = errors.New("transaction PIN has not been set")
+	ErrIncorrectPIN = errors.New("incorrect PIN")
+	ErrPINLocked    = errors.New("transaction PIN is locked after too many wrong attempts")
+
+	ErrProductNotFound = errors.New("product not found")
+)
+
+// Reasons reported by VerifyLedger for the first broken link of a chain.
+const (
+	ledgerBrokenReference    = "reference_id does not point to the previous activity"
+	ledgerBrokenHash         = "hash mismatch"
+	ledgerBrokenContinuity   = "balance_before does not match the previous balance_after"
+	ledgerBrokenArithmetic   = "balance_after does not match balance_before and nominal"
+	ledgerBrokenFinalBalance = "account balance does not match the last balance_after"
+)
+
+const (
+	defaultMutationPage  = 1
+	defaultMutationLimit = 10
 )
 
 func (accountService *AccountService) CreateAccount(c context.Context, req *model.CreateAccount) (*model.Account, *fiber.Error) {
@@ -46,46 +103,79 @@ func (accountService *AccountService) CreateAccount(c context.Context, req *mode
 		return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	var existingAccountByID model.Account
-	if err := accountService.DB.WithContext(c).Where("id_number = ?", req.IDNumber).First(&existingAccountByID).Error; err == nil {
+	repos := accountService.Store.Repositories(c)
+
+	if _, err := repos.Accounts().FindByIDNumber(req.IDNumber); err == nil {
 		return nil, fiber.NewError(fiber.StatusConflict, ErrDuplicateIDNumber.Error())
-	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-		accountService.Log.Errorf("Error checking for duplicate ID number: %+v", err)
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		accountService.Log.WithContext(c).Errorf("Error checking for duplicate ID number: %+v", err)
 		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
 	}
 
-	var existingAccountByPhone model.Account
-	if err := accountService.DB.WithContext(c).Where("phone_number = ?", req.PhoneNumber).First(&existingAccountByPhone).Error; err == nil {
+	if _, err := repos.Accounts().FindByPhoneNumber(req.PhoneNumber); err == nil {
 		return nil, fiber.NewError(fiber.StatusConflict, ErrDuplicatePhoneNumber.Error())
-	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-		accountService.Log.Errorf("Error checking for duplicate phone number: %+v", err)
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		accountService.Log.WithContext(c).Errorf("Error checking for duplicate phone number: %+v", err)
 		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
 	}
 
 	accountNumber := utils.GenerateAccountNumber()
 	for {
-		var tempAccount model.Account
-		err := accountService.DB.WithContext(c).Where("account_number = ?", accountNumber).First(&tempAccount).Error
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+		_, err := repos.Accounts().FindByNumber(accountNumber)
+		if errors.Is(err, repository.ErrNotFound) {
 			break
 		}
 		if err != nil {
-			accountService.Log.Errorf("Error checking for unique account number: %+v", err)
+			accountService.Log.WithContext(c).Errorf("Error checking for unique account number: %+v", err)
 			return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
 		}
 		accountNumber = utils.GenerateAccountNumber()
 	}
 
+	productCode := req.ProductCode
+	if productCode == "" {
+		productCode = model.DefaultProductCode
+	}
+	product, err := repos.Products().Find(productCode)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, ErrProductNotFound.Error())
+		}
+		accountService.Log.WithContext(c).Errorf("Error checking the product: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	pinHash, fiberErr := accountService.hashPIN(c, req.PIN)
+	if fiberErr != nil {
+		return nil, fiberErr
+	}
+
 	newAccount := model.Account{
 		AccountNumber: accountNumber,
 		FullName:      req.FullName,
 		IDNumber:      req.IDNumber,
 		PhoneNumber:   req.PhoneNumber,
+		Status:        model.AccountStatusActive,
+		ProductCode:   product.Code,
+		PINHash:       pinHash,
 	}
 
-	if err := accountService.DB.WithContext(c).Create(&newAccount).Error; err != nil {
-		accountService.Log.Errorf("failed to create account: %+v", err)
-		return nil, fiber.NewError(fiber.StatusInternalServerError, "failed to create account")
+	if err := accountService.Store.Transaction(c, func(tx repository.Repositories) error {
+		if err := tx.Accounts().Create(&newAccount); err != nil {
+			accountService.Log.WithContext(c).Errorf("failed to create account: %+v", err)
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to create account")
+		}
+		if err := accountService.Ledger.recordEvent(tx, newAccount.AccountNumber, model.EventAccountCreated, model.AccountCreatedData{
+			AccountNumber: newAccount.AccountNumber,
+			ProductCode:   newAccount.ProductCode,
+			Status:        newAccount.Status,
+			Balance:       newAccount.Balance,
+		}); err != nil {
+			return err
+		}
+		return nil
+	}); err != nil {
+		return nil, accountService.Ledger.transactionError(c, err)
 	}
 
 	return &newAccount, nil
@@ -97,137 +187,723 @@ func (accountService *AccountService) Deposit(c context.Context, req *model.Depo
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	var account model.Account
-	if err := accountService.DB.Where("account_number = ?", req.AccountNumber).First(&account).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return fiber.NewError(fiber.StatusNotFound, ErrAccountNotFound.Error())
+	err := accountService.Store.Transaction(c, func(tx repository.Repositories) error {
+		account, err := accountService.Ledger.lockAccount(tx, req.AccountNumber)
+		if err != nil {
+			return err
+		}
+		if err := accountStatusError(account); err != nil {
+			return err
+		}
+
+		activity := model.CashActivity{Type: "credit", Nominal: req.Nominal}
+		if err := accountService.Ledger.postActivity(tx, account, &activity, model.EventFundsDeposited); err != nil {
+			return err
 		}
-		accountService.Log.Errorf("Failed to get account: %+v", err)
-		return fiber.NewError(fiber.StatusInternalServerError, "Database error")
+		if err := accountService.Ledger.postJournal(tx, model.NewActivityJournal(&activity, model.GLCashCode, fmt.Sprintf("Deposit to %s", account.AccountNumber))); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return accountService.Ledger.transactionError(c, err)
+	}
+
+	return nil
+}
+
+func (accountService *AccountService) Withdraw(c context.Context, req *model.Withdrawal) *fiber.Error {
+
+	if err := accountService.Validate.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var pinErr *fiber.Error
+	err := accountService.Store.Transaction(c, func(tx repository.Repositories) error {
+		// The row lock is held until commit, so the balance checked here is
+		// the balance the debit is applied to.
+		account, err := accountService.Ledger.lockAccount(tx, req.AccountNumber)
+		if err != nil {
+			return err
+		}
+		if err := accountStatusError(account); err != nil {
+			return err
+		}
+
+		if pinErr = accountService.verifyPIN(tx, account, req.PIN); pinErr != nil {
+			// Commit, so a wrong attempt stays counted.
+			return nil
+		}
+
+		schedule, fee, err := accountService.withdrawalFee(tx, account)
+		if err != nil {
+			return err
+		}
+		if account.Balance.LessThan(req.Nominal.Add(fee)) {
+			return fiber.NewError(fiber.StatusBadRequest, ErrInsufficientBalance.Error())
+		}
+
+		if err := accountService.checkWithdrawalLimits(tx, account, req.Nominal); err != nil {
+			return err
+		}
+
+		activity := model.CashActivity{Type: "debit", Nominal: req.Nominal}
+		if err := accountService.Ledger.postActivity(tx, account, &activity, model.EventFundsWithdrawn); err != nil {
+			return err
+		}
+		if err := accountService.Ledger.postJournal(tx, model.NewActivityJournal(&activity, model.GLCashCode, fmt.Sprintf("Withdrawal from %s", account.AccountNumber))); err != nil {
+			return err
+		}
+		if fee.IsZero() {
+			return nil
+		}
+		feeActivity := model.CashActivity{Description: "Withdrawal fee", FeeOfID: &activity.ID}
+		if err := accountService.Ledger.chargeFee(tx, account, schedule, fee, &feeActivity); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return accountService.Ledger.transactionError(c, err)
+	}
+	if pinErr != nil {
+		return pinErr
+	}
+
+	return nil
+}
+
+func (accountService *AccountService) GetBalance(c context.Context, accountNumber string) (*model.Account, *fiber.Error) {
+	account, err := accountService.Ledger.findAccount(accountService.Store.Repositories(c), accountNumber)
+	if err != nil {
+		return nil, err
+	}
+	if account.Status == model.AccountStatusClosed {
+		return nil, fiber.NewError(fiber.StatusGone, ErrAccountClosed.Error())
+	}
+	return account, nil
+}
+
+func (accountService *AccountService) GetMutations(c context.Context, req *model.Mutation) ([]model.CashActivity, int64, *fiber.Error) {
+
+	if err := accountService.Validate.Struct(req); err != nil {
+		return nil, 0, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if req.Page == 0 {
+		req.Page = defaultMutationPage
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultMutationLimit
+	}
+
+	start, end, err := mutationPeriod(req, time.Now().In(accountService.Location))
+	if err != nil {
+		return nil, 0, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	// The history of a closed account stays available.
+	account, fiberErr := accountService.Ledger.findAccount(accountService.Store.Repositories(c), req.AccountNumber)
+	if fiberErr != nil {
+		return nil, 0, fiberErr
+	}
+
+	activities, total, err := accountService.Store.Repositories(c).CashActivities().
+		List(account.ID, start, end, (req.Page-1)*req.Limit, req.Limit)
+	if err != nil {
+		accountService.Log.WithContext(c).Errorf("Failed to get cash activities: %+v", err)
+		return nil, 0, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	return activities, total, nil
+}
+
+// mutationPeriod resolves the half-open [start, end) interval requested by a
+// mutation query, either a calendar month or an inclusive date range.
+func mutationPeriod(req *model.Mutation, now time.Time) (time.Time, time.Time, error) {
+	if req.From != "" {
+		start, err := time.ParseInLocation(time.DateOnly, req.From, now.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		end, err := time.ParseInLocation(time.DateOnly, req.To, now.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		if start.After(end) {
+			return time.Time{}, time.Time{}, ErrInvalidMutationRange
+		}
+		return start, end.AddDate(0, 0, 1), nil
+	}
+
+	year := req.Year
+	if year == 0 {
+		year = now.Year()
+	}
+	start := time.Date(year, time.Month(req.Month), 1, 0, 0, 0, 0, now.Location())
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+func (accountService *AccountService) Transfer(c context.Context, req *model.TransferRequest) (*model.TransferResponse, *fiber.Error) {
+
+	if err := accountService.Validate.Struct(req); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	reference := uuid.NewString()
+	result := &model.TransferResponse{Reference: reference}
+
+	var pinErr *fiber.Error
+	err := accountService.Store.Transaction(c, func(tx repository.Repositories) error {
+		// Lock both accounts in account number order, so two opposite
+		// transfers always wait on the same row first and cannot deadlock.
+		accountNumbers := []string{req.FromAccountNumber, req.ToAccountNumber}
+		sort.Strings(accountNumbers)
+
+		locked := make(map[string]*model.Account, len(accountNumbers))
+		for _, accountNumber := range accountNumbers {
+			account, err := accountService.Ledger.lockAccount(tx, accountNumber)
+			if err != nil {
+				return err
+			}
+			if err := accountStatusError(account); err != nil {
+				return err
+			}
+			locked[accountNumber] = account
+		}
+
+		from, to := locked[req.FromAccountNumber], locked[req.ToAccountNumber]
+		if pinErr = accountService.verifyPIN(tx, from, req.PIN); pinErr != nil {
+			// Commit, so a wrong attempt stays counted.
+			return nil
+		}
+
+		if from.Balance.LessThan(req.Nominal) {
+			return fiber.NewError(fiber.StatusBadRequest, ErrInsufficientBalance.Error())
+		}
+
+		if err := accountService.checkWithdrawalLimits(tx, from, req.Nominal); err != nil {
+			return err
+		}
+
+		// Both legs stay within customer deposits, so a transfer posts no
+		// journal to the general ledger.
+		if err := accountService.Ledger.postActivity(tx, from, &model.CashActivity{
+			Type:              "debit",
+			Nominal:           req.Nominal,
+			Description:       fmt.Sprintf("Transfer to %s", to.AccountNumber),
+			TransferReference: &reference,
+		}, model.EventTransferSent); err != nil {
+			return err
+		}
+		if err := accountService.Ledger.postActivity(tx, to, &model.CashActivity{
+			Type:              "credit",
+			Nominal:           req.Nominal,
+			Description:       fmt.Sprintf("Transfer from %s", from.AccountNumber),
+			TransferReference: &reference,
+		}, model.EventTransferReceived); err != nil {
+			return err
+		}
+
+		result.From = model.TransferBalance{AccountNumber: from.AccountNumber, Balance: from.Balance}
+		result.To = model.TransferBalance{AccountNumber: to.AccountNumber, Balance: to.Balance}
+		return nil
+	})
+	if err != nil {
+		return nil, accountService.Ledger.transactionError(c, err)
+	}
+	if pinErr != nil {
+		return nil, pinErr
+	}
+
+	return result, nil
+}
+
+// Reverse undoes a deposit or withdrawal by posting a compensating activity of
+// the opposite type, carrying the reason as its description, and marking the
+// original activity as reversed. Reversing a withdrawal refunds its fee;
+// reversing a fee alone waives it.
+func (accountService *AccountService) Reverse(c context.Context, activityID uint, req *model.ReversalRequest) (*model.ReversalResponse, *fiber.Error) {
+
+	if err := accountService.Validate.Struct(req); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var result *model.ReversalResponse
+
+	err := accountService.Store.Transaction(c, func(tx repository.Repositories) error {
+		original, err := tx.CashActivities().Find(activityID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, ErrTransactionNotFound.Error())
+			}
+			return err
+		}
+		owner, err := tx.Accounts().FindByID(original.AccountID)
+		if err != nil {
+			return err
+		}
+
+		account, fiberErr := accountService.Ledger.lockAccount(tx, owner.AccountNumber)
+		if fiberErr != nil {
+			return fiberErr
+		}
+		if fiberErr := accountStatusError(account); fiberErr != nil {
+			return fiberErr
+		}
+
+		// Read the activity again under the account lock, which every
+		// reversal of the account holds, so a concurrent reversal is seen.
+		original, err = tx.CashActivities().Find(activityID)
+		if err != nil {
+			return err
+		}
+		if original.ReversedAt != nil {
+			return fiber.NewError(fiber.StatusConflict, ErrAlreadyReversed.Error())
+		}
+		if original.ReversalOfID != nil {
+			return fiber.NewError(fiber.StatusUnprocessableEntity, ErrReversalOfReversal.Error())
+		}
+		if original.TransferReference != nil {
+			return fiber.NewError(fiber.StatusUnprocessableEntity, ErrTransferReversal.Error())
+		}
+
+		compensating, fiberErr := accountService.reverseActivity(tx, account, original, req.Reason)
+		if fiberErr != nil {
+			return fiberErr
+		}
+		result = &model.ReversalResponse{
+			TransactionID: original.ID,
+			ReversalID:    compensating.ID,
+			AccountNumber: account.AccountNumber,
+		}
+
+		// A reversed withdrawal refunds its fee.
+		fee, err := tx.CashActivities().FindUnreversedFeeOf(original.ID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		if err == nil {
+			feeCompensating, fiberErr := accountService.reverseActivity(tx, account, fee, req.Reason)
+			if fiberErr != nil {
+				return fiberErr
+			}
+			result.FeeReversalID = &feeCompensating.ID
+		}
+
+		result.Balance = account.Balance
+		return nil
+	})
+	if err != nil {
+		return nil, accountService.Ledger.transactionError(c, err)
 	}
 
-	var latestActivity model.CashActivity
-	err := accountService.DB.Where("account_id = ?", account.ID).Order("created_at desc").First(&latestActivity).Error
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		accountService.DB.Rollback()
-		accountService.Log.Errorf("Failed to get latest cash activity: %+v", err)
-		return fiber.NewError(fiber.StatusInternalServerError, "Database error during getting latest cash activity")
+	return result, nil
+}
+
+// reverseActivity posts the compensating activity of original, of the
+// opposite type and described by reason, with its journal, and marks original
+// as reversed. The account must have been locked by tx.
+func (accountService *AccountService) reverseActivity(tx repository.Repositories, account *model.Account, original *model.CashActivity, reason string) (*model.CashActivity, *fiber.Error) {
+	compensating := model.CashActivity{
+		Type:         "debit",
+		Nominal:      original.Nominal,
+		Description:  reason,
+		ReversalOfID: &original.ID,
 	}
-	var refID *uint
-	if !errors.Is(err, gorm.ErrRecordNotFound) {
-		refID = &latestActivity.ID
+	if original.Type == "debit" {
+		compensating.Type = "credit"
+	}
+	if compensating.Type == "debit" && account.Balance.LessThan(compensating.Nominal) {
+		return nil, fiber.NewError(fiber.StatusBadRequest, ErrInsufficientBalance.Error())
 	}
 
-	newActivity := model.CashActivity{
-		AccountID:     account.ID,
-		ReferenceID:   refID,
-		Type:          "credit",
-		Nominal:       req.Nominal,
-		BalanceBefore: account.Balance,
-		BalanceAfter:  account.Balance + req.Nominal,
+	if fiberErr := accountService.Ledger.postActivity(tx, account, &compensating, model.EventTransactionReversed); fiberErr != nil {
+		return nil, fiberErr
+	}
+	if fiberErr := accountService.Ledger.postReversalJournal(tx, original, &compensating); fiberErr != nil {
+		return nil, fiberErr
 	}
+	if err := tx.CashActivities().MarkReversed(original, compensating.CreatedAt); err != nil {
+		accountService.Log.WithContext(tx.Context()).Errorf("Failed to mark cash activity as reversed: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to record transaction")
+	}
+	return &compensating, nil
+}
+
+// Freeze stops all money movement on an active or dormant account.
+func (accountService *AccountService) Freeze(c context.Context, accountNumber string, req *model.AccountStatusRequest) (*model.Account, *fiber.Error) {
+	return accountService.changeStatus(c, accountNumber, model.AccountStatusFrozen, req)
+}
+
+// Unfreeze returns a frozen or dormant account to active.
+func (accountService *AccountService) Unfreeze(c context.Context, accountNumber string, req *model.AccountStatusRequest) (*model.Account, *fiber.Error) {
+	return accountService.changeStatus(c, accountNumber, model.AccountStatusActive, req)
+}
 
-	tx := accountService.DB.Begin()
-	if err := tx.Create(&newActivity).Error; err != nil {
-		accountService.Log.Errorf("Failed to create cash activity: %+v", err)
-		tx.Rollback()
-		return fiber.NewError(fiber.StatusInternalServerError, "Failed to record transaction")
+// Close permanently closes an account whose balance is zero.
+func (accountService *AccountService) Close(c context.Context, accountNumber string, req *model.AccountStatusRequest) (*model.Account, *fiber.Error) {
+	return accountService.changeStatus(c, accountNumber, model.AccountStatusClosed, req)
+}
+
+// changeStatus moves an account to status if the state machine allows it,
+// recording the transition with its actor and reason.
+func (accountService *AccountService) changeStatus(c context.Context, accountNumber string, status string, req *model.AccountStatusRequest) (*model.Account, *fiber.Error) {
+
+	if err := accountService.Validate.Struct(req); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	account.Balance += req.Nominal
-	if err := tx.Save(&account).Error; err != nil {
-		accountService.Log.Errorf("Failed to update account balance: %+v", err)
-		tx.Rollback()
-		return fiber.NewError(fiber.StatusInternalServerError, "Failed to record transaction")
+	var result *model.Account
+
+	err := accountService.Store.Transaction(c, func(tx repository.Repositories) error {
+		// Locked like any posting, so no money moves while the status changes.
+		account, err := accountService.Ledger.lockAccount(tx, accountNumber)
+		if err != nil {
+			return err
+		}
+
+		if !account.CanTransitionTo(status) {
+			return fiber.NewError(fiber.StatusConflict, ErrInvalidStatusChange.Error())
+		}
+		if status == model.AccountStatusClosed && !account.Balance.IsZero() {
+			return fiber.NewError(fiber.StatusUnprocessableEntity, ErrNonZeroBalance.Error())
+		}
+
+		history := model.AccountStatusHistory{
+			AccountID:  account.ID,
+			FromStatus: account.Status,
+			ToStatus:   status,
+			Actor:      req.Actor,
+			Reason:     req.Reason,
+		}
+		if err := tx.Accounts().CreateStatusHistory(&history); err != nil {
+			accountService.Log.WithContext(c).Errorf("Failed to record account status change: %+v", err)
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to change account status")
+		}
+
+		account.Status = status
+		if err := tx.Accounts().UpdateStatus(account); err != nil {
+			accountService.Log.WithContext(c).Errorf("Failed to update account status: %+v", err)
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to change account status")
+		}
+
+		result = account
+		return nil
+	})
+	if err != nil {
+		return nil, accountService.Ledger.transactionError(c, err)
 	}
 
-	tx.Commit()
+	return result, nil
+}
 
+// accountStatusError returns the error reported when money is moved on an
+// account that is not active, or nil if the account is active.
+func accountStatusError(account *model.Account) *fiber.Error {
+	switch account.Status {
+	case model.AccountStatusFrozen:
+		return fiber.NewError(fiber.StatusLocked, ErrAccountFrozen.Error())
+	case model.AccountStatusDormant:
+		return fiber.NewError(fiber.StatusForbidden, ErrAccountDormant.Error())
+	case model.AccountStatusClosed:
+		return fiber.NewError(fiber.StatusGone, ErrAccountClosed.Error())
+	}
 	return nil
 }
 
-func (accountService *AccountService) Withdraw(c context.Context, req *model.Withdrawal) *fiber.Error {
+// GetWithdrawalLimits returns the limits in force for an account.
+func (accountService *AccountService) GetWithdrawalLimits(c context.Context, accountNumber string) (*model.WithdrawalLimits, *fiber.Error) {
+	account, fiberErr := accountService.Ledger.findAccount(accountService.Store.Repositories(c), accountNumber)
+	if fiberErr != nil {
+		return nil, fiberErr
+	}
+
+	limits, err := accountService.withdrawalLimits(accountService.Store.Repositories(c), account)
+	if err != nil {
+		return nil, err
+	}
+	return &limits, nil
+}
+
+// SetWithdrawalLimits stores the overrides of an account. A limit left empty
+// falls back to the global default.
+func (accountService *AccountService) SetWithdrawalLimits(c context.Context, accountNumber string, req *model.WithdrawalLimitRequest) (*model.WithdrawalLimits, *fiber.Error) {
+
+	if err := accountService.Validate.Struct(req); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	account, fiberErr := accountService.Ledger.findAccount(accountService.Store.Repositories(c), accountNumber)
+	if fiberErr != nil {
+		return nil, fiberErr
+	}
+
+	override := model.AccountWithdrawalLimit{
+		AccountID:         account.ID,
+		MaxPerTransaction: req.MaxPerTransaction,
+		MaxDailyTotal:     req.MaxDailyTotal,
+		MaxDailyCount:     req.MaxDailyCount,
+	}
+	if err := accountService.Store.Repositories(c).Accounts().SaveWithdrawalLimit(&override); err != nil {
+		accountService.Log.WithContext(c).Errorf("Failed to save withdrawal limits: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	limits := accountService.Limits.Apply(&override)
+	return &limits, nil
+}
+
+// withdrawalLimits returns the global limits with the overrides of account
+// applied.
+func (accountService *AccountService) withdrawalLimits(tx repository.Repositories, account *model.Account) (model.WithdrawalLimits, *fiber.Error) {
+	override, err := tx.Accounts().FindWithdrawalLimit(account.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return accountService.Limits, nil
+		}
+		accountService.Log.WithContext(tx.Context()).Errorf("Failed to get withdrawal limits: %+v", err)
+		return model.WithdrawalLimits{}, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+	return accountService.Limits.Apply(override), nil
+}
+
+// checkWithdrawalLimits rejects a withdrawal or outgoing transfer of nominal
+// that would exceed a limit of the account, reporting the headroom left under
+// that limit. The withdrawals and outgoing transfers counted are those of the
+// current business day that have not been reversed; their fees are not
+// counted. The account must have been locked by tx.
+func (accountService *AccountService) checkWithdrawalLimits(tx repository.Repositories, account *model.Account, nominal model.Money) *fiber.Error {
+	limits, fiberErr := accountService.withdrawalLimits(tx, account)
+	if fiberErr != nil {
+		return fiberErr
+	}
+
+	if !limits.MaxPerTransaction.IsZero() && limits.MaxPerTransaction.LessThan(nominal) {
+		return fiber.NewError(fiber.StatusUnprocessableEntity,
+			fmt.Sprintf("%s: %s remaining", ErrPerTransactionLimit, limits.MaxPerTransaction))
+	}
+	if limits.MaxDailyTotal.IsZero() && limits.MaxDailyCount == 0 {
+		return nil
+	}
+
+	start, end := businessDay(time.Now().In(accountService.Location))
+	total, count, err := tx.CashActivities().OutflowUsage(account.ID, start, end)
+	if err != nil {
+		accountService.Log.WithContext(tx.Context()).Errorf("Failed to sum today's withdrawals: %+v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	if limits.MaxDailyCount != 0 && count >= limits.MaxDailyCount {
+		return fiber.NewError(fiber.StatusUnprocessableEntity,
+			fmt.Sprintf("%s: 0 remaining", ErrDailyCountLimit))
+	}
+	if !limits.MaxDailyTotal.IsZero() && limits.MaxDailyTotal.LessThan(total.Add(nominal)) {
+		remaining := limits.MaxDailyTotal.Sub(total)
+		if remaining.IsNegative() {
+			remaining = model.NewMoney(0)
+		}
+		return fiber.NewError(fiber.StatusUnprocessableEntity,
+			fmt.Sprintf("%s: %s remaining", ErrDailyTotalLimit, remaining))
+	}
+
+	return nil
+}
+
+// businessDay returns the half-open [start, end) interval of the calendar day
+// of now, in the location of now.
+func businessDay(now time.Time) (time.Time, time.Time) {
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return start, start.AddDate(0, 0, 1)
+}
+
+// ResetPIN replaces the PIN of an account and lifts its lockout. When
+// RequireOldPIN is set the current PIN must be given, and a wrong one counts
+// as a failed attempt.
+func (accountService *AccountService) ResetPIN(c context.Context, req *model.PINResetRequest) *fiber.Error {
 
 	if err := accountService.Validate.Struct(req); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	tx := accountService.DB.WithContext(c).Begin()
-	if tx.Error != nil {
-		accountService.Log.Errorf("Failed to start transaction: %+v", tx.Error)
-		return fiber.NewError(fiber.StatusInternalServerError, "Transaction failed")
+	pinHash, fiberErr := accountService.hashPIN(c, req.NewPIN)
+	if fiberErr != nil {
+		return fiberErr
 	}
 
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+	var pinErr *fiber.Error
+	err := accountService.Store.Transaction(c, func(tx repository.Repositories) error {
+		account, err := accountService.Ledger.lockAccount(tx, req.AccountNumber)
+		if err != nil {
+			return err
+		}
+		if account.Status == model.AccountStatusClosed {
+			return fiber.NewError(fiber.StatusGone, ErrAccountClosed.Error())
 		}
-	}()
 
-	var account model.Account
-	if err := tx.Where("account_number = ?", req.AccountNumber).First(&account).Error; err != nil {
-		tx.Rollback()
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return fiber.NewError(fiber.StatusNotFound, ErrAccountNotFound.Error())
+		if req.RequireOldPIN {
+			if pinErr = accountService.verifyPIN(tx, account, req.OldPIN); pinErr != nil {
+				// Commit, so a wrong attempt stays counted.
+				return nil
+			}
 		}
-		accountService.Log.Errorf("Failed to get account: %+v", err)
-		return fiber.NewError(fiber.StatusInternalServerError, "Database error")
+
+		account.PINHash = pinHash
+		account.PINFailedAttempts = 0
+		account.PINLockedAt = nil
+		if err := tx.Accounts().UpdatePIN(account); err != nil {
+			accountService.Log.WithContext(c).Errorf("Failed to reset PIN: %+v", err)
+			return fiber.NewError(fiber.StatusInternalServerError, "Database error")
+		}
+		return nil
+	})
+	if err != nil {
+		return accountService.Ledger.transactionError(c, err)
+	}
+	if pinErr != nil {
+		return pinErr
 	}
 
-	if account.Balance < req.Nominal {
-		tx.Rollback()
-		return fiber.NewError(fiber.StatusBadRequest, ErrInsufficientBalance.Error())
+	return nil
+}
+
+// hashPIN returns the bcrypt hash stored for pin.
+func (accountService *AccountService) hashPIN(c context.Context, pin model.PIN) (string, *fiber.Error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(pin), accountService.PIN.HashCost)
+	if err != nil {
+		accountService.Log.WithContext(c).Errorf("Failed to hash PIN: %+v", err)
+		return "", fiber.NewError(fiber.StatusInternalServerError, "Failed to set PIN")
 	}
+	return string(hash), nil
+}
 
-	var latestActivity model.CashActivity
-	err := tx.Where("account_id = ?", account.ID).Order("created_at desc").First(&latestActivity).Error
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		tx.Rollback()
-		accountService.Log.Errorf("Failed to get latest cash activity: %+v", err)
-		return fiber.NewError(fiber.StatusInternalServerError, "Database error during getting latest cash activity")
+// verifyPIN checks pin against the PIN of account, which tx must have locked.
+// A wrong PIN is counted and, once the policy's attempts are used up, locks
+// the PIN until it is reset. The count is written through tx, so the caller
+// commits tx when verifyPIN fails, before writing anything else.
+func (accountService *AccountService) verifyPIN(tx repository.Repositories, account *model.Account, pin model.PIN) *fiber.Error {
+	if account.PINHash == "" {
+		return fiber.NewError(fiber.StatusForbidden, ErrPINNotSet.Error())
 	}
-	var refID *uint
-	if !errors.Is(err, gorm.ErrRecordNotFound) {
-		refID = &latestActivity.ID
+	if account.PINLockedAt != nil {
+		return fiber.NewError(fiber.StatusLocked, ErrPINLocked.Error())
 	}
 
-	newActivity := model.CashActivity{
-		AccountID:     account.ID,
-		ReferenceID:   refID,
-		Type:          "debit",
-		Nominal:       req.Nominal,
-		BalanceBefore: account.Balance,
-		BalanceAfter:  account.Balance - req.Nominal,
+	err := bcrypt.CompareHashAndPassword([]byte(account.PINHash), []byte(pin))
+	if err != nil && !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		accountService.Log.WithContext(tx.Context()).Errorf("Failed to compare PIN hash: %+v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to verify PIN")
 	}
 
-	if err := tx.Create(&newActivity).Error; err != nil {
-		tx.Rollback()
-		accountService.Log.Errorf("Failed to create cash activity: %+v", err)
-		return fiber.NewError(fiber.StatusInternalServerError, "Failed to record transaction")
+	if err == nil {
+		if account.PINFailedAttempts == 0 {
+			return nil
+		}
+		account.PINFailedAttempts = 0
+		if err := tx.Accounts().UpdatePIN(account); err != nil {
+			accountService.Log.WithContext(tx.Context()).Errorf("Failed to clear PIN attempts: %+v", err)
+			return fiber.NewError(fiber.StatusInternalServerError, "Database error")
+		}
+		return nil
 	}
 
-	account.Balance -= req.Nominal
-	if err := tx.Save(&account).Error; err != nil {
-		accountService.Log.Errorf("Failed to update account balance: %+v", err)
-		tx.Rollback()
-		return fiber.NewError(fiber.StatusInternalServerError, "Failed to record transaction")
+	account.PINFailedAttempts++
+	maxAttempts := accountService.PIN.MaxAttempts
+	locked := maxAttempts > 0 && account.PINFailedAttempts >= maxAttempts
+	if locked {
+		now := time.Now()
+		account.PINLockedAt = &now
+	}
+	if err := tx.Accounts().UpdatePIN(account); err != nil {
+		accountService.Log.WithContext(tx.Context()).Errorf("Failed to count PIN attempt: %+v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Database error")
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		accountService.Log.Errorf("Failed to commit transaction: %+v", err)
-		return fiber.NewError(fiber.StatusInternalServerError, "Transaction failed")
+	switch {
+	case locked:
+		return fiber.NewError(fiber.StatusLocked, ErrPINLocked.Error())
+	case maxAttempts > 0:
+		return fiber.NewError(fiber.StatusForbidden, fmt.Sprintf("%s: %d attempts remaining", ErrIncorrectPIN, maxAttempts-account.PINFailedAttempts))
 	}
-	return nil
+	return fiber.NewError(fiber.StatusForbidden, ErrIncorrectPIN.Error())
 }
 
-func (accountService *AccountService) GetBalance(c context.Context, accountNumber string) (*model.Account, *fiber.Error) {
-	var account model.Account
-	if err := accountService.DB.WithContext(c).Where("account_number = ?", accountNumber).First(&account).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fiber.NewError(fiber.StatusNotFound, ErrAccountNotFound.Error())
+// VerifyLedger walks the activity chain of an account from the oldest entry,
+// recomputing every hash, and reports the first broken link or balance
+// discontinuity.
+func (accountService *AccountService) VerifyLedger(c context.Context, accountNumber string) (*model.LedgerVerification, *fiber.Error) {
+	var result *model.LedgerVerification
+
+	err := accountService.Store.Transaction(c, func(tx repository.Repositories) error {
+		// A shared lock keeps new activities out while the chain is walked,
+		// so the final balance is compared against a consistent history.
+		account, err := tx.Accounts().LockShared(accountNumber)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, ErrAccountNotFound.Error())
+			}
+			return err
 		}
-		accountService.Log.Errorf("Failed to get account: %+v", err)
-		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+
+		result = &model.LedgerVerification{AccountNumber: account.AccountNumber, Valid: true}
+
+		var previous *model.CashActivity
+		if err := tx.CashActivities().Walk(account.ID, func(activity *model.CashActivity) bool {
+			result.CheckedEntries++
+			if reason := ledgerBreak(previous, activity); reason != "" {
+				result.Valid = false
+				result.BrokenAt = &activity.ID
+				result.Reason = reason
+				return false
+			}
+			previous = activity
+			return true
+		}); err != nil {
+			return err
+		}
+
+		if result.Valid && previous != nil && previous.BalanceAfter.Cmp(account.Balance) != 0 {
+			result.Valid = false
+			result.BrokenAt = &previous.ID
+			result.Reason = ledgerBrokenFinalBalance
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, accountService.Ledger.transactionError(c, err)
+	}
+
+	return result, nil
+}
+
+// ledgerBreak returns why activity does not follow previous in the chain, or
+// an empty string if it does. previous is nil for the first activity.
+func ledgerBreak(previous *model.CashActivity, activity *model.CashActivity) string {
+	previousHash := ""
+	var previousID *uint
+	if previous != nil {
+		previousHash = previous.Hash
+		previousID = &previous.ID
+	}
+
+	if (previousID == nil) != (activity.ReferenceID == nil) ||
+		previousID != nil && *previousID != *activity.ReferenceID {
+		return ledgerBrokenReference
+	}
+	if activity.Hash != activity.ComputeHash(previousHash) {
+		return ledgerBrokenHash
 	}
-	return &account, nil
+	if previous != nil && previous.BalanceAfter.Cmp(activity.BalanceBefore) != 0 {
+		return ledgerBrokenContinuity
+	}
+
+	expected := activity.BalanceBefore.Add(activity.Nominal)
+	if activity.Type == "debit" {
+		expected = activity.BalanceBefore.Sub(activity.Nominal)
+	}
+	if expected.Cmp(activity.BalanceAfter) != 0 {
+		return ledgerBrokenArithmetic
+	}
+
+	return ""
 }
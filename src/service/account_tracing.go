@@ -0,0 +1,117 @@
+package service
+
+import (
+	"account-service/src/model"
+	"account-service/src/tracing"
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// accountTracing traces every call to the account service it wraps in a span
+// named after the method, so that the queries of a call are its children.
+type accountTracing struct {
+	accountService AccountServices
+}
+
+func NewAccountTracing(accountService AccountServices) AccountServices {
+	return accountTracing{accountService}
+}
+
+func (t accountTracing) CreateAccount(c context.Context, req *model.CreateAccount) (*model.Account, *fiber.Error) {
+	c, span := tracing.Tracer().Start(c, "AccountService.CreateAccount")
+	account, err := t.accountService.CreateAccount(c, req)
+	tracing.End(span, err)
+	return account, err
+}
+
+func (t accountTracing) Deposit(c context.Context, req *model.DepositRequest) *fiber.Error {
+	c, span := tracing.Tracer().Start(c, "AccountService.Deposit")
+	err := t.accountService.Deposit(c, req)
+	tracing.End(span, err)
+	return err
+}
+
+func (t accountTracing) Withdraw(c context.Context, req *model.Withdrawal) *fiber.Error {
+	c, span := tracing.Tracer().Start(c, "AccountService.Withdraw")
+	err := t.accountService.Withdraw(c, req)
+	tracing.End(span, err)
+	return err
+}
+
+func (t accountTracing) GetBalance(c context.Context, id string) (*model.Account, *fiber.Error) {
+	c, span := tracing.Tracer().Start(c, "AccountService.GetBalance")
+	account, err := t.accountService.GetBalance(c, id)
+	tracing.End(span, err)
+	return account, err
+}
+
+func (t accountTracing) GetMutations(c context.Context, req *model.Mutation) ([]model.CashActivity, int64, *fiber.Error) {
+	c, span := tracing.Tracer().Start(c, "AccountService.GetMutations")
+	activities, total, err := t.accountService.GetMutations(c, req)
+	tracing.End(span, err)
+	return activities, total, err
+}
+
+func (t accountTracing) Transfer(c context.Context, req *model.TransferRequest) (*model.TransferResponse, *fiber.Error) {
+	c, span := tracing.Tracer().Start(c, "AccountService.Transfer")
+	transfer, err := t.accountService.Transfer(c, req)
+	tracing.End(span, err)
+	return transfer, err
+}
+
+func (t accountTracing) VerifyLedger(c context.Context, accountNumber string) (*model.LedgerVerification, *fiber.Error) {
+	c, span := tracing.Tracer().Start(c, "AccountService.VerifyLedger")
+	verification, err := t.accountService.VerifyLedger(c, accountNumber)
+	tracing.End(span, err)
+	return verification, err
+}
+
+func (t accountTracing) Reverse(c context.Context, activityID uint, req *model.ReversalRequest) (*model.ReversalResponse, *fiber.Error) {
+	c, span := tracing.Tracer().Start(c, "AccountService.Reverse")
+	reversal, err := t.accountService.Reverse(c, activityID, req)
+	tracing.End(span, err)
+	return reversal, err
+}
+
+func (t accountTracing) Freeze(c context.Context, accountNumber string, req *model.AccountStatusRequest) (*model.Account, *fiber.Error) {
+	c, span := tracing.Tracer().Start(c, "AccountService.Freeze")
+	account, err := t.accountService.Freeze(c, accountNumber, req)
+	tracing.End(span, err)
+	return account, err
+}
+
+func (t accountTracing) Unfreeze(c context.Context, accountNumber string, req *model.AccountStatusRequest) (*model.Account, *fiber.Error) {
+	c, span := tracing.Tracer().Start(c, "AccountService.Unfreeze")
+	account, err := t.accountService.Unfreeze(c, accountNumber, req)
+	tracing.End(span, err)
+	return account, err
+}
+
+func (t accountTracing) Close(c context.Context, accountNumber string, req *model.AccountStatusRequest) (*model.Account, *fiber.Error) {
+	c, span := tracing.Tracer().Start(c, "AccountService.Close")
+	account, err := t.accountService.Close(c, accountNumber, req)
+	tracing.End(span, err)
+	return account, err
+}
+
+func (t accountTracing) GetWithdrawalLimits(c context.Context, accountNumber string) (*model.WithdrawalLimits, *fiber.Error) {
+	c, span := tracing.Tracer().Start(c, "AccountService.GetWithdrawalLimits")
+	limits, err := t.accountService.GetWithdrawalLimits(c, accountNumber)
+	tracing.End(span, err)
+	return limits, err
+}
+
+func (t accountTracing) SetWithdrawalLimits(c context.Context, accountNumber string, req *model.WithdrawalLimitRequest) (*model.WithdrawalLimits, *fiber.Error) {
+	c, span := tracing.Tracer().Start(c, "AccountService.SetWithdrawalLimits")
+	limits, err := t.accountService.SetWithdrawalLimits(c, accountNumber, req)
+	tracing.End(span, err)
+	return limits, err
+}
+
+func (t accountTracing) ResetPIN(c context.Context, req *model.PINResetRequest) *fiber.Error {
+	c, span := tracing.Tracer().Start(c, "AccountService.ResetPIN")
+	err := t.accountService.ResetPIN(c, req)
+	tracing.End(span, err)
+	return err
+}
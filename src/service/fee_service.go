@@ -0,0 +1,245 @@
+package service
+
+import (
+	"account-service/src/model"
+	"account-service/src/repository"
+	"account-service/src/utils"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+type FeeService interface {
+	ListFeeSchedules(c context.Context, productCode string) ([]model.FeeSchedule, *fiber.Error)
+	CreateFeeSchedule(c context.Context, productCode string, req *model.FeeScheduleRequest) (*model.FeeSchedule, *fiber.Error)
+	ChargeMonthlyFees(c context.Context, month time.Time) (*model.MonthlyFeeResult, *fiber.Error)
+}
+
+type feeService struct {
+	Log      *logrus.Logger
+	Store    repository.UnitOfWork
+	Ledger   *Ledger
+	Validate *validator.Validate
+	Location *time.Location // Time zone of the business day
+}
+
+func NewFeeService(store repository.UnitOfWork, ledger *Ledger, validate *validator.Validate, location *time.Location) FeeService {
+	return &feeService{
+		Log:      utils.Log,
+		Store:    store,
+		Ledger:   ledger,
+		Validate: validate,
+		Location: location,
+	}
+}
+
+var (
+	ErrFeeScheduleExists    = errors.New("a fee schedule of this type already takes effect on that date")
+	ErrFeeScheduleInThePast = errors.New("a fee schedule cannot take effect before today")
+	ErrMonthNotOver         = errors.New("monthly fees cannot be charged for a month that has not ended")
+)
+
+// ListFeeSchedules lists every version of the fee rules of a product, oldest
+// first.
+func (s *feeService) ListFeeSchedules(c context.Context, productCode string) ([]model.FeeSchedule, *fiber.Error) {
+	if err := s.findProduct(c, productCode); err != nil {
+		return nil, err
+	}
+
+	schedules, err := s.Store.Repositories(c).FeeSchedules().List(productCode)
+	if err != nil {
+		s.Log.WithContext(c).Errorf("Failed to get fee schedules: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+	return schedules, nil
+}
+
+// CreateFeeSchedule adds a version of a fee rule of a product. Versions only
+// take effect from today on, so fees already charged are never recomputed
+// under a different rule.
+func (s *feeService) CreateFeeSchedule(c context.Context, productCode string, req *model.FeeScheduleRequest) (*model.FeeSchedule, *fiber.Error) {
+
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	effectiveFrom, err := time.Parse(time.DateOnly, req.EffectiveFrom)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if effectiveFrom.Before(businessDate(time.Now().In(s.Location))) {
+		return nil, fiber.NewError(fiber.StatusUnprocessableEntity, ErrFeeScheduleInThePast.Error())
+	}
+
+	if err := s.findProduct(c, productCode); err != nil {
+		return nil, err
+	}
+
+	schedule := model.FeeSchedule{
+		ProductCode:   productCode,
+		Type:          req.Type,
+		Amount:        req.Amount,
+		EffectiveFrom: effectiveFrom,
+	}
+	if req.Type == model.FeeTypeWithdrawal {
+		schedule.FreePerMonth = req.FreePerMonth
+	}
+	if err := s.Store.Repositories(c).FeeSchedules().Create(&schedule); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, fiber.NewError(fiber.StatusConflict, ErrFeeScheduleExists.Error())
+		}
+		s.Log.WithContext(c).Errorf("Failed to create fee schedule: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	return &schedule, nil
+}
+
+func (s *feeService) findProduct(c context.Context, productCode string) *fiber.Error {
+	if _, err := s.Store.Repositories(c).Products().Find(productCode); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, ErrProductNotFound.Error())
+		}
+		s.Log.WithContext(c).Errorf("Failed to get product: %+v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+	return nil
+}
+
+// ChargeMonthlyFees charges every open account the monthly admin fee of its
+// product for the month of month, under the schedule in force on the last
+// day of that month. An account is charged at most once a month, so the
+// charge can be run again after a failure or once skipped accounts have been
+// funded. Accounts whose balance cannot cover the fee are skipped. Frozen
+// accounts are not charged, as no other debit reaches them either; a month
+// they were frozen through is not charged once they are unfrozen.
+func (s *feeService) ChargeMonthlyFees(c context.Context, month time.Time) (*model.MonthlyFeeResult, *fiber.Error) {
+	period := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	lastDay := period.AddDate(0, 1, -1)
+	_, end := businessDay(time.Date(lastDay.Year(), lastDay.Month(), lastDay.Day(), 0, 0, 0, 0, s.Location))
+	if time.Now().Before(end) {
+		return nil, fiber.NewError(fiber.StatusUnprocessableEntity, ErrMonthNotOver.Error())
+	}
+
+	accountNumbers, err := s.Store.Repositories(c).Accounts().ListMonthlyFeeDue(end, period)
+	if err != nil {
+		s.Log.WithContext(c).Errorf("Failed to get accounts to charge: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	result := &model.MonthlyFeeResult{Month: period.Format("2006-01")}
+	for _, accountNumber := range accountNumbers {
+		var charged model.Money
+		var skipped bool
+		err := s.Store.Transaction(c, func(tx repository.Repositories) error {
+			var fiberErr *fiber.Error
+			charged, skipped, fiberErr = s.chargeMonthlyFee(tx, accountNumber, period, lastDay)
+			if fiberErr != nil {
+				return fiberErr
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, s.Ledger.transactionError(c, err)
+		}
+		if skipped {
+			result.Skipped++
+		}
+		if !charged.IsZero() {
+			result.Charged++
+			result.Total = result.Total.Add(charged)
+		}
+	}
+
+	s.Log.WithContext(c).Infof("Monthly fees for %s: %d accounts charged %s, %d skipped", result.Month, result.Charged, result.Total, result.Skipped)
+	return result, nil
+}
+
+// chargeMonthlyFee charges one account the monthly fee of period, returning
+// the amount charged and whether the balance could not cover it.
+func (s *feeService) chargeMonthlyFee(tx repository.Repositories, accountNumber string, period time.Time, lastDay time.Time) (model.Money, bool, *fiber.Error) {
+	account, fiberErr := s.Ledger.lockAccount(tx, accountNumber)
+	if fiberErr != nil {
+		return model.Money{}, false, fiberErr
+	}
+
+	// The account may have been frozen, or charged by a concurrent run,
+	// since it was listed.
+	if account.Status == model.AccountStatusFrozen {
+		return model.Money{}, false, nil
+	}
+	_, err := tx.CashActivities().FindByFeePeriod(account.ID, period)
+	if err == nil {
+		return model.Money{}, false, nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		s.Log.WithContext(tx.Context()).Errorf("Failed to check monthly fee: %+v", err)
+		return model.Money{}, false, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	schedule, err := effectiveFeeSchedule(tx, account.ProductCode, model.FeeTypeMonthlyAdmin, lastDay)
+	if err != nil {
+		s.Log.WithContext(tx.Context()).Errorf("Failed to get fee schedule: %+v", err)
+		return model.Money{}, false, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+	if schedule == nil || schedule.Amount.IsZero() {
+		return model.Money{}, false, nil
+	}
+	if account.Balance.LessThan(schedule.Amount) {
+		s.Log.WithContext(tx.Context()).Warnf("Balance of account %s cannot cover the monthly fee of %s", account.AccountNumber, period.Format("January 2006"))
+		return model.Money{}, true, nil
+	}
+
+	activity := model.CashActivity{
+		Description: fmt.Sprintf("Monthly admin fee for %s", period.Format("January 2006")),
+		FeePeriod:   &period,
+	}
+	if fiberErr := s.Ledger.chargeFee(tx, account, schedule, schedule.Amount, &activity); fiberErr != nil {
+		return model.Money{}, false, fiberErr
+	}
+	return schedule.Amount, false, nil
+}
+
+// withdrawalFee returns the fee a withdrawal from account made now would be
+// charged, with the schedule it is charged under. The account must have been
+// locked by tx.
+func (accountService *AccountService) withdrawalFee(tx repository.Repositories, account *model.Account) (*model.FeeSchedule, model.Money, *fiber.Error) {
+	now := time.Now().In(accountService.Location)
+	schedule, err := effectiveFeeSchedule(tx, account.ProductCode, model.FeeTypeWithdrawal, businessDate(now))
+	if err != nil {
+		accountService.Log.WithContext(tx.Context()).Errorf("Failed to get fee schedule: %+v", err)
+		return nil, model.Money{}, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+	if schedule == nil || schedule.Amount.IsZero() {
+		return nil, model.NewMoney(0), nil
+	}
+
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	_, count, err := tx.CashActivities().WithdrawalUsage(account.ID, monthStart, monthStart.AddDate(0, 1, 0))
+	if err != nil {
+		accountService.Log.WithContext(tx.Context()).Errorf("Failed to count this month's withdrawals: %+v", err)
+		return nil, model.Money{}, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+	return schedule, schedule.WithdrawalFee(count), nil
+}
+
+// effectiveFeeSchedule returns the version of a fee rule of a product in force
+// on date, or nil if the product has no such fee.
+func effectiveFeeSchedule(tx repository.Repositories, productCode string, feeType string, date time.Time) (*model.FeeSchedule, error) {
+	schedule, err := tx.FeeSchedules().Effective(productCode, feeType, date)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	return schedule, err
+}
+
+// businessDate returns the calendar day of now as midnight UTC, the way DATE
+// columns are read and written.
+func businessDate(now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"account-service/src/model"
+	"account-service/src/repository"
+	"account-service/src/utils"
+	"context"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+type GeneralLedgerService interface {
+	TrialBalance(c context.Context, req *model.TrialBalanceRequest) (*model.TrialBalance, *fiber.Error)
+	Reconcile(c context.Context) (*model.GLReconciliation, *fiber.Error)
+}
+
+type generalLedgerService struct {
+	Log      *logrus.Logger
+	Store    repository.UnitOfWork
+	Validate *validator.Validate
+	Location *time.Location // Time zone of the business day
+}
+
+func NewGeneralLedgerService(store repository.UnitOfWork, validate *validator.Validate, location *time.Location) GeneralLedgerService {
+	return &generalLedgerService{
+		Log:      utils.Log,
+		Store:    store,
+		Validate: validate,
+		Location: location,
+	}
+}
+
+// TrialBalance lists the balance of every GL account at the end of a business
+// day, debit balances in the debit column and credit balances in the credit
+// column. The totals of both columns are equal while every journal balances.
+func (s *generalLedgerService) TrialBalance(c context.Context, req *model.TrialBalanceRequest) (*model.TrialBalance, *fiber.Error) {
+
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	day := time.Now().In(s.Location)
+	if req.Date != "" {
+		parsed, err := time.ParseInLocation(time.DateOnly, req.Date, s.Location)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		day = parsed
+	}
+	_, end := businessDay(day)
+
+	repos := s.Store.Repositories(c)
+	accounts, err := repos.Journals().ListGLAccounts()
+	if err != nil {
+		s.Log.WithContext(c).Errorf("Failed to get GL accounts: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	balances, err := repos.Journals().Balances(end)
+	if err != nil {
+		s.Log.WithContext(c).Errorf("Failed to sum journal lines: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	result := &model.TrialBalance{
+		Date:     day.Format(time.DateOnly),
+		Accounts: make([]model.TrialBalanceLine, 0, len(accounts)),
+	}
+	for _, account := range accounts {
+		line := model.TrialBalanceLine{Code: account.Code, Name: account.Name, Type: account.Type}
+		if balance := balances[account.Code]; balance.IsNegative() {
+			line.Credit = balance.Neg()
+		} else {
+			line.Debit = balance
+		}
+		result.Accounts = append(result.Accounts, line)
+		result.TotalDebit = result.TotalDebit.Add(line.Debit)
+		result.TotalCredit = result.TotalCredit.Add(line.Credit)
+	}
+	result.Balanced = result.TotalDebit.Cmp(result.TotalCredit) == 0
+
+	return result, nil
+}
+
+// Reconcile checks the invariant that the balances of all customer accounts
+// add up to the customer deposits liability of the general ledger.
+func (s *generalLedgerService) Reconcile(c context.Context) (*model.GLReconciliation, *fiber.Error) {
+	result, err := s.Store.Repositories(c).Journals().Reconciliation()
+	if err != nil {
+		s.Log.WithContext(c).Errorf("Failed to reconcile the general ledger: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	result.Difference = result.CustomerBalances.Sub(result.LiabilityBalance)
+	result.Balanced = result.Difference.IsZero()
+	if !result.Balanced {
+		s.Log.WithContext(c).Warnf("Customer balances differ from the customer deposits liability by %s", result.Difference)
+	}
+
+	return result, nil
+}
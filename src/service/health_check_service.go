@@ -3,6 +3,7 @@ package service
 import (
 	"account-service/src/utils"
 	"errors"
+	"fmt"
 	"runtime"
 
 	"github.com/sirupsen/logrus"
@@ -12,6 +13,7 @@ import (
 type HealthCheckService interface {
 	GormCheck() error
 	MemoryHeapCheck() error
+	SchemaVersion() (uint, error)
 }
 
 type healthCheckService struct {
@@ -59,3 +61,23 @@ func (s *healthCheckService) MemoryHeapCheck() error {
 
 	return nil
 }
+
+// SchemaVersion returns the version of the last applied migration, and an
+// error if no migration was applied or the last one failed halfway.
+func (s *healthCheckService) SchemaVersion() (uint, error) {
+	var migration struct {
+		Version uint
+		Dirty   bool
+	}
+	if err := s.DB.Table("schema_migrations").Select("version", "dirty").Take(&migration).Error; err != nil {
+		s.Log.Errorf("failed to read the schema version: %v", err)
+		return 0, err
+	}
+
+	if migration.Dirty {
+		s.Log.Errorf("schema version %d is dirty", migration.Version)
+		return migration.Version, fmt.Errorf("migration %d did not complete", migration.Version)
+	}
+
+	return migration.Version, nil
+}
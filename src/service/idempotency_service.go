@@ -0,0 +1,138 @@
+package service
+
+import (
+	"account-service/src/model"
+	"account-service/src/repository"
+	"account-service/src/utils"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+type IdempotencyService interface {
+	Begin(c context.Context, principal string, key string, requestHash string) (*model.IdempotencyKey, *fiber.Error)
+	Complete(c context.Context, principal string, key string, statusCode int, contentType string, body []byte) error
+	Release(c context.Context, principal string, key string) error
+}
+
+type idempotencyService struct {
+	Log   *logrus.Logger
+	Store repository.UnitOfWork
+	TTL   time.Duration
+}
+
+func NewIdempotencyService(store repository.UnitOfWork, ttl time.Duration) IdempotencyService {
+	return &idempotencyService{
+		Log:   utils.Log,
+		Store: store,
+		TTL:   ttl,
+	}
+}
+
+var (
+	ErrIdempotencyKeyReused   = errors.New("idempotency key was already used with a different request")
+	ErrIdempotencyKeyInFlight = errors.New("a request with this idempotency key is still being processed")
+)
+
+// Begin reserves key of principal for a request. It returns nil when the
+// caller should process the request, or the stored record when the request is
+// a replay of a completed one. Keys are scoped to the principal that chose
+// them, so the same key of another principal is another key.
+func (s *idempotencyService) Begin(c context.Context, principal string, key string, requestHash string) (*model.IdempotencyKey, *fiber.Error) {
+	now := time.Now()
+
+	reservation := model.IdempotencyKey{
+		Principal:   principal,
+		Key:         key,
+		RequestHash: requestHash,
+		ExpiresAt:   now.Add(s.TTL),
+	}
+	repos := s.Store.Repositories(c)
+	reserved, err := repos.IdempotencyKeys().Reserve(&reservation)
+	if err != nil {
+		s.Log.WithContext(c).Errorf("Failed to reserve idempotency key: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+	if reserved {
+		return nil, nil
+	}
+
+	existing, err := repos.IdempotencyKeys().Find(principal, key)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			// The holder released the key between our insert and this read.
+			return nil, fiber.NewError(fiber.StatusConflict, ErrIdempotencyKeyInFlight.Error())
+		}
+		s.Log.WithContext(c).Errorf("Failed to get idempotency key: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	// An expired key the purger has not deleted yet is taken over.
+	if existing.ExpiresAt.Before(now) {
+		if err := repos.IdempotencyKeys().DeleteExpired(existing.ID, now); err != nil {
+			s.Log.WithContext(c).Errorf("Failed to delete expired idempotency key: %+v", err)
+			return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+		}
+		return s.Begin(c, principal, key, requestHash)
+	}
+
+	if existing.RequestHash != requestHash {
+		return nil, fiber.NewError(fiber.StatusUnprocessableEntity, ErrIdempotencyKeyReused.Error())
+	}
+	if !existing.IsCompleted() {
+		return nil, fiber.NewError(fiber.StatusConflict, ErrIdempotencyKeyInFlight.Error())
+	}
+
+	return existing, nil
+}
+
+// Complete stores the response of the request that reserved key of
+// principal, so later replays receive it unchanged.
+func (s *idempotencyService) Complete(c context.Context, principal string, key string, statusCode int, contentType string, body []byte) error {
+	return s.Store.Repositories(c).IdempotencyKeys().Complete(&model.IdempotencyKey{
+		Principal:    principal,
+		Key:          key,
+		StatusCode:   statusCode,
+		ContentType:  contentType,
+		ResponseBody: body,
+	})
+}
+
+// Release drops the reservation of key of principal, allowing the request to
+// be retried.
+func (s *idempotencyService) Release(c context.Context, principal string, key string) error {
+	return s.Store.Repositories(c).IdempotencyKeys().Delete(principal, key)
+}
+
+// idempotencyPurgeBatchSize is the number of expired keys deleted by each
+// pass of the purger, keeping every delete short.
+const idempotencyPurgeBatchSize = 1000
+
+// IdempotencyKeyPurger deletes expired idempotency keys in the background,
+// away from the requests that use them.
+type IdempotencyKeyPurger struct {
+	Log      *logrus.Logger
+	Store    repository.UnitOfWork
+	Interval time.Duration
+}
+
+func NewIdempotencyKeyPurger(store repository.UnitOfWork, interval time.Duration) *IdempotencyKeyPurger {
+	return &IdempotencyKeyPurger{
+		Log:      utils.Log,
+		Store:    store,
+		Interval: interval,
+	}
+}
+
+// Run deletes expired keys until c is cancelled.
+func (p *IdempotencyKeyPurger) Run(c context.Context) {
+	poll(c, p.Log, "Idempotency key purger", p.Interval, p.PurgeOnce)
+}
+
+// PurgeOnce deletes a batch of expired keys, returning how many it deleted.
+func (p *IdempotencyKeyPurger) PurgeOnce(c context.Context) (int, error) {
+	return p.Store.Repositories(c).IdempotencyKeys().Purge(time.Now(), idempotencyPurgeBatchSize)
+}
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"account-service/src/model"
+	"account-service/src/repository"
+	"account-service/src/utils"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+type InterestService interface {
+	Run(c context.Context, day time.Time) (*model.InterestRun, *fiber.Error)
+	PendingInterest(c context.Context, accountNumber string) (*model.PendingInterestResponse, *fiber.Error)
+	SetProductRate(c context.Context, code string, req *model.ProductRateRequest) (*model.Product, *fiber.Error)
+}
+
+type interestService struct {
+	Log      *logrus.Logger
+	Store    repository.UnitOfWork
+	Ledger   *Ledger
+	Validate *validator.Validate
+	Location *time.Location // Time zone of the business day
+}
+
+func NewInterestService(store repository.UnitOfWork, ledger *Ledger, validate *validator.Validate, location *time.Location) InterestService {
+	return &interestService{
+		Log:      utils.Log,
+		Store:    store,
+		Ledger:   ledger,
+		Validate: validate,
+		Location: location,
+	}
+}
+
+var ErrBusinessDayNotOver = errors.New("interest cannot be run for a business day that has not ended")
+
+// Run accrues a day of interest on the end-of-day balance of every open
+// account and, on the last day of a month, credits each account with its
+// pending interest. A date is done once: running it again returns the first
+// run. A run that failed part way is resumed by running the date again, as
+// every accrual and credit is recorded at most once. Dates should be run in
+// order, so a month-end credit earns interest from the next day.
+func (s *interestService) Run(c context.Context, day time.Time) (*model.InterestRun, *fiber.Error) {
+	date := businessDate(day)
+	_, end := businessDay(time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, s.Location))
+	if time.Now().Before(end) {
+		return nil, fiber.NewError(fiber.StatusUnprocessableEntity, ErrBusinessDayNotOver.Error())
+	}
+
+	repos := s.Store.Repositories(c)
+	completed, err := repos.InterestRuns().Find(date)
+	if err == nil {
+		s.Log.WithContext(c).Infof("Interest for %s was already run", date.Format(time.DateOnly))
+		completed.AlreadyCompleted = true
+		return completed, nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		s.Log.WithContext(c).Errorf("Failed to get interest run: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	if err := s.accrue(c, date, end); err != nil {
+		return nil, err
+	}
+	monthEnd := date.AddDate(0, 0, 1).Day() == 1
+	if monthEnd {
+		if err := s.capitalize(c, date); err != nil {
+			return nil, err
+		}
+	}
+
+	run := model.InterestRun{BusinessDate: date, TotalCapitalized: model.NewMoney(0)}
+	run.AccruedAccounts, run.TotalAccrued, err = repos.InterestAccruals().Accrued(date)
+	if err != nil {
+		s.Log.WithContext(c).Errorf("Failed to sum interest accruals: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+	if monthEnd {
+		monthStart := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+		run.CapitalizedAccounts, run.TotalCapitalized, err = repos.InterestAccruals().Capitalized(monthStart, date)
+		if err != nil {
+			s.Log.WithContext(c).Errorf("Failed to sum capitalized interest: %+v", err)
+			return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+		}
+	}
+
+	// A concurrent run of the same date may have finished first.
+	if err := repos.InterestRuns().Create(&run); err != nil {
+		s.Log.WithContext(c).Errorf("Failed to record interest run: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+	s.Log.WithContext(c).Infof("Interest for %s: %d accounts accrued %s, %d accounts credited %s",
+		date.Format(time.DateOnly), run.AccruedAccounts, run.TotalAccrued, run.CapitalizedAccounts, run.TotalCapitalized)
+
+	return &run, nil
+}
+
+// accrue records the interest of date for every account holding a balance at
+// end, the end of the business day. Accounts accrued by an earlier attempt
+// are left alone.
+func (s *interestService) accrue(c context.Context, date time.Time, end time.Time) *fiber.Error {
+	repos := s.Store.Repositories(c)
+	balances, err := repos.Accounts().EndOfDayBalances(end)
+	if err != nil {
+		s.Log.WithContext(c).Errorf("Failed to get end-of-day balances: %+v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	accruals := make([]model.InterestAccrual, 0, len(balances))
+	for _, balance := range balances {
+		amount := model.DailyInterest(balance.Balance, balance.AnnualRateBPS)
+		if !amount.IsNegative() && !amount.IsZero() {
+			accruals = append(accruals, model.InterestAccrual{
+				AccountID:     balance.AccountID,
+				BusinessDate:  date,
+				Balance:       balance.Balance,
+				AnnualRateBPS: balance.AnnualRateBPS,
+				Amount:        amount,
+			})
+		}
+	}
+	if err := repos.InterestAccruals().CreateAll(accruals); err != nil {
+		s.Log.WithContext(c).Errorf("Failed to record interest accruals: %+v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+	return nil
+}
+
+// capitalize credits every account with the interest pending up to date, one
+// credit per month accrued, each in a transaction of its own.
+func (s *interestService) capitalize(c context.Context, date time.Time) *fiber.Error {
+	accountNumbers, err := s.Store.Repositories(c).InterestAccruals().PendingAccounts(date)
+	if err != nil {
+		s.Log.WithContext(c).Errorf("Failed to get accounts with pending interest: %+v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	for _, accountNumber := range accountNumbers {
+		err := s.Store.Transaction(c, func(tx repository.Repositories) error {
+			if err := s.capitalizeAccount(tx, accountNumber, date); err != nil {
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			return s.Ledger.transactionError(c, err)
+		}
+	}
+	return nil
+}
+
+// capitalizeAccount credits one account with its interest pending up to date.
+func (s *interestService) capitalizeAccount(tx repository.Repositories, accountNumber string, date time.Time) *fiber.Error {
+	// The account lock keeps a concurrent run from crediting the same
+	// accruals twice.
+	account, err := s.Ledger.lockAccount(tx, accountNumber)
+	if err != nil {
+		return err
+	}
+	if account.Status == model.AccountStatusClosed {
+		s.Log.WithContext(tx.Context()).Warnf("Not crediting pending interest to closed account %s", account.AccountNumber)
+		return nil
+	}
+
+	pending, pendingErr := tx.InterestAccruals().Pending(account.ID)
+	if pendingErr != nil {
+		s.Log.WithContext(tx.Context()).Errorf("Failed to get pending interest: %+v", pendingErr)
+		return fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+	var accruals []model.InterestAccrual
+	for _, accrual := range pending {
+		if !accrual.BusinessDate.After(date) {
+			accruals = append(accruals, accrual)
+		}
+	}
+
+	for len(accruals) > 0 {
+		month := accruals[0].BusinessDate
+		ids := []uint{}
+		total := model.NewMoney(0)
+		for len(accruals) > 0 && accruals[0].BusinessDate.Year() == month.Year() && accruals[0].BusinessDate.Month() == month.Month() {
+			ids = append(ids, accruals[0].ID)
+			total = total.Add(accruals[0].Amount)
+			accruals = accruals[1:]
+		}
+
+		description := fmt.Sprintf("Interest for %s", month.Format("January 2006"))
+		activity := model.CashActivity{Type: "credit", Nominal: total, Description: description}
+		if err := s.Ledger.postActivity(tx, account, &activity, model.EventInterestCredited); err != nil {
+			return err
+		}
+		if err := s.Ledger.postJournal(tx, model.NewActivityJournal(&activity, model.GLInterestExpenseCode, fmt.Sprintf("%s to %s", description, account.AccountNumber))); err != nil {
+			return err
+		}
+		if err := tx.InterestAccruals().MarkCapitalized(ids, activity.ID); err != nil {
+			s.Log.WithContext(tx.Context()).Errorf("Failed to mark interest as capitalized: %+v", err)
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to record transaction")
+		}
+	}
+	return nil
+}
+
+// PendingInterest lists the interest an account has accrued that has not yet
+// been credited to it.
+func (s *interestService) PendingInterest(c context.Context, accountNumber string) (*model.PendingInterestResponse, *fiber.Error) {
+	repos := s.Store.Repositories(c)
+	account, fiberErr := s.Ledger.findAccount(repos, accountNumber)
+	if fiberErr != nil {
+		return nil, fiberErr
+	}
+	if account.Status == model.AccountStatusClosed {
+		return nil, fiber.NewError(fiber.StatusGone, ErrAccountClosed.Error())
+	}
+
+	product, err := repos.Products().Find(account.ProductCode)
+	if err != nil {
+		s.Log.WithContext(c).Errorf("Failed to get product: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	accruals, err := repos.InterestAccruals().Pending(account.ID)
+	if err != nil {
+		s.Log.WithContext(c).Errorf("Failed to get pending interest: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	result := &model.PendingInterestResponse{
+		AccountNumber: account.AccountNumber,
+		ProductCode:   product.Code,
+		AnnualRateBPS: product.AnnualRateBPS,
+		Accruals:      make([]model.InterestAccrualLine, 0, len(accruals)),
+	}
+	for _, accrual := range accruals {
+		result.Pending = result.Pending.Add(accrual.Amount)
+		result.Accruals = append(result.Accruals, model.InterestAccrualLine{
+			Date:          accrual.BusinessDate.Format(time.DateOnly),
+			Balance:       accrual.Balance,
+			AnnualRateBPS: accrual.AnnualRateBPS,
+			Amount:        accrual.Amount,
+		})
+	}
+
+	return result, nil
+}
+
+// SetProductRate changes the annual interest rate of a product. Days already
+// accrued keep the rate they were accrued at.
+func (s *interestService) SetProductRate(c context.Context, code string, req *model.ProductRateRequest) (*model.Product, *fiber.Error) {
+
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	repos := s.Store.Repositories(c)
+	product, err := repos.Products().Find(code)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, ErrProductNotFound.Error())
+		}
+		s.Log.WithContext(c).Errorf("Failed to get product: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	product.AnnualRateBPS = *req.AnnualRateBPS
+	if err := repos.Products().UpdateRate(product); err != nil {
+		s.Log.WithContext(c).Errorf("Failed to update product rate: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	return product, nil
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"account-service/src/model"
+	"account-service/src/repository"
+	"account-service/src/utils"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// Ledger posts cash activities, with their journals and outbox events, in the
+// transaction of the service that moves the money.
+type Ledger struct {
+	Log *logrus.Logger
+}
+
+func NewLedger() *Ledger {
+	return &Ledger{Log: utils.Log}
+}
+
+// findAccount loads an account by number whatever its status.
+func (ledger *Ledger) findAccount(repos repository.Repositories, accountNumber string) (*model.Account, *fiber.Error) {
+	account, err := repos.Accounts().FindByNumber(accountNumber)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, ErrAccountNotFound.Error())
+		}
+		ledger.Log.WithContext(repos.Context()).Errorf("Failed to get account: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+	return account, nil
+}
+
+// lockAccount loads an account, holding its lock until tx ends.
+func (ledger *Ledger) lockAccount(tx repository.Repositories, accountNumber string) (*model.Account, *fiber.Error) {
+	account, err := tx.Accounts().Lock(accountNumber)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, ErrAccountNotFound.Error())
+		}
+		ledger.Log.WithContext(tx.Context()).Errorf("Failed to lock account: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+	return account, nil
+}
+
+// postActivity records activity, of which the caller sets Type, Nominal and
+// the optional descriptive fields, chained to the latest activity of the
+// account, applies it to the account balance and records the eventType event
+// of the change in the outbox. The account must have been locked by tx.
+func (ledger *Ledger) postActivity(tx repository.Repositories, account *model.Account, activity *model.CashActivity, eventType string) *fiber.Error {
+	latestActivity, err := tx.CashActivities().Latest(account.ID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		ledger.Log.WithContext(tx.Context()).Errorf("Failed to get latest cash activity: %+v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Database error during getting latest cash activity")
+	}
+	var refID *uint
+	previousHash := ""
+	if latestActivity != nil {
+		refID = &latestActivity.ID
+		previousHash = latestActivity.Hash
+	}
+
+	balanceAfter := account.Balance.Add(activity.Nominal)
+	if activity.Type == "debit" {
+		balanceAfter = account.Balance.Sub(activity.Nominal)
+	}
+
+	activity.AccountID = account.ID
+	activity.ReferenceID = refID
+	activity.BalanceBefore = account.Balance
+	activity.BalanceAfter = balanceAfter
+	// Set explicitly at the precision the database keeps, so the hash can be
+	// recomputed from the stored row.
+	activity.CreatedAt = time.Now().Truncate(time.Microsecond)
+	activity.Hash = activity.ComputeHash(previousHash)
+	if err := tx.CashActivities().Create(activity); err != nil {
+		ledger.Log.WithContext(tx.Context()).Errorf("Failed to create cash activity: %+v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to record transaction")
+	}
+
+	account.Balance = balanceAfter
+	if err := tx.Accounts().UpdateBalance(account); err != nil {
+		ledger.Log.WithContext(tx.Context()).Errorf("Failed to update account balance: %+v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to record transaction")
+	}
+
+	return ledger.recordEvent(tx, account.AccountNumber, eventType, model.NewActivityData(account, activity))
+}
+
+// recordEvent writes an event to the outbox in tx, so it is published if and
+// only if tx commits.
+func (ledger *Ledger) recordEvent(tx repository.Repositories, accountNumber string, eventType string, data any) *fiber.Error {
+	event, err := model.NewOutboxEvent(accountNumber, eventType, data)
+	if err != nil {
+		ledger.Log.WithContext(tx.Context()).Errorf("Failed to encode %s event: %+v", eventType, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to record transaction")
+	}
+	if err := tx.Outbox().Create(event); err != nil {
+		ledger.Log.WithContext(tx.Context()).Errorf("Failed to record %s event: %+v", eventType, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to record transaction")
+	}
+	return nil
+}
+
+// postJournal records a balanced journal entry with its lines.
+func (ledger *Ledger) postJournal(tx repository.Repositories, entry *model.JournalEntry) *fiber.Error {
+	if !entry.IsBalanced() {
+		ledger.Log.WithContext(tx.Context()).Errorf("Refusing unbalanced journal entry: %q", entry.Description)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to record transaction")
+	}
+	if err := tx.Journals().Create(entry); err != nil {
+		ledger.Log.WithContext(tx.Context()).Errorf("Failed to create journal entry: %+v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to record transaction")
+	}
+	return nil
+}
+
+// postReversalJournal records the journal of a compensating activity, undoing
+// the journal of the original. An original posted before the general ledger
+// existed has no journal; its counterpart is unknown, so it is reversed
+// against suspense.
+func (ledger *Ledger) postReversalJournal(tx repository.Repositories, original *model.CashActivity, compensating *model.CashActivity) *fiber.Error {
+	description := fmt.Sprintf("Reversal of transaction %d", original.ID)
+
+	originalEntry, err := tx.Journals().FindByCashActivity(original.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ledger.postJournal(tx, model.NewActivityJournal(compensating, model.GLSuspenseCode, description))
+	}
+	if err != nil {
+		ledger.Log.WithContext(tx.Context()).Errorf("Failed to get journal entry: %+v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to record transaction")
+	}
+	return ledger.postJournal(tx, originalEntry.Reverse(compensating, description))
+}
+
+// transactionError converts the error returned by a transaction into the
+// *fiber.Error reported to the caller.
+func (ledger *Ledger) transactionError(c context.Context, err error) *fiber.Error {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fiberErr
+	}
+	ledger.Log.WithContext(c).Errorf("Transaction failed: %+v", err)
+	return fiber.NewError(fiber.StatusInternalServerError, "Transaction failed")
+}
+
+// chargeFee posts activity, of which the caller sets the description and the
+// link to what triggered the fee, as a debit of amount charged under schedule,
+// with its journal to fee income. The account must have been locked by tx.
+func (ledger *Ledger) chargeFee(tx repository.Repositories, account *model.Account, schedule *model.FeeSchedule, amount model.Money, activity *model.CashActivity) *fiber.Error {
+	activity.Type = "debit"
+	activity.Nominal = amount
+	activity.FeeScheduleID = &schedule.ID
+	if err := ledger.postActivity(tx, account, activity, model.EventFeeCharged); err != nil {
+		return err
+	}
+	return ledger.postJournal(tx, model.NewActivityJournal(activity, model.GLFeeIncomeCode, fmt.Sprintf("%s from %s", activity.Description, account.AccountNumber)))
+}
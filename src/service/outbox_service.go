@@ -0,0 +1,119 @@
+package service
+
+import (
+	"account-service/src/model"
+	"account-service/src/publisher"
+	"account-service/src/repository"
+	"account-service/src/utils"
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// OutboxDispatcher publishes the events of the outbox in the background.
+type OutboxDispatcher struct {
+	Log       *logrus.Logger
+	Store     repository.UnitOfWork
+	Publisher publisher.Publisher
+	Policy    model.OutboxPolicy
+}
+
+func NewOutboxDispatcher(store repository.UnitOfWork, pub publisher.Publisher, policy model.OutboxPolicy) *OutboxDispatcher {
+	return &OutboxDispatcher{
+		Log:       utils.Log,
+		Store:     store,
+		Publisher: pub,
+		Policy:    policy,
+	}
+}
+
+// Run publishes events until c is cancelled. A pass that is under way when c
+// is cancelled is finished first, so no event is left published but not
+// marked as such.
+func (d *OutboxDispatcher) Run(c context.Context) {
+	poll(c, d.Log, "Outbox dispatcher", d.Policy.PollInterval, d.DispatchOnce)
+}
+
+// poll runs pass until c is cancelled: right away again after a pass that
+// did something, since more work may be waiting behind it, and interval
+// later otherwise. A pass under way when c is cancelled is finished first.
+func poll(c context.Context, log *logrus.Logger, name string, interval time.Duration, pass func(context.Context) (int, error)) {
+	log.Infof("%s started", name)
+	for c.Err() == nil {
+		done, err := pass(context.WithoutCancel(c))
+		if err != nil {
+			log.Errorf("%s pass failed: %+v", name, err)
+		}
+		if err == nil && done > 0 {
+			continue
+		}
+
+		select {
+		case <-c.Done():
+		case <-time.After(interval):
+		}
+	}
+	log.Infof("%s stopped", name)
+}
+
+// DispatchOnce publishes the oldest unpublished event of each account that
+// is due, returning how many were published. An event that fails is retried
+// after a growing delay, and the later events of its account wait for it.
+// The events are claimed before they are published, so dispatchers running
+// in several processes never publish the same event at once, and no
+// transaction is held open while the publisher is waited on.
+func (d *OutboxDispatcher) DispatchOnce(c context.Context) (int, error) {
+	events, err := d.claim(c)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	published := 0
+	for i := range events {
+		event := &events[i]
+		if publishErr := d.Publisher.Publish(c, event.Event()); publishErr != nil {
+			event.Attempts++
+			delay := event.RetryDelay(d.Policy.RetryDelay, d.Policy.MaxRetryDelay)
+			d.Log.WithContext(c).Warnf("Failed to publish %s event %d, attempt %d, retrying in %s: %v", event.Type, event.ID, event.Attempts, delay, publishErr)
+			event.NextAttemptAt = time.Now().Add(delay)
+			event.LastError = publishErr.Error()
+			continue
+		}
+		now := time.Now()
+		event.PublishedAt = &now
+		published++
+	}
+
+	// An event whose result is not recorded is published again once its
+	// claim runs out.
+	if err := d.Store.Transaction(c, func(tx repository.Repositories) error {
+		for i := range events {
+			if err := tx.Outbox().Update(&events[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	return published, nil
+}
+
+// claim returns the events due to be published, pushing their next attempt
+// back by the claim timeout, so other dispatchers leave them alone until this
+// one has recorded how publishing them went, or until it is presumed to have
+// died.
+func (d *OutboxDispatcher) claim(c context.Context) ([]model.OutboxEvent, error) {
+	var events []model.OutboxEvent
+	err := d.Store.Transaction(c, func(tx repository.Repositories) error {
+		now := time.Now()
+		var err error
+		events, err = tx.Outbox().Claim(now, d.Policy.BatchSize, now.Add(d.Policy.ClaimTimeout))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"account-service/src/model"
+	"account-service/src/pii"
+	"account-service/src/utils"
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// piiRotationBatchSize is the number of accounts read by each query of a
+// rotation.
+const piiRotationBatchSize = 500
+
+// PIIService re-encrypts the ID numbers and phone numbers of accounts and the
+// secrets of webhook subscriptions.
+type PIIService struct {
+	Log     *logrus.Logger
+	DB      *gorm.DB
+	Keyring *pii.Keyring
+}
+
+func NewPIIService(db *gorm.DB, keyring *pii.Keyring) *PIIService {
+	return &PIIService{
+		Log:     utils.Log,
+		DB:      db,
+		Keyring: keyring,
+	}
+}
+
+// accountPII holds the columns of an account with its ID number and phone
+// number as they are stored, encrypted or not.
+type accountPII struct {
+	ID              uint
+	IDNumber        string
+	PhoneNumber     string
+	IDNumberHash    *string
+	PhoneNumberHash *string
+}
+
+// Rotate encrypts with the active key of the keyring of s the ID numbers
+// and phone numbers of the accounts and the webhook secrets still encrypted
+// with another key or not encrypted at all, and indexes the accounts again
+// where their blind index is missing or stale. It is run after a new key is
+// made active, before the previous one is retired; running it again
+// rewrites nothing.
+func (s *PIIService) Rotate(c context.Context) (*model.PIIRotationResult, *fiber.Error) {
+	result, err := s.rewrite(c, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.rewriteSecrets(c, result, false); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// unindexed selects the accounts written before the migration to encrypted
+// columns, which have no blind index yet.
+const unindexed = "id_number_hash IS NULL OR phone_number_hash IS NULL"
+
+// Backfill encrypts and indexes the ID numbers and phone numbers of the
+// accounts that have no blind index yet, without which their duplicates go
+// unnoticed, and encrypts the webhook secrets written before encryption. It
+// is run whenever the migrations are applied.
+func (s *PIIService) Backfill(c context.Context) (*model.PIIRotationResult, *fiber.Error) {
+	result, err := s.rewrite(c, unindexed)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.rewriteSecrets(c, result, true); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Unindexed counts the accounts that have no blind index yet.
+func (s *PIIService) Unindexed(c context.Context) (int64, *fiber.Error) {
+	var count int64
+	if err := s.DB.WithContext(c).Table("accounts").Where(unindexed).Count(&count).Error; err != nil {
+		s.Log.WithContext(c).Errorf("Error counting the accounts without a blind index: %+v", err)
+		return 0, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+	return count, nil
+}
+
+// rewrite rotates the PII of the accounts matching condition, or of every
+// account if condition is empty.
+func (s *PIIService) rewrite(c context.Context, condition string) (*model.PIIRotationResult, *fiber.Error) {
+	result := &model.PIIRotationResult{}
+	var lastID uint
+	for {
+		query := s.DB.WithContext(c).Table("accounts").
+			Select("id, id_number, phone_number, id_number_hash, phone_number_hash").
+			Where("id > ?", lastID)
+		if condition != "" {
+			query = query.Where("(" + condition + ")")
+		}
+		var batch []accountPII
+		if err := query.Order("id").Limit(piiRotationBatchSize).Find(&batch).Error; err != nil {
+			s.Log.WithContext(c).Errorf("Error reading the account PII: %+v", err)
+			return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+		}
+		if len(batch) == 0 {
+			return result, nil
+		}
+
+		for _, account := range batch {
+			rewritten, err := s.rotate(c, account)
+			if err != nil {
+				s.Log.WithContext(c).Errorf("Error rotating the PII of account %d: %+v", account.ID, err)
+				return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to rotate the PII of an account")
+			}
+			result.Accounts++
+			if rewritten {
+				result.Rewritten++
+			}
+		}
+		lastID = batch[len(batch)-1].ID
+	}
+}
+
+// rotate rewrites the PII of an account if it needs to, reporting whether it
+// did.
+func (s *PIIService) rotate(c context.Context, account accountPII) (bool, error) {
+	updates := map[string]interface{}{}
+	for _, column := range []struct {
+		name, value string
+		hash        *string
+	}{
+		{"id_number", account.IDNumber, account.IDNumberHash},
+		{"phone_number", account.PhoneNumber, account.PhoneNumberHash},
+	} {
+		plaintext, err := s.Keyring.Decrypt(column.value)
+		if err != nil {
+			return false, err
+		}
+		if version, _, ok := pii.Version(column.value); !ok || version != s.Keyring.ActiveVersion() {
+			encrypted, err := s.Keyring.Encrypt(plaintext)
+			if err != nil {
+				return false, err
+			}
+			updates[column.name] = encrypted
+		}
+		if hash := s.Keyring.BlindIndex(plaintext); column.hash == nil || *column.hash != hash {
+			updates[column.name+"_hash"] = hash
+		}
+	}
+	if len(updates) == 0 {
+		return false, nil
+	}
+	return true, s.DB.WithContext(c).Table("accounts").Where("id = ?", account.ID).UpdateColumns(updates).Error
+}
+
+// subscriptionSecrets holds the secrets of a webhook subscription as they
+// are stored, encrypted or not.
+type subscriptionSecrets struct {
+	ID             uint
+	Secret         string
+	PreviousSecret *string
+}
+
+// rewriteSecrets encrypts with the active key the webhook secrets that are
+// not encrypted, or, unless plaintextOnly, encrypted with another key,
+// counting them in result. Subscriptions are few, so they are read at once.
+func (s *PIIService) rewriteSecrets(c context.Context, result *model.PIIRotationResult, plaintextOnly bool) *fiber.Error {
+	var subscriptions []subscriptionSecrets
+	if err := s.DB.WithContext(c).Table("webhook_subscriptions").
+		Select("id, secret, previous_secret").Order("id").Find(&subscriptions).Error; err != nil {
+		s.Log.WithContext(c).Errorf("Error reading the webhook secrets: %+v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	for _, subscription := range subscriptions {
+		updates := map[string]interface{}{}
+		for _, column := range []struct {
+			name  string
+			value *string
+		}{
+			{"secret", &subscription.Secret},
+			{"previous_secret", subscription.PreviousSecret},
+		} {
+			if column.value == nil || *column.value == "" {
+				continue
+			}
+			version, _, ok := pii.Version(*column.value)
+			if ok && (plaintextOnly || version == s.Keyring.ActiveVersion()) {
+				continue
+			}
+			encrypted, err := s.reencrypt(*column.value)
+			if err != nil {
+				s.Log.WithContext(c).Errorf("Error rotating the secrets of webhook subscription %d: %+v", subscription.ID, err)
+				return fiber.NewError(fiber.StatusInternalServerError, "Failed to rotate the secrets of a webhook subscription")
+			}
+			updates[column.name] = encrypted
+		}
+
+		result.Subscriptions++
+		if len(updates) == 0 {
+			continue
+		}
+		if err := s.DB.WithContext(c).Table("webhook_subscriptions").Where("id = ?", subscription.ID).UpdateColumns(updates).Error; err != nil {
+			s.Log.WithContext(c).Errorf("Error writing the secrets of webhook subscription %d: %+v", subscription.ID, err)
+			return fiber.NewError(fiber.StatusInternalServerError, "Database error")
+		}
+		result.SubscriptionsRewritten++
+	}
+	return nil
+}
+
+// reencrypt encrypts value, encrypted or not, with the active key.
+func (s *PIIService) reencrypt(value string) (string, error) {
+	plaintext, err := s.Keyring.Decrypt(value)
+	if err != nil {
+		return "", err
+	}
+	return s.Keyring.Encrypt(plaintext)
+}
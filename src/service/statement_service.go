@@ -0,0 +1,120 @@
+package service
+
+import (
+	"account-service/src/model"
+	"account-service/src/repository"
+	"account-service/src/utils"
+	"context"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// statementBatchSize is the number of lines read from the database at a time
+// while a statement is written.
+const statementBatchSize = 500
+
+type StatementService interface {
+	Statement(c context.Context, accountNumber string, req *model.StatementRequest) (*model.Statement, *fiber.Error)
+	StreamLines(c context.Context, statement *model.Statement, write func(line *model.StatementLine) error) error
+}
+
+type statementService struct {
+	Log      *logrus.Logger
+	Store    repository.UnitOfWork
+	Ledger   *Ledger
+	Validate *validator.Validate
+	Location *time.Location // Time zone of the business day
+}
+
+func NewStatementService(store repository.UnitOfWork, ledger *Ledger, validate *validator.Validate, location *time.Location) StatementService {
+	return &statementService{
+		Log:      utils.Log,
+		Store:    store,
+		Ledger:   ledger,
+		Validate: validate,
+		Location: location,
+	}
+}
+
+// Statement returns the opening and closing balances and the totals of the
+// statement of an account over an inclusive range of business days, without
+// its lines. The statement of a closed account stays available.
+func (s *statementService) Statement(c context.Context, accountNumber string, req *model.StatementRequest) (*model.Statement, *fiber.Error) {
+
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if req.Format == "" {
+		req.Format = model.StatementFormatCSV
+	}
+
+	start, end, err := mutationPeriod(&model.Mutation{From: req.From, To: req.To}, time.Now().In(s.Location))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	repos := s.Store.Repositories(c)
+	account, fiberErr := s.Ledger.findAccount(repos, accountNumber)
+	if fiberErr != nil {
+		return nil, fiberErr
+	}
+
+	statement, err := repos.CashActivities().Totals(account.ID, start, end)
+	if err != nil {
+		s.Log.WithContext(c).Errorf("Failed to sum statement activities: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+	statement.AccountNumber = account.AccountNumber
+	statement.FullName = account.FullName
+	statement.From = start
+	statement.To = end
+	statement.GeneratedAt = time.Now().In(s.Location)
+	statement.AccountID = account.ID
+
+	statement.OpeningBalance, err = repos.CashActivities().BalanceAt(account.ID, start)
+	if err != nil {
+		s.Log.WithContext(c).Errorf("Failed to get opening balance: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	statement.ClosingBalance = statement.OpeningBalance
+	if statement.LastActivityID != 0 {
+		last, err := repos.CashActivities().Find(statement.LastActivityID)
+		if err != nil {
+			s.Log.WithContext(c).Errorf("Failed to get closing balance: %+v", err)
+			return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+		}
+		statement.ClosingBalance = last.BalanceAfter
+	}
+
+	return statement, nil
+}
+
+// StreamLines passes the lines of statement to write oldest first, reading
+// them in batches so a statement of any length is never held in memory. It
+// stops at the first error of write.
+func (s *statementService) StreamLines(c context.Context, statement *model.Statement, write func(line *model.StatementLine) error) error {
+	var lastID uint
+	for lastID < statement.LastActivityID {
+		lines, err := s.Store.Repositories(c).CashActivities().
+			StatementLines(statement.AccountID, statement.From, lastID, statement.LastActivityID, statementBatchSize)
+		if err != nil {
+			s.Log.WithContext(c).Errorf("Failed to get statement lines: %+v", err)
+			return err
+		}
+		if len(lines) == 0 {
+			break
+		}
+
+		for i := range lines {
+			if err := write(&lines[i]); err != nil {
+				return err
+			}
+		}
+		lastID = lines[len(lines)-1].ID
+	}
+	return nil
+}
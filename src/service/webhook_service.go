@@ -0,0 +1,420 @@
+package service
+
+import (
+	"account-service/src/model"
+	"account-service/src/publisher"
+	"account-service/src/repository"
+	"account-service/src/utils"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+type WebhookService interface {
+	ListSubscriptions(c context.Context) ([]model.WebhookSubscriptionResponse, *fiber.Error)
+	CreateSubscription(c context.Context, req *model.WebhookSubscriptionRequest) (*model.WebhookSubscriptionResponse, *fiber.Error)
+	UpdateSubscription(c context.Context, id uint, req *model.WebhookSubscriptionRequest) (*model.WebhookSubscriptionResponse, *fiber.Error)
+	RotateSecret(c context.Context, id uint) (*model.WebhookSubscriptionResponse, *fiber.Error)
+	DeleteSubscription(c context.Context, id uint) *fiber.Error
+	ListDeliveries(c context.Context, subscriptionID uint, query *model.WebhookDeliveryQuery) ([]model.WebhookDelivery, int64, *fiber.Error)
+	GetDelivery(c context.Context, id uint) (*model.WebhookDelivery, *fiber.Error)
+	Redeliver(c context.Context, id uint) (*model.WebhookDelivery, *fiber.Error)
+}
+
+type webhookService struct {
+	Log      *logrus.Logger
+	Store    repository.UnitOfWork
+	Validate *validator.Validate
+	Policy   model.WebhookPolicy
+}
+
+func NewWebhookService(store repository.UnitOfWork, validate *validator.Validate, policy model.WebhookPolicy) WebhookService {
+	return &webhookService{
+		Log:      utils.Log,
+		Store:    store,
+		Validate: validate,
+		Policy:   policy,
+	}
+}
+
+var (
+	ErrWebhookSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrWebhookSubscriptionInactive = errors.New("webhook subscription is not active")
+	ErrWebhookDeliveryNotFound     = errors.New("webhook delivery not found")
+)
+
+const (
+	defaultWebhookDeliveryPage  = 1
+	defaultWebhookDeliveryLimit = 10
+)
+
+// ListSubscriptions lists every webhook subscription, oldest first.
+func (s *webhookService) ListSubscriptions(c context.Context) ([]model.WebhookSubscriptionResponse, *fiber.Error) {
+	subscriptions, err := s.Store.Repositories(c).WebhookSubscriptions().List(false)
+	if err != nil {
+		s.Log.WithContext(c).Errorf("Failed to get webhook subscriptions: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	responses := make([]model.WebhookSubscriptionResponse, len(subscriptions))
+	for i := range subscriptions {
+		responses[i] = subscriptions[i].Response()
+	}
+	return responses, nil
+}
+
+// CreateSubscription registers a URL for the events of the requested types
+// and returns it with its secret, which is not shown again.
+func (s *webhookService) CreateSubscription(c context.Context, req *model.WebhookSubscriptionRequest) (*model.WebhookSubscriptionResponse, *fiber.Error) {
+
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		s.Log.WithContext(c).Errorf("Failed to generate webhook secret: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Internal server error")
+	}
+
+	subscription := model.WebhookSubscription{
+		URL:        req.URL,
+		EventTypes: strings.Join(req.EventTypes, ","),
+		Active:     req.Active == nil || *req.Active,
+		Secret:     secret,
+	}
+	if err := s.Store.Repositories(c).WebhookSubscriptions().Create(&subscription); err != nil {
+		s.Log.WithContext(c).Errorf("Failed to create webhook subscription: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	response := subscription.Response()
+	response.Secret = subscription.Secret
+	return &response, nil
+}
+
+// UpdateSubscription changes the URL and event types of a subscription, and
+// whether it is active when the request says so. Deliveries of an inactive
+// subscription are held until it is active again.
+func (s *webhookService) UpdateSubscription(c context.Context, id uint, req *model.WebhookSubscriptionRequest) (*model.WebhookSubscriptionResponse, *fiber.Error) {
+
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	subscription, fiberErr := s.findSubscription(c, id)
+	if fiberErr != nil {
+		return nil, fiberErr
+	}
+
+	subscription.URL = req.URL
+	subscription.EventTypes = strings.Join(req.EventTypes, ",")
+	if req.Active != nil {
+		subscription.Active = *req.Active
+	}
+	if err := s.Store.Repositories(c).WebhookSubscriptions().Update(subscription); err != nil {
+		s.Log.WithContext(c).Errorf("Failed to update webhook subscription: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	response := subscription.Response()
+	return &response, nil
+}
+
+// RotateSecret gives a subscription a new secret and returns it. Deliveries
+// are also signed with the old secret for the grace period, so the partner
+// can switch without rejecting any.
+func (s *webhookService) RotateSecret(c context.Context, id uint) (*model.WebhookSubscriptionResponse, *fiber.Error) {
+	subscription, fiberErr := s.findSubscription(c, id)
+	if fiberErr != nil {
+		return nil, fiberErr
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		s.Log.WithContext(c).Errorf("Failed to generate webhook secret: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Internal server error")
+	}
+
+	expiresAt := time.Now().Add(s.Policy.SecretGracePeriod)
+	subscription.PreviousSecret = subscription.Secret
+	subscription.PreviousSecretExpiresAt = &expiresAt
+	subscription.Secret = secret
+	if err := s.Store.Repositories(c).WebhookSubscriptions().UpdateSecrets(subscription); err != nil {
+		s.Log.WithContext(c).Errorf("Failed to rotate webhook secret: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	response := subscription.Response()
+	response.Secret = subscription.Secret
+	return &response, nil
+}
+
+// DeleteSubscription deletes a subscription with its deliveries.
+func (s *webhookService) DeleteSubscription(c context.Context, id uint) *fiber.Error {
+	if err := s.Store.Repositories(c).WebhookSubscriptions().Delete(id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, ErrWebhookSubscriptionNotFound.Error())
+		}
+		s.Log.WithContext(c).Errorf("Failed to delete webhook subscription: %+v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+	return nil
+}
+
+// ListDeliveries lists the deliveries of a subscription, newest first.
+func (s *webhookService) ListDeliveries(c context.Context, subscriptionID uint, query *model.WebhookDeliveryQuery) ([]model.WebhookDelivery, int64, *fiber.Error) {
+
+	if err := s.Validate.Struct(query); err != nil {
+		return nil, 0, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if query.Page == 0 {
+		query.Page = defaultWebhookDeliveryPage
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultWebhookDeliveryLimit
+	}
+
+	if _, err := s.findSubscription(c, subscriptionID); err != nil {
+		return nil, 0, err
+	}
+
+	deliveries, total, err := s.Store.Repositories(c).WebhookDeliveries().
+		List(subscriptionID, query.Status, (query.Page-1)*query.Limit, query.Limit)
+	if err != nil {
+		s.Log.WithContext(c).Errorf("Failed to get webhook deliveries: %+v", err)
+		return nil, 0, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+	return deliveries, total, nil
+}
+
+// GetDelivery returns a delivery with the log of its attempts, oldest first.
+func (s *webhookService) GetDelivery(c context.Context, id uint) (*model.WebhookDelivery, *fiber.Error) {
+	delivery, err := s.Store.Repositories(c).WebhookDeliveries().Find(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, ErrWebhookDeliveryNotFound.Error())
+		}
+		s.Log.WithContext(c).Errorf("Failed to get webhook delivery: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+	return delivery, nil
+}
+
+// Redeliver makes a delivery again as soon as possible with a fresh set of
+// attempts, whether it is dead, pending or was already delivered.
+func (s *webhookService) Redeliver(c context.Context, id uint) (*model.WebhookDelivery, *fiber.Error) {
+	var delivery *model.WebhookDelivery
+	err := s.Store.Transaction(c, func(tx repository.Repositories) error {
+		var err error
+		delivery, err = tx.WebhookDeliveries().Lock(id)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, ErrWebhookDeliveryNotFound.Error())
+			}
+			return err
+		}
+
+		subscription, err := tx.WebhookSubscriptions().Find(delivery.SubscriptionID)
+		if err != nil {
+			return err
+		}
+		if !subscription.Active {
+			return fiber.NewError(fiber.StatusConflict, ErrWebhookSubscriptionInactive.Error())
+		}
+
+		delivery.Status = model.WebhookDeliveryPending
+		delivery.Attempts = 0
+		delivery.NextAttemptAt = time.Now()
+		return tx.WebhookDeliveries().Update(delivery)
+	})
+	if err != nil {
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			return nil, fiberErr
+		}
+		s.Log.WithContext(c).Errorf("Failed to redeliver webhook delivery: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+	return delivery, nil
+}
+
+func (s *webhookService) findSubscription(c context.Context, id uint) (*model.WebhookSubscription, *fiber.Error) {
+	subscription, err := s.Store.Repositories(c).WebhookSubscriptions().Find(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, ErrWebhookSubscriptionNotFound.Error())
+		}
+		s.Log.WithContext(c).Errorf("Failed to get webhook subscription: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+	return subscription, nil
+}
+
+// newWebhookSecret returns a random secret to sign deliveries with.
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
+
+// WebhookDeliverer fans the events of the outbox out to the webhook
+// subscriptions and posts them in the background.
+type WebhookDeliverer struct {
+	Log    *logrus.Logger
+	Store  repository.UnitOfWork
+	Policy model.WebhookPolicy
+	Client *http.Client
+}
+
+func NewWebhookDeliverer(store repository.UnitOfWork, policy model.WebhookPolicy) *WebhookDeliverer {
+	return &WebhookDeliverer{
+		Log:    utils.Log,
+		Store:  store,
+		Policy: policy,
+		Client: &http.Client{Timeout: policy.Timeout},
+	}
+}
+
+// Publish records a delivery of event for every active subscription to its
+// type, which makes the deliverer a publisher for the outbox dispatcher. An
+// event published again is not delivered again.
+func (d *WebhookDeliverer) Publish(c context.Context, event *model.Event) error {
+	repos := d.Store.Repositories(c)
+	subscriptions, err := repos.WebhookSubscriptions().List(true)
+	if err != nil {
+		return err
+	}
+
+	var deliveries []model.WebhookDelivery
+	now := time.Now()
+	for i := range subscriptions {
+		if subscriptions[i].Subscribes(event.Type) {
+			deliveries = append(deliveries, model.WebhookDelivery{
+				SubscriptionID: subscriptions[i].ID,
+				OutboxID:       event.ID,
+				EventType:      event.Type,
+				Status:         model.WebhookDeliveryPending,
+				NextAttemptAt:  now,
+			})
+		}
+	}
+	return repos.WebhookDeliveries().CreateAll(deliveries)
+}
+
+// Run makes deliveries until c is cancelled.
+func (d *WebhookDeliverer) Run(c context.Context) {
+	poll(c, d.Log, "Webhook deliverer", d.Policy.PollInterval, d.DeliverOnce)
+}
+
+// DeliverOnce posts the pending deliveries that are due, returning how many
+// were delivered. A delivery that fails is retried after a growing delay
+// until it has failed MaxAttempts times, when it is dead. Every post is
+// recorded in the attempt log. The deliveries are locked while they are
+// posted, so deliverers running in several processes never post the same
+// one at once.
+func (d *WebhookDeliverer) DeliverOnce(c context.Context) (int, error) {
+	delivered := 0
+	err := d.Store.Transaction(c, func(tx repository.Repositories) error {
+		deliveries, err := tx.WebhookDeliveries().Claim(time.Now(), d.Policy.BatchSize)
+		if err != nil {
+			return err
+		}
+		if len(deliveries) == 0 {
+			return nil
+		}
+
+		subscriptionIDs := make([]uint, len(deliveries))
+		outboxIDs := make([]uint, len(deliveries))
+		for i := range deliveries {
+			subscriptionIDs[i] = deliveries[i].SubscriptionID
+			outboxIDs[i] = deliveries[i].OutboxID
+		}
+		subscriptions, err := tx.WebhookSubscriptions().FindAll(subscriptionIDs)
+		if err != nil {
+			return err
+		}
+		events, err := tx.Outbox().FindAll(outboxIDs)
+		if err != nil {
+			return err
+		}
+		subscriptionByID := make(map[uint]*model.WebhookSubscription, len(subscriptions))
+		for i := range subscriptions {
+			subscriptionByID[subscriptions[i].ID] = &subscriptions[i]
+		}
+		eventByID := make(map[uint]*model.OutboxEvent, len(events))
+		for i := range events {
+			eventByID[events[i].ID] = &events[i]
+		}
+
+		for i := range deliveries {
+			delivery := &deliveries[i]
+			ok, err := d.deliver(c, tx, delivery, subscriptionByID[delivery.SubscriptionID], eventByID[delivery.OutboxID])
+			if err != nil {
+				return err
+			}
+			if ok {
+				delivered++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return delivered, nil
+}
+
+// deliver posts one delivery and records the outcome, reporting whether it
+// was delivered.
+func (d *WebhookDeliverer) deliver(c context.Context, tx repository.Repositories, delivery *model.WebhookDelivery, subscription *model.WebhookSubscription, event *model.OutboxEvent) (bool, error) {
+	start := time.Now()
+	webhook := publisher.Webhook{URL: subscription.URL, Secrets: subscription.Secrets(start), Client: d.Client}
+	statusCode, postErr := webhook.Post(c, event.Event())
+
+	attempt := model.WebhookDeliveryAttempt{
+		DeliveryID: delivery.ID,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	if statusCode != 0 {
+		attempt.StatusCode = &statusCode
+	}
+	delivery.LastStatusCode = attempt.StatusCode
+	if postErr == nil {
+		deliveredAt := time.Now()
+		delivery.Status = model.WebhookDeliveryDelivered
+		delivery.DeliveredAt = &deliveredAt
+		delivery.LastError = ""
+	} else {
+		attempt.Error = postErr.Error()
+		delivery.Attempts++
+		delivery.LastError = attempt.Error
+		if delivery.Attempts >= d.Policy.MaxAttempts {
+			d.Log.WithContext(c).Warnf("Webhook delivery %d of %s event %d to subscription %d is dead after %d attempts: %v", delivery.ID, delivery.EventType, delivery.OutboxID, delivery.SubscriptionID, delivery.Attempts, postErr)
+			delivery.Status = model.WebhookDeliveryDead
+		} else {
+			delay := delivery.RetryDelay(d.Policy.RetryDelay, d.Policy.MaxRetryDelay)
+			d.Log.WithContext(c).Warnf("Failed webhook delivery %d, attempt %d, retrying in %s: %v", delivery.ID, delivery.Attempts, delay, postErr)
+			delivery.NextAttemptAt = time.Now().Add(delay)
+		}
+	}
+
+	if err := tx.WebhookDeliveries().CreateAttempt(&attempt); err != nil {
+		return false, err
+	}
+	if err := tx.WebhookDeliveries().Update(delivery); err != nil {
+		return false, err
+	}
+	return postErr == nil, nil
+}
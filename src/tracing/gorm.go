@@ -0,0 +1,94 @@
+package tracing
+
+import (
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// spanKey is the key of the span of a statement in its instance settings.
+const spanKey = "tracing:span"
+
+// GORM returns the GORM plugin tracing every query in a span, child of the
+// span in the context of the query. Queries outside a trace, like the polling
+// of the background workers, are not traced. The spans carry the SQL with its
+// placeholders, never the values bound to them.
+func GORM() gorm.Plugin {
+	return gormPlugin{}
+}
+
+type gormPlugin struct{}
+
+func (gormPlugin) Name() string {
+	return "tracing"
+}
+
+func (gormPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	return errors.Join(
+		callback.Create().Before("gorm:create").Register("tracing:before_create", startSpan),
+		callback.Create().After("gorm:create").Register("tracing:after_create", endSpan),
+		callback.Query().Before("gorm:query").Register("tracing:before_query", startSpan),
+		callback.Query().After("gorm:query").Register("tracing:after_query", endSpan),
+		callback.Update().Before("gorm:update").Register("tracing:before_update", startSpan),
+		callback.Update().After("gorm:update").Register("tracing:after_update", endSpan),
+		callback.Delete().Before("gorm:delete").Register("tracing:before_delete", startSpan),
+		callback.Delete().After("gorm:delete").Register("tracing:after_delete", endSpan),
+		callback.Row().Before("gorm:row").Register("tracing:before_row", startSpan),
+		callback.Row().After("gorm:row").Register("tracing:after_row", endSpan),
+		callback.Raw().Before("gorm:raw").Register("tracing:before_raw", startSpan),
+		callback.Raw().After("gorm:raw").Register("tracing:after_raw", endSpan),
+	)
+}
+
+func startSpan(db *gorm.DB) {
+	if db.Statement.Context == nil || !trace.SpanContextFromContext(db.Statement.Context).IsValid() {
+		return
+	}
+	_, span := Tracer().Start(db.Statement.Context, "gorm",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemKey.String(dbSystem(db))),
+	)
+	db.InstanceSet(spanKey, span)
+}
+
+// endSpan names the span of a statement after the SQL it ran, like
+// "SELECT accounts", which is only known once the statement has run.
+func endSpan(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	defer span.End()
+
+	query := db.Statement.SQL.String()
+	name := "gorm"
+	if fields := strings.Fields(query); len(fields) > 0 {
+		name = strings.ToUpper(fields[0])
+		span.SetAttributes(semconv.DBOperationName(name))
+	}
+	if db.Statement.Table != "" {
+		name += " " + db.Statement.Table
+		span.SetAttributes(semconv.DBCollectionName(db.Statement.Table))
+	}
+	span.SetName(name)
+	span.SetAttributes(semconv.DBQueryText(query))
+
+	if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// dbSystem returns the OpenTelemetry name of the database of db.
+func dbSystem(db *gorm.DB) string {
+	if db.Dialector.Name() == "postgres" {
+		return semconv.DBSystemPostgreSQL.Value.AsString()
+	}
+	return db.Dialector.Name()
+}
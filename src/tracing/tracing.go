@@ -0,0 +1,81 @@
+// Package tracing traces requests with OpenTelemetry through the middleware
+// chain, the services and the database queries, continuing the traces of
+// callers that send a W3C traceparent header.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the spans of the service.
+const ScopeName = "account-service"
+
+// Kinds of exporters accepted by Setup.
+const (
+	KindOTLP   = "otlp"
+	KindStdout = "stdout"
+	KindNone   = "none"
+)
+
+// Tracer returns the tracer of the service from the global tracer provider,
+// so that spans go to whichever provider is installed when they start.
+func Tracer() trace.Tracer {
+	return otel.Tracer(ScopeName)
+}
+
+// Setup installs the global tracer provider, exporting the sampled spans with
+// the exporter of kind, and the W3C trace context propagator. endpoint is the
+// URL of the OTLP/HTTP collector. The returned function flushes the spans
+// still buffered and stops the provider.
+func Setup(c context.Context, kind, endpoint, serviceName string, sampleRatio float64) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch kind {
+	case KindNone:
+		return func(context.Context) error { return nil }, nil
+	case KindStdout:
+		exporter, err = stdouttrace.New()
+	case KindOTLP:
+		exporter, err = otlptracehttp.New(c, otlptracehttp.WithEndpointURL(endpoint))
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// End ends span, recording err if any. Client errors are part of the normal
+// operation of the service, so only server errors mark the span as failed.
+func End(span trace.Span, err *fiber.Error) {
+	if err != nil {
+		span.SetAttributes(semconv.ErrorTypeKey.String(strconv.Itoa(err.Code)))
+		if err.Code >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, err.Message)
+		}
+	}
+	span.End()
+}
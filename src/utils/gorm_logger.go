@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// GormLogger routes the logs of GORM through a logrus logger, with the
+// request ID of the query. Queries are logged at debug level, slow ones at
+// warning level and failed ones at error level, always with their parameters
+// left out: they hold ID numbers, phone numbers and PIN hashes.
+type GormLogger struct {
+	Log           *logrus.Logger
+	SlowThreshold time.Duration // 0 never reports a query as slow
+	Level         logger.LogLevel
+}
+
+func NewGormLogger(log *logrus.Logger, slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{Log: log, SlowThreshold: slowThreshold, Level: logger.Info}
+}
+
+func (l *GormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	copied := *l
+	copied.Level = level
+	return &copied
+}
+
+func (l *GormLogger) Info(c context.Context, msg string, args ...interface{}) {
+	if l.Level >= logger.Info {
+		l.Log.WithContext(c).Infof(msg, args...)
+	}
+}
+
+func (l *GormLogger) Warn(c context.Context, msg string, args ...interface{}) {
+	if l.Level >= logger.Warn {
+		l.Log.WithContext(c).Warnf(msg, args...)
+	}
+}
+
+func (l *GormLogger) Error(c context.Context, msg string, args ...interface{}) {
+	if l.Level >= logger.Error {
+		l.Log.WithContext(c).Errorf(msg, args...)
+	}
+}
+
+// Trace logs a query once it has run. A missing record is not an error: the
+// services look records up to find out whether they exist.
+func (l *GormLogger) Trace(c context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.Level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+	slow := l.SlowThreshold > 0 && elapsed > l.SlowThreshold
+	switch {
+	case failed && l.Level >= logger.Error:
+	case slow && l.Level >= logger.Warn:
+	case l.Level >= logger.Info && l.Log.IsLevelEnabled(logrus.DebugLevel):
+	default:
+		return
+	}
+
+	sql, rows := fc()
+	entry := l.Log.WithContext(c).WithFields(logrus.Fields{
+		"sql":         sql,
+		"rows":        rows,
+		"duration_ms": float64(elapsed.Microseconds()) / 1000,
+	})
+	switch {
+	case failed:
+		entry.WithError(err).Error("Query failed")
+	case slow:
+		entry.Warn(fmt.Sprintf("Slow query, over %s", l.SlowThreshold))
+	default:
+		entry.Debug("Query")
+	}
+}
+
+// redactedParam stands for every parameter of a logged query.
+const redactedParam = "***"
+
+// ParamsFilter replaces the parameters of the logged queries with
+// redactedParam.
+func (l *GormLogger) ParamsFilter(c context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	redacted := make([]interface{}, len(params))
+	for i := range redacted {
+		redacted[i] = redactedParam
+	}
+	return sql, redacted
+}
@@ -1,15 +1,25 @@
 package utils
 
 import (
+	"account-service/src/pii"
+	"context"
 	"os"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type CustomFormatter struct {
 	logrus.TextFormatter
 }
 
+// Formats of the log.
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
 var Log *logrus.Logger
 
 func init() {
@@ -25,4 +35,70 @@ func init() {
 	})
 
 	Log.SetOutput(os.Stdout)
+	Log.AddHook(contextHook{})
+	Log.AddHook(maskHook{})
+}
+
+// ConfigureLog sets the format and the level of Log. The JSON format writes
+// one object per line, with the time in full.
+func ConfigureLog(format string, level logrus.Level) {
+	if format == LogFormatJSON {
+		Log.SetFormatter(&logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano})
+	}
+	Log.SetLevel(level)
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of c carrying the ID of the request it serves.
+func WithRequestID(c context.Context, id string) context.Context {
+	return context.WithValue(c, requestIDKey{}, id)
+}
+
+// RequestID returns the ID of the request c serves, or "" outside a request.
+func RequestID(c context.Context) string {
+	id, _ := c.Value(requestIDKey{}).(string)
+	return id
+}
+
+// contextHook adds the request ID and the trace ID of the context of an entry,
+// logged through Log.WithContext, to its fields.
+type contextHook struct{}
+
+func (contextHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (contextHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	if id := RequestID(entry.Context); id != "" {
+		entry.Data["request_id"] = id
+	}
+	if span := trace.SpanContextFromContext(entry.Context); span.IsValid() {
+		entry.Data["trace_id"] = span.TraceID().String()
+	}
+	return nil
+}
+
+// maskHook masks the ID numbers and phone numbers in the message and in the
+// text fields of every entry.
+type maskHook struct{}
+
+func (maskHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (maskHook) Fire(entry *logrus.Entry) error {
+	entry.Message = pii.MaskText(entry.Message)
+	for key, value := range entry.Data {
+		switch v := value.(type) {
+		case string:
+			entry.Data[key] = pii.MaskText(v)
+		case error:
+			entry.Data[key] = pii.MaskText(v.Error())
+		}
+	}
+	return nil
 }
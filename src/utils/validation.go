@@ -1,8 +1,10 @@
 package utils
 
 import (
+	"account-service/src/model"
 	"errors"
 	"fmt"
+	"reflect"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -58,5 +60,16 @@ func defaultErrorMessage(err validator.FieldError) string {
 func Validator() *validator.Validate {
 	validate := validator.New()
 
+	// Validate money as its minor units, so tags such as required and gt=0
+	// keep working on model.Money fields.
+	validate.RegisterCustomTypeFunc(moneyValue, model.Money{})
+
 	return validate
 }
+
+func moneyValue(field reflect.Value) interface{} {
+	if money, ok := field.Interface().(model.Money); ok {
+		return money.MinorUnits()
+	}
+	return nil
+}
@@ -8,5 +8,6 @@ var (
 		FullName:    "Test User",
 		IDNumber:    "1234567890123456",
 		PhoneNumber: "081234567890",
+		PIN:         "123456",
 	}
 )
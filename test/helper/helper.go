@@ -5,6 +5,7 @@ import (
 	"account-service/src/model"
 	"account-service/src/router"
 	"account-service/src/utils"
+	"account-service/test"
 	"errors"
 	"fmt"
 	"net/http"
@@ -16,13 +17,38 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/helmet"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+// TestPIN is the transaction PIN of the accounts created by CreateAccount and
+// CreateTestAccount.
+const TestPIN model.PIN = "123456"
+
+// DefaultProductRateBPS is the rate the migrations give the default product.
+const DefaultProductRateBPS = 100
+
+// testPINHash is TestPIN hashed at the lowest cost, to keep tests fast.
+var testPINHash = func() string {
+	hash, err := bcrypt.GenerateFromPassword([]byte(TestPIN), bcrypt.MinCost)
+	if err != nil {
+		logrus.Fatalf("Failed to hash test PIN: %+v", err)
+	}
+	return string(hash)
+}()
+
 // ClearAll clears all data from the account and cash_activity tables.  USE WITH CAUTION.
 func ClearAll(db *gorm.DB) {
+	ClearInterest(db)
+	ClearJournalEntries(db)
 	ClearCashActivities(db)
+	ClearFeeSchedules(db)
+	ClearAccountStatusHistories(db)
+	ClearAccountWithdrawalLimits(db)
 	ClearAccounts(db)
+	ClearIdempotencyKeys(db)
+	ClearWebhooks(db)
+	ClearOutbox(db)
 }
 
 // ClearAccounts deletes all accounts from the database.
@@ -32,6 +58,38 @@ func ClearAccounts(db *gorm.DB) {
 	}
 }
 
+// ClearJournalEntries deletes all general ledger journal entries and their
+// lines from the database, keeping the chart of accounts.
+func ClearJournalEntries(db *gorm.DB) {
+	if err := db.Where("id is not null").Delete(&model.JournalLine{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear journal line data: %+v", err)
+	}
+	if err := db.Where("id is not null").Delete(&model.JournalEntry{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear journal entry data: %+v", err)
+	}
+}
+
+// ClearInterest deletes all interest accruals and runs from the database and
+// restores the rate of the default product.
+func ClearInterest(db *gorm.DB) {
+	if err := db.Where("id is not null").Delete(&model.InterestAccrual{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear interest accrual data: %+v", err)
+	}
+	if err := db.Where("id is not null").Delete(&model.InterestRun{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear interest run data: %+v", err)
+	}
+	if err := db.Model(&model.Product{}).Where("code = ?", model.DefaultProductCode).Update("annual_rate_bps", DefaultProductRateBPS).Error; err != nil {
+		logrus.Fatalf("Failed to restore the default product rate: %+v", err)
+	}
+}
+
+// ClearFeeSchedules deletes all fee schedules from the database.
+func ClearFeeSchedules(db *gorm.DB) {
+	if err := db.Where("id is not null").Delete(&model.FeeSchedule{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear fee schedule data: %+v", err)
+	}
+}
+
 // ClearCashActivities deletes all cash activities from the database.
 func ClearCashActivities(db *gorm.DB) {
 	if err := db.Where("id is not null").Delete(&model.CashActivity{}).Error; err != nil {
@@ -39,6 +97,42 @@ func ClearCashActivities(db *gorm.DB) {
 	}
 }
 
+// ClearAccountStatusHistories deletes all account status changes from the database.
+func ClearAccountStatusHistories(db *gorm.DB) {
+	if err := db.Where("id is not null").Delete(&model.AccountStatusHistory{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear account status history data: %+v", err)
+	}
+}
+
+// ClearAccountWithdrawalLimits deletes all withdrawal limit overrides from the database.
+func ClearAccountWithdrawalLimits(db *gorm.DB) {
+	if err := db.Where("id is not null").Delete(&model.AccountWithdrawalLimit{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear account withdrawal limit data: %+v", err)
+	}
+}
+
+// ClearWebhooks deletes all webhook subscriptions, and with them their
+// deliveries, from the database.
+func ClearWebhooks(db *gorm.DB) {
+	if err := db.Where("id is not null").Delete(&model.WebhookSubscription{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear webhook subscription data: %+v", err)
+	}
+}
+
+// ClearOutbox deletes all outbox events from the database.
+func ClearOutbox(db *gorm.DB) {
+	if err := db.Where("id is not null").Delete(&model.OutboxEvent{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear outbox data: %+v", err)
+	}
+}
+
+// ClearIdempotencyKeys deletes all stored idempotency keys from the database.
+func ClearIdempotencyKeys(db *gorm.DB) {
+	if err := db.Where("id is not null").Delete(&model.IdempotencyKey{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear idempotency key data: %+v", err)
+	}
+}
+
 // CreateAccount creates a new account in the database.  It handles generating
 // a unique account number.
 func CreateAccount(db *gorm.DB, fullName, idNumber, phoneNumber string) (*model.Account, error) {
@@ -61,6 +155,7 @@ func CreateAccount(db *gorm.DB, fullName, idNumber, phoneNumber string) (*model.
 		FullName:      fullName,
 		IDNumber:      idNumber,
 		PhoneNumber:   phoneNumber,
+		PINHash:       testPINHash,
 	}
 
 	if err := db.Create(newAccount).Error; err != nil {
@@ -107,7 +202,7 @@ func GetAccountByNumber(db *gorm.DB, accountNumber string) (*model.Account, erro
 }
 
 // CreateCashActivity adds a new cash activity record to the database.
-func CreateCashActivity(db *gorm.DB, accountID uint, referenceID *uint, activityType string, nominal, balanceBefore, balanceAfter float64, description string) (*model.CashActivity, error) {
+func CreateCashActivity(db *gorm.DB, accountID uint, referenceID *uint, activityType string, nominal, balanceBefore, balanceAfter model.Money, description string) (*model.CashActivity, error) {
 	newActivity := &model.CashActivity{
 		AccountID:     accountID,
 		ReferenceID:   referenceID,
@@ -126,7 +221,7 @@ func CreateCashActivity(db *gorm.DB, accountID uint, referenceID *uint, activity
 }
 
 // UpdateAccountBalance updates the balance of a given account.
-func UpdateAccountBalance(db *gorm.DB, accountID uint, newBalance float64) error {
+func UpdateAccountBalance(db *gorm.DB, accountID uint, newBalance model.Money) error {
 	if err := db.Model(&model.Account{}).Where("id = ?", accountID).Update("balance", newBalance).Error; err != nil {
 		logrus.Errorf("Failed to update account balance: %+v", err)
 		return fmt.Errorf("failed to update account balance: %w", err)
@@ -148,6 +243,31 @@ func GetLatestCashActivity(db *gorm.DB, accountID uint) (*model.CashActivity, er
 	return &latestActivity, nil
 }
 
+// AuthHeaders returns an Authorization header carrying a token for subject
+// with role, to pass to MakeRequest.
+func AuthHeaders(role, subject string) map[string]string {
+	token, err := middleware.NewJWT(test.Config.JWTSecret, test.Config.JWTTTL).Issue(subject, role)
+	if err != nil {
+		logrus.Fatalf("Failed to issue test token: %+v", err)
+	}
+	return map[string]string{fiber.HeaderAuthorization: "Bearer " + token.Token}
+}
+
+// CustomerHeaders returns the Authorization header of the customer owning accountNumber.
+func CustomerHeaders(accountNumber string) map[string]string {
+	return AuthHeaders(middleware.RoleCustomer, accountNumber)
+}
+
+// TellerHeaders returns the Authorization header of a teller.
+func TellerHeaders() map[string]string {
+	return AuthHeaders(middleware.RoleTeller, "teller-01")
+}
+
+// AdminHeaders returns the Authorization header of an admin.
+func AdminHeaders() map[string]string {
+	return AuthHeaders(middleware.RoleAdmin, "admin-01")
+}
+
 // MakeRequest is a helper function to make HTTP requests to the test server.
 func MakeRequest(app *fiber.App, method, path, body string, headers map[string]string) (*http.Response, error) {
 	req := httptest.NewRequest(method, path, strings.NewReader(body))
@@ -167,22 +287,29 @@ func MakeRequest(app *fiber.App, method, path, body string, headers map[string]s
 func NewTestServer(db *gorm.DB) *fiber.App {
 	app := fiber.New() // Use default Fiber config, or a test-specific one if needed.
 
-	// Apply middleware (IMPORTANT: mirror your main.go setup)
-	app.Use("/v1", middleware.LimiterConfig())
+	// Apply middleware (IMPORTANT: mirror your main.go setup). The limiter is
+	// left out: the suite makes more failing requests than it lets one client.
+	app.Use(middleware.RequestIDConfig())
+	app.Use(middleware.TracingConfig())
+	app.Use(middleware.MetricsConfig())
 	app.Use(middleware.LoggerConfig())
 	app.Use(helmet.New())
 	app.Use(compress.New())
 	app.Use(cors.New())
 	app.Use(middleware.RecoverConfig())
 
-	router.Routes(app, db)         // Use the same router setup as your main app
-	app.Use(utils.NotFoundHandler) // and not found handler
+	router.Routes(app, db, test.Config) // Use the same router setup as your main app
+	app.Use(utils.NotFoundHandler)      // and not found handler
 
 	return app
 }
 
-// CreateTestAccount creates an account directly in the DB for testing.
+// CreateTestAccount creates an account directly in the DB for testing. Unless
+// the account has a PIN hash, its PIN is TestPIN.
 func CreateTestAccount(db *gorm.DB, account *model.Account) error {
+	if account.PINHash == "" {
+		account.PINHash = testPINHash
+	}
 	if err := db.Create(account).Error; err != nil {
 		return fmt.Errorf("failed to create test account: %w", err)
 	}
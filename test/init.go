@@ -1,9 +1,12 @@
 package test
 
 import (
+	"account-service/src/config"
 	"account-service/src/database"
 	"account-service/src/router"
 	"account-service/src/utils"
+	"os"
+	"path/filepath"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
@@ -16,9 +19,49 @@ var App = fiber.New(fiber.Config{
 var DB *gorm.DB
 var Log = utils.Log
 
+// Config is the configuration of the tests, read from the .env file at the
+// root of the repository and the environment.
+var Config *config.Config
+
+// dbDir is the directory of the temporary SQLite database, if any.
+var dbDir string
+
 func init() {
-	// TODO: You can modify host and database configuration for tests
-	DB = database.Connect()
-	router.Routes(App, DB)
+	// TODO: You can modify host and database configuration for tests
+	cfg, _, err := config.Load([]string{"-config", "../../.env"})
+	if err != nil {
+		Log.Fatalf("%v", err)
+	}
+	Config = cfg
+
+	// With DB_DRIVER=sqlite the tests get a database in a temporary file of
+	// their own, migrated from scratch.
+	if Config.DBDriver == "sqlite" {
+		dir, err := os.MkdirTemp("", "account-service-test")
+		if err != nil {
+			Log.Fatalf("Failed to create the test database directory: %+v", err)
+		}
+		dbDir = dir
+		Config.DBPath = filepath.Join(dir, "account.db")
+	}
+
+	DB, err = database.Connect(Config)
+	if err != nil {
+		Log.Fatalf("%v", err)
+	}
+	if err := database.MigrateUp(DB); err != nil {
+		Log.Fatalf("Failed to migrate the test database: %+v", err)
+	}
+	router.Routes(App, DB, Config)
 	App.Use(utils.NotFoundHandler)
 }
+
+// Close closes DB and removes the temporary SQLite database, if any.
+func Close() {
+	if sqlDB, err := DB.DB(); err == nil {
+		sqlDB.Close()
+	}
+	if dbDir != "" {
+		os.RemoveAll(dbDir)
+	}
+}
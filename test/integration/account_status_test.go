@@ -0,0 +1,169 @@
+package integration
+
+import (
+	"account-service/src/model"
+	"account-service/src/service"
+	"account-service/src/utils"
+	"account-service/test/helper"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// changeAccountStatus calls one of the freeze, unfreeze or close endpoints.
+func changeAccountStatus(t *testing.T, accountNumber, action string) *http.Response {
+	requestBody, _ := json.Marshal(model.AccountStatusRequest{Reason: "Integration test"})
+	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/admin/rekening/"+accountNumber+"/"+action, string(requestBody), helper.AdminHeaders())
+	assert.NoError(t, err)
+	return resp
+}
+
+// moveMoney deposits to and withdraws from an account, returning both status codes.
+func moveMoney(t *testing.T, accountNumber string) (int, int) {
+	depositBody, _ := json.Marshal(model.DepositRequest{AccountNumber: accountNumber, Nominal: model.NewMoney(1000)})
+	deposit, err := helper.MakeRequest(app, http.MethodPost, "/v1/tabung", string(depositBody), helper.TellerHeaders())
+	assert.NoError(t, err)
+
+	withdrawalBody, _ := json.Marshal(model.Withdrawal{AccountNumber: accountNumber, Nominal: model.NewMoney(1000), PIN: helper.TestPIN})
+	withdrawal, err := helper.MakeRequest(app, http.MethodPost, "/v1/tarik", string(withdrawalBody), helper.TellerHeaders())
+	assert.NoError(t, err)
+
+	return deposit.StatusCode, withdrawal.StatusCode
+}
+
+func TestAccountStatus_FreezeAndUnfreeze(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := model.Account{
+		FullName:      "Status Test User",
+		IDNumber:      "3131313131313131",
+		PhoneNumber:   "083131313131",
+		AccountNumber: utils.GenerateAccountNumber(),
+		Balance:       model.NewMoney(50000),
+	}
+	err := helper.CreateTestAccount(db, &existingAccount)
+	assert.NoError(t, err)
+
+	// 1. A frozen account moves no money but still reports its balance.
+	resp := changeAccountStatus(t, existingAccount.AccountNumber, "freeze")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	depositStatus, withdrawalStatus := moveMoney(t, existingAccount.AccountNumber)
+	assert.Equal(t, http.StatusLocked, depositStatus)
+	assert.Equal(t, http.StatusLocked, withdrawalStatus)
+
+	resp, err = helper.MakeRequest(app, http.MethodGet, "/v1/saldo/"+existingAccount.AccountNumber, "", helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// 2. Freezing twice is not a valid transition.
+	resp = changeAccountStatus(t, existingAccount.AccountNumber, "freeze")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, service.ErrInvalidStatusChange.Error(), errorMessage(t, resp))
+
+	// 3. Unfreezing restores deposits and withdrawals.
+	resp = changeAccountStatus(t, existingAccount.AccountNumber, "unfreeze")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	depositStatus, withdrawalStatus = moveMoney(t, existingAccount.AccountNumber)
+	assert.Equal(t, http.StatusOK, depositStatus)
+	assert.Equal(t, http.StatusOK, withdrawalStatus)
+
+	// 4. Both transitions were recorded with their actor and reason.
+	var histories []model.AccountStatusHistory
+	err = db.Where("account_id = ?", existingAccount.ID).Order("id asc").Find(&histories).Error
+	assert.NoError(t, err)
+	assert.Len(t, histories, 2)
+	assert.Equal(t, model.AccountStatusActive, histories[0].FromStatus)
+	assert.Equal(t, model.AccountStatusFrozen, histories[0].ToStatus)
+	assert.Equal(t, model.AccountStatusFrozen, histories[1].FromStatus)
+	assert.Equal(t, model.AccountStatusActive, histories[1].ToStatus)
+	assert.Equal(t, "admin-01", histories[1].Actor)
+	assert.Equal(t, "Integration test", histories[1].Reason)
+
+	helper.ClearAll(db)
+}
+
+func TestAccountStatus_Dormant(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := model.Account{
+		FullName:      "Status Test User",
+		IDNumber:      "3232323232323232",
+		PhoneNumber:   "083232323232",
+		AccountNumber: utils.GenerateAccountNumber(),
+		Balance:       model.NewMoney(50000),
+		Status:        model.AccountStatusDormant,
+	}
+	err := helper.CreateTestAccount(db, &existingAccount)
+	assert.NoError(t, err)
+
+	depositStatus, withdrawalStatus := moveMoney(t, existingAccount.AccountNumber)
+	assert.Equal(t, http.StatusForbidden, depositStatus)
+	assert.Equal(t, http.StatusForbidden, withdrawalStatus)
+
+	resp := changeAccountStatus(t, existingAccount.AccountNumber, "unfreeze")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	depositStatus, withdrawalStatus = moveMoney(t, existingAccount.AccountNumber)
+	assert.Equal(t, http.StatusOK, depositStatus)
+	assert.Equal(t, http.StatusOK, withdrawalStatus)
+
+	helper.ClearAll(db)
+}
+
+func TestAccountStatus_Close(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := model.Account{
+		FullName:      "Status Test User",
+		IDNumber:      "3333333333333333",
+		PhoneNumber:   "083333333333",
+		AccountNumber: utils.GenerateAccountNumber(),
+		Balance:       model.NewMoney(50000),
+	}
+	err := helper.CreateTestAccount(db, &existingAccount)
+	assert.NoError(t, err)
+
+	// 1. An account with money left cannot be closed.
+	resp := changeAccountStatus(t, existingAccount.AccountNumber, "close")
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, service.ErrNonZeroBalance.Error(), errorMessage(t, resp))
+
+	// 2. Once emptied it can.
+	withdrawalBody, _ := json.Marshal(model.Withdrawal{AccountNumber: existingAccount.AccountNumber, Nominal: model.NewMoney(50000), PIN: helper.TestPIN})
+	resp, err = helper.MakeRequest(app, http.MethodPost, "/v1/tarik", string(withdrawalBody), helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = changeAccountStatus(t, existingAccount.AccountNumber, "close")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// 3. A closed account is gone for every operation.
+	depositStatus, withdrawalStatus := moveMoney(t, existingAccount.AccountNumber)
+	assert.Equal(t, http.StatusGone, depositStatus)
+	assert.Equal(t, http.StatusGone, withdrawalStatus)
+
+	resp, err = helper.MakeRequest(app, http.MethodGet, "/v1/saldo/"+existingAccount.AccountNumber, "", helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusGone, resp.StatusCode)
+	assert.Equal(t, service.ErrAccountClosed.Error(), errorMessage(t, resp))
+
+	// 4. Closing is final.
+	resp = changeAccountStatus(t, existingAccount.AccountNumber, "unfreeze")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	helper.ClearAll(db)
+}
+
+func TestAccountStatus_AccountNotFound(t *testing.T) {
+	helper.ClearAll(db)
+
+	resp := changeAccountStatus(t, "9999999999", "freeze")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, service.ErrAccountNotFound.Error(), errorMessage(t, resp))
+
+	helper.ClearAll(db)
+}
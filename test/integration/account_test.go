@@ -1,16 +1,17 @@
 package integration
 
 import (
-	"account-service/src/config"
 	"account-service/src/controller" // Import your controller
-	"errors"
+	"account-service/src/middleware"
 	"log"
 
 	// Import your database package
 	"account-service/src/model"
+	"account-service/src/repository"
 	"account-service/src/response"
 	"account-service/src/service"
 	"account-service/src/utils"
+	"account-service/test"
 	"account-service/test/fixture"
 	"account-service/test/helper"
 	"encoding/json"
@@ -19,13 +20,11 @@ import (
 	"net/http"
 	"os"
 	"testing"
+	"time"
 
-	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 var (
@@ -34,34 +33,23 @@ var (
 	accountNumber string
 )
 
-// setup sets up the test environment before *all* tests in the package.
+// setup sets up the test environment before *all* tests in the package, on
+// the database of the test package.
 func setup() {
-	// Replace with your actual test database connection details
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%d sslmode=disable TimeZone=Asia/Shanghai",
-		config.DBHost, config.DBUser, config.DBPassword, config.DBName, config.DBPort,
-	)
-	err := errors.New("test")
-	db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger:                 logger.Default.LogMode(logger.Info),
-		SkipDefaultTransaction: true,
-		PrepareStmt:            true,
-		TranslateError:         true,
-	})
-	if err != nil {
-		utils.Log.Errorf("Failed to connect to database: %+v", err)
-	}
+	db = test.DB
 	app = helper.NewTestServer(db) // Create a Fiber app instance
 
-	validate := validator.New()
-	accountService := service.NewAccountService(db, validate) //Use DB
-	accountController := controller.NewAccountController(accountService, validate)
+	validate := utils.Validator()
+	accountService := service.NewAccountService(repository.NewGorm(db, test.Config.PIIKeyring), service.NewLedger(), validate, model.WithdrawalLimits{}, test.Config.PINPolicy, time.Local) //Use DB
+	accountController := controller.NewAccountController(accountService, middleware.NewJWT(test.Config.JWTSecret, test.Config.JWTTTL), validate)
 
 	//Define routes
 	app.Post("/daftar", accountController.Register)
 	app.Post("/tabung", accountController.Deposit)
 	app.Post("/tarik", accountController.Withdrawal)
 	app.Get("/saldo/:accountNumber", accountController.GetBalance)
+	app.Get("/mutasi", accountController.GetMutations)
+	app.Post("/transfer", accountController.Transfer)
 
 	helper.ClearAll(db) // Clean the database before running tests.
 }
@@ -70,7 +58,7 @@ func setup() {
 func TestMain(m *testing.M) {
 	setup()
 	code := m.Run() // Run the tests
-	// teardown() // Optionally clean up after *all* tests (e.g., drop the test database)
+	test.Close()
 	os.Exit(code)
 }
 
@@ -105,6 +93,10 @@ func TestRegisterAccount_Success(t *testing.T) {
 	assert.True(t, ok, "account_number should be a string")
 	assert.NotEmpty(t, accountNumber)
 
+	// The ID number and the phone number are only shown masked
+	assert.Equal(t, "1234********3456", accountData["id_number"])
+	assert.Equal(t, "0812****7890", accountData["phone_number"])
+
 	//Check in DB
 	var createdAccount model.Account
 	err = db.Where("account_number = ?", accountNumber).First(&createdAccount).Error
@@ -113,6 +105,13 @@ func TestRegisterAccount_Success(t *testing.T) {
 	assert.Equal(t, fixture.ValidCreateAccount.IDNumber, createdAccount.IDNumber)
 	assert.Equal(t, fixture.ValidCreateAccount.PhoneNumber, createdAccount.PhoneNumber)
 
+	// and stored encrypted
+	var stored struct{ IDNumber, PhoneNumber string }
+	err = db.Table("accounts").Select("id_number, phone_number").Where("account_number = ?", accountNumber).Scan(&stored).Error
+	assert.NoError(t, err)
+	assert.NotContains(t, stored.IDNumber, fixture.ValidCreateAccount.IDNumber)
+	assert.NotContains(t, stored.PhoneNumber, fixture.ValidCreateAccount.PhoneNumber)
+
 	helper.ClearAll(db) //Clear all data
 }
 
@@ -133,6 +132,7 @@ func TestRegisterAccount_DuplicateID(t *testing.T) {
 		FullName:    "New User",
 		IDNumber:    "1234567890123456", // Duplicate ID
 		PhoneNumber: "081234567890",
+		PIN:         helper.TestPIN,
 	})
 
 	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/daftar", string(requestBody), nil)
@@ -169,6 +169,7 @@ func TestRegisterAccount_DuplicatePhoneNumber(t *testing.T) {
 		FullName:    "New User",
 		IDNumber:    "1234567890123456",
 		PhoneNumber: "081234567890", // Duplicate Phone Number
+		PIN:         helper.TestPIN,
 	})
 
 	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/daftar", string(requestBody), nil)
@@ -218,7 +219,7 @@ func TestDeposit_Success(t *testing.T) {
 		IDNumber:      "1122334455667788",
 		PhoneNumber:   "081122334455",
 		AccountNumber: utils.GenerateAccountNumber(), // Use helper, ensure uniqueness
-		Balance:       0,                             // Initial balance
+		Balance:       model.NewMoney(0),             // Initial balance
 	}
 	err := helper.CreateTestAccount(db, &existingAccount)
 	assert.NoError(t, err)
@@ -226,12 +227,12 @@ func TestDeposit_Success(t *testing.T) {
 	// 2. Prepare the deposit request.
 	depositRequest := model.DepositRequest{
 		AccountNumber: existingAccount.AccountNumber, // Use the created account's number
-		Nominal:       500000,
+		Nominal:       model.NewMoney(500000),
 	}
 	requestBody, _ := json.Marshal(depositRequest)
 
 	// 3. Make the deposit request.
-	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tabung", string(requestBody), nil)
+	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tabung", string(requestBody), helper.TellerHeaders())
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 
@@ -266,7 +267,7 @@ func TestDeposit_Success(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "credit", cashActivity.Type)
 	assert.Equal(t, depositRequest.Nominal, cashActivity.Nominal)
-	assert.Equal(t, 0.0, cashActivity.BalanceBefore) // Initial balance was 0
+	assert.Equal(t, model.NewMoney(0), cashActivity.BalanceBefore) // Initial balance was 0
 	assert.Equal(t, depositRequest.Nominal, cashActivity.BalanceAfter)
 	assert.Nil(t, cashActivity.ReferenceID) //Should be nil (first transaction)
 
@@ -278,11 +279,11 @@ func TestDeposit_AccountNotFound(t *testing.T) {
 
 	depositRequest := model.DepositRequest{
 		AccountNumber: "9999999999", // Non-existent account
-		Nominal:       500000,
+		Nominal:       model.NewMoney(500000),
 	}
 	requestBody, _ := json.Marshal(depositRequest)
 
-	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tabung", string(requestBody), nil)
+	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tabung", string(requestBody), helper.TellerHeaders())
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusNotFound, resp.StatusCode) // Expect 404 Not Found
 
@@ -303,11 +304,11 @@ func TestDeposit_ValidationError(t *testing.T) {
 
 	depositRequest := model.DepositRequest{
 		AccountNumber: "1234567890",
-		Nominal:       -100, //Invalid Deposit
+		Nominal:       model.NewMoney(-100), //Invalid Deposit
 	}
 	requestBody, _ := json.Marshal(depositRequest)
 
-	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tabung", string(requestBody), nil)
+	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tabung", string(requestBody), helper.TellerHeaders())
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
 
@@ -326,7 +327,7 @@ func TestDeposit_ValidationError(t *testing.T) {
 func TestWithdrawal_Success(t *testing.T) {
 	helper.ClearAll(db)
 	// 1. Create an account
-	initialBalance := 1000000.0 // Start with some balance
+	initialBalance := model.NewMoney(1000000) // Start with some balance
 	existingAccount := model.Account{
 		FullName:      "Withdrawal Test User",
 		IDNumber:      "2233445566778899",
@@ -340,12 +341,13 @@ func TestWithdrawal_Success(t *testing.T) {
 	// 2. Prepare the withdrawal request
 	withdrawalRequest := model.Withdrawal{
 		AccountNumber: existingAccount.AccountNumber, // Use the created account's number
-		Nominal:       250000,
+		Nominal:       model.NewMoney(250000),
+		PIN:           helper.TestPIN,
 	}
 	requestBody, _ := json.Marshal(withdrawalRequest)
 
 	// 3. Make the withdrawal request
-	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tarik", string(requestBody), nil)
+	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tarik", string(requestBody), helper.TellerHeaders())
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 
@@ -364,13 +366,13 @@ func TestWithdrawal_Success(t *testing.T) {
 	// Now you can access fields from accountData
 	balance, ok := accountData["saldo"].(float64)
 	assert.True(t, ok, "Data should be a float64 (balance)")
-	assert.Equal(t, initialBalance-withdrawalRequest.Nominal, balance)
+	assert.Equal(t, 750000.0, balance)
 
 	// 6. Verify account balance in DB
 	var updatedAccount model.Account
 	err = db.Where("account_number = ?", existingAccount.AccountNumber).First(&updatedAccount).Error
 	assert.NoError(t, err)
-	assert.Equal(t, initialBalance-withdrawalRequest.Nominal, updatedAccount.Balance)
+	assert.Equal(t, initialBalance.Sub(withdrawalRequest.Nominal), updatedAccount.Balance)
 
 	//7. Verify that cash activity also created
 	var cashActivity model.CashActivity
@@ -379,7 +381,7 @@ func TestWithdrawal_Success(t *testing.T) {
 	assert.Equal(t, "debit", cashActivity.Type)
 	assert.Equal(t, withdrawalRequest.Nominal, cashActivity.Nominal)
 	assert.Equal(t, initialBalance, cashActivity.BalanceBefore) // Initial balance was 0
-	assert.Equal(t, initialBalance-withdrawalRequest.Nominal, cashActivity.BalanceAfter)
+	assert.Equal(t, initialBalance.Sub(withdrawalRequest.Nominal), cashActivity.BalanceAfter)
 	assert.Nil(t, cashActivity.ReferenceID) //Should be nil (first transaction)
 
 	helper.ClearAll(db) //Clear all data
@@ -388,11 +390,12 @@ func TestWithdrawal_AccountNotFound(t *testing.T) {
 	helper.ClearAll(db)
 	withdrawalRequest := model.Withdrawal{
 		AccountNumber: "9999999999", // Non-existent account
-		Nominal:       500000,
+		Nominal:       model.NewMoney(500000),
+		PIN:           helper.TestPIN,
 	}
 	requestBody, _ := json.Marshal(withdrawalRequest)
 
-	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tarik", string(requestBody), nil)
+	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tarik", string(requestBody), helper.TellerHeaders())
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusNotFound, resp.StatusCode) // Expect 404
 
@@ -415,18 +418,19 @@ func TestWithdrawal_InsufficientBalance(t *testing.T) {
 		IDNumber:      "3344556677889900",
 		PhoneNumber:   "083344556677",
 		AccountNumber: utils.GenerateAccountNumber(), // Use helper, ensure uniqueness
-		Balance:       100000,                        // Initial balance
+		Balance:       model.NewMoney(100000),        // Initial balance
 	}
 	err := helper.CreateTestAccount(db, &existingAccount)
 	assert.NoError(t, err)
 
 	withdrawalRequest := model.Withdrawal{
 		AccountNumber: existingAccount.AccountNumber, // Use existing account
-		Nominal:       500000,                        // More than balance
+		Nominal:       model.NewMoney(500000),        // More than balance
+		PIN:           helper.TestPIN,
 	}
 	requestBody, _ := json.Marshal(withdrawalRequest)
 
-	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tarik", string(requestBody), nil)
+	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tarik", string(requestBody), helper.TellerHeaders())
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusBadRequest, resp.StatusCode) // Expect 400 Bad Request
 
@@ -444,11 +448,12 @@ func TestWithdrawal_ValidationError(t *testing.T) {
 	helper.ClearAll(db)
 	withdrawalRequest := model.Withdrawal{
 		AccountNumber: "123456789",
-		Nominal:       -100, //Invalid
+		Nominal:       model.NewMoney(-100), //Invalid
+		PIN:           helper.TestPIN,
 	}
 
 	requestBody, _ := json.Marshal(withdrawalRequest)
-	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tarik", string(requestBody), nil)
+	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tarik", string(requestBody), helper.TellerHeaders())
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
 
@@ -468,7 +473,7 @@ func TestGetBalance_Success(t *testing.T) {
 	helper.ClearAll(db)
 
 	// 1. Create an account with a known balance.
-	initialBalance := 750000.0
+	initialBalance := model.NewMoney(750000)
 	existingAccount := model.Account{
 		FullName:      "Balance Test User",
 		IDNumber:      "4455667788990011",
@@ -480,7 +485,7 @@ func TestGetBalance_Success(t *testing.T) {
 	assert.NoError(t, err)
 
 	// 2. Make the GET request to /saldo/{accountNumber}.
-	resp, err := helper.MakeRequest(app, http.MethodGet, "/v1/saldo/"+existingAccount.AccountNumber, "", nil)
+	resp, err := helper.MakeRequest(app, http.MethodGet, "/v1/saldo/"+existingAccount.AccountNumber, "", helper.TellerHeaders())
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 
@@ -500,7 +505,7 @@ func TestGetBalance_Success(t *testing.T) {
 	balance, ok := accountData["saldo"].(float64)
 	assert.True(t, ok, "Data should be a float64 (balance)")
 	assert.Equal(t, 750000.0, balance)
-	assert.Equal(t, initialBalance, balance)
+	assert.Equal(t, initialBalance.Float64(), balance)
 
 	helper.ClearAll(db) // Clean up
 }
@@ -508,7 +513,7 @@ func TestGetBalance_Success(t *testing.T) {
 func TestGetBalance_AccountNotFound(t *testing.T) {
 	helper.ClearAll(db)
 
-	resp, err := helper.MakeRequest(app, http.MethodGet, "/v1/saldo/9999999999", "", nil) // Non-existent account
+	resp, err := helper.MakeRequest(app, http.MethodGet, "/v1/saldo/9999999999", "", helper.TellerHeaders()) // Non-existent account
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
 
@@ -527,7 +532,7 @@ func TestGetBalance_InvalidAccountNumber(t *testing.T) {
 	helper.ClearAll(db)
 
 	// 1. Make the GET request to /saldo/{accountNumber} with an invalid account number.
-	resp, err := helper.MakeRequest(app, http.MethodGet, "/v1/saldo/invalid-account-number", "", nil)
+	resp, err := helper.MakeRequest(app, http.MethodGet, "/v1/saldo/invalid-account-number", "", helper.TellerHeaders())
 	assert.NoError(t, err)
 
 	// 2. Assert on the HTTP status code.
@@ -535,3 +540,331 @@ func TestGetBalance_InvalidAccountNumber(t *testing.T) {
 
 	helper.ClearAll(db)
 }
+
+func TestGetMutations_Success(t *testing.T) {
+	helper.ClearAll(db)
+
+	// 1. Create an account and make a few deposits.
+	existingAccount := model.Account{
+		FullName:      "Mutation Test User",
+		IDNumber:      "5566778899001122",
+		PhoneNumber:   "085566778899",
+		AccountNumber: utils.GenerateAccountNumber(),
+	}
+	err := helper.CreateTestAccount(db, &existingAccount)
+	assert.NoError(t, err)
+
+	for _, nominal := range []int64{500000, 250000, 100000} {
+		requestBody, _ := json.Marshal(model.DepositRequest{
+			AccountNumber: existingAccount.AccountNumber,
+			Nominal:       model.NewMoney(nominal),
+		})
+		resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tabung", string(requestBody), helper.TellerHeaders())
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	// 2. Request the first page of this month's history.
+	now := time.Now()
+	path := fmt.Sprintf("/v1/mutasi?no_rekening=%s&bulan=%d&tahun=%d&limit=2", existingAccount.AccountNumber, now.Month(), now.Year())
+	resp, err := helper.MakeRequest(app, http.MethodGet, path, "", helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var apiResponse response.SuccessWithPaginate
+	err = json.Unmarshal(body, &apiResponse)
+	assert.NoError(t, err)
+
+	// 3. Verify pagination and the running balance of the oldest entries.
+	assert.Equal(t, fiber.StatusOK, apiResponse.Code)
+	assert.Equal(t, int64(3), apiResponse.Count)
+	assert.Equal(t, int64(2), apiResponse.TotalPages)
+	assert.Equal(t, 1, apiResponse.Page)
+	assert.Equal(t, 2, apiResponse.Limit)
+	assert.Len(t, apiResponse.Data, 2)
+
+	first, ok := apiResponse.Data[0].(map[string]interface{})
+	assert.True(t, ok, "Data item should be a map[string]interface{}")
+	assert.Equal(t, 0.0, first["balance_before"])
+	assert.Equal(t, 500000.0, first["balance_after"])
+
+	second, ok := apiResponse.Data[1].(map[string]interface{})
+	assert.True(t, ok, "Data item should be a map[string]interface{}")
+	assert.Equal(t, 500000.0, second["balance_before"])
+	assert.Equal(t, 750000.0, second["balance_after"])
+
+	helper.ClearAll(db)
+}
+
+func TestGetMutations_DateRange(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := model.Account{
+		FullName:      "Mutation Range User",
+		IDNumber:      "6677889900112233",
+		PhoneNumber:   "086677889900",
+		AccountNumber: utils.GenerateAccountNumber(),
+	}
+	err := helper.CreateTestAccount(db, &existingAccount)
+	assert.NoError(t, err)
+
+	_, err = helper.CreateCashActivity(db, existingAccount.ID, nil, "credit", model.NewMoney(100000), model.NewMoney(0), model.NewMoney(100000), "")
+	assert.NoError(t, err)
+
+	// A range that ends yesterday must not include today's activity.
+	yesterday := time.Now().AddDate(0, 0, -1).Format(time.DateOnly)
+	path := fmt.Sprintf("/v1/mutasi?no_rekening=%s&dari=%s&sampai=%s", existingAccount.AccountNumber, yesterday, yesterday)
+	resp, err := helper.MakeRequest(app, http.MethodGet, path, "", helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var apiResponse response.SuccessWithPaginate
+	err = json.Unmarshal(body, &apiResponse)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), apiResponse.Count)
+	assert.Empty(t, apiResponse.Data)
+
+	helper.ClearAll(db)
+}
+
+func TestGetMutations_AccountNotFound(t *testing.T) {
+	helper.ClearAll(db)
+
+	resp, err := helper.MakeRequest(app, http.MethodGet, "/v1/mutasi?no_rekening=9999999999&bulan=1", "", helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var errorResponse response.ErrorDetails
+	err = json.Unmarshal(body, &errorResponse)
+	assert.NoError(t, err)
+	assert.Equal(t, service.ErrAccountNotFound.Error(), errorResponse.Message)
+
+	helper.ClearAll(db)
+}
+
+func TestGetMutations_ValidationError(t *testing.T) {
+	helper.ClearAll(db)
+
+	// Neither bulan nor dari/sampai is given.
+	resp, err := helper.MakeRequest(app, http.MethodGet, "/v1/mutasi?no_rekening=1234567890", "", helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var errorResponse response.ErrorDetails
+	err = json.Unmarshal(body, &errorResponse)
+	assert.NoError(t, err)
+	assert.Contains(t, errorResponse.Message, "Month")
+
+	helper.ClearAll(db)
+}
+
+func TestTransfer_Success(t *testing.T) {
+	helper.ClearAll(db)
+
+	// 1. Create the source and destination accounts.
+	fromAccount := model.Account{
+		FullName:      "Transfer Sender",
+		IDNumber:      "7788990011223344",
+		PhoneNumber:   "087788990011",
+		AccountNumber: utils.GenerateAccountNumber(),
+		Balance:       model.NewMoney(1000000),
+	}
+	err := helper.CreateTestAccount(db, &fromAccount)
+	assert.NoError(t, err)
+
+	toAccount := model.Account{
+		FullName:      "Transfer Receiver",
+		IDNumber:      "8899001122334455",
+		PhoneNumber:   "088899001122",
+		AccountNumber: utils.GenerateAccountNumber(),
+		Balance:       model.NewMoney(200000),
+	}
+	err = helper.CreateTestAccount(db, &toAccount)
+	assert.NoError(t, err)
+
+	// 2. Make the transfer request.
+	transferRequest := model.TransferRequest{
+		FromAccountNumber: fromAccount.AccountNumber,
+		ToAccountNumber:   toAccount.AccountNumber,
+		Nominal:           model.NewMoney(300000),
+		PIN:               helper.TestPIN,
+	}
+	requestBody, _ := json.Marshal(transferRequest)
+
+	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/transfer", string(requestBody), helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var apiResponse struct {
+		Code int                    `json:"code"`
+		Data model.TransferResponse `json:"data"`
+	}
+	err = json.Unmarshal(body, &apiResponse)
+	assert.NoError(t, err)
+
+	// 3. Both new balances are returned.
+	assert.Equal(t, fiber.StatusOK, apiResponse.Code)
+	assert.NotEmpty(t, apiResponse.Data.Reference)
+	assert.Equal(t, fromAccount.AccountNumber, apiResponse.Data.From.AccountNumber)
+	assert.Equal(t, model.NewMoney(700000), apiResponse.Data.From.Balance)
+	assert.Equal(t, toAccount.AccountNumber, apiResponse.Data.To.AccountNumber)
+	assert.Equal(t, model.NewMoney(500000), apiResponse.Data.To.Balance)
+
+	// 4. Verify both balances in the database.
+	updatedFrom, err := helper.GetAccountByNumber(db, fromAccount.AccountNumber)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(700000), updatedFrom.Balance)
+
+	updatedTo, err := helper.GetAccountByNumber(db, toAccount.AccountNumber)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(500000), updatedTo.Balance)
+
+	// 5. Both legs are recorded and share the transfer reference.
+	var legs []model.CashActivity
+	err = db.Where("transfer_reference = ?", apiResponse.Data.Reference).Order("id asc").Find(&legs).Error
+	assert.NoError(t, err)
+	assert.Len(t, legs, 2)
+	assert.Equal(t, "debit", legs[0].Type)
+	assert.Equal(t, fromAccount.ID, legs[0].AccountID)
+	assert.Equal(t, model.NewMoney(1000000), legs[0].BalanceBefore)
+	assert.Equal(t, model.NewMoney(700000), legs[0].BalanceAfter)
+	assert.Equal(t, "credit", legs[1].Type)
+	assert.Equal(t, toAccount.ID, legs[1].AccountID)
+	assert.Equal(t, model.NewMoney(200000), legs[1].BalanceBefore)
+	assert.Equal(t, model.NewMoney(500000), legs[1].BalanceAfter)
+
+	helper.ClearAll(db)
+}
+
+func TestTransfer_InsufficientBalance(t *testing.T) {
+	helper.ClearAll(db)
+
+	fromAccount := model.Account{
+		FullName:      "Transfer Sender",
+		IDNumber:      "9900112233445566",
+		PhoneNumber:   "089900112233",
+		AccountNumber: utils.GenerateAccountNumber(),
+		Balance:       model.NewMoney(100000),
+	}
+	err := helper.CreateTestAccount(db, &fromAccount)
+	assert.NoError(t, err)
+
+	toAccount := model.Account{
+		FullName:      "Transfer Receiver",
+		IDNumber:      "0011223344556677",
+		PhoneNumber:   "080011223344",
+		AccountNumber: utils.GenerateAccountNumber(),
+	}
+	err = helper.CreateTestAccount(db, &toAccount)
+	assert.NoError(t, err)
+
+	requestBody, _ := json.Marshal(model.TransferRequest{
+		FromAccountNumber: fromAccount.AccountNumber,
+		ToAccountNumber:   toAccount.AccountNumber,
+		Nominal:           model.NewMoney(500000), // More than balance
+		PIN:               helper.TestPIN,
+	})
+
+	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/transfer", string(requestBody), helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var errorResponse response.ErrorDetails
+	err = json.Unmarshal(body, &errorResponse)
+	assert.NoError(t, err)
+	assert.Equal(t, service.ErrInsufficientBalance.Error(), errorResponse.Message)
+
+	// Nothing is posted on either side.
+	var count int64
+	err = db.Model(&model.CashActivity{}).Where("account_id IN ?", []uint{fromAccount.ID, toAccount.ID}).Count(&count).Error
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+
+	helper.ClearAll(db)
+}
+
+func TestTransfer_AccountNotFound(t *testing.T) {
+	helper.ClearAll(db)
+
+	fromAccount := model.Account{
+		FullName:      "Transfer Sender",
+		IDNumber:      "1212121212121212",
+		PhoneNumber:   "081212121212",
+		AccountNumber: utils.GenerateAccountNumber(),
+		Balance:       model.NewMoney(100000),
+	}
+	err := helper.CreateTestAccount(db, &fromAccount)
+	assert.NoError(t, err)
+
+	requestBody, _ := json.Marshal(model.TransferRequest{
+		FromAccountNumber: fromAccount.AccountNumber,
+		ToAccountNumber:   "9999999999", // Non-existent account
+		Nominal:           model.NewMoney(50000),
+		PIN:               helper.TestPIN,
+	})
+
+	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/transfer", string(requestBody), helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var errorResponse response.ErrorDetails
+	err = json.Unmarshal(body, &errorResponse)
+	assert.NoError(t, err)
+	assert.Equal(t, service.ErrAccountNotFound.Error(), errorResponse.Message)
+
+	helper.ClearAll(db)
+}
+
+func TestTransfer_ValidationError(t *testing.T) {
+	helper.ClearAll(db)
+
+	requestBody, _ := json.Marshal(model.TransferRequest{
+		FromAccountNumber: "1234567890",
+		ToAccountNumber:   "1234567890", // Same account
+		Nominal:           model.NewMoney(50000),
+		PIN:               helper.TestPIN,
+	})
+
+	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/transfer", string(requestBody), helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var errorResponse response.ErrorDetails
+	err = json.Unmarshal(body, &errorResponse)
+	assert.NoError(t, err)
+	assert.Contains(t, errorResponse.Message, "ToAccountNumber")
+
+	helper.ClearAll(db)
+}
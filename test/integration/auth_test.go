@@ -0,0 +1,228 @@
+package integration
+
+import (
+	"account-service/src/middleware"
+	"account-service/src/model"
+	"account-service/src/response"
+	"account-service/src/utils"
+	"account-service/test"
+	"account-service/test/fixture"
+	"account-service/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// createAuthTestAccount creates an account whose owner is used as a customer.
+func createAuthTestAccount(t *testing.T, idNumber, phoneNumber string) model.Account {
+	account := model.Account{
+		FullName:      "Auth Test User",
+		IDNumber:      idNumber,
+		PhoneNumber:   phoneNumber,
+		AccountNumber: utils.GenerateAccountNumber(),
+		Balance:       model.NewMoney(100000),
+	}
+	err := helper.CreateTestAccount(db, &account)
+	assert.NoError(t, err)
+	return account
+}
+
+func TestAuth_MissingOrInvalidToken(t *testing.T) {
+	helper.ClearAll(db)
+
+	account := createAuthTestAccount(t, "4141414141414141", "084141414141")
+	path := "/v1/saldo/" + account.AccountNumber
+
+	expired, err := middleware.NewJWT(test.Config.JWTSecret, -time.Minute).Issue(account.AccountNumber, middleware.RoleCustomer)
+	assert.NoError(t, err)
+	forged, err := middleware.NewJWT("not-the-secret", time.Hour).Issue(account.AccountNumber, middleware.RoleCustomer)
+	assert.NoError(t, err)
+
+	cases := map[string]map[string]string{
+		"no header":    nil,
+		"not a bearer": {fiber.HeaderAuthorization: "Basic dXNlcjpwYXNz"},
+		"malformed":    {fiber.HeaderAuthorization: "Bearer not-a-token"},
+		"expired":      {fiber.HeaderAuthorization: "Bearer " + expired.Token},
+		"wrong secret": {fiber.HeaderAuthorization: "Bearer " + forged.Token},
+		"empty bearer": {fiber.HeaderAuthorization: "Bearer "},
+	}
+	for name, headers := range cases {
+		resp, err := helper.MakeRequest(app, http.MethodGet, path, "", headers)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, name)
+		assert.Equal(t, middleware.ErrUnauthenticated.Error(), errorMessage(t, resp), name)
+	}
+
+	helper.ClearAll(db)
+}
+
+func TestAuth_CustomerScopedToOwnAccount(t *testing.T) {
+	helper.ClearAll(db)
+
+	own := createAuthTestAccount(t, "4242424242424242", "084242424242")
+	other := createAuthTestAccount(t, "4343434343434343", "084343434343")
+	headers := helper.CustomerHeaders(own.AccountNumber)
+
+	// 1. The customer reads and moves money on their own account.
+	resp, err := helper.MakeRequest(app, http.MethodGet, "/v1/saldo/"+own.AccountNumber, "", headers)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	transferBody, _ := json.Marshal(model.TransferRequest{
+		FromAccountNumber: own.AccountNumber,
+		ToAccountNumber:   other.AccountNumber,
+		Nominal:           model.NewMoney(1000),
+		PIN:               helper.TestPIN,
+	})
+	resp, err = helper.MakeRequest(app, http.MethodPost, "/v1/transfer", string(transferBody), headers)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// 2. Every endpoint refuses another customer's account.
+	withdrawalBody, _ := json.Marshal(model.Withdrawal{AccountNumber: other.AccountNumber, Nominal: model.NewMoney(1000), PIN: helper.TestPIN})
+	depositBody, _ := json.Marshal(model.DepositRequest{AccountNumber: other.AccountNumber, Nominal: model.NewMoney(1000)})
+	reverseTransferBody, _ := json.Marshal(model.TransferRequest{
+		FromAccountNumber: other.AccountNumber,
+		ToAccountNumber:   own.AccountNumber,
+		Nominal:           model.NewMoney(1000),
+		PIN:               helper.TestPIN,
+	})
+	forbidden := []struct {
+		method, path, body string
+	}{
+		{http.MethodGet, "/v1/saldo/" + other.AccountNumber, ""},
+		{http.MethodGet, "/v1/mutasi?no_rekening=" + other.AccountNumber, ""},
+		{http.MethodPost, "/v1/tarik", string(withdrawalBody)},
+		{http.MethodPost, "/v1/tabung", string(depositBody)},
+		{http.MethodPost, "/v1/transfer", string(reverseTransferBody)},
+	}
+	for _, request := range forbidden {
+		resp, err := helper.MakeRequest(app, request.method, request.path, request.body, headers)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode, request.path)
+		assert.Equal(t, middleware.ErrForbidden.Error(), errorMessage(t, resp), request.path)
+	}
+
+	// 3. Only the transfer moved money.
+	updatedOther, err := helper.GetAccountByNumber(db, other.AccountNumber)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(101000), updatedOther.Balance)
+
+	helper.ClearAll(db)
+}
+
+func TestAuth_RoleRestrictedRoutes(t *testing.T) {
+	helper.ClearAll(db)
+
+	account := createAuthTestAccount(t, "4444444444444444", "084444444444")
+	reversalBody, _ := json.Marshal(model.ReversalRequest{Reason: "Auth test"})
+	statusBody, _ := json.Marshal(model.AccountStatusRequest{Reason: "Auth test"})
+
+	cases := []struct {
+		name, method, path, body string
+		headers                  map[string]string
+		status                   int
+	}{
+		{"customer reversal", http.MethodPost, "/v1/transaksi/1/reversal", string(reversalBody), helper.CustomerHeaders(account.AccountNumber), http.StatusForbidden},
+		{"customer freeze", http.MethodPost, "/v1/admin/rekening/" + account.AccountNumber + "/freeze", string(statusBody), helper.CustomerHeaders(account.AccountNumber), http.StatusForbidden},
+		{"teller freeze", http.MethodPost, "/v1/admin/rekening/" + account.AccountNumber + "/freeze", string(statusBody), helper.TellerHeaders(), http.StatusForbidden},
+		{"teller ledger", http.MethodGet, "/v1/admin/ledger/" + account.AccountNumber + "/verify", "", helper.TellerHeaders(), http.StatusForbidden},
+		{"admin ledger", http.MethodGet, "/v1/admin/ledger/" + account.AccountNumber + "/verify", "", helper.AdminHeaders(), http.StatusOK},
+		{"teller reversal of unknown transaction", http.MethodPost, "/v1/transaksi/999999/reversal", string(reversalBody), helper.TellerHeaders(), http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		resp, err := helper.MakeRequest(app, tc.method, tc.path, tc.body, tc.headers)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.status, resp.StatusCode, tc.name)
+	}
+
+	updatedAccount, err := helper.GetAccountByNumber(db, account.AccountNumber)
+	assert.NoError(t, err)
+	assert.Equal(t, model.AccountStatusActive, updatedAccount.Status)
+
+	helper.ClearAll(db)
+}
+
+func TestAuth_RegisterReturnsCustomerToken(t *testing.T) {
+	helper.ClearAll(db)
+
+	requestBody, _ := json.Marshal(&fixture.ValidCreateAccount)
+	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/daftar", string(requestBody), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var apiResponse struct {
+		Data model.RegisterResponse `json:"data"`
+	}
+	err = json.Unmarshal(body, &apiResponse)
+	assert.NoError(t, err)
+	if !assert.NotNil(t, apiResponse.Data.Account) || !assert.NotNil(t, apiResponse.Data.Token) {
+		return
+	}
+
+	// The token is bound to the new account.
+	headers := map[string]string{fiber.HeaderAuthorization: "Bearer " + apiResponse.Data.Token.Token}
+	resp, err = helper.MakeRequest(app, http.MethodGet, "/v1/saldo/"+apiResponse.Data.AccountNumber, "", headers)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	helper.ClearAll(db)
+}
+
+func TestAuth_IssueToken(t *testing.T) {
+	helper.ClearAll(db)
+
+	account := createAuthTestAccount(t, "4545454545454545", "084545454545")
+
+	issue := func(req model.TokenRequest, headers map[string]string) *http.Response {
+		requestBody, _ := json.Marshal(req)
+		resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/admin/token", string(requestBody), headers)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	// 1. An admin issues a customer token that works on the account.
+	resp := issue(model.TokenRequest{Subject: account.AccountNumber, Role: middleware.RoleCustomer}, helper.AdminHeaders())
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var apiResponse struct {
+		response.SuccessWithData
+		Data model.Token `json:"data"`
+	}
+	err = json.Unmarshal(body, &apiResponse)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, apiResponse.Data.Token)
+	assert.True(t, apiResponse.Data.Expires.After(time.Now()))
+
+	headers := map[string]string{fiber.HeaderAuthorization: "Bearer " + apiResponse.Data.Token}
+	resp, err = helper.MakeRequest(app, http.MethodGet, "/v1/saldo/"+account.AccountNumber, "", headers)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// 2. Customer tokens are only issued for existing accounts.
+	resp = issue(model.TokenRequest{Subject: "999999999999", Role: middleware.RoleCustomer}, helper.AdminHeaders())
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// 3. Unknown roles are rejected.
+	resp = issue(model.TokenRequest{Subject: "auditor-01", Role: "auditor"}, helper.AdminHeaders())
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// 4. Only admins issue tokens.
+	resp = issue(model.TokenRequest{Subject: "admin-02", Role: middleware.RoleAdmin}, helper.TellerHeaders())
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	helper.ClearAll(db)
+}
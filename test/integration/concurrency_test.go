@@ -0,0 +1,171 @@
+package integration
+
+import (
+	"account-service/src/model"
+	"account-service/src/repository"
+	"account-service/src/service"
+	"account-service/src/utils"
+	"account-service/test"
+	"account-service/test/helper"
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	concurrentDeposits    = 200
+	concurrentWithdrawals = 200
+)
+
+func TestConcurrentDepositsAndWithdrawals(t *testing.T) {
+	helper.ClearAll(db)
+
+	// Keep the pool below the server's connection limit, so requests queue
+	// on the pool and on the row lock instead of failing to connect.
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	sqlDB.SetMaxOpenConns(50)
+
+	// 1. Start with enough balance that every withdrawal must succeed in any
+	// interleaving.
+	initialBalance := model.NewMoney(100000)
+	existingAccount := model.Account{
+		FullName:      "Concurrency Test User",
+		IDNumber:      "3434343434343434",
+		PhoneNumber:   "083434343434",
+		AccountNumber: utils.GenerateAccountNumber(),
+		Balance:       initialBalance,
+	}
+	err = helper.CreateTestAccount(db, &existingAccount)
+	assert.NoError(t, err)
+
+	accountService := service.NewAccountService(repository.NewGorm(db, test.Config.PIIKeyring), service.NewLedger(), utils.Validator(), model.WithdrawalLimits{}, test.Config.PINPolicy, time.Local)
+	depositNominal := model.NewMoney(1000)
+	withdrawalNominal := model.NewMoney(500)
+
+	// 2. Fire all deposits and withdrawals at once.
+	var wg sync.WaitGroup
+	var failures atomic.Int64
+	start := make(chan struct{})
+	for i := 0; i < concurrentDeposits; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if err := accountService.Deposit(context.Background(), &model.DepositRequest{
+				AccountNumber: existingAccount.AccountNumber,
+				Nominal:       depositNominal,
+			}); err != nil {
+				t.Logf("deposit failed: %v", err)
+				failures.Add(1)
+			}
+		}()
+	}
+	for i := 0; i < concurrentWithdrawals; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if err := accountService.Withdraw(context.Background(), &model.Withdrawal{
+				AccountNumber: existingAccount.AccountNumber,
+				Nominal:       withdrawalNominal,
+				PIN:           helper.TestPIN,
+			}); err != nil {
+				t.Logf("withdrawal failed: %v", err)
+				failures.Add(1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	assert.Equal(t, int64(0), failures.Load())
+
+	// 3. The final balance reflects every operation exactly once.
+	expectedBalance := initialBalance
+	for i := 0; i < concurrentDeposits; i++ {
+		expectedBalance = expectedBalance.Add(depositNominal)
+	}
+	for i := 0; i < concurrentWithdrawals; i++ {
+		expectedBalance = expectedBalance.Sub(withdrawalNominal)
+	}
+	updatedAccount, err := helper.GetAccountByNumber(db, existingAccount.AccountNumber)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedBalance, updatedAccount.Balance)
+
+	// 4. The activities form an unbroken chain of balances.
+	var activities []model.CashActivity
+	err = db.Where("account_id = ?", existingAccount.ID).Order("id asc").Find(&activities).Error
+	assert.NoError(t, err)
+	assert.Len(t, activities, concurrentDeposits+concurrentWithdrawals)
+
+	balance := initialBalance
+	var previousID *uint
+	for i := range activities {
+		assert.Equal(t, balance, activities[i].BalanceBefore, "activity %d", activities[i].ID)
+		assert.Equal(t, previousID, activities[i].ReferenceID, "activity %d", activities[i].ID)
+		balance = activities[i].BalanceAfter
+		previousID = &activities[i].ID
+	}
+	assert.Equal(t, expectedBalance, balance)
+
+	helper.ClearAll(db)
+}
+
+func TestConcurrentWithdrawals_NoOverdraft(t *testing.T) {
+	helper.ClearAll(db)
+
+	// 1. The balance covers exactly ten of the withdrawals.
+	existingAccount := model.Account{
+		FullName:      "Overdraft Test User",
+		IDNumber:      "5656565656565656",
+		PhoneNumber:   "085656565656",
+		AccountNumber: utils.GenerateAccountNumber(),
+		Balance:       model.NewMoney(10000),
+	}
+	err := helper.CreateTestAccount(db, &existingAccount)
+	assert.NoError(t, err)
+
+	accountService := service.NewAccountService(repository.NewGorm(db, test.Config.PIIKeyring), service.NewLedger(), utils.Validator(), model.WithdrawalLimits{}, test.Config.PINPolicy, time.Local)
+
+	// 2. Race a hundred withdrawals against each other.
+	var wg sync.WaitGroup
+	var succeeded, insufficient atomic.Int64
+	start := make(chan struct{})
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			err := accountService.Withdraw(context.Background(), &model.Withdrawal{
+				AccountNumber: existingAccount.AccountNumber,
+				Nominal:       model.NewMoney(1000),
+				PIN:           helper.TestPIN,
+			})
+			switch {
+			case err == nil:
+				succeeded.Add(1)
+			case err.Code == http.StatusBadRequest && err.Message == service.ErrInsufficientBalance.Error():
+				insufficient.Add(1)
+			default:
+				t.Logf("withdrawal failed: %v", err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	// 3. Exactly the covered withdrawals went through.
+	assert.Equal(t, int64(10), succeeded.Load())
+	assert.Equal(t, int64(90), insufficient.Load())
+
+	updatedAccount, err := helper.GetAccountByNumber(db, existingAccount.AccountNumber)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(0), updatedAccount.Balance)
+
+	helper.ClearAll(db)
+}
@@ -0,0 +1,280 @@
+package integration
+
+import (
+	"account-service/src/model"
+	"account-service/src/repository"
+	"account-service/src/service"
+	"account-service/src/utils"
+	"account-service/test"
+	"account-service/test/helper"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// createFeeSchedule stores a fee schedule of the default product directly, so
+// it can take effect in the past.
+func createFeeSchedule(t *testing.T, feeType string, amount model.Money, freePerMonth int) model.FeeSchedule {
+	schedule := model.FeeSchedule{
+		ProductCode:   model.DefaultProductCode,
+		Type:          feeType,
+		Amount:        amount,
+		FreePerMonth:  freePerMonth,
+		EffectiveFrom: time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+	err := db.Create(&schedule).Error
+	assert.NoError(t, err)
+	return schedule
+}
+
+// postFeeSchedule calls the fee schedule endpoint as an admin.
+func postFeeSchedule(t *testing.T, req model.FeeScheduleRequest) *http.Response {
+	requestBody, _ := json.Marshal(req)
+	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/admin/produk/"+model.DefaultProductCode+"/biaya", string(requestBody), helper.AdminHeaders())
+	assert.NoError(t, err)
+	return resp
+}
+
+func TestFee_WithdrawalFeeAfterFreeWithdrawals(t *testing.T) {
+	helper.ClearAll(db)
+
+	schedule := createFeeSchedule(t, model.FeeTypeWithdrawal, model.NewMoney(2500), 2)
+	existingAccount := createLimitTestAccount(t, "6565656565656565", "086565656565")
+
+	// 1. The free withdrawals of the month carry no fee.
+	for i := 0; i < 2; i++ {
+		resp := withdraw(t, existingAccount.AccountNumber, model.NewMoney(10000))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	updatedAccount, err := helper.GetAccountByNumber(db, existingAccount.AccountNumber)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(980000), updatedAccount.Balance)
+
+	// 2. The next one is followed by a fee linked to it.
+	resp := withdraw(t, existingAccount.AccountNumber, model.NewMoney(10000))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var activities []model.CashActivity
+	err = db.Where("account_id = ?", existingAccount.ID).Order("id desc").Limit(2).Find(&activities).Error
+	assert.NoError(t, err)
+	if !assert.Len(t, activities, 2) {
+		return
+	}
+	fee, withdrawal := activities[0], activities[1]
+	assert.Equal(t, "debit", fee.Type)
+	assert.Equal(t, model.NewMoney(2500), fee.Nominal)
+	assert.Equal(t, withdrawal.ID, *fee.FeeOfID)
+	assert.Equal(t, schedule.ID, *fee.FeeScheduleID)
+	assert.Equal(t, model.NewMoney(967500), fee.BalanceAfter)
+
+	var entry model.JournalEntry
+	err = db.Preload("Lines").Where("cash_activity_id = ?", fee.ID).First(&entry).Error
+	assert.NoError(t, err)
+	for _, line := range entry.Lines {
+		switch line.GLAccountCode {
+		case model.GLFeeIncomeCode:
+			assert.Equal(t, model.NewMoney(-2500), line.Amount)
+		case model.GLCustomerDepositsCode:
+			assert.Equal(t, model.NewMoney(2500), line.Amount)
+		default:
+			t.Errorf("unexpected GL account %s", line.GLAccountCode)
+		}
+	}
+
+	// 3. The balance must cover the withdrawal and its fee.
+	resp = withdraw(t, existingAccount.AccountNumber, model.NewMoney(966000))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, service.ErrInsufficientBalance.Error(), errorMessage(t, resp))
+
+	resp = withdraw(t, existingAccount.AccountNumber, model.NewMoney(965000))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	updatedAccount, err = helper.GetAccountByNumber(db, existingAccount.AccountNumber)
+	assert.NoError(t, err)
+	assert.True(t, updatedAccount.Balance.IsZero())
+
+	helper.ClearAll(db)
+}
+
+func TestFee_NotCountedAsWithdrawals(t *testing.T) {
+	helper.ClearAll(db)
+
+	createFeeSchedule(t, model.FeeTypeWithdrawal, model.NewMoney(2500), 0)
+	existingAccount := createLimitTestAccount(t, "6666666666666666", "086666666666")
+	maxDailyCount := 2
+	setWithdrawalLimits(t, existingAccount.AccountNumber, model.WithdrawalLimitRequest{MaxDailyCount: &maxDailyCount})
+
+	// Two withdrawals and two fees stay within a limit of two withdrawals.
+	for i := 0; i < 2; i++ {
+		resp := withdraw(t, existingAccount.AccountNumber, model.NewMoney(10000))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	resp := withdraw(t, existingAccount.AccountNumber, model.NewMoney(10000))
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	updatedAccount, err := helper.GetAccountByNumber(db, existingAccount.AccountNumber)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(975000), updatedAccount.Balance)
+
+	helper.ClearAll(db)
+}
+
+func TestFee_ReversingWithdrawalRefundsFee(t *testing.T) {
+	helper.ClearAll(db)
+
+	createFeeSchedule(t, model.FeeTypeWithdrawal, model.NewMoney(2500), 0)
+	existingAccount := createLimitTestAccount(t, "6767676767676767", "086767676767")
+
+	resp := withdraw(t, existingAccount.AccountNumber, model.NewMoney(10000))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	fee := latestActivity(t, existingAccount.ID)
+	if !assert.NotNil(t, fee.FeeOfID) {
+		return
+	}
+
+	resp = reverse(t, *fee.FeeOfID, "Cash was not handed out")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	var apiResponse struct {
+		Data model.ReversalResponse `json:"data"`
+	}
+	err = json.Unmarshal(body, &apiResponse)
+	assert.NoError(t, err)
+	assert.NotNil(t, apiResponse.Data.FeeReversalID)
+	assert.Equal(t, model.NewMoney(1000000), apiResponse.Data.Balance)
+
+	var reversedFee model.CashActivity
+	err = db.First(&reversedFee, fee.ID).Error
+	assert.NoError(t, err)
+	assert.NotNil(t, reversedFee.ReversedAt)
+
+	helper.ClearAll(db)
+}
+
+func TestFee_SchedulesAreVersioned(t *testing.T) {
+	helper.ClearAll(db)
+
+	original := createFeeSchedule(t, model.FeeTypeWithdrawal, model.NewMoney(2500), 0)
+	existingAccount := createLimitTestAccount(t, "6868686868686868", "086868686868")
+
+	resp := withdraw(t, existingAccount.AccountNumber, model.NewMoney(10000))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	firstFee := latestActivity(t, existingAccount.ID)
+
+	// 1. A new version takes effect today; earlier dates are refused.
+	today := time.Now().In(test.Config.BusinessLocation)
+	resp = postFeeSchedule(t, model.FeeScheduleRequest{Type: model.FeeTypeWithdrawal, Amount: model.NewMoney(1000), EffectiveFrom: today.AddDate(0, 0, -1).Format(time.DateOnly)})
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, service.ErrFeeScheduleInThePast.Error(), errorMessage(t, resp))
+
+	resp = postFeeSchedule(t, model.FeeScheduleRequest{Type: model.FeeTypeWithdrawal, Amount: model.NewMoney(1000), EffectiveFrom: today.Format(time.DateOnly)})
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = postFeeSchedule(t, model.FeeScheduleRequest{Type: model.FeeTypeWithdrawal, Amount: model.NewMoney(1500), EffectiveFrom: today.Format(time.DateOnly)})
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp = postFeeSchedule(t, model.FeeScheduleRequest{Type: "transfer", Amount: model.NewMoney(1500), EffectiveFrom: today.Format(time.DateOnly)})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// 2. New fees follow the new version; the fee already charged keeps its own.
+	resp = withdraw(t, existingAccount.AccountNumber, model.NewMoney(10000))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	secondFee := latestActivity(t, existingAccount.ID)
+	assert.Equal(t, model.NewMoney(1000), secondFee.Nominal)
+	assert.NotEqual(t, original.ID, *secondFee.FeeScheduleID)
+
+	err := db.First(&firstFee, firstFee.ID).Error
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(2500), firstFee.Nominal)
+	assert.Equal(t, original.ID, *firstFee.FeeScheduleID)
+
+	// 3. Both versions are listed.
+	resp, err = helper.MakeRequest(app, http.MethodGet, "/v1/admin/produk/"+model.DefaultProductCode+"/biaya", "", helper.AdminHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	var apiResponse struct {
+		Data []model.FeeSchedule `json:"data"`
+	}
+	err = json.Unmarshal(body, &apiResponse)
+	assert.NoError(t, err)
+	assert.Len(t, apiResponse.Data, 2)
+
+	helper.ClearAll(db)
+}
+
+func TestFee_MonthlyAdminFee(t *testing.T) {
+	helper.ClearAll(db)
+
+	createFeeSchedule(t, model.FeeTypeMonthlyAdmin, model.NewMoney(5000), 0)
+	feeService := service.NewFeeService(repository.NewGorm(db, test.Config.PIIKeyring), service.NewLedger(), utils.Validator(), test.Config.BusinessLocation)
+	now := time.Now().In(test.Config.BusinessLocation)
+	lastMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, test.Config.BusinessLocation).AddDate(0, -1, 0)
+
+	createAccount := func(idNumber, phoneNumber string, balance model.Money, status string) model.Account {
+		account := model.Account{
+			FullName:      "Fee Test User",
+			IDNumber:      idNumber,
+			PhoneNumber:   phoneNumber,
+			AccountNumber: utils.GenerateAccountNumber(),
+			Balance:       balance,
+			Status:        status,
+			CreatedAt:     now.AddDate(0, -3, 0),
+		}
+		err := helper.CreateTestAccount(db, &account)
+		assert.NoError(t, err)
+		return account
+	}
+	fundedAccount := createAccount("6969696969696969", "086969696969", model.NewMoney(100000), model.AccountStatusActive)
+	createAccount("7070707070707070", "087070707070", model.NewMoney(1000), model.AccountStatusActive)
+	createAccount("7171717171717171", "087171717171", model.NewMoney(0), model.AccountStatusClosed)
+	frozenAccount := createAccount("7272727272727272", "087272727272", model.NewMoney(100000), model.AccountStatusFrozen)
+
+	// 1. The month that has not ended cannot be charged.
+	_, fiberErr := feeService.ChargeMonthlyFees(context.Background(), now)
+	if assert.NotNil(t, fiberErr) {
+		assert.Equal(t, http.StatusUnprocessableEntity, fiberErr.Code)
+	}
+
+	// 2. Last month charges the funded account and skips the one that cannot
+	// pay. The frozen account is not charged.
+	result, fiberErr := feeService.ChargeMonthlyFees(context.Background(), lastMonth)
+	if !assert.Nil(t, fiberErr) {
+		return
+	}
+	assert.Equal(t, lastMonth.Format("2006-01"), result.Month)
+	assert.Equal(t, 1, result.Charged)
+	assert.Equal(t, model.NewMoney(5000), result.Total)
+	assert.Equal(t, 1, result.Skipped)
+
+	fee := latestActivity(t, fundedAccount.ID)
+	assert.Equal(t, model.NewMoney(5000), fee.Nominal)
+	assert.Equal(t, "Monthly admin fee for "+lastMonth.Format("January 2006"), fee.Description)
+	assert.Nil(t, fee.FeeOfID)
+	if assert.NotNil(t, fee.FeePeriod) {
+		assert.Equal(t, lastMonth.Format(time.DateOnly), fee.FeePeriod.Format(time.DateOnly))
+	}
+
+	// 3. Charging the month again charges no one twice.
+	result, fiberErr = feeService.ChargeMonthlyFees(context.Background(), lastMonth)
+	assert.Nil(t, fiberErr)
+	assert.Equal(t, 0, result.Charged)
+
+	updatedAccount, err := helper.GetAccountByNumber(db, fundedAccount.AccountNumber)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(95000), updatedAccount.Balance)
+
+	updatedAccount, err = helper.GetAccountByNumber(db, frozenAccount.AccountNumber)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(100000), updatedAccount.Balance)
+
+	helper.ClearAll(db)
+}
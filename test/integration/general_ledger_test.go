@@ -0,0 +1,213 @@
+package integration
+
+import (
+	"account-service/src/model"
+	"account-service/src/utils"
+	"account-service/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// getGL calls one of the general ledger report endpoints and decodes its data.
+func getGL(t *testing.T, path string, data any) *http.Response {
+	resp, err := helper.MakeRequest(app, http.MethodGet, path, "", helper.AdminHeaders())
+	assert.NoError(t, err)
+	if resp.StatusCode != http.StatusOK {
+		return resp
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	apiResponse := struct {
+		Data any `json:"data"`
+	}{Data: data}
+	err = json.Unmarshal(body, &apiResponse)
+	assert.NoError(t, err)
+	return resp
+}
+
+func TestGeneralLedger_PostsBalancedJournals(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := model.Account{
+		FullName:      "Ledger Test User",
+		IDNumber:      "5555555555555555",
+		PhoneNumber:   "085555555555",
+		AccountNumber: utils.GenerateAccountNumber(),
+	}
+	err := helper.CreateTestAccount(db, &existingAccount)
+	assert.NoError(t, err)
+
+	// 1. Deposit, withdraw and reverse the withdrawal.
+	depositBody, _ := json.Marshal(model.DepositRequest{AccountNumber: existingAccount.AccountNumber, Nominal: model.NewMoney(100000)})
+	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tabung", string(depositBody), helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	withdrawalBody, _ := json.Marshal(model.Withdrawal{AccountNumber: existingAccount.AccountNumber, Nominal: model.NewMoney(30000), PIN: helper.TestPIN})
+	resp, err = helper.MakeRequest(app, http.MethodPost, "/v1/tarik", string(withdrawalBody), helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	withdrawal := latestActivity(t, existingAccount.ID)
+
+	resp = reverse(t, withdrawal.ID, "Cash was not handed out")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	reversal := latestActivity(t, existingAccount.ID)
+
+	// 2. Every activity has a journal whose lines sum to zero.
+	var entries []model.JournalEntry
+	err = db.Preload("Lines").Order("id asc").Find(&entries).Error
+	assert.NoError(t, err)
+	if !assert.Len(t, entries, 3) {
+		return
+	}
+	for _, entry := range entries {
+		assert.True(t, entry.IsBalanced(), entry.Description)
+		assert.NotNil(t, entry.CashActivityID, entry.Description)
+	}
+	assert.Equal(t, withdrawal.ID, *entries[1].CashActivityID)
+	assert.Equal(t, reversal.ID, *entries[2].CashActivityID)
+
+	amounts := func(entry model.JournalEntry) map[string]model.Money {
+		result := make(map[string]model.Money)
+		for _, line := range entry.Lines {
+			result[line.GLAccountCode] = line.Amount
+		}
+		return result
+	}
+	assert.Equal(t, map[string]model.Money{model.GLCashCode: model.NewMoney(100000), model.GLCustomerDepositsCode: model.NewMoney(-100000)}, amounts(entries[0]))
+	assert.Equal(t, map[string]model.Money{model.GLCashCode: model.NewMoney(-30000), model.GLCustomerDepositsCode: model.NewMoney(30000)}, amounts(entries[1]))
+	assert.Equal(t, map[string]model.Money{model.GLCashCode: model.NewMoney(30000), model.GLCustomerDepositsCode: model.NewMoney(-30000)}, amounts(entries[2]))
+
+	// 3. The trial balance shows the cash against the customer deposits.
+	var trialBalance model.TrialBalance
+	resp = getGL(t, "/v1/admin/gl/trial-balance", &trialBalance)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, trialBalance.Balanced)
+	assert.Equal(t, model.NewMoney(100000), trialBalance.TotalDebit)
+	assert.Equal(t, model.NewMoney(100000), trialBalance.TotalCredit)
+	for _, line := range trialBalance.Accounts {
+		switch line.Code {
+		case model.GLCashCode:
+			assert.Equal(t, model.NewMoney(100000), line.Debit)
+		case model.GLCustomerDepositsCode:
+			assert.Equal(t, model.NewMoney(100000), line.Credit)
+		default:
+			assert.True(t, line.Debit.IsZero() && line.Credit.IsZero(), line.Code)
+		}
+	}
+
+	// 4. The customer balances equal the liability.
+	var reconciliation model.GLReconciliation
+	resp = getGL(t, "/v1/admin/gl/reconcile", &reconciliation)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, reconciliation.Balanced)
+	assert.Equal(t, model.NewMoney(100000), reconciliation.CustomerBalances)
+	assert.Equal(t, model.NewMoney(100000), reconciliation.LiabilityBalance)
+
+	helper.ClearAll(db)
+}
+
+func TestGeneralLedger_TrialBalanceAsOfDate(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := model.Account{
+		FullName:      "Ledger Test User",
+		IDNumber:      "5656565656565656",
+		PhoneNumber:   "085656565656",
+		AccountNumber: utils.GenerateAccountNumber(),
+	}
+	err := helper.CreateTestAccount(db, &existingAccount)
+	assert.NoError(t, err)
+
+	depositBody, _ := json.Marshal(model.DepositRequest{AccountNumber: existingAccount.AccountNumber, Nominal: model.NewMoney(100000)})
+	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tabung", string(depositBody), helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Journals of today are not part of an earlier trial balance.
+	earlier := time.Now().AddDate(0, 0, -2).Format(time.DateOnly)
+	var trialBalance model.TrialBalance
+	resp = getGL(t, "/v1/admin/gl/trial-balance?tanggal="+earlier, &trialBalance)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, earlier, trialBalance.Date)
+	assert.True(t, trialBalance.TotalDebit.IsZero())
+	assert.True(t, trialBalance.Balanced)
+
+	resp = getGL(t, "/v1/admin/gl/trial-balance?tanggal=31-01-2025", nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	helper.ClearAll(db)
+}
+
+func TestGeneralLedger_ReconcileDetectsDrift(t *testing.T) {
+	helper.ClearAll(db)
+
+	// A balance written without a journal breaks the invariant.
+	existingAccount := model.Account{
+		FullName:      "Ledger Test User",
+		IDNumber:      "5757575757575757",
+		PhoneNumber:   "085757575757",
+		AccountNumber: utils.GenerateAccountNumber(),
+		Balance:       model.NewMoney(2500),
+	}
+	err := helper.CreateTestAccount(db, &existingAccount)
+	assert.NoError(t, err)
+
+	var reconciliation model.GLReconciliation
+	resp := getGL(t, "/v1/admin/gl/reconcile", &reconciliation)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.False(t, reconciliation.Balanced)
+	assert.Equal(t, model.NewMoney(2500), reconciliation.Difference)
+
+	helper.ClearAll(db)
+}
+
+func TestGeneralLedger_RejectsUnbalancedJournal(t *testing.T) {
+	helper.ClearAll(db)
+
+	// The database refuses to commit a journal whose lines do not sum to zero.
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&model.JournalEntry{
+			Description: "Unbalanced",
+			Lines: []model.JournalLine{
+				{GLAccountCode: model.GLCashCode, Amount: model.NewMoney(100)},
+				{GLAccountCode: model.GLCustomerDepositsCode, Amount: model.NewMoney(-99)},
+			},
+		}).Error
+	})
+	assert.Error(t, err)
+
+	var count int64
+	err = db.Model(&model.JournalEntry{}).Count(&count).Error
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+
+	// The lines of an entry that balances can be inserted one by one.
+	err = db.Transaction(func(tx *gorm.DB) error {
+		entry := model.JournalEntry{Description: "Balanced"}
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+		for _, line := range []model.JournalLine{
+			{JournalEntryID: entry.ID, GLAccountCode: model.GLCashCode, Amount: model.NewMoney(100)},
+			{JournalEntryID: entry.ID, GLAccountCode: model.GLCustomerDepositsCode, Amount: model.NewMoney(-100)},
+		} {
+			if err := tx.Create(&line).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+
+	helper.ClearAll(db)
+}
@@ -36,12 +36,18 @@ func TestHealthCheckRoutes(t *testing.T) {
 			assert.Equal(t, "success", responseBody.Status)
 			assert.Equal(t, "Health check completed", responseBody.Message)
 			assert.Equal(t, true, responseBody.IsHealthy)
+			assert.NotNil(t, responseBody.SchemaVersion)
 			assert.Equal(t, []response.HealthCheck{
 				{
 					Name:   "Postgre",
 					Status: "Up",
 					IsUp:   true,
 				},
+				{
+					Name:   "Schema",
+					Status: "Up",
+					IsUp:   true,
+				},
 				{
 					Name:   "Memory",
 					Status: "Up",
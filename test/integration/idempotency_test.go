@@ -0,0 +1,263 @@
+package integration
+
+import (
+	"account-service/src/controller"
+	"account-service/src/middleware"
+	"account-service/src/model"
+	"account-service/src/repository"
+	"account-service/src/response"
+	"account-service/src/service"
+	"account-service/src/utils"
+	"account-service/test"
+	"account-service/test/helper"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotency_ReplaysDeposit(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := model.Account{
+		FullName:      "Idempotency Test User",
+		IDNumber:      "1313131313131313",
+		PhoneNumber:   "081313131313",
+		AccountNumber: utils.GenerateAccountNumber(),
+	}
+	err := helper.CreateTestAccount(db, &existingAccount)
+	assert.NoError(t, err)
+
+	requestBody, _ := json.Marshal(model.DepositRequest{
+		AccountNumber: existingAccount.AccountNumber,
+		Nominal:       model.NewMoney(100000),
+	})
+	headers := helper.TellerHeaders()
+	headers[middleware.IdempotencyKeyHeader] = "deposit-replay-key"
+
+	// 1. The first request is processed.
+	first, err := helper.MakeRequest(app, http.MethodPost, "/v1/tabung", string(requestBody), headers)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, first.StatusCode)
+	assert.Empty(t, first.Header.Get(middleware.IdempotentReplayedHeader))
+	firstBody, err := io.ReadAll(first.Body)
+	assert.NoError(t, err)
+	defer first.Body.Close()
+
+	// 2. The retry gets the original response without posting again.
+	retry, err := helper.MakeRequest(app, http.MethodPost, "/v1/tabung", string(requestBody), headers)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, retry.StatusCode)
+	assert.Equal(t, "true", retry.Header.Get(middleware.IdempotentReplayedHeader))
+	retryBody, err := io.ReadAll(retry.Body)
+	assert.NoError(t, err)
+	defer retry.Body.Close()
+	assert.Equal(t, string(firstBody), string(retryBody))
+
+	updatedAccount, err := helper.GetAccountByNumber(db, existingAccount.AccountNumber)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(100000), updatedAccount.Balance)
+
+	var count int64
+	err = db.Model(&model.CashActivity{}).Where("account_id = ?", existingAccount.ID).Count(&count).Error
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	helper.ClearAll(db)
+}
+
+func TestIdempotency_ReplaysErrorResponse(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := model.Account{
+		FullName:      "Idempotency Test User",
+		IDNumber:      "1414141414141414",
+		PhoneNumber:   "081414141414",
+		AccountNumber: utils.GenerateAccountNumber(),
+		Balance:       model.NewMoney(1000),
+	}
+	err := helper.CreateTestAccount(db, &existingAccount)
+	assert.NoError(t, err)
+
+	requestBody, _ := json.Marshal(model.Withdrawal{
+		AccountNumber: existingAccount.AccountNumber,
+		Nominal:       model.NewMoney(5000),
+		PIN:           helper.TestPIN,
+	})
+	headers := helper.TellerHeaders()
+	headers[middleware.IdempotencyKeyHeader] = "withdrawal-replay-key"
+
+	first, err := helper.MakeRequest(app, http.MethodPost, "/v1/tarik", string(requestBody), headers)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, first.StatusCode)
+
+	// A deposit in between must not change the replayed outcome.
+	err = helper.UpdateAccountBalance(db, existingAccount.ID, model.NewMoney(10000))
+	assert.NoError(t, err)
+
+	retry, err := helper.MakeRequest(app, http.MethodPost, "/v1/tarik", string(requestBody), headers)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, retry.StatusCode)
+	assert.Equal(t, "true", retry.Header.Get(middleware.IdempotentReplayedHeader))
+
+	updatedAccount, err := helper.GetAccountByNumber(db, existingAccount.AccountNumber)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(10000), updatedAccount.Balance)
+
+	helper.ClearAll(db)
+}
+
+func TestIdempotency_KeyReusedWithDifferentBody(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := model.Account{
+		FullName:      "Idempotency Test User",
+		IDNumber:      "1515151515151515",
+		PhoneNumber:   "081515151515",
+		AccountNumber: utils.GenerateAccountNumber(),
+	}
+	err := helper.CreateTestAccount(db, &existingAccount)
+	assert.NoError(t, err)
+
+	headers := helper.TellerHeaders()
+	headers[middleware.IdempotencyKeyHeader] = "deposit-reused-key"
+
+	firstBody, _ := json.Marshal(model.DepositRequest{
+		AccountNumber: existingAccount.AccountNumber,
+		Nominal:       model.NewMoney(100000),
+	})
+	first, err := helper.MakeRequest(app, http.MethodPost, "/v1/tabung", string(firstBody), headers)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, first.StatusCode)
+
+	secondBody, _ := json.Marshal(model.DepositRequest{
+		AccountNumber: existingAccount.AccountNumber,
+		Nominal:       model.NewMoney(200000),
+	})
+	second, err := helper.MakeRequest(app, http.MethodPost, "/v1/tabung", string(secondBody), headers)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnprocessableEntity, second.StatusCode)
+
+	body, err := io.ReadAll(second.Body)
+	assert.NoError(t, err)
+	defer second.Body.Close()
+
+	var errorResponse response.ErrorDetails
+	err = json.Unmarshal(body, &errorResponse)
+	assert.NoError(t, err)
+	assert.Equal(t, service.ErrIdempotencyKeyReused.Error(), errorResponse.Message)
+
+	updatedAccount, err := helper.GetAccountByNumber(db, existingAccount.AccountNumber)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(100000), updatedAccount.Balance)
+
+	helper.ClearAll(db)
+}
+
+func TestIdempotency_KeyScopedToCaller(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := model.Account{
+		FullName:      "Idempotency Test User",
+		IDNumber:      "1717171717171717",
+		PhoneNumber:   "081717171717",
+		AccountNumber: utils.GenerateAccountNumber(),
+	}
+	err := helper.CreateTestAccount(db, &existingAccount)
+	assert.NoError(t, err)
+
+	requestBody, _ := json.Marshal(model.DepositRequest{
+		AccountNumber: existingAccount.AccountNumber,
+		Nominal:       model.NewMoney(100000),
+	})
+
+	// Two callers that happen to choose the same key each get their deposit.
+	for _, headers := range []map[string]string{helper.TellerHeaders(), helper.CustomerHeaders(existingAccount.AccountNumber)} {
+		headers[middleware.IdempotencyKeyHeader] = "deposit-shared-key"
+		resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tabung", string(requestBody), headers)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get(middleware.IdempotentReplayedHeader))
+	}
+
+	updatedAccount, err := helper.GetAccountByNumber(db, existingAccount.AccountNumber)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(200000), updatedAccount.Balance)
+
+	helper.ClearAll(db)
+}
+
+func TestIdempotency_ExpiredKey(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := model.Account{
+		FullName:      "Idempotency Test User",
+		IDNumber:      "1616161616161616",
+		PhoneNumber:   "081616161616",
+		AccountNumber: utils.GenerateAccountNumber(),
+	}
+	err := helper.CreateTestAccount(db, &existingAccount)
+	assert.NoError(t, err)
+
+	// A dedicated app whose keys expire almost immediately.
+	validate := utils.Validator()
+	jwt := middleware.NewJWT(test.Config.JWTSecret, test.Config.JWTTTL)
+	accountController := controller.NewAccountController(service.NewAccountService(repository.NewGorm(db, test.Config.PIIKeyring), service.NewLedger(), validate, model.WithdrawalLimits{}, test.Config.PINPolicy, time.Local), jwt, validate)
+	shortLived := fiber.New()
+	shortLived.Post("/v1/tabung",
+		middleware.Auth(jwt, middleware.RoleTeller),
+		middleware.Idempotency(service.NewIdempotencyService(repository.NewGorm(db, test.Config.PIIKeyring), time.Millisecond)),
+		accountController.Deposit)
+
+	requestBody, _ := json.Marshal(model.DepositRequest{
+		AccountNumber: existingAccount.AccountNumber,
+		Nominal:       model.NewMoney(100000),
+	})
+	headers := helper.TellerHeaders()
+	headers[middleware.IdempotencyKeyHeader] = "deposit-expired-key"
+
+	first, err := helper.MakeRequest(shortLived, http.MethodPost, "/v1/tabung", string(requestBody), headers)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, first.StatusCode)
+
+	time.Sleep(10 * time.Millisecond)
+
+	// Once expired, the same key starts a new request.
+	second, err := helper.MakeRequest(shortLived, http.MethodPost, "/v1/tabung", string(requestBody), headers)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, second.StatusCode)
+	assert.Empty(t, second.Header.Get(middleware.IdempotentReplayedHeader))
+
+	updatedAccount, err := helper.GetAccountByNumber(db, existingAccount.AccountNumber)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(200000), updatedAccount.Balance)
+
+	helper.ClearAll(db)
+}
+
+func TestIdempotency_PurgeDeletesExpiredKeys(t *testing.T) {
+	helper.ClearAll(db)
+
+	now := time.Now()
+	keys := []model.IdempotencyKey{
+		{Principal: "teller:teller-01", Key: "expired-key", RequestHash: "hash", ExpiresAt: now.Add(-time.Minute)},
+		{Principal: "teller:teller-01", Key: "live-key", RequestHash: "hash", ExpiresAt: now.Add(time.Hour)},
+	}
+	assert.NoError(t, db.Create(&keys).Error)
+
+	purged, err := service.NewIdempotencyKeyPurger(repository.NewGorm(db, test.Config.PIIKeyring), time.Minute).PurgeOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	var remaining []model.IdempotencyKey
+	assert.NoError(t, db.Find(&remaining).Error)
+	assert.Len(t, remaining, 1)
+	assert.Equal(t, "live-key", remaining[0].Key)
+
+	helper.ClearAll(db)
+}
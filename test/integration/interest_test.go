@@ -0,0 +1,263 @@
+package integration
+
+import (
+	"account-service/src/model"
+	"account-service/src/repository"
+	"account-service/src/service"
+	"account-service/src/utils"
+	"account-service/test"
+	"account-service/test/helper"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setProductRate calls the product rate endpoint as an admin.
+func setProductRate(t *testing.T, code string, annualRateBPS int) *http.Response {
+	requestBody, _ := json.Marshal(model.ProductRateRequest{AnnualRateBPS: &annualRateBPS})
+	resp, err := helper.MakeRequest(app, http.MethodPut, "/v1/admin/produk/"+code, string(requestBody), helper.AdminHeaders())
+	assert.NoError(t, err)
+	return resp
+}
+
+// pendingInterest calls the pending interest endpoint as the account's
+// customer and decodes its data.
+func pendingInterest(t *testing.T, accountNumber string) model.PendingInterestResponse {
+	resp, err := helper.MakeRequest(app, http.MethodGet, "/v1/bunga/"+accountNumber, "", helper.CustomerHeaders(accountNumber))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var apiResponse struct {
+		Data model.PendingInterestResponse `json:"data"`
+	}
+	err = json.Unmarshal(body, &apiResponse)
+	assert.NoError(t, err)
+	return apiResponse.Data
+}
+
+func TestInterest_AccruesDailyAndCapitalizesAtMonthEnd(t *testing.T) {
+	helper.ClearAll(db)
+
+	interestService := service.NewInterestService(repository.NewGorm(db, test.Config.PIIKeyring), service.NewLedger(), utils.Validator(), test.Config.BusinessLocation)
+	now := time.Now().In(test.Config.BusinessLocation)
+	monthEnd := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, test.Config.BusinessLocation).AddDate(0, 0, -1)
+	dayBefore := monthEnd.AddDate(0, 0, -1)
+
+	// 36.5% a year is 0.1% a day, 1000 a day on 1000000.
+	resp := setProductRate(t, model.DefaultProductCode, 3650)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	existingAccount := model.Account{
+		FullName:      "Interest Test User",
+		IDNumber:      "5858585858585858",
+		PhoneNumber:   "085858585858",
+		AccountNumber: utils.GenerateAccountNumber(),
+		Balance:       model.NewMoney(1000000),
+		CreatedAt:     now.AddDate(0, -3, 0),
+	}
+	err := helper.CreateTestAccount(db, &existingAccount)
+	assert.NoError(t, err)
+
+	// 1. A day before month end accrues without crediting.
+	run, fiberErr := interestService.Run(context.Background(), dayBefore)
+	if !assert.Nil(t, fiberErr) {
+		return
+	}
+	assert.False(t, run.AlreadyCompleted)
+	assert.Equal(t, 1, run.AccruedAccounts)
+	assert.Equal(t, model.NewMoney(1000), run.TotalAccrued)
+	assert.Equal(t, 0, run.CapitalizedAccounts)
+
+	pending := pendingInterest(t, existingAccount.AccountNumber)
+	assert.Equal(t, model.NewMoney(1000), pending.Pending)
+	assert.Equal(t, 3650, pending.AnnualRateBPS)
+	if assert.Len(t, pending.Accruals, 1) {
+		assert.Equal(t, dayBefore.Format(time.DateOnly), pending.Accruals[0].Date)
+		assert.Equal(t, model.NewMoney(1000000), pending.Accruals[0].Balance)
+	}
+
+	// 2. Running the same date again does nothing.
+	run, fiberErr = interestService.Run(context.Background(), dayBefore)
+	assert.Nil(t, fiberErr)
+	assert.True(t, run.AlreadyCompleted)
+
+	var accrualCount int64
+	err = db.Model(&model.InterestAccrual{}).Count(&accrualCount).Error
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), accrualCount)
+
+	// 3. Month end credits the interest of the month in one activity.
+	run, fiberErr = interestService.Run(context.Background(), monthEnd)
+	if !assert.Nil(t, fiberErr) {
+		return
+	}
+	assert.Equal(t, 1, run.CapitalizedAccounts)
+	assert.Equal(t, model.NewMoney(2000), run.TotalCapitalized)
+
+	updatedAccount, err := helper.GetAccountByNumber(db, existingAccount.AccountNumber)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(1002000), updatedAccount.Balance)
+
+	credit := latestActivity(t, existingAccount.ID)
+	assert.Equal(t, "credit", credit.Type)
+	assert.Equal(t, model.NewMoney(2000), credit.Nominal)
+	assert.Equal(t, "Interest for "+monthEnd.Format("January 2006"), credit.Description)
+
+	var entry model.JournalEntry
+	err = db.Preload("Lines").Where("cash_activity_id = ?", credit.ID).First(&entry).Error
+	assert.NoError(t, err)
+	assert.True(t, entry.IsBalanced())
+	for _, line := range entry.Lines {
+		switch line.GLAccountCode {
+		case model.GLInterestExpenseCode:
+			assert.Equal(t, model.NewMoney(2000), line.Amount)
+		case model.GLCustomerDepositsCode:
+			assert.Equal(t, model.NewMoney(-2000), line.Amount)
+		default:
+			t.Errorf("unexpected GL account %s", line.GLAccountCode)
+		}
+	}
+
+	pending = pendingInterest(t, existingAccount.AccountNumber)
+	assert.True(t, pending.Pending.IsZero())
+	assert.Empty(t, pending.Accruals)
+
+	// 4. Re-running month end credits nothing more.
+	_, fiberErr = interestService.Run(context.Background(), monthEnd)
+	assert.Nil(t, fiberErr)
+
+	updatedAccount, err = helper.GetAccountByNumber(db, existingAccount.AccountNumber)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(1002000), updatedAccount.Balance)
+
+	helper.ClearAll(db)
+}
+
+func TestInterest_EndOfDayBalance(t *testing.T) {
+	helper.ClearAll(db)
+
+	interestService := service.NewInterestService(repository.NewGorm(db, test.Config.PIIKeyring), service.NewLedger(), utils.Validator(), test.Config.BusinessLocation)
+	now := time.Now().In(test.Config.BusinessLocation)
+	resp := setProductRate(t, model.DefaultProductCode, 3650)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// An account whose first activity is today earned on its balance before it.
+	existingAccount := model.Account{
+		FullName:      "Interest Test User",
+		IDNumber:      "5959595959595959",
+		PhoneNumber:   "085959595959",
+		AccountNumber: utils.GenerateAccountNumber(),
+		Balance:       model.NewMoney(500000),
+		CreatedAt:     now.AddDate(0, -1, 0),
+	}
+	err := helper.CreateTestAccount(db, &existingAccount)
+	assert.NoError(t, err)
+
+	depositBody, _ := json.Marshal(model.DepositRequest{AccountNumber: existingAccount.AccountNumber, Nominal: model.NewMoney(500000)})
+	resp, err = helper.MakeRequest(app, http.MethodPost, "/v1/tabung", string(depositBody), helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Closed accounts and accounts opened later earn nothing.
+	closedAccount := model.Account{
+		FullName:      "Interest Test User",
+		IDNumber:      "6060606060606060",
+		PhoneNumber:   "086060606060",
+		AccountNumber: utils.GenerateAccountNumber(),
+		Status:        model.AccountStatusClosed,
+		Balance:       model.NewMoney(500000),
+		CreatedAt:     now.AddDate(0, -1, 0),
+	}
+	err = helper.CreateTestAccount(db, &closedAccount)
+	assert.NoError(t, err)
+	newAccount := model.Account{
+		FullName:      "Interest Test User",
+		IDNumber:      "6161616161616161",
+		PhoneNumber:   "086161616161",
+		AccountNumber: utils.GenerateAccountNumber(),
+		Balance:       model.NewMoney(500000),
+	}
+	err = helper.CreateTestAccount(db, &newAccount)
+	assert.NoError(t, err)
+
+	run, fiberErr := interestService.Run(context.Background(), now.AddDate(0, 0, -2))
+	if !assert.Nil(t, fiberErr) {
+		return
+	}
+	assert.Equal(t, 1, run.AccruedAccounts)
+	assert.Equal(t, model.NewMoney(500), run.TotalAccrued)
+
+	var accrual model.InterestAccrual
+	err = db.Where("account_id = ?", existingAccount.ID).First(&accrual).Error
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(500000), accrual.Balance)
+	assert.Equal(t, 3650, accrual.AnnualRateBPS)
+
+	helper.ClearAll(db)
+}
+
+func TestInterest_RunRefusesOpenBusinessDay(t *testing.T) {
+	helper.ClearAll(db)
+
+	interestService := service.NewInterestService(repository.NewGorm(db, test.Config.PIIKeyring), service.NewLedger(), utils.Validator(), test.Config.BusinessLocation)
+	_, fiberErr := interestService.Run(context.Background(), time.Now().In(test.Config.BusinessLocation))
+	if assert.NotNil(t, fiberErr) {
+		assert.Equal(t, http.StatusUnprocessableEntity, fiberErr.Code)
+		assert.Equal(t, service.ErrBusinessDayNotOver.Error(), fiberErr.Message)
+	}
+
+	var count int64
+	err := db.Model(&model.InterestRun{}).Count(&count).Error
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+
+	helper.ClearAll(db)
+}
+
+func TestInterest_SetProductRate(t *testing.T) {
+	helper.ClearAll(db)
+
+	resp := setProductRate(t, model.DefaultProductCode, 250)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var product model.Product
+	err := db.Where("code = ?", model.DefaultProductCode).First(&product).Error
+	assert.NoError(t, err)
+	assert.Equal(t, 250, product.AnnualRateBPS)
+
+	resp = setProductRate(t, model.DefaultProductCode, 10001)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = setProductRate(t, "giro", 250)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, service.ErrProductNotFound.Error(), errorMessage(t, resp))
+
+	resp, err = helper.MakeRequest(app, http.MethodPut, "/v1/admin/produk/"+model.DefaultProductCode, `{"suku_bunga_bps": 250}`, helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Accounts can only be opened with a known product.
+	registerBody, _ := json.Marshal(model.CreateAccount{FullName: "Interest Test User", IDNumber: "6464646464646464", PhoneNumber: "086464646464", PIN: helper.TestPIN, ProductCode: "giro"})
+	resp, err = helper.MakeRequest(app, http.MethodPost, "/v1/daftar", string(registerBody), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, service.ErrProductNotFound.Error(), errorMessage(t, resp))
+
+	// Customers see only their own pending interest.
+	existingAccount := createPINTestAccount(t, "6262626262626262", "086262626262")
+	otherAccount := createPINTestAccount(t, "6363636363636363", "086363636363")
+	resp, err = helper.MakeRequest(app, http.MethodGet, "/v1/bunga/"+otherAccount.AccountNumber, "", helper.CustomerHeaders(existingAccount.AccountNumber))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	helper.ClearAll(db)
+}
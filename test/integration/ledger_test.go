@@ -0,0 +1,179 @@
+package integration
+
+import (
+	"account-service/src/model"
+	"account-service/src/response"
+	"account-service/src/service"
+	"account-service/src/utils"
+	"account-service/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// verifyLedger calls the ledger verification endpoint for an account.
+func verifyLedger(t *testing.T, accountNumber string) model.LedgerVerification {
+	resp, err := helper.MakeRequest(app, http.MethodGet, "/v1/admin/ledger/"+accountNumber+"/verify", "", helper.AdminHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var apiResponse struct {
+		Data model.LedgerVerification `json:"data"`
+	}
+	err = json.Unmarshal(body, &apiResponse)
+	assert.NoError(t, err)
+	return apiResponse.Data
+}
+
+// postLedgerActivities makes a deposit and two withdrawals on a fresh account.
+func postLedgerActivities(t *testing.T, idNumber, phoneNumber string) model.Account {
+	existingAccount := model.Account{
+		FullName:      "Ledger Test User",
+		IDNumber:      idNumber,
+		PhoneNumber:   phoneNumber,
+		AccountNumber: utils.GenerateAccountNumber(),
+	}
+	err := helper.CreateTestAccount(db, &existingAccount)
+	assert.NoError(t, err)
+
+	depositBody, _ := json.Marshal(model.DepositRequest{AccountNumber: existingAccount.AccountNumber, Nominal: model.NewMoney(300000)})
+	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tabung", string(depositBody), helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	for i := 0; i < 2; i++ {
+		withdrawalBody, _ := json.Marshal(model.Withdrawal{AccountNumber: existingAccount.AccountNumber, Nominal: model.NewMoney(50000), PIN: helper.TestPIN})
+		resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tarik", string(withdrawalBody), helper.TellerHeaders())
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	return existingAccount
+}
+
+func TestVerifyLedger_Valid(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := postLedgerActivities(t, "2121212121212121", "082121212121")
+
+	verification := verifyLedger(t, existingAccount.AccountNumber)
+	assert.True(t, verification.Valid)
+	assert.Equal(t, 3, verification.CheckedEntries)
+	assert.Nil(t, verification.BrokenAt)
+	assert.Empty(t, verification.Reason)
+
+	helper.ClearAll(db)
+}
+
+func TestVerifyLedger_TamperedNominal(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := postLedgerActivities(t, "2323232323232323", "082323232323")
+
+	// Rewrite the second activity behind the service's back.
+	var activities []model.CashActivity
+	err := db.Where("account_id = ?", existingAccount.ID).Order("id asc").Find(&activities).Error
+	assert.NoError(t, err)
+	err = db.Model(&model.CashActivity{}).Where("id = ?", activities[1].ID).Update("nominal", model.NewMoney(5000)).Error
+	assert.NoError(t, err)
+
+	verification := verifyLedger(t, existingAccount.AccountNumber)
+	assert.False(t, verification.Valid)
+	assert.Equal(t, 2, verification.CheckedEntries)
+	assert.Equal(t, &activities[1].ID, verification.BrokenAt)
+	assert.Equal(t, "hash mismatch", verification.Reason)
+
+	helper.ClearAll(db)
+}
+
+func TestVerifyLedger_DeletedActivity(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := postLedgerActivities(t, "2424242424242424", "082424242424")
+
+	var activities []model.CashActivity
+	err := db.Where("account_id = ?", existingAccount.ID).Order("id asc").Find(&activities).Error
+	assert.NoError(t, err)
+	// The journal entry of the activity references it, so it goes first.
+	entries := db.Model(&model.JournalEntry{}).Select("id").Where("cash_activity_id = ?", activities[1].ID)
+	err = db.Where("journal_entry_id IN (?)", entries).Delete(&model.JournalLine{}).Error
+	assert.NoError(t, err)
+	err = db.Where("cash_activity_id = ?", activities[1].ID).Delete(&model.JournalEntry{}).Error
+	assert.NoError(t, err)
+	err = db.Delete(&model.CashActivity{}, activities[1].ID).Error
+	assert.NoError(t, err)
+
+	verification := verifyLedger(t, existingAccount.AccountNumber)
+	assert.False(t, verification.Valid)
+	assert.Equal(t, &activities[2].ID, verification.BrokenAt)
+	assert.Equal(t, "reference_id does not point to the previous activity", verification.Reason)
+
+	helper.ClearAll(db)
+}
+
+func TestVerifyLedger_RepointedReversal(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := postLedgerActivities(t, "2626262626262626", "082626262626")
+
+	var activities []model.CashActivity
+	err := db.Where("account_id = ?", existingAccount.ID).Order("id asc").Find(&activities).Error
+	assert.NoError(t, err)
+	resp := reverse(t, activities[2].ID, "Withdrawal posted twice")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Point the reversal at the other withdrawal behind the service's back.
+	reversal := latestActivity(t, existingAccount.ID)
+	err = db.Model(&model.CashActivity{}).Where("id = ?", reversal.ID).Update("reversal_of_id", activities[1].ID).Error
+	assert.NoError(t, err)
+
+	verification := verifyLedger(t, existingAccount.AccountNumber)
+	assert.False(t, verification.Valid)
+	assert.Equal(t, &reversal.ID, verification.BrokenAt)
+	assert.Equal(t, "hash mismatch", verification.Reason)
+
+	helper.ClearAll(db)
+}
+
+func TestVerifyLedger_BalanceDiscontinuity(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := postLedgerActivities(t, "2525252525252525", "082525252525")
+
+	// The account balance drifts away from the activity history.
+	err := helper.UpdateAccountBalance(db, existingAccount.ID, model.NewMoney(1))
+	assert.NoError(t, err)
+
+	verification := verifyLedger(t, existingAccount.AccountNumber)
+	assert.False(t, verification.Valid)
+	assert.Equal(t, 3, verification.CheckedEntries)
+	assert.Equal(t, "account balance does not match the last balance_after", verification.Reason)
+
+	helper.ClearAll(db)
+}
+
+func TestVerifyLedger_AccountNotFound(t *testing.T) {
+	helper.ClearAll(db)
+
+	resp, err := helper.MakeRequest(app, http.MethodGet, "/v1/admin/ledger/9999999999/verify", "", helper.AdminHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var errorResponse response.ErrorDetails
+	err = json.Unmarshal(body, &errorResponse)
+	assert.NoError(t, err)
+	assert.Equal(t, service.ErrAccountNotFound.Error(), errorResponse.Message)
+
+	helper.ClearAll(db)
+}
@@ -0,0 +1,49 @@
+package integration
+
+import (
+	"account-service/test"
+	"account-service/test/helper"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsRoutes(t *testing.T) {
+	t.Run("GET /metrics", func(t *testing.T) {
+		t.Run("should expose the business and runtime metrics in the Prometheus text format", func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			for key, value := range helper.AdminHeaders() {
+				request.Header.Set(key, value)
+			}
+
+			apiResponse, err := test.App.Test(request, 2000)
+			require.Nil(t, err)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Contains(t, apiResponse.Header.Get("Content-Type"), "text/plain")
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			require.Nil(t, err)
+			body := string(bytes)
+			assert.Contains(t, body, `account_deposits_total{outcome="insufficient_balance"}`)
+			assert.Contains(t, body, `account_withdrawals_total{outcome="success"}`)
+			assert.Contains(t, body, "go_goroutines")
+			assert.NotContains(t, body, "worker=")
+		})
+
+		t.Run("should refuse a request without a token", func(t *testing.T) {
+			apiResponse, err := test.App.Test(httptest.NewRequest(http.MethodGet, "/metrics", nil), 2000)
+			require.Nil(t, err)
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should refuse a teller", func(t *testing.T) {
+			apiResponse, err := helper.MakeRequest(test.App, http.MethodGet, "/metrics", "", helper.TellerHeaders())
+			require.Nil(t, err)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+	})
+}
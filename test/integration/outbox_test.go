@@ -0,0 +1,226 @@
+package integration
+
+import (
+	"account-service/src/model"
+	"account-service/src/repository"
+	"account-service/src/service"
+	"account-service/test"
+	"account-service/test/helper"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingPublisher records the events it is given and fails those of the
+// accounts in failing.
+type recordingPublisher struct {
+	mu        sync.Mutex
+	published []model.Event
+	failing   map[string]bool
+}
+
+func (p *recordingPublisher) Publish(c context.Context, event *model.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failing[event.AccountNumber] {
+		return errors.New("consumer unavailable")
+	}
+	p.published = append(p.published, *event)
+	return nil
+}
+
+var testOutboxPolicy = model.OutboxPolicy{
+	PollInterval:  10 * time.Millisecond,
+	RetryDelay:    time.Minute,
+	MaxRetryDelay: time.Hour,
+	BatchSize:     100,
+	ClaimTimeout:  time.Minute,
+}
+
+// outboxEvents returns the outbox events of an account, oldest first.
+func outboxEvents(t *testing.T, accountNumber string) []model.OutboxEvent {
+	var events []model.OutboxEvent
+	err := db.Where("account_number = ?", accountNumber).Order("id asc").Find(&events).Error
+	assert.NoError(t, err)
+	return events
+}
+
+func TestOutbox_EventsRecordedWithChanges(t *testing.T) {
+	helper.ClearAll(db)
+
+	registerBody, _ := json.Marshal(model.CreateAccount{FullName: "Outbox Test User", IDNumber: "7676767676767676", PhoneNumber: "087676767676", PIN: helper.TestPIN})
+	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/daftar", string(registerBody), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var account model.Account
+	err = db.Where("id_number_hash = ?", test.Config.PIIKeyring.BlindIndex("7676767676767676")).First(&account).Error
+	assert.NoError(t, err)
+
+	depositBody, _ := json.Marshal(model.DepositRequest{AccountNumber: account.AccountNumber, Nominal: model.NewMoney(50000)})
+	resp, err = helper.MakeRequest(app, http.MethodPost, "/v1/tabung", string(depositBody), helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// A change that is rolled back records no event.
+	resp = withdraw(t, account.AccountNumber, model.NewMoney(60000))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	events := outboxEvents(t, account.AccountNumber)
+	if !assert.Len(t, events, 2) {
+		return
+	}
+	assert.Equal(t, model.EventAccountCreated, events[0].Type)
+	assert.Equal(t, model.EventFundsDeposited, events[1].Type)
+	assert.Nil(t, events[1].PublishedAt)
+
+	var data model.ActivityData
+	err = json.Unmarshal([]byte(events[1].Payload), &data)
+	assert.NoError(t, err)
+	deposit := latestActivity(t, account.ID)
+	assert.Equal(t, deposit.ID, data.ActivityID)
+	assert.Equal(t, "credit", data.Type)
+	assert.Equal(t, model.NewMoney(50000), data.Nominal)
+	assert.Equal(t, model.NewMoney(50000), data.BalanceAfter)
+
+	helper.ClearAll(db)
+}
+
+func TestOutbox_DispatcherRetriesInOrderPerAccount(t *testing.T) {
+	helper.ClearAll(db)
+
+	blockedAccount := createLimitTestAccount(t, "7777777777777777", "087777777777")
+	otherAccount := createLimitTestAccount(t, "7878787878787878", "087878787878")
+	for _, accountNumber := range []string{blockedAccount.AccountNumber, blockedAccount.AccountNumber, otherAccount.AccountNumber} {
+		resp := withdraw(t, accountNumber, model.NewMoney(10000))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	pub := &recordingPublisher{failing: map[string]bool{blockedAccount.AccountNumber: true}}
+	dispatcher := service.NewOutboxDispatcher(repository.NewGorm(db, test.Config.PIIKeyring), pub, testOutboxPolicy)
+
+	// 1. A failing event holds back the later events of its account only.
+	published, err := dispatcher.DispatchOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, published)
+	if assert.Len(t, pub.published, 1) {
+		assert.Equal(t, otherAccount.AccountNumber, pub.published[0].AccountNumber)
+		assert.Equal(t, model.EventFundsWithdrawn, pub.published[0].Type)
+	}
+
+	events := outboxEvents(t, blockedAccount.AccountNumber)
+	if !assert.Len(t, events, 2) {
+		return
+	}
+	assert.Equal(t, 1, events[0].Attempts)
+	assert.Equal(t, "consumer unavailable", events[0].LastError)
+	assert.True(t, events[0].NextAttemptAt.After(time.Now()))
+	assert.Equal(t, 0, events[1].Attempts)
+
+	// 2. Nothing is retried before its delay.
+	pub.failing = nil
+	published, err = dispatcher.DispatchOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, published)
+
+	// 3. Once due, the events of the account are published in order.
+	err = db.Model(&model.OutboxEvent{}).Where("id = ?", events[0].ID).Update("next_attempt_at", time.Now().Add(-time.Second)).Error
+	assert.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		published, err = dispatcher.DispatchOnce(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, published)
+	}
+	if assert.Len(t, pub.published, 3) {
+		assert.Equal(t, events[0].ID, pub.published[1].ID)
+		assert.Equal(t, events[1].ID, pub.published[2].ID)
+	}
+
+	var pending int64
+	err = db.Model(&model.OutboxEvent{}).Where("published_at IS NULL").Count(&pending).Error
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), pending)
+
+	helper.ClearAll(db)
+}
+
+// publisherFunc publishes an event by calling itself.
+type publisherFunc func(c context.Context, event *model.Event) error
+
+func (f publisherFunc) Publish(c context.Context, event *model.Event) error {
+	return f(c, event)
+}
+
+func TestOutbox_ClaimedEventsLeftToTheirDispatcher(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := createLimitTestAccount(t, "8080808080808080", "088080808080")
+	resp := withdraw(t, existingAccount.AccountNumber, model.NewMoney(10000))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	err := db.Model(&model.OutboxEvent{}).Where("account_number = ?", existingAccount.AccountNumber).Update("published_at", time.Now()).Error
+	assert.NoError(t, err)
+	resp = withdraw(t, existingAccount.AccountNumber, model.NewMoney(10000))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// A second dispatcher runs while the first is publishing, which it could
+	// not do on SQLite if the first held a transaction open meanwhile.
+	other := &recordingPublisher{}
+	var otherPublished int
+	var otherErr error
+	pub := publisherFunc(func(c context.Context, event *model.Event) error {
+		otherPublished, otherErr = service.NewOutboxDispatcher(repository.NewGorm(db, test.Config.PIIKeyring), other, testOutboxPolicy).DispatchOnce(c)
+		return nil
+	})
+
+	published, err := service.NewOutboxDispatcher(repository.NewGorm(db, test.Config.PIIKeyring), pub, testOutboxPolicy).DispatchOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, published)
+	assert.NoError(t, otherErr)
+	assert.Equal(t, 0, otherPublished)
+	assert.Empty(t, other.published)
+
+	events := outboxEvents(t, existingAccount.AccountNumber)
+	if assert.Len(t, events, 2) {
+		assert.NotNil(t, events[1].PublishedAt)
+		assert.Equal(t, 0, events[1].Attempts)
+	}
+
+	helper.ClearAll(db)
+}
+
+func TestOutbox_RunStopsWithContext(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := createLimitTestAccount(t, "7979797979797979", "087979797979")
+	resp := withdraw(t, existingAccount.AccountNumber, model.NewMoney(10000))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	pub := &recordingPublisher{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		service.NewOutboxDispatcher(repository.NewGorm(db, test.Config.PIIKeyring), pub, testOutboxPolicy).Run(ctx)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		pub.mu.Lock()
+		defer pub.mu.Unlock()
+		return len(pub.published) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("dispatcher did not stop")
+	}
+
+	helper.ClearAll(db)
+}
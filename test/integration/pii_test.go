@@ -0,0 +1,171 @@
+package integration
+
+import (
+	"account-service/src/database"
+	"account-service/src/model"
+	"account-service/src/pii"
+	"account-service/src/repository"
+	"account-service/src/service"
+	"account-service/src/utils"
+	"account-service/test"
+	"account-service/test/helper"
+	"context"
+	"crypto/rand"
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// storedPII returns the ID number and the phone number of an account as they
+// are stored.
+func storedPII(t *testing.T, accountNumber string) (idNumber, phoneNumber string) {
+	var stored struct{ IDNumber, PhoneNumber string }
+	require.NoError(t, db.Table("accounts").Select("id_number, phone_number").
+		Where("account_number = ?", accountNumber).Scan(&stored).Error)
+	return stored.IDNumber, stored.PhoneNumber
+}
+
+// createLegacyAccount creates an account as it was stored before encryption,
+// in plaintext and without a blind index.
+func createLegacyAccount(t *testing.T, account *model.Account) {
+	require.NoError(t, helper.CreateTestAccount(db, account))
+	require.NoError(t, db.Table("accounts").Where("id = ?", account.ID).UpdateColumns(map[string]interface{}{
+		"id_number": account.IDNumber, "phone_number": account.PhoneNumber, "id_number_hash": nil, "phone_number_hash": nil,
+	}).Error)
+}
+
+func TestPII_BackfillIndexesLegacyAccounts(t *testing.T) {
+	helper.ClearAll(db)
+	defer helper.ClearAll(db)
+
+	encrypted := model.Account{FullName: "PII Test User", IDNumber: "3201123456780003", PhoneNumber: "081200000003", AccountNumber: utils.GenerateAccountNumber()}
+	require.NoError(t, helper.CreateTestAccount(db, &encrypted))
+	legacy := model.Account{FullName: "PII Legacy User", IDNumber: "3201123456780004", PhoneNumber: "081200000004", AccountNumber: utils.GenerateAccountNumber()}
+	createLegacyAccount(t, &legacy)
+
+	piiService := service.NewPIIService(db, test.Config.PIIKeyring)
+	unindexed, fiberErr := piiService.Unindexed(context.Background())
+	require.Nil(t, fiberErr)
+	assert.Equal(t, int64(1), unindexed)
+
+	result, fiberErr := piiService.Backfill(context.Background())
+	require.Nil(t, fiberErr)
+	assert.Equal(t, &model.PIIRotationResult{Accounts: 1, Rewritten: 1}, result)
+
+	unindexed, fiberErr = piiService.Unindexed(context.Background())
+	require.Nil(t, fiberErr)
+	assert.Equal(t, int64(0), unindexed)
+
+	idNumber, phoneNumber := storedPII(t, legacy.AccountNumber)
+	assert.True(t, strings.HasPrefix(idNumber, "v1:"), idNumber)
+	assert.True(t, strings.HasPrefix(phoneNumber, "v1:"), phoneNumber)
+	found, err := repository.NewGorm(db, test.Config.PIIKeyring).Repositories(context.Background()).Accounts().FindByPhoneNumber(legacy.PhoneNumber)
+	require.NoError(t, err)
+	assert.Equal(t, legacy.AccountNumber, found.AccountNumber)
+}
+
+func TestPII_RotateEncryptsWithTheActiveKey(t *testing.T) {
+	helper.ClearAll(db)
+	defer helper.ClearAll(db)
+
+	encrypted := model.Account{FullName: "PII Test User", IDNumber: "3201123456780001", PhoneNumber: "081200000001", AccountNumber: utils.GenerateAccountNumber()}
+	require.NoError(t, helper.CreateTestAccount(db, &encrypted))
+	legacy := model.Account{FullName: "PII Legacy User", IDNumber: "3201123456780002", PhoneNumber: "081200000002", AccountNumber: utils.GenerateAccountNumber()}
+	createLegacyAccount(t, &legacy)
+
+	idNumber, _ := storedPII(t, encrypted.AccountNumber)
+	assert.True(t, strings.HasPrefix(idNumber, "v1:"), idNumber)
+
+	// Add a second key and make it active, as a restart would
+	keys := maps.Clone(test.Config.PIIKeys)
+	keys[2] = make([]byte, pii.KeySize)
+	_, err := rand.Read(keys[2])
+	require.NoError(t, err)
+	rotated, err := pii.NewKeyring(keys, 2, test.Config.PIIBlindIndexKey)
+	require.NoError(t, err)
+	rotatedConfig := *test.Config
+	rotatedConfig.PIIKeyring = rotated
+	rotatedDB, err := database.Connect(&rotatedConfig)
+	require.NoError(t, err)
+	sqlDB, err := rotatedDB.DB()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	result, fiberErr := service.NewPIIService(rotatedDB, rotated).Rotate(context.Background())
+	require.Nil(t, fiberErr)
+	assert.Equal(t, &model.PIIRotationResult{Accounts: 2, Rewritten: 2}, result)
+
+	for _, account := range []model.Account{encrypted, legacy} {
+		idNumber, phoneNumber := storedPII(t, account.AccountNumber)
+		assert.True(t, strings.HasPrefix(idNumber, "v2:"), idNumber)
+		assert.True(t, strings.HasPrefix(phoneNumber, "v2:"), phoneNumber)
+
+		found, err := repository.NewGorm(rotatedDB, rotated).Repositories(context.Background()).Accounts().FindByIDNumber(account.IDNumber)
+		require.NoError(t, err)
+		assert.Equal(t, account.AccountNumber, found.AccountNumber)
+		assert.Equal(t, account.PhoneNumber, found.PhoneNumber)
+	}
+
+	// The previous key is no longer needed
+	delete(keys, 1)
+	retired, err := pii.NewKeyring(keys, 0, test.Config.PIIBlindIndexKey)
+	require.NoError(t, err)
+
+	result, fiberErr = service.NewPIIService(rotatedDB, retired).Rotate(context.Background())
+	require.Nil(t, fiberErr)
+	assert.Equal(t, &model.PIIRotationResult{Accounts: 2, Rewritten: 0}, result)
+}
+
+// storedSecret returns the secret of a webhook subscription as it is stored.
+func storedSecret(t *testing.T, id uint) string {
+	var secret string
+	require.NoError(t, db.Table("webhook_subscriptions").Select("secret").Where("id = ?", id).Scan(&secret).Error)
+	return secret
+}
+
+func TestPII_WebhookSecretsAreEncrypted(t *testing.T) {
+	helper.ClearAll(db)
+	defer helper.ClearAll(db)
+
+	subscriptions := repository.NewGorm(db, test.Config.PIIKeyring).Repositories(context.Background()).WebhookSubscriptions()
+	encrypted := model.WebhookSubscription{URL: "http://partner.test/a", EventTypes: model.EventFundsDeposited, Active: true, Secret: "whsec_encrypted"}
+	require.NoError(t, subscriptions.Create(&encrypted))
+	assert.True(t, strings.HasPrefix(storedSecret(t, encrypted.ID), "v1:"))
+
+	// A subscription created before encryption
+	legacy := model.WebhookSubscription{URL: "http://partner.test/b", EventTypes: model.EventFundsDeposited, Active: true, Secret: "whsec_legacy"}
+	require.NoError(t, subscriptions.Create(&legacy))
+	require.NoError(t, db.Table("webhook_subscriptions").Where("id = ?", legacy.ID).
+		UpdateColumns(map[string]interface{}{"secret": legacy.Secret, "previous_secret": nil}).Error)
+
+	result, fiberErr := service.NewPIIService(db, test.Config.PIIKeyring).Backfill(context.Background())
+	require.Nil(t, fiberErr)
+	assert.Equal(t, 2, result.Subscriptions)
+	assert.Equal(t, 1, result.SubscriptionsRewritten)
+	assert.True(t, strings.HasPrefix(storedSecret(t, legacy.ID), "v1:"))
+
+	found, err := subscriptions.Find(legacy.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "whsec_legacy", found.Secret)
+	assert.Empty(t, found.PreviousSecret)
+
+	// Rotating moves both to a new key
+	keys := maps.Clone(test.Config.PIIKeys)
+	keys[2] = make([]byte, pii.KeySize)
+	_, err = rand.Read(keys[2])
+	require.NoError(t, err)
+	rotated, err := pii.NewKeyring(keys, 2, test.Config.PIIBlindIndexKey)
+	require.NoError(t, err)
+
+	result, fiberErr = service.NewPIIService(db, rotated).Rotate(context.Background())
+	require.Nil(t, fiberErr)
+	assert.Equal(t, 2, result.SubscriptionsRewritten)
+	for _, subscription := range []model.WebhookSubscription{encrypted, legacy} {
+		secret, err := rotated.Decrypt(storedSecret(t, subscription.ID))
+		require.NoError(t, err)
+		assert.Equal(t, subscription.Secret, secret)
+	}
+}
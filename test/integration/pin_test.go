@@ -0,0 +1,223 @@
+package integration
+
+import (
+	"account-service/src/model"
+	"account-service/src/service"
+	"account-service/src/utils"
+	"account-service/test"
+	"account-service/test/helper"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// resetPIN calls the PIN reset endpoint.
+func resetPIN(t *testing.T, req model.PINResetRequest, headers map[string]string) *http.Response {
+	requestBody, _ := json.Marshal(req)
+	resp, err := helper.MakeRequest(app, http.MethodPut, "/v1/pin", string(requestBody), headers)
+	assert.NoError(t, err)
+	return resp
+}
+
+// createPINTestAccount creates an account with TestPIN and some balance.
+func createPINTestAccount(t *testing.T, idNumber, phoneNumber string) model.Account {
+	account := model.Account{
+		FullName:      "PIN Test User",
+		IDNumber:      idNumber,
+		PhoneNumber:   phoneNumber,
+		AccountNumber: utils.GenerateAccountNumber(),
+		Balance:       model.NewMoney(100000),
+	}
+	err := helper.CreateTestAccount(db, &account)
+	assert.NoError(t, err)
+	return account
+}
+
+func TestPIN_WrongPINLocksAfterMaxAttempts(t *testing.T) {
+	helper.ClearAll(db)
+
+	maxAttempts := test.Config.PINPolicy.MaxAttempts
+	if maxAttempts == 0 {
+		t.Skip("PIN lockout is disabled")
+	}
+	existingAccount := createPINTestAccount(t, "4646464646464646", "084646464646")
+	headers := helper.CustomerHeaders(existingAccount.AccountNumber)
+	withdraw := func(pin model.PIN) *http.Response {
+		requestBody, _ := json.Marshal(model.Withdrawal{AccountNumber: existingAccount.AccountNumber, Nominal: model.NewMoney(1000), PIN: pin})
+		resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tarik", string(requestBody), headers)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	// 1. A wrong PIN is refused with the attempts left, and counted.
+	resp := withdraw("000000")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, fmt.Sprintf("%s: %d attempts remaining", service.ErrIncorrectPIN, maxAttempts-1), errorMessage(t, resp))
+
+	updatedAccount, err := helper.GetAccountByNumber(db, existingAccount.AccountNumber)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, updatedAccount.PINFailedAttempts)
+	assert.Equal(t, model.NewMoney(100000), updatedAccount.Balance)
+
+	// 2. The right PIN clears the count.
+	resp = withdraw(helper.TestPIN)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	updatedAccount, err = helper.GetAccountByNumber(db, existingAccount.AccountNumber)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, updatedAccount.PINFailedAttempts)
+
+	// 3. Running out of attempts locks the PIN, even for the right PIN.
+	for i := 1; i < maxAttempts; i++ {
+		resp = withdraw("000000")
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	}
+	resp = withdraw("000000")
+	assert.Equal(t, http.StatusLocked, resp.StatusCode)
+	assert.Equal(t, service.ErrPINLocked.Error(), errorMessage(t, resp))
+
+	resp = withdraw(helper.TestPIN)
+	assert.Equal(t, http.StatusLocked, resp.StatusCode)
+
+	otherAccount := createPINTestAccount(t, "4747474747474747", "084747474747")
+	transferBody, _ := json.Marshal(model.TransferRequest{
+		FromAccountNumber: existingAccount.AccountNumber,
+		ToAccountNumber:   otherAccount.AccountNumber,
+		Nominal:           model.NewMoney(1000),
+		PIN:               helper.TestPIN,
+	})
+	resp, err = helper.MakeRequest(app, http.MethodPost, "/v1/transfer", string(transferBody), headers)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusLocked, resp.StatusCode)
+
+	// 4. Only the one withdrawal with the right PIN went through.
+	updatedAccount, err = helper.GetAccountByNumber(db, existingAccount.AccountNumber)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(99000), updatedAccount.Balance)
+	assert.NotNil(t, updatedAccount.PINLockedAt)
+
+	helper.ClearAll(db)
+}
+
+func TestPIN_CustomerChangesPIN(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := createPINTestAccount(t, "4848484848484848", "084848484848")
+	headers := helper.CustomerHeaders(existingAccount.AccountNumber)
+
+	// 1. Customers must give their current PIN.
+	resp := resetPIN(t, model.PINResetRequest{AccountNumber: existingAccount.AccountNumber, NewPIN: "654321"}, headers)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = resetPIN(t, model.PINResetRequest{AccountNumber: existingAccount.AccountNumber, OldPIN: "000000", NewPIN: "654321"}, headers)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = resetPIN(t, model.PINResetRequest{AccountNumber: existingAccount.AccountNumber, OldPIN: helper.TestPIN, NewPIN: "654321"}, headers)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// 2. The new PIN replaces the old one and the count starts over.
+	updatedAccount, err := helper.GetAccountByNumber(db, existingAccount.AccountNumber)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, updatedAccount.PINFailedAttempts)
+	assert.NotContains(t, updatedAccount.PINHash, "654321")
+
+	withdrawalBody, _ := json.Marshal(model.Withdrawal{AccountNumber: existingAccount.AccountNumber, Nominal: model.NewMoney(1000), PIN: helper.TestPIN})
+	resp, err = helper.MakeRequest(app, http.MethodPost, "/v1/tarik", string(withdrawalBody), headers)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	withdrawalBody, _ = json.Marshal(model.Withdrawal{AccountNumber: existingAccount.AccountNumber, Nominal: model.NewMoney(1000), PIN: "654321"})
+	resp, err = helper.MakeRequest(app, http.MethodPost, "/v1/tarik", string(withdrawalBody), headers)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// 3. Customers cannot touch another account's PIN.
+	otherAccount := createPINTestAccount(t, "4949494949494949", "084949494949")
+	resp = resetPIN(t, model.PINResetRequest{AccountNumber: otherAccount.AccountNumber, OldPIN: helper.TestPIN, NewPIN: "654321"}, headers)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	helper.ClearAll(db)
+}
+
+func TestPIN_TellerResetsLockedPIN(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := createPINTestAccount(t, "5151515151515151", "085151515151")
+	err := db.Model(&existingAccount).Updates(map[string]interface{}{"pin_failed_attempts": 3, "pin_locked_at": existingAccount.CreatedAt}).Error
+	assert.NoError(t, err)
+
+	// 1. A locked customer cannot change their own PIN.
+	resp := resetPIN(t, model.PINResetRequest{AccountNumber: existingAccount.AccountNumber, OldPIN: helper.TestPIN, NewPIN: "654321"}, helper.CustomerHeaders(existingAccount.AccountNumber))
+	assert.Equal(t, http.StatusLocked, resp.StatusCode)
+
+	// 2. A teller resets it without the old PIN, lifting the lock.
+	resp = resetPIN(t, model.PINResetRequest{AccountNumber: existingAccount.AccountNumber, NewPIN: "654321"}, helper.TellerHeaders())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	updatedAccount, err := helper.GetAccountByNumber(db, existingAccount.AccountNumber)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, updatedAccount.PINFailedAttempts)
+	assert.Nil(t, updatedAccount.PINLockedAt)
+
+	withdrawalBody, _ := json.Marshal(model.Withdrawal{AccountNumber: existingAccount.AccountNumber, Nominal: model.NewMoney(1000), PIN: "654321"})
+	resp, err = helper.MakeRequest(app, http.MethodPost, "/v1/tarik", string(withdrawalBody), helper.CustomerHeaders(existingAccount.AccountNumber))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	helper.ClearAll(db)
+}
+
+func TestPIN_NotSet(t *testing.T) {
+	helper.ClearAll(db)
+
+	// Accounts opened before PINs existed have none until a teller sets one.
+	existingAccount := createPINTestAccount(t, "5252525252525252", "085252525252")
+	err := db.Model(&existingAccount).Update("pin_hash", "").Error
+	assert.NoError(t, err)
+
+	withdrawalBody, _ := json.Marshal(model.Withdrawal{AccountNumber: existingAccount.AccountNumber, Nominal: model.NewMoney(1000), PIN: helper.TestPIN})
+	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tarik", string(withdrawalBody), helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, service.ErrPINNotSet.Error(), errorMessage(t, resp))
+
+	resp = resetPIN(t, model.PINResetRequest{AccountNumber: existingAccount.AccountNumber, NewPIN: helper.TestPIN}, helper.TellerHeaders())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = helper.MakeRequest(app, http.MethodPost, "/v1/tarik", string(withdrawalBody), helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	helper.ClearAll(db)
+}
+
+func TestPIN_NotEchoedInErrors(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := createPINTestAccount(t, "5353535353535353", "085353535353")
+	requests := []struct {
+		method, path, body string
+	}{
+		{http.MethodPost, "/v1/daftar", `{"nama": "PIN Test User", "nik": "5454545454545454", "no_hp": "085454545454", "pin": 987654`},
+		{http.MethodPost, "/v1/daftar", `{"nama": "PIN Test User", "nik": "5454545454545454", "no_hp": "085454545454", "pin": "98765"}`},
+		{http.MethodPost, "/v1/tarik", `{"no_rekening": "` + existingAccount.AccountNumber + `", "nominal": 1000, "pin": "98765x"}`},
+		{http.MethodPost, "/v1/tarik", `{"no_rekening": "` + existingAccount.AccountNumber + `", "nominal": 1000, "pin": "987654"}`},
+	}
+	for _, request := range requests {
+		resp, err := helper.MakeRequest(app, request.method, request.path, request.body, helper.TellerHeaders())
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, resp.StatusCode, http.StatusBadRequest, request.body)
+
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.False(t, strings.Contains(string(body), "98765"), string(body))
+	}
+
+	helper.ClearAll(db)
+}
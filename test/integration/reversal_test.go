@@ -0,0 +1,242 @@
+package integration
+
+import (
+	"account-service/src/model"
+	"account-service/src/response"
+	"account-service/src/service"
+	"account-service/src/utils"
+	"account-service/test/helper"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// reverse calls the reversal endpoint for a cash activity.
+func reverse(t *testing.T, activityID uint, reason string) *http.Response {
+	requestBody, _ := json.Marshal(model.ReversalRequest{Reason: reason})
+	resp, err := helper.MakeRequest(app, http.MethodPost, fmt.Sprintf("/v1/transaksi/%d/reversal", activityID), string(requestBody), helper.TellerHeaders())
+	assert.NoError(t, err)
+	return resp
+}
+
+// latestActivity returns the most recent cash activity of an account.
+func latestActivity(t *testing.T, accountID uint) model.CashActivity {
+	var activity model.CashActivity
+	err := db.Where("account_id = ?", accountID).Order("id desc").First(&activity).Error
+	assert.NoError(t, err)
+	return activity
+}
+
+// errorMessage decodes the message of an error response.
+func errorMessage(t *testing.T, resp *http.Response) string {
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var errorResponse response.ErrorDetails
+	err = json.Unmarshal(body, &errorResponse)
+	assert.NoError(t, err)
+	return errorResponse.Message
+}
+
+func TestReversal_Deposit(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := model.Account{
+		FullName:      "Reversal Test User",
+		IDNumber:      "2626262626262626",
+		PhoneNumber:   "082626262626",
+		AccountNumber: utils.GenerateAccountNumber(),
+	}
+	err := helper.CreateTestAccount(db, &existingAccount)
+	assert.NoError(t, err)
+
+	depositBody, _ := json.Marshal(model.DepositRequest{AccountNumber: existingAccount.AccountNumber, Nominal: model.NewMoney(100000)})
+	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tabung", string(depositBody), helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	deposit := latestActivity(t, existingAccount.ID)
+
+	// 1. The deposit is undone by a debit carrying the reason.
+	resp = reverse(t, deposit.ID, "Deposit posted to the wrong account")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	var apiResponse struct {
+		Data model.ReversalResponse `json:"data"`
+	}
+	err = json.Unmarshal(body, &apiResponse)
+	assert.NoError(t, err)
+	assert.Equal(t, deposit.ID, apiResponse.Data.TransactionID)
+	assert.Equal(t, existingAccount.AccountNumber, apiResponse.Data.AccountNumber)
+	assert.Equal(t, model.NewMoney(0), apiResponse.Data.Balance)
+
+	compensating := latestActivity(t, existingAccount.ID)
+	assert.Equal(t, apiResponse.Data.ReversalID, compensating.ID)
+	assert.Equal(t, "debit", compensating.Type)
+	assert.Equal(t, model.NewMoney(100000), compensating.Nominal)
+	assert.Equal(t, "Deposit posted to the wrong account", compensating.Description)
+	assert.Equal(t, &deposit.ID, compensating.ReversalOfID)
+	assert.Equal(t, &deposit.ID, compensating.ReferenceID)
+
+	var original model.CashActivity
+	err = db.First(&original, deposit.ID).Error
+	assert.NoError(t, err)
+	assert.NotNil(t, original.ReversedAt)
+
+	updatedAccount, err := helper.GetAccountByNumber(db, existingAccount.AccountNumber)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(0), updatedAccount.Balance)
+
+	// 2. The chain stays intact.
+	verification := verifyLedger(t, existingAccount.AccountNumber)
+	assert.True(t, verification.Valid)
+
+	// 3. A second reversal is refused.
+	resp = reverse(t, deposit.ID, "Again")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, service.ErrAlreadyReversed.Error(), errorMessage(t, resp))
+
+	// 4. So is reversing the reversal.
+	resp = reverse(t, compensating.ID, "Undo the undo")
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, service.ErrReversalOfReversal.Error(), errorMessage(t, resp))
+
+	helper.ClearAll(db)
+}
+
+func TestReversal_Withdrawal(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := model.Account{
+		FullName:      "Reversal Test User",
+		IDNumber:      "2727272727272727",
+		PhoneNumber:   "082727272727",
+		AccountNumber: utils.GenerateAccountNumber(),
+		Balance:       model.NewMoney(100000),
+	}
+	err := helper.CreateTestAccount(db, &existingAccount)
+	assert.NoError(t, err)
+
+	withdrawalBody, _ := json.Marshal(model.Withdrawal{AccountNumber: existingAccount.AccountNumber, Nominal: model.NewMoney(40000), PIN: helper.TestPIN})
+	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tarik", string(withdrawalBody), helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	withdrawal := latestActivity(t, existingAccount.ID)
+
+	resp = reverse(t, withdrawal.ID, "ATM did not dispense cash")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	compensating := latestActivity(t, existingAccount.ID)
+	assert.Equal(t, "credit", compensating.Type)
+	assert.Equal(t, "ATM did not dispense cash", compensating.Description)
+
+	updatedAccount, err := helper.GetAccountByNumber(db, existingAccount.AccountNumber)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(100000), updatedAccount.Balance)
+
+	helper.ClearAll(db)
+}
+
+func TestReversal_InsufficientBalance(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := model.Account{
+		FullName:      "Reversal Test User",
+		IDNumber:      "2828282828282828",
+		PhoneNumber:   "082828282828",
+		AccountNumber: utils.GenerateAccountNumber(),
+	}
+	err := helper.CreateTestAccount(db, &existingAccount)
+	assert.NoError(t, err)
+
+	depositBody, _ := json.Marshal(model.DepositRequest{AccountNumber: existingAccount.AccountNumber, Nominal: model.NewMoney(100000)})
+	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tabung", string(depositBody), helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	deposit := latestActivity(t, existingAccount.ID)
+
+	// Most of the deposit has already been withdrawn.
+	withdrawalBody, _ := json.Marshal(model.Withdrawal{AccountNumber: existingAccount.AccountNumber, Nominal: model.NewMoney(60000), PIN: helper.TestPIN})
+	resp, err = helper.MakeRequest(app, http.MethodPost, "/v1/tarik", string(withdrawalBody), helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = reverse(t, deposit.ID, "Deposit posted to the wrong account")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, service.ErrInsufficientBalance.Error(), errorMessage(t, resp))
+
+	var original model.CashActivity
+	err = db.First(&original, deposit.ID).Error
+	assert.NoError(t, err)
+	assert.Nil(t, original.ReversedAt)
+
+	updatedAccount, err := helper.GetAccountByNumber(db, existingAccount.AccountNumber)
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(40000), updatedAccount.Balance)
+
+	helper.ClearAll(db)
+}
+
+func TestReversal_TransferLeg(t *testing.T) {
+	helper.ClearAll(db)
+
+	fromAccount := model.Account{
+		FullName:      "Reversal Sender",
+		IDNumber:      "2929292929292929",
+		PhoneNumber:   "082929292929",
+		AccountNumber: utils.GenerateAccountNumber(),
+		Balance:       model.NewMoney(100000),
+	}
+	err := helper.CreateTestAccount(db, &fromAccount)
+	assert.NoError(t, err)
+	toAccount := model.Account{
+		FullName:      "Reversal Receiver",
+		IDNumber:      "3030303030303030",
+		PhoneNumber:   "083030303030",
+		AccountNumber: utils.GenerateAccountNumber(),
+	}
+	err = helper.CreateTestAccount(db, &toAccount)
+	assert.NoError(t, err)
+
+	transferBody, _ := json.Marshal(model.TransferRequest{
+		FromAccountNumber: fromAccount.AccountNumber,
+		ToAccountNumber:   toAccount.AccountNumber,
+		Nominal:           model.NewMoney(50000),
+		PIN:               helper.TestPIN,
+	})
+	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/transfer", string(transferBody), helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = reverse(t, latestActivity(t, fromAccount.ID).ID, "Wrong recipient")
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, service.ErrTransferReversal.Error(), errorMessage(t, resp))
+
+	helper.ClearAll(db)
+}
+
+func TestReversal_NotFound(t *testing.T) {
+	helper.ClearAll(db)
+
+	resp := reverse(t, 999999, "Missing")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, service.ErrTransactionNotFound.Error(), errorMessage(t, resp))
+
+	helper.ClearAll(db)
+}
+
+func TestReversal_ValidationError(t *testing.T) {
+	helper.ClearAll(db)
+
+	resp := reverse(t, 1, "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	helper.ClearAll(db)
+}
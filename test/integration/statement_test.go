@@ -0,0 +1,141 @@
+package integration
+
+import (
+	"account-service/src/model"
+	"account-service/test"
+	"account-service/test/helper"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// getStatement calls the statement endpoint as the account's customer.
+func getStatement(t *testing.T, accountNumber, from, to, format string) *http.Response {
+	path := fmt.Sprintf("/v1/rekening/%s/statement?from=%s&to=%s&format=%s", accountNumber, from, to, format)
+	resp, err := helper.MakeRequest(app, http.MethodGet, path, "", helper.CustomerHeaders(accountNumber))
+	assert.NoError(t, err)
+	return resp
+}
+
+func TestStatement_Formats(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := createLimitTestAccount(t, "7272727272727272", "087272727272")
+	depositBody, _ := json.Marshal(model.DepositRequest{AccountNumber: existingAccount.AccountNumber, Nominal: model.NewMoney(50000)})
+	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tabung", string(depositBody), helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = withdraw(t, existingAccount.AccountNumber, model.NewMoney(20000))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	today := time.Now().In(test.Config.BusinessLocation).Format(time.DateOnly)
+
+	// 1. CSV, the default format.
+	resp = getStatement(t, existingAccount.AccountNumber, today, today, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/csv")
+	assert.Contains(t, resp.Header.Get("Content-Disposition"), "statement-"+existingAccount.AccountNumber)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	assert.NoError(t, err)
+	if assert.Len(t, records, 6) {
+		assert.Equal(t, []string{today, "", "", "Opening balance", "", "", "1000000.00"}, records[1])
+		assert.Equal(t, model.ActivityKindDeposit, records[2][2])
+		assert.Equal(t, "50000.00", records[2][5])
+		assert.Equal(t, model.ActivityKindWithdrawal, records[3][2])
+		assert.Equal(t, "20000.00", records[3][4])
+		assert.Equal(t, []string{today, "", "", "Total", "20000.00", "50000.00", ""}, records[4])
+		assert.Equal(t, []string{today, "", "", "Closing balance", "", "", "1030000.00"}, records[5])
+	}
+
+	// 2. camt.053 carries the same balances and entries.
+	resp = getStatement(t, existingAccount.AccountNumber, today, today, model.StatementFormatCamt053)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err = io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+
+	var document struct {
+		Balances []string `xml:"BkToCstmrStmt>Stmt>Bal>Amt"`
+		Entries  []string `xml:"BkToCstmrStmt>Stmt>Ntry>Amt"`
+	}
+	err = xml.Unmarshal(body, &document)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1000000.00", "1030000.00"}, document.Balances)
+	assert.Equal(t, []string{"50000.00", "20000.00"}, document.Entries)
+
+	// 3. PDF.
+	resp = getStatement(t, existingAccount.AccountNumber, today, today, model.StatementFormatPDF)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/pdf", resp.Header.Get("Content-Type"))
+	body, err = io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(body, []byte("%PDF-")))
+	assert.True(t, bytes.HasSuffix(body, []byte("%%EOF\n")))
+
+	helper.ClearAll(db)
+}
+
+func TestStatement_EarlierPeriod(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := createLimitTestAccount(t, "7373737373737373", "087373737373")
+	resp := withdraw(t, existingAccount.AccountNumber, model.NewMoney(20000))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// A period before any activity opens and closes on the balance held then.
+	now := time.Now().In(test.Config.BusinessLocation)
+	from := now.AddDate(0, -1, 0).Format(time.DateOnly)
+	to := now.AddDate(0, 0, -1).Format(time.DateOnly)
+	resp = getStatement(t, existingAccount.AccountNumber, from, to, model.StatementFormatCSV)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	assert.NoError(t, err)
+	if assert.Len(t, records, 4) {
+		assert.Equal(t, "1000000.00", records[1][6])
+		assert.Equal(t, []string{to, "", "", "Total", "0.00", "0.00", ""}, records[2])
+		assert.Equal(t, "1000000.00", records[3][6])
+	}
+
+	helper.ClearAll(db)
+}
+
+func TestStatement_Validation(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := createLimitTestAccount(t, "7474747474747474", "087474747474")
+	otherAccount := createLimitTestAccount(t, "7575757575757575", "087575757575")
+
+	resp := getStatement(t, existingAccount.AccountNumber, "2025-02-01", "2025-01-01", model.StatementFormatCSV)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = getStatement(t, existingAccount.AccountNumber, "2025-01-01", "2025-01-31", "xlsx")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = getStatement(t, existingAccount.AccountNumber, "2025-01-01", "", model.StatementFormatCSV)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	path := fmt.Sprintf("/v1/rekening/%s/statement?from=2025-01-01&to=2025-01-31", otherAccount.AccountNumber)
+	resp, err := helper.MakeRequest(app, http.MethodGet, path, "", helper.CustomerHeaders(existingAccount.AccountNumber))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	path = "/v1/rekening/1111111111/statement?from=2025-01-01&to=2025-01-31"
+	resp, err = helper.MakeRequest(app, http.MethodGet, path, "", helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	helper.ClearAll(db)
+}
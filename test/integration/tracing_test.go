@@ -0,0 +1,72 @@
+package integration
+
+import (
+	"account-service/src/model"
+	"account-service/test/helper"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing_Withdrawal(t *testing.T) {
+	helper.ClearAll(db)
+
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTracerProvider(previous)
+
+	account := createLimitTestAccount(t, "3636363636363636", "083636363636")
+
+	requestBody, _ := json.Marshal(model.Withdrawal{AccountNumber: account.AccountNumber, Nominal: model.NewMoney(1000), PIN: helper.TestPIN})
+	headers := helper.TellerHeaders()
+	headers["traceparent"] = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tarik", string(requestBody), headers)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var server, withdraw, getBalance sdktrace.ReadOnlySpan
+	children := map[string][]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		if span.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			continue
+		}
+		switch span.Name() {
+		case "POST /v1/tarik":
+			server = span
+		case "AccountService.Withdraw":
+			withdraw = span
+		case "AccountService.GetBalance":
+			getBalance = span
+		}
+		children[span.Parent().SpanID().String()] = append(children[span.Parent().SpanID().String()], span)
+	}
+
+	require.NotNil(t, server)
+	require.NotNil(t, withdraw)
+	require.NotNil(t, getBalance)
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	assert.Equal(t, server.SpanContext().SpanID(), withdraw.Parent().SpanID())
+	assert.Equal(t, server.SpanContext().SpanID(), getBalance.Parent().SpanID())
+
+	// The queries of each call are its children
+	var queries []string
+	for _, span := range children[withdraw.SpanContext().SpanID().String()] {
+		queries = append(queries, span.Name())
+	}
+	assert.Contains(t, queries, "SELECT accounts")
+	assert.Contains(t, queries, "INSERT cash_activities")
+	assert.NotEmpty(t, children[getBalance.SpanContext().SpanID().String()])
+	for _, span := range children[getBalance.SpanContext().SpanID().String()] {
+		assert.True(t, strings.HasPrefix(span.Name(), "SELECT"), span.Name())
+	}
+}
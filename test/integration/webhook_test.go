@@ -0,0 +1,347 @@
+package integration
+
+import (
+	"account-service/src/model"
+	"account-service/src/publisher"
+	"account-service/src/repository"
+	"account-service/src/service"
+	"account-service/test"
+	"account-service/test/helper"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// webhookReceiver is a partner endpoint that records the posts it receives
+// and answers them with status.
+type webhookReceiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	status   int
+	requests []receivedWebhook
+}
+
+type receivedWebhook struct {
+	Header http.Header
+	Body   []byte
+}
+
+func newWebhookReceiver(t *testing.T) *webhookReceiver {
+	receiver := &webhookReceiver{status: http.StatusNoContent}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+		receiver.requests = append(receiver.requests, receivedWebhook{Header: r.Header.Clone(), Body: body})
+		w.WriteHeader(receiver.status)
+	}))
+	t.Cleanup(receiver.Close)
+	return receiver
+}
+
+func (receiver *webhookReceiver) setStatus(status int) {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	receiver.status = status
+}
+
+func (receiver *webhookReceiver) received() []receivedWebhook {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	return append([]receivedWebhook(nil), receiver.requests...)
+}
+
+var testWebhookPolicy = model.WebhookPolicy{
+	PollInterval:      10 * time.Millisecond,
+	Timeout:           time.Second,
+	RetryDelay:        time.Minute,
+	MaxRetryDelay:     time.Hour,
+	MaxAttempts:       2,
+	BatchSize:         50,
+	SecretGracePeriod: time.Hour,
+}
+
+// webhookRequest calls a webhook admin endpoint and decodes its data.
+func webhookRequest(t *testing.T, method, path string, body any, data any) *http.Response {
+	requestBody := ""
+	if body != nil {
+		encoded, _ := json.Marshal(body)
+		requestBody = string(encoded)
+	}
+	resp, err := helper.MakeRequest(app, method, path, requestBody, helper.AdminHeaders())
+	assert.NoError(t, err)
+	if data == nil || resp.StatusCode >= http.StatusBadRequest {
+		return resp
+	}
+
+	responseBody, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	apiResponse := struct {
+		Data any `json:"data"`
+	}{Data: data}
+	err = json.Unmarshal(responseBody, &apiResponse)
+	assert.NoError(t, err)
+	return resp
+}
+
+// subscribe registers a webhook subscription through the API.
+func subscribe(t *testing.T, url string, eventTypes ...string) model.WebhookSubscriptionResponse {
+	var subscription model.WebhookSubscriptionResponse
+	resp := webhookRequest(t, http.MethodPost, "/v1/admin/webhooks", model.WebhookSubscriptionRequest{URL: url, EventTypes: eventTypes}, &subscription)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	return subscription
+}
+
+// dispatchWebhooks publishes the outbox to the webhook subscriptions and
+// makes the deliveries that are due.
+func dispatchWebhooks(t *testing.T, deliverer *service.WebhookDeliverer) {
+	_, err := service.NewOutboxDispatcher(repository.NewGorm(db, test.Config.PIIKeyring), deliverer, testOutboxPolicy).DispatchOnce(context.Background())
+	assert.NoError(t, err)
+	_, err = deliverer.DeliverOnce(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestWebhook_DeliversSignedEvents(t *testing.T) {
+	helper.ClearAll(db)
+
+	receiver := newWebhookReceiver(t)
+	subscription := subscribe(t, receiver.URL, model.EventFundsWithdrawn)
+	assert.NotEmpty(t, subscription.Secret)
+	other := subscribe(t, receiver.URL, model.EventFundsDeposited)
+
+	// 1. The secret is only shown when the subscription is created.
+	var subscriptions []model.WebhookSubscriptionResponse
+	resp := webhookRequest(t, http.MethodGet, "/v1/admin/webhooks", nil, &subscriptions)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	if assert.Len(t, subscriptions, 2) {
+		assert.Empty(t, subscriptions[0].Secret)
+		assert.Equal(t, []string{model.EventFundsWithdrawn}, subscriptions[0].EventTypes)
+	}
+
+	// 2. A withdrawal reaches the subscription to withdrawals only, signed.
+	existingAccount := createLimitTestAccount(t, "8181818181818181", "088181818181")
+	resp = withdraw(t, existingAccount.AccountNumber, model.NewMoney(10000))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	deliverer := service.NewWebhookDeliverer(repository.NewGorm(db, test.Config.PIIKeyring), testWebhookPolicy)
+	dispatchWebhooks(t, deliverer)
+
+	received := receiver.received()
+	if !assert.Len(t, received, 1) {
+		return
+	}
+	err := publisher.VerifySignature(subscription.Secret, received[0].Header.Get(publisher.HeaderTimestamp), received[0].Header.Get(publisher.HeaderSignature), received[0].Body, time.Now(), time.Minute)
+	assert.NoError(t, err)
+	err = publisher.VerifySignature(other.Secret, received[0].Header.Get(publisher.HeaderTimestamp), received[0].Header.Get(publisher.HeaderSignature), received[0].Body, time.Now(), time.Minute)
+	assert.ErrorIs(t, err, publisher.ErrSignatureMismatch)
+
+	var event model.Event
+	err = json.Unmarshal(received[0].Body, &event)
+	assert.NoError(t, err)
+	assert.Equal(t, model.EventFundsWithdrawn, event.Type)
+	assert.Equal(t, existingAccount.AccountNumber, event.AccountNumber)
+
+	// 3. The delivery and its attempt are logged.
+	var deliveries []model.WebhookDelivery
+	resp = webhookRequest(t, http.MethodGet, fmt.Sprintf("/v1/admin/webhooks/%d/deliveries", subscription.ID), nil, &deliveries)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	if !assert.Len(t, deliveries, 1) {
+		return
+	}
+	assert.Equal(t, model.WebhookDeliveryDelivered, deliveries[0].Status)
+	assert.Equal(t, event.ID, deliveries[0].OutboxID)
+
+	var delivery model.WebhookDelivery
+	resp = webhookRequest(t, http.MethodGet, fmt.Sprintf("/v1/admin/webhooks/deliveries/%d", deliveries[0].ID), nil, &delivery)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	if assert.Len(t, delivery.AttemptLog, 1) && assert.NotNil(t, delivery.AttemptLog[0].StatusCode) {
+		assert.Equal(t, http.StatusNoContent, *delivery.AttemptLog[0].StatusCode)
+	}
+
+	// 4. An event published again is not delivered again.
+	err = db.Model(&model.OutboxEvent{}).Where("id = ?", event.ID).Update("published_at", nil).Error
+	assert.NoError(t, err)
+	dispatchWebhooks(t, deliverer)
+	assert.Len(t, receiver.received(), 1)
+
+	helper.ClearAll(db)
+}
+
+func TestWebhook_RetriesThenDeadLettersThenRedelivers(t *testing.T) {
+	helper.ClearAll(db)
+
+	receiver := newWebhookReceiver(t)
+	receiver.setStatus(http.StatusInternalServerError)
+	subscription := subscribe(t, receiver.URL, model.EventFundsWithdrawn)
+
+	existingAccount := createLimitTestAccount(t, "8282828282828282", "088282828282")
+	resp := withdraw(t, existingAccount.AccountNumber, model.NewMoney(10000))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// 1. A failed delivery is retried after the retry delay.
+	deliverer := service.NewWebhookDeliverer(repository.NewGorm(db, test.Config.PIIKeyring), testWebhookPolicy)
+	dispatchWebhooks(t, deliverer)
+
+	var delivery model.WebhookDelivery
+	err := db.Where("subscription_id = ?", subscription.ID).First(&delivery).Error
+	assert.NoError(t, err)
+	assert.Equal(t, model.WebhookDeliveryPending, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.True(t, delivery.NextAttemptAt.After(time.Now().Add(30*time.Second)))
+	if assert.NotNil(t, delivery.LastStatusCode) {
+		assert.Equal(t, http.StatusInternalServerError, *delivery.LastStatusCode)
+	}
+
+	delivered, err := deliverer.DeliverOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+	assert.Len(t, receiver.received(), 1)
+
+	// 2. It is dead once it has failed MaxAttempts times.
+	err = db.Model(&delivery).Update("next_attempt_at", time.Now().Add(-time.Second)).Error
+	assert.NoError(t, err)
+	_, err = deliverer.DeliverOnce(context.Background())
+	assert.NoError(t, err)
+
+	var deliveries []model.WebhookDelivery
+	resp = webhookRequest(t, http.MethodGet, fmt.Sprintf("/v1/admin/webhooks/%d/deliveries?status=dead", subscription.ID), nil, &deliveries)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	if !assert.Len(t, deliveries, 1) {
+		return
+	}
+	assert.Equal(t, 2, deliveries[0].Attempts)
+
+	// 3. A redelivery starts over and is logged with the earlier attempts.
+	receiver.setStatus(http.StatusOK)
+	var redelivery model.WebhookDelivery
+	resp = webhookRequest(t, http.MethodPost, fmt.Sprintf("/v1/admin/webhooks/deliveries/%d/redeliver", delivery.ID), nil, &redelivery)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, model.WebhookDeliveryPending, redelivery.Status)
+	assert.Equal(t, 0, redelivery.Attempts)
+
+	delivered, err = deliverer.DeliverOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+
+	resp = webhookRequest(t, http.MethodGet, fmt.Sprintf("/v1/admin/webhooks/deliveries/%d", delivery.ID), nil, &delivery)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, model.WebhookDeliveryDelivered, delivery.Status)
+	if assert.Len(t, delivery.AttemptLog, 3) {
+		assert.NotEmpty(t, delivery.AttemptLog[0].Error)
+		assert.Empty(t, delivery.AttemptLog[2].Error)
+	}
+
+	helper.ClearAll(db)
+}
+
+func TestWebhook_RotateSecretSignsWithBothDuringGracePeriod(t *testing.T) {
+	helper.ClearAll(db)
+
+	receiver := newWebhookReceiver(t)
+	subscription := subscribe(t, receiver.URL, model.EventFundsWithdrawn)
+
+	var rotated model.WebhookSubscriptionResponse
+	resp := webhookRequest(t, http.MethodPost, fmt.Sprintf("/v1/admin/webhooks/%d/rotate-secret", subscription.ID), nil, &rotated)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEmpty(t, rotated.Secret)
+	assert.NotEqual(t, subscription.Secret, rotated.Secret)
+	assert.NotNil(t, rotated.PreviousSecretExpiresAt)
+
+	existingAccount := createLimitTestAccount(t, "8383838383838383", "088383838383")
+	resp = withdraw(t, existingAccount.AccountNumber, model.NewMoney(10000))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	dispatchWebhooks(t, service.NewWebhookDeliverer(repository.NewGorm(db, test.Config.PIIKeyring), testWebhookPolicy))
+
+	received := receiver.received()
+	if !assert.Len(t, received, 1) {
+		return
+	}
+	for _, secret := range []string{subscription.Secret, rotated.Secret} {
+		err := publisher.VerifySignature(secret, received[0].Header.Get(publisher.HeaderTimestamp), received[0].Header.Get(publisher.HeaderSignature), received[0].Body, time.Now(), time.Minute)
+		assert.NoError(t, err)
+	}
+
+	helper.ClearAll(db)
+}
+
+func TestWebhook_InactiveSubscriptionHoldsDeliveries(t *testing.T) {
+	helper.ClearAll(db)
+
+	receiver := newWebhookReceiver(t)
+	subscription := subscribe(t, receiver.URL, model.EventFundsWithdrawn)
+
+	existingAccount := createLimitTestAccount(t, "8484848484848484", "088484848484")
+	resp := withdraw(t, existingAccount.AccountNumber, model.NewMoney(10000))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	deliverer := service.NewWebhookDeliverer(repository.NewGorm(db, test.Config.PIIKeyring), testWebhookPolicy)
+	_, err := service.NewOutboxDispatcher(repository.NewGorm(db, test.Config.PIIKeyring), deliverer, testOutboxPolicy).DispatchOnce(context.Background())
+	assert.NoError(t, err)
+
+	// 1. A paused subscription is not posted to.
+	inactive := false
+	update := model.WebhookSubscriptionRequest{URL: receiver.URL, EventTypes: []string{model.EventFundsWithdrawn}, Active: &inactive}
+	resp = webhookRequest(t, http.MethodPut, fmt.Sprintf("/v1/admin/webhooks/%d", subscription.ID), update, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	_, err = deliverer.DeliverOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, receiver.received())
+
+	var delivery model.WebhookDelivery
+	err = db.Where("subscription_id = ?", subscription.ID).First(&delivery).Error
+	assert.NoError(t, err)
+	resp = webhookRequest(t, http.MethodPost, fmt.Sprintf("/v1/admin/webhooks/deliveries/%d/redeliver", delivery.ID), nil, nil)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	// 2. Its deliveries are made once it is resumed.
+	active := true
+	update.Active = &active
+	resp = webhookRequest(t, http.MethodPut, fmt.Sprintf("/v1/admin/webhooks/%d", subscription.ID), update, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	_, err = deliverer.DeliverOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, receiver.received(), 1)
+
+	// 3. Deleting it deletes its deliveries.
+	resp = webhookRequest(t, http.MethodDelete, fmt.Sprintf("/v1/admin/webhooks/%d", subscription.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var deliveries int64
+	err = db.Model(&model.WebhookDelivery{}).Count(&deliveries).Error
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), deliveries)
+
+	resp = webhookRequest(t, http.MethodDelete, fmt.Sprintf("/v1/admin/webhooks/%d", subscription.ID), nil, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	helper.ClearAll(db)
+}
+
+func TestWebhook_ValidatesSubscriptions(t *testing.T) {
+	helper.ClearAll(db)
+
+	tests := []model.WebhookSubscriptionRequest{
+		{URL: "not a url", EventTypes: []string{model.EventFundsWithdrawn}},
+		{URL: "ftp://partner.example.com/events", EventTypes: []string{model.EventFundsWithdrawn}},
+		{URL: "https://partner.example.com/events"},
+		{URL: "https://partner.example.com/events", EventTypes: []string{"BalanceChanged"}},
+	}
+	for _, req := range tests {
+		resp := webhookRequest(t, http.MethodPost, "/v1/admin/webhooks", req, nil)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "%+v", req)
+	}
+
+	resp, err := helper.MakeRequest(app, http.MethodGet, "/v1/admin/webhooks", "", helper.TellerHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	helper.ClearAll(db)
+}
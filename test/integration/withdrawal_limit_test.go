@@ -0,0 +1,187 @@
+package integration
+
+import (
+	"account-service/src/model"
+	"account-service/src/service"
+	"account-service/src/utils"
+	"account-service/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setWithdrawalLimits overrides the withdrawal limits of an account.
+func setWithdrawalLimits(t *testing.T, accountNumber string, req model.WithdrawalLimitRequest) model.WithdrawalLimits {
+	requestBody, _ := json.Marshal(req)
+	resp, err := helper.MakeRequest(app, http.MethodPut, "/v1/admin/rekening/"+accountNumber+"/limit", string(requestBody), helper.AdminHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var apiResponse struct {
+		Data model.WithdrawalLimits `json:"data"`
+	}
+	err = json.Unmarshal(body, &apiResponse)
+	assert.NoError(t, err)
+	return apiResponse.Data
+}
+
+// withdraw calls the withdrawal endpoint.
+func withdraw(t *testing.T, accountNumber string, nominal model.Money) *http.Response {
+	requestBody, _ := json.Marshal(model.Withdrawal{AccountNumber: accountNumber, Nominal: nominal, PIN: helper.TestPIN})
+	resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/tarik", string(requestBody), helper.TellerHeaders())
+	assert.NoError(t, err)
+	return resp
+}
+
+// createLimitTestAccount creates a funded account for the limit tests.
+func createLimitTestAccount(t *testing.T, idNumber, phoneNumber string) model.Account {
+	existingAccount := model.Account{
+		FullName:      "Limit Test User",
+		IDNumber:      idNumber,
+		PhoneNumber:   phoneNumber,
+		AccountNumber: utils.GenerateAccountNumber(),
+		Balance:       model.NewMoney(1000000),
+	}
+	err := helper.CreateTestAccount(db, &existingAccount)
+	assert.NoError(t, err)
+	return existingAccount
+}
+
+func TestWithdrawalLimit_PerTransaction(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := createLimitTestAccount(t, "3535353535353535", "083535353535")
+	maxPerTransaction := model.NewMoney(100000)
+	limits := setWithdrawalLimits(t, existingAccount.AccountNumber, model.WithdrawalLimitRequest{MaxPerTransaction: &maxPerTransaction})
+	assert.Equal(t, maxPerTransaction, limits.MaxPerTransaction)
+
+	resp := withdraw(t, existingAccount.AccountNumber, model.NewMoney(100001))
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, service.ErrPerTransactionLimit.Error()+": 100000.00 remaining", errorMessage(t, resp))
+
+	resp = withdraw(t, existingAccount.AccountNumber, model.NewMoney(100000))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	helper.ClearAll(db)
+}
+
+func TestWithdrawalLimit_DailyTotal(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := createLimitTestAccount(t, "3636363636363636", "083636363636")
+	maxDailyTotal := model.NewMoney(250000)
+	setWithdrawalLimits(t, existingAccount.AccountNumber, model.WithdrawalLimitRequest{MaxDailyTotal: &maxDailyTotal})
+
+	resp := withdraw(t, existingAccount.AccountNumber, model.NewMoney(200000))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// 1. The next withdrawal would cross the daily total.
+	resp = withdraw(t, existingAccount.AccountNumber, model.NewMoney(60000))
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, service.ErrDailyTotalLimit.Error()+": 50000.00 remaining", errorMessage(t, resp))
+
+	// 2. Reversing a withdrawal gives its amount back.
+	resp = reverse(t, latestActivity(t, existingAccount.ID).ID, "Cash not dispensed")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = withdraw(t, existingAccount.AccountNumber, model.NewMoney(250000))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	helper.ClearAll(db)
+}
+
+func TestWithdrawalLimit_Transfer(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := createLimitTestAccount(t, "3939393939393939", "083939393939")
+	recipient := createLimitTestAccount(t, "4040404040404040", "084040404040")
+	maxDailyTotal := model.NewMoney(250000)
+	setWithdrawalLimits(t, existingAccount.AccountNumber, model.WithdrawalLimitRequest{MaxDailyTotal: &maxDailyTotal})
+
+	resp := withdraw(t, existingAccount.AccountNumber, model.NewMoney(200000))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// 1. A transfer cannot move out what the daily total keeps from being withdrawn.
+	transfer := func(nominal model.Money) *http.Response {
+		requestBody, _ := json.Marshal(model.TransferRequest{
+			FromAccountNumber: existingAccount.AccountNumber,
+			ToAccountNumber:   recipient.AccountNumber,
+			Nominal:           nominal,
+			PIN:               helper.TestPIN,
+		})
+		resp, err := helper.MakeRequest(app, http.MethodPost, "/v1/transfer", string(requestBody), helper.TellerHeaders())
+		assert.NoError(t, err)
+		return resp
+	}
+	resp = transfer(model.NewMoney(60000))
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, service.ErrDailyTotalLimit.Error()+": 50000.00 remaining", errorMessage(t, resp))
+
+	// 2. A transfer within the limit uses it up for withdrawals too.
+	resp = transfer(model.NewMoney(50000))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = withdraw(t, existingAccount.AccountNumber, model.NewMoney(1))
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, service.ErrDailyTotalLimit.Error()+": 0.00 remaining", errorMessage(t, resp))
+
+	helper.ClearAll(db)
+}
+
+func TestWithdrawalLimit_DailyCount(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := createLimitTestAccount(t, "3737373737373737", "083737373737")
+	maxDailyCount := 2
+	setWithdrawalLimits(t, existingAccount.AccountNumber, model.WithdrawalLimitRequest{MaxDailyCount: &maxDailyCount})
+
+	for i := 0; i < maxDailyCount; i++ {
+		resp := withdraw(t, existingAccount.AccountNumber, model.NewMoney(1000))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	resp := withdraw(t, existingAccount.AccountNumber, model.NewMoney(1000))
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, service.ErrDailyCountLimit.Error()+": 0 remaining", errorMessage(t, resp))
+
+	helper.ClearAll(db)
+}
+
+func TestWithdrawalLimit_Overrides(t *testing.T) {
+	helper.ClearAll(db)
+
+	existingAccount := createLimitTestAccount(t, "3838383838383838", "083838383838")
+
+	resp, err := helper.MakeRequest(app, http.MethodGet, "/v1/admin/rekening/"+existingAccount.AccountNumber+"/limit", "", helper.AdminHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// 1. Overrides replace only the limits they set.
+	maxDailyCount := 3
+	limits := setWithdrawalLimits(t, existingAccount.AccountNumber, model.WithdrawalLimitRequest{MaxDailyCount: &maxDailyCount})
+	assert.Equal(t, 3, limits.MaxDailyCount)
+
+	// 2. Clearing an override restores the default.
+	limits = setWithdrawalLimits(t, existingAccount.AccountNumber, model.WithdrawalLimitRequest{})
+	assert.NotEqual(t, 3, limits.MaxDailyCount)
+
+	helper.ClearAll(db)
+}
+
+func TestWithdrawalLimit_AccountNotFound(t *testing.T) {
+	helper.ClearAll(db)
+
+	resp, err := helper.MakeRequest(app, http.MethodGet, "/v1/admin/rekening/9999999999/limit", "", helper.AdminHeaders())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, service.ErrAccountNotFound.Error(), errorMessage(t, resp))
+
+	helper.ClearAll(db)
+}
@@ -0,0 +1,136 @@
+package config_test
+
+import (
+	"account-service/src/config"
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testKey = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+
+// validConfig is a config file with every setting that has no default.
+var validConfig = `
+DB_HOST=localhost # trailing comments are left out
+DB_USER=postgres
+DB_PASSWORD=fromfile
+DB_NAME=account
+JWT_SECRET=secret
+PII_KEYS=1:` + testKey + `
+PII_BLIND_INDEX_KEY=` + testKey + `
+`
+
+// writeFile writes content to a file in a temporary directory, returning its
+// path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// unsetEnv unsets keys until the test ends, in case the environment of the
+// tests sets them, as the Makefile does.
+func unsetEnv(t *testing.T, keys ...string) {
+	t.Helper()
+	for _, key := range keys {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	unsetEnv(t, "APP_ENV", "APP_HOST", "APP_PORT", "LOG_FORMAT", "DB_DRIVER", "DB_HOST", "DB_USER", "DB_PASSWORD", "DB_PASSWORD_FILE",
+		"DB_NAME", "DB_PORT", "JWT_SECRET", "JWT_TTL", "PII_KEYS", "PII_BLIND_INDEX_KEY", "PII_ACTIVE_KEY_VERSION", "TRACING_SAMPLE_RATIO")
+
+	t.Run("should apply the defaults to a valid config file", func(t *testing.T) {
+		cfg, args, err := config.Load([]string{"-config", writeFile(t, "app.env", validConfig), "migrate", "up"})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"migrate", "up"}, args)
+		assert.Equal(t, "localhost", cfg.AppHost)
+		assert.Equal(t, 3000, cfg.AppPort)
+		assert.Equal(t, "localhost", cfg.DBHost)
+		assert.Equal(t, 5432, cfg.DBPort)
+		assert.Equal(t, "text", cfg.LogFormat)
+		assert.Equal(t, 24*time.Hour, cfg.JWTTTL)
+		assert.Equal(t, 1, cfg.PIIKeyring.ActiveVersion())
+	})
+
+	t.Run("should let the environment override the file and flags override both", func(t *testing.T) {
+		path := writeFile(t, "app.env", validConfig+"APP_HOST=file.local\nAPP_PORT=4000\nAPP_ENV=prod\n")
+		t.Setenv("APP_HOST", "env.local")
+		t.Setenv("APP_PORT", "5000")
+
+		cfg, _, err := config.Load([]string{"-config", path, "-app-port", "6000"})
+		require.NoError(t, err)
+
+		assert.Equal(t, "env.local", cfg.AppHost)
+		assert.Equal(t, 6000, cfg.AppPort)
+		assert.True(t, cfg.IsProd)
+		assert.Equal(t, "json", cfg.LogFormat, "the default format in prod")
+	})
+
+	t.Run("should read a secret from the file named by its _FILE key", func(t *testing.T) {
+		secret := writeFile(t, "db_password", "fromsecret\n")
+		t.Setenv("DB_PASSWORD_FILE", secret)
+
+		cfg, _, err := config.Load([]string{"-config", writeFile(t, "app.env", validConfig)})
+		require.NoError(t, err)
+		assert.Equal(t, "fromsecret", cfg.DBPassword)
+
+		cfg, _, err = config.Load([]string{"-config", writeFile(t, "app.env", validConfig), "-db-password", "fromflag"})
+		require.NoError(t, err)
+		assert.Equal(t, "fromflag", cfg.DBPassword)
+	})
+
+	t.Run("should refuse a key set along with its _FILE key", func(t *testing.T) {
+		t.Setenv("DB_PASSWORD", "fromenv")
+		t.Setenv("DB_PASSWORD_FILE", writeFile(t, "db_password", "fromsecret"))
+
+		_, _, err := config.Load([]string{"-config", writeFile(t, "app.env", validConfig)})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "DB_PASSWORD and DB_PASSWORD_FILE are both set")
+	})
+
+	t.Run("should list every invalid setting", func(t *testing.T) {
+		path := writeFile(t, "app.env", "APP_PORT=http\nJWT_TTL=forever\nTRACING_SAMPLE_RATIO=2\n")
+
+		_, _, err := config.Load([]string{"-config", path})
+		require.Error(t, err)
+		for _, problem := range []string{
+			"APP_PORT must be an integer",
+			"DB_HOST must be set",
+			"DB_USER must be set",
+			"DB_NAME must be set",
+			"JWT_SECRET must be set",
+			"JWT_TTL must be a duration",
+			"PII_KEYS must be set",
+			"TRACING_SAMPLE_RATIO must be between 0 and 1",
+		} {
+			assert.Contains(t, err.Error(), problem)
+		}
+		assert.NotContains(t, err.Error(), "DB_PATH", "only the settings of the driver in use are required")
+	})
+
+	t.Run("should only require DB_PATH of the sqlite driver", func(t *testing.T) {
+		path := writeFile(t, "app.env", strings.Replace(validConfig, "DB_HOST=localhost", "DB_HOST=", 1)+"DB_DRIVER=sqlite\n")
+
+		cfg, _, err := config.Load([]string{"-config", path})
+		require.NoError(t, err)
+		assert.Equal(t, "account.db", cfg.DBPath)
+		assert.Empty(t, cfg.DBHost)
+	})
+
+	t.Run("should fail on a missing config file", func(t *testing.T) {
+		_, _, err := config.Load([]string{"-config", filepath.Join(t.TempDir(), "missing.env")})
+		assert.Error(t, err)
+	})
+}
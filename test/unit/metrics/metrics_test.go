@@ -0,0 +1,135 @@
+package metrics_test
+
+import (
+	"account-service/src/metrics"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// find returns the metric named name with the given labels among those of g.
+func find(t *testing.T, g prometheus.Gatherer, name string, labels map[string]string) *dto.Metric {
+	t.Helper()
+	families, err := g.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, metric := range family.Metric {
+			got := map[string]string{}
+			for _, label := range metric.Label {
+				got[label.GetName()] = label.GetValue()
+			}
+			for k, v := range labels {
+				if got[k] != v {
+					continue metrics
+				}
+			}
+			return metric
+		}
+	}
+	return nil
+}
+
+func counter(t *testing.T, name string, labels map[string]string) float64 {
+	t.Helper()
+	metric := find(t, metrics.Registry, name, labels)
+	if metric == nil {
+		return 0
+	}
+	return metric.GetCounter().GetValue()
+}
+
+func TestObserve(t *testing.T) {
+	t.Run("should count deposits by outcome and the amount of the successful ones", func(t *testing.T) {
+		success := counter(t, "account_deposits_total", map[string]string{"outcome": metrics.OutcomeSuccess})
+		rejected := counter(t, "account_deposits_total", map[string]string{"outcome": metrics.OutcomeInsufficientBalance})
+		amount := counter(t, "account_deposited_amount_total", nil)
+
+		metrics.ObserveDeposit(metrics.OutcomeSuccess, 150)
+		metrics.ObserveDeposit(metrics.OutcomeInsufficientBalance, 1000)
+
+		assert.Equal(t, success+1, counter(t, "account_deposits_total", map[string]string{"outcome": metrics.OutcomeSuccess}))
+		assert.Equal(t, rejected+1, counter(t, "account_deposits_total", map[string]string{"outcome": metrics.OutcomeInsufficientBalance}))
+		assert.Equal(t, amount+150, counter(t, "account_deposited_amount_total", nil))
+	})
+
+	t.Run("should export every outcome before it happens", func(t *testing.T) {
+		assert.NotNil(t, find(t, metrics.Registry, "account_withdrawals_total", map[string]string{"outcome": metrics.OutcomeNotFound}))
+	})
+
+	t.Run("should count requests by route", func(t *testing.T) {
+		labels := map[string]string{"method": "GET", "route": "/v1/saldo/:accountNumber", "status": "404"}
+		before := counter(t, "http_requests_total", labels)
+
+		metrics.ObserveRequest("GET", "/v1/saldo/:accountNumber", 404, time.Millisecond)
+
+		assert.Equal(t, before+1, counter(t, "http_requests_total", labels))
+	})
+}
+
+func TestGatherer(t *testing.T) {
+	t.Run("should gather only this process without prefork", func(t *testing.T) {
+		metric := find(t, metrics.Gatherer(""), "account_deposits_total", map[string]string{"outcome": metrics.OutcomeSuccess})
+		require.NotNil(t, metric)
+		assert.Len(t, metric.Label, 1)
+	})
+
+	t.Run("should gather the processes sharing the directory with prefork", func(t *testing.T) {
+		dir, err := os.MkdirTemp("", "metrics")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		served := make(chan error, 1)
+		go func() { served <- metrics.Serve(ctx, dir) }()
+		socket := filepath.Join(dir, strconv.Itoa(os.Getpid())+".sock")
+		require.Eventually(t, func() bool {
+			_, err := os.Stat(socket)
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+
+		// Another worker, and one that has died
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "account_deposits_total", Help: "Number of deposits by outcome.", ConstLabels: prometheus.Labels{"outcome": metrics.OutcomeSuccess, "worker": "1"},
+		}))
+		listener, err := net.Listen("unix", filepath.Join(dir, "1.sock"))
+		require.NoError(t, err)
+		server := &http.Server{Handler: promhttp.HandlerFor(registry, promhttp.HandlerOpts{})}
+		go server.Serve(listener)
+		defer server.Close()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "2.sock"), nil, 0o600))
+
+		worker := strconv.Itoa(os.Getpid())
+		app := fiber.New()
+		app.Get("/metrics", metrics.Handler(dir))
+		resp, err := app.Test(httptest.NewRequest("GET", "/metrics", nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), `account_deposits_total{outcome="success",worker="`+worker+`"}`)
+		assert.Contains(t, string(body), `account_deposits_total{outcome="success",worker="1"} 0`)
+
+		cancel()
+		assert.NoError(t, <-served)
+		assert.NoFileExists(t, socket)
+	})
+}
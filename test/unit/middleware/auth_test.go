@@ -0,0 +1,137 @@
+package middleware_test
+
+import (
+	"account-service/src/middleware"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSecret = "unit-test-secret"
+
+func TestJWT(t *testing.T) {
+	j := middleware.NewJWT(testSecret, time.Hour)
+
+	t.Run("should verify the tokens it issues", func(t *testing.T) {
+		token, err := j.Issue("1234567890", middleware.RoleCustomer)
+		assert.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), token.Expires, time.Minute)
+
+		claims, err := j.Verify(token.Token)
+		assert.NoError(t, err)
+		assert.Equal(t, "1234567890", claims.Subject)
+		assert.Equal(t, middleware.RoleCustomer, claims.Role)
+	})
+
+	t.Run("should refuse to issue a token for an unknown role", func(t *testing.T) {
+		_, err := j.Issue("auditor-01", "auditor")
+		assert.Error(t, err)
+	})
+
+	t.Run("should reject a token signed with another secret", func(t *testing.T) {
+		token, err := middleware.NewJWT("another-secret", time.Hour).Issue("admin-01", middleware.RoleAdmin)
+		assert.NoError(t, err)
+
+		_, err = j.Verify(token.Token)
+		assert.Error(t, err)
+	})
+
+	t.Run("should reject an expired token", func(t *testing.T) {
+		token, err := middleware.NewJWT(testSecret, -time.Minute).Issue("admin-01", middleware.RoleAdmin)
+		assert.NoError(t, err)
+
+		_, err = j.Verify(token.Token)
+		assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+	})
+
+	t.Run("should reject an unsigned token", func(t *testing.T) {
+		claims := middleware.Claims{
+			Role: middleware.RoleAdmin,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "admin-01",
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+		assert.NoError(t, err)
+
+		_, err = j.Verify(token)
+		assert.Error(t, err)
+	})
+
+	t.Run("should reject a token without expiry or subject", func(t *testing.T) {
+		for name, registered := range map[string]jwt.RegisteredClaims{
+			"no expiry":  {Subject: "admin-01"},
+			"no subject": {ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+		} {
+			claims := middleware.Claims{Role: middleware.RoleAdmin, RegisteredClaims: registered}
+			token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+			assert.NoError(t, err)
+
+			_, err = j.Verify(token)
+			assert.Error(t, err, name)
+		}
+	})
+}
+
+func TestClaimsCanAccessAccount(t *testing.T) {
+	customer := middleware.Claims{Role: middleware.RoleCustomer, RegisteredClaims: jwt.RegisteredClaims{Subject: "1234567890"}}
+	assert.True(t, customer.CanAccessAccount("1234567890"))
+	assert.False(t, customer.CanAccessAccount("9876543210"))
+
+	for _, role := range []string{middleware.RoleTeller, middleware.RoleAdmin} {
+		staff := middleware.Claims{Role: role, RegisteredClaims: jwt.RegisteredClaims{Subject: role + "-01"}}
+		assert.True(t, staff.CanAccessAccount("9876543210"), role)
+	}
+
+	unknown := middleware.Claims{Role: "auditor", RegisteredClaims: jwt.RegisteredClaims{Subject: "9876543210"}}
+	assert.False(t, unknown.CanAccessAccount("9876543210"))
+}
+
+func TestAuth(t *testing.T) {
+	j := middleware.NewJWT(testSecret, time.Hour)
+	app := fiber.New()
+	app.Get("/staff", middleware.Auth(j, middleware.RoleTeller, middleware.RoleAdmin), func(c *fiber.Ctx) error {
+		return c.SendString(middleware.CurrentClaims(c).Subject)
+	})
+
+	request := func(authorization string) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, "/staff", nil)
+		if authorization != "" {
+			req.Header.Set(fiber.HeaderAuthorization, authorization)
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+	bearer := func(subject, role string) string {
+		token, err := j.Issue(subject, role)
+		assert.NoError(t, err)
+		return "Bearer " + token.Token
+	}
+
+	t.Run("should reject a request without a bearer token", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, request("").StatusCode)
+		assert.Equal(t, http.StatusUnauthorized, request("Basic dXNlcjpwYXNz").StatusCode)
+		assert.Equal(t, http.StatusUnauthorized, request("Bearer not-a-token").StatusCode)
+	})
+
+	t.Run("should reject a role that is not allowed", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, request(bearer("1234567890", middleware.RoleCustomer)).StatusCode)
+	})
+
+	t.Run("should pass the claims of an allowed role", func(t *testing.T) {
+		resp := request(bearer("teller-01", middleware.RoleTeller))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "teller-01", string(body))
+	})
+}
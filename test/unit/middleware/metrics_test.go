@@ -0,0 +1,68 @@
+package middleware_test
+
+import (
+	"account-service/src/metrics"
+	"account-service/src/middleware"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// requestCount returns how many requests metrics counted for route with
+// status.
+func requestCount(t *testing.T, route, status string) float64 {
+	t.Helper()
+	families, err := metrics.Registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "http_requests_total" {
+			continue
+		}
+		for _, metric := range family.Metric {
+			labels := map[string]string{}
+			for _, label := range metric.Label {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["route"] == route && labels["status"] == status {
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestMetricsConfig(t *testing.T) {
+	app := fiber.New()
+	app.Use(middleware.MetricsConfig())
+	app.Get("/accounts/:accountNumber", func(c *fiber.Ctx) error {
+		if c.Params("accountNumber") == "missing" {
+			return fiber.NewError(fiber.StatusNotFound, "account not found")
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	t.Run("should count requests by route rather than by path", func(t *testing.T) {
+		before := requestCount(t, "/accounts/:accountNumber", "200")
+
+		for _, path := range []string{"/accounts/1", "/accounts/2"} {
+			resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+			require.NoError(t, err)
+			assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		}
+
+		assert.Equal(t, before+2, requestCount(t, "/accounts/:accountNumber", "200"))
+	})
+
+	t.Run("should count the status of a returned error", func(t *testing.T) {
+		before := requestCount(t, "/accounts/:accountNumber", "404")
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/accounts/missing", nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+		assert.Equal(t, before+1, requestCount(t, "/accounts/:accountNumber", "404"))
+	})
+}
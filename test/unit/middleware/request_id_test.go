@@ -0,0 +1,58 @@
+package middleware_test
+
+import (
+	"account-service/src/middleware"
+	"account-service/src/utils"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestIDConfig(t *testing.T) {
+	app := fiber.New()
+	app.Use(middleware.RequestIDConfig())
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(utils.RequestID(c.UserContext()))
+	})
+
+	// request returns the request ID the handler saw and the one echoed.
+	request := func(t *testing.T, header string) (string, string) {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/", nil)
+		if header != "" {
+			req.Header.Set(middleware.RequestIDHeader, header)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body), resp.Header.Get(middleware.RequestIDHeader)
+	}
+
+	t.Run("should keep the request ID of the caller", func(t *testing.T) {
+		seen, echoed := request(t, "gateway-7f3a.42")
+		assert.Equal(t, "gateway-7f3a.42", seen)
+		assert.Equal(t, "gateway-7f3a.42", echoed)
+	})
+
+	t.Run("should generate a request ID when the caller sends none", func(t *testing.T) {
+		seen, echoed := request(t, "")
+		_, err := uuid.Parse(seen)
+		assert.NoError(t, err)
+		assert.Equal(t, seen, echoed)
+
+		other, _ := request(t, "")
+		assert.NotEqual(t, seen, other)
+	})
+
+	t.Run("should replace a request ID that could forge log lines", func(t *testing.T) {
+		seen, _ := request(t, `abc" level=error msg="forged`)
+		_, err := uuid.Parse(seen)
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,89 @@
+package middleware_test
+
+import (
+	"account-service/src/middleware"
+	"account-service/src/tracing"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans installs a tracer provider recording the spans that end.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestTracingConfig(t *testing.T) {
+	app := fiber.New()
+	app.Use(middleware.TracingConfig())
+	app.Get("/accounts/:accountNumber", func(c *fiber.Ctx) error {
+		_, span := tracing.Tracer().Start(c.UserContext(), "handler")
+		defer span.End()
+		if c.Params("accountNumber") == "broken" {
+			return fiber.NewError(fiber.StatusInternalServerError, "broken")
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	t.Run("should continue the trace of the traceparent header under the route", func(t *testing.T) {
+		recorder := recordSpans(t)
+		request := httptest.NewRequest("GET", "/accounts/1234567890", nil)
+		request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+		resp, err := app.Test(request)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 2)
+		handler, server := spans[0], spans[1]
+		assert.Equal(t, "GET /accounts/:accountNumber", server.Name())
+		assert.Equal(t, trace.SpanKindServer, server.SpanKind())
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+		assert.True(t, server.Parent().IsRemote())
+		assert.Contains(t, server.Attributes(), semconv.HTTPRoute("/accounts/:accountNumber"))
+		assert.Contains(t, server.Attributes(), semconv.HTTPResponseStatusCode(fiber.StatusOK))
+		assert.Equal(t, server.SpanContext().SpanID(), handler.Parent().SpanID())
+	})
+
+	t.Run("should start a trace without a traceparent header", func(t *testing.T) {
+		recorder := recordSpans(t)
+
+		_, err := app.Test(httptest.NewRequest("GET", "/accounts/1234567890", nil))
+		require.NoError(t, err)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 2)
+		assert.False(t, spans[1].Parent().IsValid())
+	})
+
+	t.Run("should mark the span of a server error as failed", func(t *testing.T) {
+		recorder := recordSpans(t)
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/accounts/broken", nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 2)
+		assert.Equal(t, codes.Error, spans[1].Status().Code)
+		assert.Contains(t, spans[1].Attributes(), semconv.HTTPResponseStatusCode(fiber.StatusInternalServerError))
+	})
+}
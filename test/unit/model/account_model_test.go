@@ -3,6 +3,8 @@ package model_test
 import (
 	"account-service/src/model"
 	"account-service/src/utils"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -16,6 +18,7 @@ func TestAccountModel(t *testing.T) {
 			FullName:    "John Doe",
 			IDNumber:    "1234567890123456",
 			PhoneNumber: "081234567890",
+			PIN:         "123456",
 		}
 
 		t.Run("should validate a valid account", func(t *testing.T) {
@@ -86,7 +89,7 @@ func TestDepositRequestModel(t *testing.T) {
 	t.Run("DepositRequest validation", func(t *testing.T) {
 		validDeposit := model.DepositRequest{
 			AccountNumber: "1234567890",
-			Nominal:       100.0,
+			Nominal:       model.NewMoney(100),
 		}
 
 		t.Run("should validate a valid deposit request", func(t *testing.T) {
@@ -104,7 +107,7 @@ func TestDepositRequestModel(t *testing.T) {
 
 		t.Run("should fail with zero Nominal", func(t *testing.T) {
 			invalidDeposit := validDeposit
-			invalidDeposit.Nominal = 0
+			invalidDeposit.Nominal = model.NewMoney(0)
 			err := validate.Struct(invalidDeposit)
 			assert.Error(t, err)
 			assert.Contains(t, err.Error(), "Nominal")
@@ -112,7 +115,7 @@ func TestDepositRequestModel(t *testing.T) {
 
 		t.Run("should fail with negative Nominal", func(t *testing.T) {
 			invalidDeposit := validDeposit
-			invalidDeposit.Nominal = -100.0
+			invalidDeposit.Nominal = model.NewMoney(-100)
 			err := validate.Struct(invalidDeposit)
 			assert.Error(t, err)
 			assert.Contains(t, err.Error(), "Nominal")
@@ -124,7 +127,8 @@ func TestWithdrawalModel(t *testing.T) {
 	t.Run("Withdrawal validation", func(t *testing.T) {
 		validWithdrawal := model.Withdrawal{
 			AccountNumber: "1234567890",
-			Nominal:       100.0,
+			Nominal:       model.NewMoney(100),
+			PIN:           "123456",
 		}
 
 		t.Run("should validate a valid withdrawal request", func(t *testing.T) {
@@ -141,7 +145,7 @@ func TestWithdrawalModel(t *testing.T) {
 		})
 		t.Run("should fail with zero Nominal", func(t *testing.T) {
 			invalidWithdrawal := validWithdrawal
-			invalidWithdrawal.Nominal = 0
+			invalidWithdrawal.Nominal = model.NewMoney(0)
 			err := validate.Struct(invalidWithdrawal)
 			assert.Error(t, err)
 			assert.Contains(t, err.Error(), "Nominal")
@@ -149,10 +153,215 @@ func TestWithdrawalModel(t *testing.T) {
 
 		t.Run("should fail with negative Nominal", func(t *testing.T) {
 			invalidWithdrawal := validWithdrawal
-			invalidWithdrawal.Nominal = -100.0
+			invalidWithdrawal.Nominal = model.NewMoney(-100)
 			err := validate.Struct(invalidWithdrawal)
 			assert.Error(t, err)
 			assert.Contains(t, err.Error(), "Nominal")
 		})
+
+		t.Run("should fail with missing or malformed PIN", func(t *testing.T) {
+			for _, pin := range []model.PIN{"", "12345", "1234567", "12345a"} {
+				invalidWithdrawal := validWithdrawal
+				invalidWithdrawal.PIN = pin
+				err := validate.Struct(invalidWithdrawal)
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "PIN")
+			}
+		})
+	})
+}
+
+func TestMutationModel(t *testing.T) {
+	t.Run("Mutation validation", func(t *testing.T) {
+		validMonth := model.Mutation{
+			AccountNumber: "1234567890",
+			Month:         1,
+		}
+		validRange := model.Mutation{
+			AccountNumber: "1234567890",
+			From:          "2025-01-01",
+			To:            "2025-01-31",
+		}
+
+		t.Run("should validate a month query", func(t *testing.T) {
+			err := validate.Struct(validMonth)
+			assert.NoError(t, err)
+		})
+
+		t.Run("should validate a date range query", func(t *testing.T) {
+			err := validate.Struct(validRange)
+			assert.NoError(t, err)
+		})
+
+		t.Run("should fail without month or date range", func(t *testing.T) {
+			invalidMutation := validMonth
+			invalidMutation.Month = 0
+			err := validate.Struct(invalidMutation)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "Month")
+		})
+
+		t.Run("should fail with month out of range", func(t *testing.T) {
+			invalidMutation := validMonth
+			invalidMutation.Month = 13
+			err := validate.Struct(invalidMutation)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "Month")
+		})
+
+		t.Run("should fail with both month and date range", func(t *testing.T) {
+			invalidMutation := validRange
+			invalidMutation.Month = 1
+			err := validate.Struct(invalidMutation)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "Month")
+		})
+
+		t.Run("should fail with start date but no end date", func(t *testing.T) {
+			invalidMutation := validRange
+			invalidMutation.To = ""
+			err := validate.Struct(invalidMutation)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "To")
+		})
+
+		t.Run("should fail with malformed date", func(t *testing.T) {
+			invalidMutation := validRange
+			invalidMutation.From = "01-01-2025"
+			err := validate.Struct(invalidMutation)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "From")
+		})
+
+		t.Run("should fail with limit above maximum", func(t *testing.T) {
+			invalidMutation := validMonth
+			invalidMutation.Limit = 101
+			err := validate.Struct(invalidMutation)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "Limit")
+		})
+	})
+}
+
+func TestTransferRequestModel(t *testing.T) {
+	t.Run("TransferRequest validation", func(t *testing.T) {
+		validTransfer := model.TransferRequest{
+			FromAccountNumber: "1234567890",
+			ToAccountNumber:   "0987654321",
+			Nominal:           model.NewMoney(100),
+			PIN:               "123456",
+		}
+
+		t.Run("should validate a valid transfer request", func(t *testing.T) {
+			err := validate.Struct(validTransfer)
+			assert.NoError(t, err)
+		})
+
+		t.Run("should fail when both accounts are the same", func(t *testing.T) {
+			invalidTransfer := validTransfer
+			invalidTransfer.ToAccountNumber = validTransfer.FromAccountNumber
+			err := validate.Struct(invalidTransfer)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "ToAccountNumber")
+		})
+
+		t.Run("should fail with empty FromAccountNumber", func(t *testing.T) {
+			invalidTransfer := validTransfer
+			invalidTransfer.FromAccountNumber = ""
+			err := validate.Struct(invalidTransfer)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "FromAccountNumber")
+		})
+
+		t.Run("should fail without PIN", func(t *testing.T) {
+			invalidTransfer := validTransfer
+			invalidTransfer.PIN = ""
+			err := validate.Struct(invalidTransfer)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "PIN")
+		})
+
+		t.Run("should fail with zero Nominal", func(t *testing.T) {
+			invalidTransfer := validTransfer
+			invalidTransfer.Nominal = model.NewMoney(0)
+			err := validate.Struct(invalidTransfer)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "Nominal")
+		})
+	})
+}
+
+func TestReversalRequestModel(t *testing.T) {
+	t.Run("ReversalRequest validation", func(t *testing.T) {
+		t.Run("should validate a valid reversal request", func(t *testing.T) {
+			err := validate.Struct(model.ReversalRequest{Reason: "Deposit posted to the wrong account"})
+			assert.NoError(t, err)
+		})
+
+		t.Run("should fail with empty Reason", func(t *testing.T) {
+			err := validate.Struct(model.ReversalRequest{})
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "Reason")
+		})
+
+		t.Run("should fail with too long Reason", func(t *testing.T) {
+			err := validate.Struct(model.ReversalRequest{Reason: strings.Repeat("a", 256)})
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "Reason")
+		})
+	})
+}
+
+func TestAccountStatusTransitions(t *testing.T) {
+	t.Run("CanTransitionTo", func(t *testing.T) {
+		allowed := map[string][]string{
+			model.AccountStatusActive:  {model.AccountStatusFrozen, model.AccountStatusDormant, model.AccountStatusClosed},
+			model.AccountStatusFrozen:  {model.AccountStatusActive, model.AccountStatusClosed},
+			model.AccountStatusDormant: {model.AccountStatusActive, model.AccountStatusFrozen, model.AccountStatusClosed},
+			model.AccountStatusClosed:  {},
+		}
+		statuses := []string{model.AccountStatusActive, model.AccountStatusFrozen, model.AccountStatusDormant, model.AccountStatusClosed}
+
+		for _, from := range statuses {
+			for _, to := range statuses {
+				account := model.Account{Status: from}
+				assert.Equal(t, slices.Contains(allowed[from], to), account.CanTransitionTo(to), "%s to %s", from, to)
+			}
+		}
+	})
+
+	t.Run("should reject unknown statuses", func(t *testing.T) {
+		account := model.Account{Status: model.AccountStatusActive}
+		assert.False(t, account.CanTransitionTo("suspended"))
+
+		unknown := model.Account{Status: "suspended"}
+		assert.False(t, unknown.CanTransitionTo(model.AccountStatusActive))
+	})
+}
+
+func TestAccountStatusRequestModel(t *testing.T) {
+	t.Run("AccountStatusRequest validation", func(t *testing.T) {
+		validRequest := model.AccountStatusRequest{Actor: "teller-01", Reason: "Customer request"}
+
+		t.Run("should validate a valid status request", func(t *testing.T) {
+			err := validate.Struct(validRequest)
+			assert.NoError(t, err)
+		})
+
+		t.Run("should fail with empty Actor", func(t *testing.T) {
+			invalidRequest := validRequest
+			invalidRequest.Actor = ""
+			err := validate.Struct(invalidRequest)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "Actor")
+		})
+
+		t.Run("should fail with empty Reason", func(t *testing.T) {
+			invalidRequest := validRequest
+			invalidRequest.Reason = ""
+			err := validate.Struct(invalidRequest)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "Reason")
+		})
 	})
 }
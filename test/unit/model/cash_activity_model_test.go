@@ -0,0 +1,101 @@
+package model_test
+
+import (
+	"account-service/src/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCashActivityModel(t *testing.T) {
+	t.Run("ComputeHash", func(t *testing.T) {
+		referenceID := uint(3)
+		jakarta := time.FixedZone("WIB", 7*60*60)
+		activity := model.CashActivity{
+			AccountID:     7,
+			ReferenceID:   &referenceID,
+			Type:          "debit",
+			Nominal:       model.NewMoney(50000),
+			BalanceBefore: model.NewMoney(100000),
+			BalanceAfter:  model.NewMoney(50000),
+			Description:   "ATM",
+			CreatedAt:     time.Date(2025, 1, 2, 10, 4, 5, 123456000, jakarta),
+		}
+
+		t.Run("should match the documented encoding", func(t *testing.T) {
+			// Pinned so the sealing migration and the service keep agreeing.
+			assert.Equal(t, "cfca2048544f0316f062a081842a14157161d878fb971a25565a539deaff127a", activity.ComputeHash("abc"))
+
+			first := model.CashActivity{
+				AccountID:     7,
+				Type:          "credit",
+				Nominal:       model.NewMoney(100000),
+				BalanceBefore: model.NewMoney(0),
+				BalanceAfter:  model.NewMoney(100000),
+				CreatedAt:     time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+			}
+			assert.Equal(t, "b365f184d095cedf7eff0488cb9fa085c36c3a8a8f25add93bee72d48695a40a", first.ComputeHash(""))
+		})
+
+		t.Run("should not depend on the time zone of created_at", func(t *testing.T) {
+			utc := activity
+			utc.CreatedAt = activity.CreatedAt.UTC()
+			assert.Equal(t, activity.ComputeHash("abc"), utc.ComputeHash("abc"))
+		})
+
+		t.Run("should change when the previous hash changes", func(t *testing.T) {
+			assert.NotEqual(t, activity.ComputeHash("abc"), activity.ComputeHash("abd"))
+		})
+
+		t.Run("should change when any field changes", func(t *testing.T) {
+			base := activity.ComputeHash("abc")
+			otherReferenceID := uint(4)
+			transferReference := "0b6f3c52-8a51-4c1e-9f0e-3f9b7d6a2c11"
+			mutations := map[string]func(a *model.CashActivity){
+				"AccountID":         func(a *model.CashActivity) { a.AccountID = 8 },
+				"ReferenceID":       func(a *model.CashActivity) { a.ReferenceID = &otherReferenceID },
+				"Type":              func(a *model.CashActivity) { a.Type = "credit" },
+				"Nominal":           func(a *model.CashActivity) { a.Nominal = model.NewMoney(50001) },
+				"BalanceBefore":     func(a *model.CashActivity) { a.BalanceBefore = model.NewMoney(100001) },
+				"BalanceAfter":      func(a *model.CashActivity) { a.BalanceAfter = model.NewMoney(50001) },
+				"Description":       func(a *model.CashActivity) { a.Description = "ATM2" },
+				"TransferReference": func(a *model.CashActivity) { a.TransferReference = &transferReference },
+				"CreatedAt":         func(a *model.CashActivity) { a.CreatedAt = a.CreatedAt.Add(time.Microsecond) },
+				"ReversalOfID":      func(a *model.CashActivity) { a.ReversalOfID = &otherReferenceID },
+				"FeeScheduleID":     func(a *model.CashActivity) { a.FeeScheduleID = &otherReferenceID },
+				"FeeOfID":           func(a *model.CashActivity) { a.FeeOfID = &otherReferenceID },
+				"FeePeriod":         func(a *model.CashActivity) { a.FeePeriod = &a.CreatedAt },
+			}
+			for field, mutate := range mutations {
+				changed := activity
+				mutate(&changed)
+				assert.NotEqual(t, base, changed.ComputeHash("abc"), field)
+			}
+		})
+
+		t.Run("should tell the links apart", func(t *testing.T) {
+			linkID := uint(12)
+			reversal := activity
+			reversal.ReversalOfID = &linkID
+			fee := activity
+			fee.FeeOfID = &linkID
+			assert.NotEqual(t, reversal.ComputeHash("abc"), fee.ComputeHash("abc"))
+
+			// Pinned so the sealing migration of the links and the service keep agreeing.
+			scheduleID := uint(2)
+			period := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+			fee.FeeScheduleID = &scheduleID
+			fee.FeePeriod = &period
+			assert.Equal(t, "bd33b3816eb88ec118233eca1d2dedab54656fd010bef6a3d711697f4f3e54d9", fee.ComputeHash("abc"))
+		})
+
+		t.Run("should not be fooled by moving the field separator", func(t *testing.T) {
+			left := activity
+			left.Description = "A;"
+			right := activity
+			right.Description = "A"
+			assert.NotEqual(t, left.ComputeHash(""), right.ComputeHash(";"))
+		})
+	})
+}
@@ -0,0 +1,78 @@
+package model_test
+
+import (
+	"account-service/src/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeeModel(t *testing.T) {
+	t.Run("WithdrawalFee", func(t *testing.T) {
+		schedule := &model.FeeSchedule{Type: model.FeeTypeWithdrawal, Amount: model.NewMoney(2500), FreePerMonth: 2}
+
+		tests := []struct {
+			name                 string
+			schedule             *model.FeeSchedule
+			withdrawalsThisMonth int
+			expected             model.Money
+		}{
+			{"should waive the first withdrawal", schedule, 0, model.NewMoney(0)},
+			{"should waive the last free withdrawal", schedule, 1, model.NewMoney(0)},
+			{"should charge once the free withdrawals are used", schedule, 2, model.NewMoney(2500)},
+			{"should charge every later withdrawal", schedule, 10, model.NewMoney(2500)},
+			{"should charge nothing without a schedule", nil, 10, model.NewMoney(0)},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				assert.Equal(t, test.expected, test.schedule.WithdrawalFee(test.withdrawalsThisMonth))
+			})
+		}
+	})
+
+	t.Run("FeeScheduleRequest validation", func(t *testing.T) {
+		valid := func() model.FeeScheduleRequest {
+			return model.FeeScheduleRequest{Type: model.FeeTypeWithdrawal, Amount: model.NewMoney(2500), FreePerMonth: 5, EffectiveFrom: "2025-02-01"}
+		}
+
+		t.Run("should accept a valid request", func(t *testing.T) {
+			err := validate.Struct(valid())
+			assert.NoError(t, err)
+		})
+
+		t.Run("should accept a zero fee", func(t *testing.T) {
+			req := valid()
+			req.Amount = model.NewMoney(0)
+			err := validate.Struct(req)
+			assert.NoError(t, err)
+		})
+
+		t.Run("should reject an unknown type", func(t *testing.T) {
+			req := valid()
+			req.Type = "transfer"
+			err := validate.Struct(req)
+			assert.Error(t, err)
+		})
+
+		t.Run("should reject a negative fee", func(t *testing.T) {
+			req := valid()
+			req.Amount = model.NewMoney(-1)
+			err := validate.Struct(req)
+			assert.Error(t, err)
+		})
+
+		t.Run("should reject negative free withdrawals", func(t *testing.T) {
+			req := valid()
+			req.FreePerMonth = -1
+			err := validate.Struct(req)
+			assert.Error(t, err)
+		})
+
+		t.Run("should reject a malformed date", func(t *testing.T) {
+			req := valid()
+			req.EffectiveFrom = "01-02-2025"
+			err := validate.Struct(req)
+			assert.Error(t, err)
+		})
+	})
+}
@@ -0,0 +1,81 @@
+package model_test
+
+import (
+	"account-service/src/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournalEntryModel(t *testing.T) {
+	amounts := func(entry *model.JournalEntry) map[string]model.Money {
+		result := make(map[string]model.Money)
+		for _, line := range entry.Lines {
+			result[line.GLAccountCode] = line.Amount
+		}
+		return result
+	}
+
+	t.Run("NewActivityJournal", func(t *testing.T) {
+		t.Run("should debit the contra account for a credit to the customer", func(t *testing.T) {
+			deposit := model.CashActivity{ID: 7, Type: "credit", Nominal: model.NewMoney(50000)}
+			entry := model.NewActivityJournal(&deposit, model.GLCashCode, "Deposit")
+
+			assert.True(t, entry.IsBalanced())
+			assert.Equal(t, uint(7), *entry.CashActivityID)
+			assert.Equal(t, map[string]model.Money{
+				model.GLCashCode:             model.NewMoney(50000),
+				model.GLCustomerDepositsCode: model.NewMoney(-50000),
+			}, amounts(entry))
+		})
+
+		t.Run("should credit the contra account for a debit to the customer", func(t *testing.T) {
+			fee := model.CashActivity{ID: 8, Type: "debit", Nominal: model.NewMoney(2500)}
+			entry := model.NewActivityJournal(&fee, model.GLFeeIncomeCode, "Fee")
+
+			assert.True(t, entry.IsBalanced())
+			assert.Equal(t, map[string]model.Money{
+				model.GLFeeIncomeCode:        model.NewMoney(-2500),
+				model.GLCustomerDepositsCode: model.NewMoney(2500),
+			}, amounts(entry))
+		})
+	})
+
+	t.Run("Reverse", func(t *testing.T) {
+		withdrawal := model.CashActivity{ID: 7, Type: "debit", Nominal: model.NewMoney(30000)}
+		compensating := model.CashActivity{ID: 9, Type: "credit", Nominal: model.NewMoney(30000)}
+		original := model.NewActivityJournal(&withdrawal, model.GLCashCode, "Withdrawal")
+		reversal := original.Reverse(&compensating, "Reversal")
+
+		assert.True(t, reversal.IsBalanced())
+		assert.Equal(t, uint(9), *reversal.CashActivityID)
+		assert.Equal(t, "Reversal", reversal.Description)
+		for code, amount := range amounts(original) {
+			assert.Equal(t, amount.Neg(), amounts(reversal)[code], code)
+		}
+	})
+
+	t.Run("IsBalanced", func(t *testing.T) {
+		t.Run("should reject lines that do not sum to zero", func(t *testing.T) {
+			entry := model.JournalEntry{Lines: []model.JournalLine{
+				{GLAccountCode: model.GLCashCode, Amount: model.NewMoney(100)},
+				{GLAccountCode: model.GLCustomerDepositsCode, Amount: model.MustParseMoney("-99.99")},
+			}}
+			assert.False(t, entry.IsBalanced())
+		})
+
+		t.Run("should reject an entry with fewer than two lines", func(t *testing.T) {
+			assert.False(t, (&model.JournalEntry{}).IsBalanced())
+			assert.False(t, (&model.JournalEntry{Lines: []model.JournalLine{{GLAccountCode: model.GLCashCode}}}).IsBalanced())
+		})
+
+		t.Run("should accept several lines that sum to zero", func(t *testing.T) {
+			entry := model.JournalEntry{Lines: []model.JournalLine{
+				{GLAccountCode: model.GLCashCode, Amount: model.NewMoney(100)},
+				{GLAccountCode: model.GLCustomerDepositsCode, Amount: model.NewMoney(-90)},
+				{GLAccountCode: model.GLFeeIncomeCode, Amount: model.NewMoney(-10)},
+			}}
+			assert.True(t, entry.IsBalanced())
+		})
+	})
+}
@@ -0,0 +1,50 @@
+package model_test
+
+import (
+	"account-service/src/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterestModel(t *testing.T) {
+	t.Run("DailyInterest", func(t *testing.T) {
+		tests := []struct {
+			name          string
+			balance       model.Money
+			annualRateBPS int
+			expected      model.Money
+		}{
+			{"should compute a day of a year's rate", model.NewMoney(1000000), 3650, model.NewMoney(1000)},
+			{"should round to the sen", model.NewMoney(1000000), 100, model.MustParseMoney("27.40")},
+			{"should round half up", model.MustParseMoney("182.50"), 100, model.MustParseMoney("0.01")},
+			{"should round a small balance down to zero", model.NewMoney(10), 100, model.NewMoney(0)},
+			{"should earn nothing at a zero rate", model.NewMoney(1000000), 0, model.NewMoney(0)},
+			{"should not overflow on the largest balance", model.MustParseMoney("9999999999999.99"), 10000, model.MoneyFromMinorUnits(2739726027397)},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				assert.Equal(t, test.expected, model.DailyInterest(test.balance, test.annualRateBPS))
+			})
+		}
+	})
+
+	t.Run("ProductRateRequest validation", func(t *testing.T) {
+		rate := func(bps int) *int { return &bps }
+
+		t.Run("should require a rate", func(t *testing.T) {
+			err := validate.Struct(model.ProductRateRequest{})
+			assert.Error(t, err)
+		})
+
+		t.Run("should accept a zero rate", func(t *testing.T) {
+			err := validate.Struct(model.ProductRateRequest{AnnualRateBPS: rate(0)})
+			assert.NoError(t, err)
+		})
+
+		t.Run("should reject a rate above 100%", func(t *testing.T) {
+			err := validate.Struct(model.ProductRateRequest{AnnualRateBPS: rate(10001)})
+			assert.Error(t, err)
+		})
+	})
+}
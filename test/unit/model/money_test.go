@@ -0,0 +1,137 @@
+package model_test
+
+import (
+	"account-service/src/model"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoney(t *testing.T) {
+	t.Run("ParseMoney", func(t *testing.T) {
+		t.Run("should parse whole and fractional amounts", func(t *testing.T) {
+			cases := map[string]int64{
+				"1500":    150000,
+				"1500.5":  150050,
+				"1500.50": 150050,
+				"0.01":    1,
+				".5":      50,
+				"-12.34":  -1234,
+				"+7":      700,
+				"100.000": 10000,
+			}
+			for input, minor := range cases {
+				money, err := model.ParseMoney(input)
+				assert.NoError(t, err, input)
+				assert.Equal(t, minor, money.MinorUnits(), input)
+			}
+		})
+
+		t.Run("should reject amounts with too much precision", func(t *testing.T) {
+			_, err := model.ParseMoney("0.001")
+			assert.ErrorIs(t, err, model.ErrMoneyPrecision)
+		})
+
+		t.Run("should reject amounts that do not fit NUMERIC(15, 2)", func(t *testing.T) {
+			_, err := model.ParseMoney("12345678901234")
+			assert.ErrorIs(t, err, model.ErrMoneyOverflow)
+		})
+
+		t.Run("should reject malformed amounts", func(t *testing.T) {
+			for _, input := range []string{"", ".", "-", "1e5", "12a", "1.2.3", "1,000"} {
+				_, err := model.ParseMoney(input)
+				assert.ErrorIs(t, err, model.ErrInvalidMoney, input)
+			}
+		})
+	})
+
+	t.Run("Arithmetic", func(t *testing.T) {
+		t.Run("should not drift on repeated small deposits", func(t *testing.T) {
+			balance := model.NewMoney(0)
+			for i := 0; i < 10; i++ {
+				balance = balance.Add(model.MustParseMoney("0.1"))
+			}
+			assert.Equal(t, model.NewMoney(1), balance)
+		})
+
+		t.Run("should compare amounts", func(t *testing.T) {
+			small, large := model.NewMoney(1), model.NewMoney(2)
+			assert.True(t, small.LessThan(large))
+			assert.Equal(t, -1, small.Cmp(large))
+			assert.Equal(t, 1, large.Cmp(small))
+			assert.Equal(t, 0, small.Cmp(model.MustParseMoney("1.00")))
+			assert.True(t, small.Sub(large).IsNegative())
+			assert.Equal(t, small, large.Sub(small))
+		})
+
+		t.Run("should format with two decimal places", func(t *testing.T) {
+			assert.Equal(t, "1500.05", model.MoneyFromMinorUnits(150005).String())
+			assert.Equal(t, "-0.50", model.MoneyFromMinorUnits(-50).String())
+			assert.Equal(t, "0.00", model.Money{}.String())
+		})
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		t.Run("should marshal as a number", func(t *testing.T) {
+			data, err := json.Marshal(model.BalanceResponse{Balance: model.MustParseMoney("450000.5")})
+			assert.NoError(t, err)
+			assert.JSONEq(t, `{"saldo": 450000.50}`, string(data))
+		})
+
+		t.Run("should unmarshal numbers and strings", func(t *testing.T) {
+			var req model.DepositRequest
+			err := json.Unmarshal([]byte(`{"no_rekening": "1234567890", "nominal": 100000.25}`), &req)
+			assert.NoError(t, err)
+			assert.Equal(t, model.MustParseMoney("100000.25"), req.Nominal)
+
+			err = json.Unmarshal([]byte(`{"no_rekening": "1234567890", "nominal": "99.9"}`), &req)
+			assert.NoError(t, err)
+			assert.Equal(t, model.MustParseMoney("99.90"), req.Nominal)
+		})
+
+		t.Run("should reject amounts with too much precision", func(t *testing.T) {
+			var req model.DepositRequest
+			err := json.Unmarshal([]byte(`{"no_rekening": "1234567890", "nominal": 0.001}`), &req)
+			assert.ErrorIs(t, err, model.ErrMoneyPrecision)
+		})
+	})
+
+	t.Run("Database", func(t *testing.T) {
+		t.Run("should store an exact decimal", func(t *testing.T) {
+			value, err := model.MustParseMoney("1234.5").Value()
+			assert.NoError(t, err)
+			assert.Equal(t, "1234.50", value)
+		})
+
+		t.Run("should scan decimals and round to minor units", func(t *testing.T) {
+			var money model.Money
+			assert.NoError(t, money.Scan([]byte("1234.50")))
+			assert.Equal(t, model.MoneyFromMinorUnits(123450), money)
+
+			assert.NoError(t, money.Scan("0.005"))
+			assert.Equal(t, model.MoneyFromMinorUnits(1), money)
+
+			assert.NoError(t, money.Scan(0.1+0.2))
+			assert.Equal(t, model.MoneyFromMinorUnits(30), money)
+
+			assert.NoError(t, money.Scan(int64(5)))
+			assert.Equal(t, model.NewMoney(5), money)
+		})
+
+		t.Run("should fail on unsupported types", func(t *testing.T) {
+			var money model.Money
+			assert.Error(t, money.Scan(true))
+		})
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		t.Run("should accept the smallest positive amount", func(t *testing.T) {
+			err := validate.Struct(model.DepositRequest{
+				AccountNumber: "1234567890",
+				Nominal:       model.MoneyFromMinorUnits(1),
+			})
+			assert.NoError(t, err)
+		})
+	})
+}
@@ -0,0 +1,59 @@
+package model_test
+
+import (
+	"account-service/src/model"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutboxModel(t *testing.T) {
+	t.Run("RetryDelay", func(t *testing.T) {
+		tests := []struct {
+			attempts int
+			expected time.Duration
+		}{
+			{attempts: 1, expected: time.Second},
+			{attempts: 2, expected: 2 * time.Second},
+			{attempts: 4, expected: 8 * time.Second},
+			{attempts: 9, expected: time.Minute},   // capped
+			{attempts: 100, expected: time.Minute}, // never overflows
+		}
+		for _, tt := range tests {
+			event := model.OutboxEvent{Attempts: tt.attempts}
+			assert.Equal(t, tt.expected, event.RetryDelay(time.Second, time.Minute), "attempts %d", tt.attempts)
+		}
+	})
+
+	t.Run("should publish the payload as the event data", func(t *testing.T) {
+		outboxEvent, err := model.NewOutboxEvent("1234567890", model.EventFundsDeposited, model.ActivityData{
+			ActivityID:   7,
+			Type:         "credit",
+			Nominal:      model.NewMoney(10000),
+			BalanceAfter: model.NewMoney(15000),
+		})
+		assert.NoError(t, err)
+		assert.False(t, outboxEvent.NextAttemptAt.IsZero())
+		outboxEvent.ID = 42
+
+		body, err := json.Marshal(outboxEvent.Event())
+		assert.NoError(t, err)
+
+		var message struct {
+			ID            uint               `json:"id"`
+			Type          string             `json:"type"`
+			AccountNumber string             `json:"account_number"`
+			Data          model.ActivityData `json:"data"`
+		}
+		err = json.Unmarshal(body, &message)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(42), message.ID)
+		assert.Equal(t, model.EventFundsDeposited, message.Type)
+		assert.Equal(t, "1234567890", message.AccountNumber)
+		assert.Equal(t, uint(7), message.Data.ActivityID)
+		assert.Equal(t, model.NewMoney(15000), message.Data.BalanceAfter)
+		assert.NotContains(t, string(body), "fee_of_id")
+	})
+}
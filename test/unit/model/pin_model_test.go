@@ -0,0 +1,55 @@
+package model_test
+
+import (
+	"account-service/src/model"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPINModel(t *testing.T) {
+	t.Run("should never format the PIN", func(t *testing.T) {
+		withdrawal := model.Withdrawal{AccountNumber: "1234567890", Nominal: model.NewMoney(100), PIN: "987654"}
+		for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+			assert.NotContains(t, fmt.Sprintf(format, withdrawal), "987654", format)
+		}
+		assert.NotContains(t, fmt.Sprint(withdrawal.PIN), "987654")
+	})
+
+	t.Run("PINResetRequest validation", func(t *testing.T) {
+		validReset := model.PINResetRequest{
+			AccountNumber: "1234567890",
+			OldPIN:        "123456",
+			NewPIN:        "654321",
+			RequireOldPIN: true,
+		}
+
+		t.Run("should validate a valid reset request", func(t *testing.T) {
+			err := validate.Struct(validReset)
+			assert.NoError(t, err)
+		})
+
+		t.Run("should require OldPIN when asked to", func(t *testing.T) {
+			invalidReset := validReset
+			invalidReset.OldPIN = ""
+			err := validate.Struct(invalidReset)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "OldPIN")
+
+			invalidReset.RequireOldPIN = false
+			err = validate.Struct(invalidReset)
+			assert.NoError(t, err)
+		})
+
+		t.Run("should fail with malformed NewPIN", func(t *testing.T) {
+			for _, pin := range []model.PIN{"", "12345", "abcdef"} {
+				invalidReset := validReset
+				invalidReset.NewPIN = pin
+				err := validate.Struct(invalidReset)
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "NewPIN")
+			}
+		})
+	})
+}
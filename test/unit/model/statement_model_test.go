@@ -0,0 +1,66 @@
+package model_test
+
+import (
+	"account-service/src/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatementModel(t *testing.T) {
+	t.Run("Kind", func(t *testing.T) {
+		id := uint(1)
+		reference := "0b6f3c52-8a51-4c1e-9f0e-3f9b7d6a2c11"
+
+		tests := []struct {
+			name     string
+			line     model.StatementLine
+			expected string
+		}{
+			{"should be a deposit", model.StatementLine{CashActivity: model.CashActivity{Type: "credit"}}, model.ActivityKindDeposit},
+			{"should be a withdrawal", model.StatementLine{CashActivity: model.CashActivity{Type: "debit"}}, model.ActivityKindWithdrawal},
+			{"should be a transfer", model.StatementLine{CashActivity: model.CashActivity{Type: "debit", TransferReference: &reference}}, model.ActivityKindTransfer},
+			{"should be a fee", model.StatementLine{CashActivity: model.CashActivity{Type: "debit", FeeScheduleID: &id, FeeOfID: &id}}, model.ActivityKindFee},
+			{"should be interest", model.StatementLine{CashActivity: model.CashActivity{Type: "credit"}, Interest: true}, model.ActivityKindInterest},
+			{"should be a reversal before anything else", model.StatementLine{CashActivity: model.CashActivity{Type: "credit", ReversalOfID: &id}}, model.ActivityKindReversal},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				assert.Equal(t, test.expected, test.line.Kind())
+			})
+		}
+	})
+
+	t.Run("Debit and Credit", func(t *testing.T) {
+		debit := model.StatementLine{CashActivity: model.CashActivity{Type: "debit", Nominal: model.NewMoney(500)}}
+		assert.Equal(t, model.NewMoney(500), debit.Debit())
+		assert.True(t, debit.Credit().IsZero())
+
+		credit := model.StatementLine{CashActivity: model.CashActivity{Type: "credit", Nominal: model.NewMoney(500)}}
+		assert.True(t, credit.Debit().IsZero())
+		assert.Equal(t, model.NewMoney(500), credit.Credit())
+	})
+
+	t.Run("LastDay", func(t *testing.T) {
+		statement := model.Statement{To: time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC)}
+		assert.Equal(t, "2025-01-31", statement.LastDay().Format(time.DateOnly))
+	})
+
+	t.Run("StatementRequest validation", func(t *testing.T) {
+		t.Run("should accept a range without a format", func(t *testing.T) {
+			err := validate.Struct(model.StatementRequest{From: "2025-01-01", To: "2025-01-31"})
+			assert.NoError(t, err)
+		})
+
+		t.Run("should require both dates", func(t *testing.T) {
+			err := validate.Struct(model.StatementRequest{From: "2025-01-01"})
+			assert.Error(t, err)
+		})
+
+		t.Run("should reject an unknown format", func(t *testing.T) {
+			err := validate.Struct(model.StatementRequest{From: "2025-01-01", To: "2025-01-31", Format: "xlsx"})
+			assert.Error(t, err)
+		})
+	})
+}
@@ -0,0 +1,58 @@
+package model_test
+
+import (
+	"account-service/src/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookModel(t *testing.T) {
+	t.Run("Subscribes", func(t *testing.T) {
+		subscription := model.WebhookSubscription{EventTypes: "FundsDeposited,FundsWithdrawn"}
+		assert.True(t, subscription.Subscribes(model.EventFundsDeposited))
+		assert.True(t, subscription.Subscribes(model.EventFundsWithdrawn))
+		assert.False(t, subscription.Subscribes(model.EventTransferSent))
+		assert.False(t, subscription.Subscribes("Funds"))
+	})
+
+	t.Run("Secrets", func(t *testing.T) {
+		now := time.Now()
+		expiresAt := now.Add(time.Hour)
+		subscription := model.WebhookSubscription{Secret: "new", PreviousSecret: "old", PreviousSecretExpiresAt: &expiresAt}
+		assert.Equal(t, []string{"new", "old"}, subscription.Secrets(now))
+		assert.Equal(t, []string{"new"}, subscription.Secrets(expiresAt))
+		assert.Equal(t, []string{"new"}, (&model.WebhookSubscription{Secret: "new"}).Secrets(now))
+	})
+
+	t.Run("Response should never carry the secrets", func(t *testing.T) {
+		subscription := model.WebhookSubscription{ID: 1, URL: "https://partner.example.com", EventTypes: "FundsDeposited", Secret: "new", PreviousSecret: "old"}
+		response := subscription.Response()
+		assert.Empty(t, response.Secret)
+		assert.Equal(t, []string{model.EventFundsDeposited}, response.EventTypes)
+	})
+
+	t.Run("RetryDelay", func(t *testing.T) {
+		delivery := model.WebhookDelivery{Attempts: 3}
+		assert.Equal(t, 40*time.Second, delivery.RetryDelay(10*time.Second, time.Hour))
+		delivery.Attempts = 20
+		assert.Equal(t, time.Hour, delivery.RetryDelay(10*time.Second, time.Hour))
+	})
+
+	t.Run("WebhookSubscriptionRequest validation", func(t *testing.T) {
+		valid := model.WebhookSubscriptionRequest{URL: "https://partner.example.com/events", EventTypes: []string{model.EventFundsDeposited, model.EventTransactionReversed}}
+		assert.NoError(t, validate.Struct(valid))
+
+		invalid := []model.WebhookSubscriptionRequest{
+			{URL: "partner.example.com", EventTypes: valid.EventTypes},
+			{URL: "ftp://partner.example.com/events", EventTypes: valid.EventTypes},
+			{URL: valid.URL},
+			{URL: valid.URL, EventTypes: []string{"BalanceChanged"}},
+			{URL: valid.URL, EventTypes: []string{model.EventFundsDeposited, model.EventFundsDeposited}},
+		}
+		for _, req := range invalid {
+			assert.Error(t, validate.Struct(req), "%+v", req)
+		}
+	})
+}
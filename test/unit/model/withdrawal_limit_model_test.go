@@ -0,0 +1,69 @@
+package model_test
+
+import (
+	"account-service/src/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithdrawalLimitsModel(t *testing.T) {
+	defaults := model.WithdrawalLimits{
+		MaxPerTransaction: model.NewMoney(10000000),
+		MaxDailyTotal:     model.NewMoney(50000000),
+		MaxDailyCount:     20,
+	}
+
+	t.Run("Apply", func(t *testing.T) {
+		t.Run("should keep the defaults without an override", func(t *testing.T) {
+			assert.Equal(t, defaults, defaults.Apply(nil))
+			assert.Equal(t, defaults, defaults.Apply(&model.AccountWithdrawalLimit{}))
+		})
+
+		t.Run("should replace only the overridden limits", func(t *testing.T) {
+			maxDailyTotal := model.NewMoney(1000000)
+			maxDailyCount := 3
+			limits := defaults.Apply(&model.AccountWithdrawalLimit{
+				MaxDailyTotal: &maxDailyTotal,
+				MaxDailyCount: &maxDailyCount,
+			})
+
+			assert.Equal(t, model.WithdrawalLimits{
+				MaxPerTransaction: model.NewMoney(10000000),
+				MaxDailyTotal:     model.NewMoney(1000000),
+				MaxDailyCount:     3,
+			}, limits)
+		})
+	})
+
+	t.Run("WithdrawalLimitRequest validation", func(t *testing.T) {
+		t.Run("should validate an empty request", func(t *testing.T) {
+			err := validate.Struct(model.WithdrawalLimitRequest{})
+			assert.NoError(t, err)
+		})
+
+		t.Run("should validate positive limits", func(t *testing.T) {
+			maxPerTransaction := model.MustParseMoney("2500000.50")
+			maxDailyCount := 5
+			err := validate.Struct(model.WithdrawalLimitRequest{
+				MaxPerTransaction: &maxPerTransaction,
+				MaxDailyCount:     &maxDailyCount,
+			})
+			assert.NoError(t, err)
+		})
+
+		t.Run("should fail with zero MaxDailyTotal", func(t *testing.T) {
+			maxDailyTotal := model.NewMoney(0)
+			err := validate.Struct(model.WithdrawalLimitRequest{MaxDailyTotal: &maxDailyTotal})
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "MaxDailyTotal")
+		})
+
+		t.Run("should fail with negative MaxDailyCount", func(t *testing.T) {
+			maxDailyCount := -1
+			err := validate.Struct(model.WithdrawalLimitRequest{MaxDailyCount: &maxDailyCount})
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "MaxDailyCount")
+		})
+	})
+}
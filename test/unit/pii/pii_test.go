@@ -0,0 +1,167 @@
+package pii_test
+
+import (
+	"account-service/src/pii"
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func key(b byte) []byte {
+	return bytes.Repeat([]byte{b}, pii.KeySize)
+}
+
+func newKeyring(t *testing.T, keys map[int][]byte, active int) *pii.Keyring {
+	t.Helper()
+	keyring, err := pii.NewKeyring(keys, active, key(0xff))
+	require.NoError(t, err)
+	return keyring
+}
+
+func TestKeyring(t *testing.T) {
+	t.Run("should encrypt with the active key and decrypt back", func(t *testing.T) {
+		keyring := newKeyring(t, map[int][]byte{1: key(1), 2: key(2)}, 1)
+
+		first, err := keyring.Encrypt("3201123456780001")
+		require.NoError(t, err)
+		second, err := keyring.Encrypt("3201123456780001")
+		require.NoError(t, err)
+
+		assert.True(t, strings.HasPrefix(first, "v1:"), first)
+		assert.NotContains(t, first, "3201123456780001")
+		assert.NotEqual(t, first, second, "every encryption has a nonce of its own")
+		plaintext, err := keyring.Decrypt(first)
+		require.NoError(t, err)
+		assert.Equal(t, "3201123456780001", plaintext)
+	})
+
+	t.Run("should default to the highest version", func(t *testing.T) {
+		keyring := newKeyring(t, map[int][]byte{1: key(1), 3: key(3), 2: key(2)}, 0)
+		assert.Equal(t, 3, keyring.ActiveVersion())
+		assert.Equal(t, []int{1, 2, 3}, keyring.Versions())
+	})
+
+	t.Run("should decrypt values of a previous key after rotation", func(t *testing.T) {
+		encrypted, err := newKeyring(t, map[int][]byte{1: key(1)}, 1).Encrypt("081234567890")
+		require.NoError(t, err)
+
+		rotated := newKeyring(t, map[int][]byte{1: key(1), 2: key(2)}, 2)
+		plaintext, err := rotated.Decrypt(encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, "081234567890", plaintext)
+
+		_, err = newKeyring(t, map[int][]byte{2: key(2)}, 2).Decrypt(encrypted)
+		assert.ErrorIs(t, err, pii.ErrUnknownKey)
+	})
+
+	t.Run("should reject a value encrypted with another key of the same version", func(t *testing.T) {
+		encrypted, err := newKeyring(t, map[int][]byte{1: key(1)}, 1).Encrypt("081234567890")
+		require.NoError(t, err)
+		_, err = newKeyring(t, map[int][]byte{1: key(9)}, 1).Decrypt(encrypted)
+		assert.Error(t, err)
+	})
+
+	t.Run("should return a value that is not encrypted as it is", func(t *testing.T) {
+		plaintext, err := newKeyring(t, map[int][]byte{1: key(1)}, 1).Decrypt("3201123456780001")
+		require.NoError(t, err)
+		assert.Equal(t, "3201123456780001", plaintext)
+	})
+
+	t.Run("should index the same value the same way under the same key only", func(t *testing.T) {
+		keyring := newKeyring(t, map[int][]byte{1: key(1)}, 1)
+		rotated := newKeyring(t, map[int][]byte{2: key(2)}, 2)
+		other, err := pii.NewKeyring(map[int][]byte{1: key(1)}, 1, key(0xee))
+		require.NoError(t, err)
+
+		index := keyring.BlindIndex("3201123456780001")
+		assert.Len(t, index, 64)
+		assert.Equal(t, index, keyring.BlindIndex("3201123456780001"))
+		assert.Equal(t, index, rotated.BlindIndex("3201123456780001"), "the encryption keys play no part")
+		assert.NotEqual(t, index, keyring.BlindIndex("3201123456780002"))
+		assert.NotEqual(t, index, other.BlindIndex("3201123456780001"))
+	})
+
+	t.Run("should reject invalid keys", func(t *testing.T) {
+		_, err := pii.NewKeyring(nil, 0, key(0xff))
+		assert.Error(t, err)
+		_, err = pii.NewKeyring(map[int][]byte{1: key(1)[:16]}, 1, key(0xff))
+		assert.Error(t, err)
+		_, err = pii.NewKeyring(map[int][]byte{0: key(1)}, 0, key(0xff))
+		assert.Error(t, err)
+		_, err = pii.NewKeyring(map[int][]byte{1: key(1)}, 2, key(0xff))
+		assert.ErrorIs(t, err, pii.ErrUnknownKey)
+		_, err = pii.NewKeyring(map[int][]byte{1: key(1)}, 1, nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestParseKeys(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString(key(1))
+
+	keys, err := pii.ParseKeys("1:" + encoded + ", 2:" + base64.StdEncoding.EncodeToString(key(2)))
+	require.NoError(t, err)
+	assert.Equal(t, map[int][]byte{1: key(1), 2: key(2)}, keys)
+
+	for _, invalid := range []string{encoded, "one:" + encoded, "1:not base64!", "1:" + encoded + ",1:" + encoded} {
+		_, err := pii.ParseKeys(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestMask(t *testing.T) {
+	assert.Equal(t, "3201********0001", pii.Mask("3201123456780001"))
+	assert.Equal(t, "0812****7890", pii.Mask("081234567890"))
+	assert.Equal(t, "*****", pii.Mask("12345"))
+	assert.Equal(t, "", pii.Mask(""))
+}
+
+func TestMaskText(t *testing.T) {
+	assert.Equal(t,
+		"NIK 3201********0001, phone 0812****7890 or +628******7890, account 6281234567",
+		pii.MaskText("NIK 3201123456780001, phone 081234567890 or +6281234567890, account 6281234567"))
+}
+
+type customer struct {
+	ID          uint
+	IDNumber    string `gorm:"serializer:pii"`
+	PhoneNumber string `gorm:"serializer:pii"`
+}
+
+func TestSerializer(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	defer sqlDB.Close()
+	require.NoError(t, db.AutoMigrate(&customer{}))
+
+	// Without the plugin the database has no keyring to encrypt with.
+	saved := customer{IDNumber: "3201123456780001", PhoneNumber: "081234567890"}
+	err = db.Create(&saved).Error
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), pii.ErrNoKeyring.Error())
+
+	require.NoError(t, db.Use(pii.GORM(newKeyring(t, map[int][]byte{1: key(1)}, 1))))
+	require.NoError(t, db.Create(&saved).Error)
+
+	var stored struct{ IDNumber, PhoneNumber string }
+	require.NoError(t, db.Table("customers").Select("id_number, phone_number").Scan(&stored).Error)
+	assert.True(t, strings.HasPrefix(stored.IDNumber, "v1:"), stored.IDNumber)
+	assert.True(t, strings.HasPrefix(stored.PhoneNumber, "v1:"), stored.PhoneNumber)
+
+	var loaded customer
+	require.NoError(t, db.First(&loaded, saved.ID).Error)
+	assert.Equal(t, saved, loaded)
+
+	// A value written before encryption is read as it is
+	require.NoError(t, db.Exec("UPDATE customers SET phone_number = ?", "089999999999").Error)
+	require.NoError(t, db.First(&loaded, saved.ID).Error)
+	assert.Equal(t, "089999999999", loaded.PhoneNumber)
+}
@@ -0,0 +1,111 @@
+package publisher_test
+
+import (
+	"account-service/src/model"
+	"account-service/src/publisher"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testEvent() *model.Event {
+	return &model.Event{
+		ID:            42,
+		Type:          model.EventFundsWithdrawn,
+		AccountNumber: "1234567890",
+		OccurredAt:    time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC),
+		Data:          json.RawMessage(`{"activity_id":7}`),
+	}
+}
+
+func TestWriterPublisher(t *testing.T) {
+	var out bytes.Buffer
+	pub := publisher.NewWriter(&out)
+
+	assert.NoError(t, pub.Publish(context.Background(), testEvent()))
+	assert.NoError(t, pub.Publish(context.Background(), testEvent()))
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.JSONEq(t, `{"id":42,"type":"FundsWithdrawn","account_number":"1234567890","occurred_at":"2024-03-01T09:00:00Z","data":{"activity_id":7}}`, lines[0])
+	}
+}
+
+func TestWebhookPublisher(t *testing.T) {
+	t.Run("should post the event as JSON", func(t *testing.T) {
+		var received *http.Request
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer server.Close()
+
+		err := publisher.NewWebhook(server.URL, time.Second).Publish(context.Background(), testEvent())
+		assert.NoError(t, err)
+		if assert.NotNil(t, received) {
+			assert.Equal(t, http.MethodPost, received.Method)
+			assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+			assert.Equal(t, "42", received.Header.Get("X-Event-ID"))
+			assert.Equal(t, model.EventFundsWithdrawn, received.Header.Get("X-Event-Type"))
+			assert.Empty(t, received.Header.Get(publisher.HeaderSignature)) // Unsigned without secrets
+		}
+		assert.Contains(t, string(body), `"data":{"activity_id":7}`)
+	})
+
+	t.Run("should fail on a response other than 2xx", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		err := publisher.NewWebhook(server.URL, time.Second).Publish(context.Background(), testEvent())
+		var statusErr *publisher.StatusError
+		if assert.ErrorAs(t, err, &statusErr) {
+			assert.Equal(t, http.StatusServiceUnavailable, statusErr.StatusCode)
+		}
+		assert.Contains(t, err.Error(), "503")
+	})
+
+	t.Run("should fail when the consumer is too slow", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}))
+		defer server.Close()
+
+		err := publisher.NewWebhook(server.URL, 50*time.Millisecond).Publish(context.Background(), testEvent())
+		assert.Error(t, err)
+	})
+}
+
+type failingPublisher struct{}
+
+func (failingPublisher) Publish(c context.Context, event *model.Event) error {
+	return errors.New("unavailable")
+}
+
+func TestMultiPublisher(t *testing.T) {
+	var first, last bytes.Buffer
+
+	err := publisher.NewMulti(publisher.NewWriter(&first), publisher.NewWriter(&last)).Publish(context.Background(), testEvent())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, first.String())
+	assert.Equal(t, first.String(), last.String())
+
+	first.Reset()
+	last.Reset()
+	err = publisher.NewMulti(publisher.NewWriter(&first), failingPublisher{}, publisher.NewWriter(&last)).Publish(context.Background(), testEvent())
+	assert.Error(t, err)
+	assert.NotEmpty(t, first.String())
+	assert.Empty(t, last.String())
+}
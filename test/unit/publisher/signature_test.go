@@ -0,0 +1,67 @@
+package publisher_test
+
+import (
+	"account-service/src/publisher"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignature(t *testing.T) {
+	body := []byte(`{"id":42}`)
+	sentAt := time.Unix(1700000000, 0)
+	timestamp := strconv.FormatInt(sentAt.Unix(), 10)
+
+	t.Run("should verify a signature with the same secret", func(t *testing.T) {
+		signature := publisher.Signature([]string{"secret"}, sentAt, body)
+		assert.True(t, strings.HasPrefix(signature, "sha256="))
+		assert.NoError(t, publisher.VerifySignature("secret", timestamp, signature, body, sentAt.Add(time.Minute), 5*time.Minute))
+	})
+
+	t.Run("should refuse another secret, body or timestamp", func(t *testing.T) {
+		signature := publisher.Signature([]string{"secret"}, sentAt, body)
+		assert.ErrorIs(t, publisher.VerifySignature("other", timestamp, signature, body, sentAt, time.Minute), publisher.ErrSignatureMismatch)
+		assert.ErrorIs(t, publisher.VerifySignature("secret", timestamp, signature, []byte(`{"id":43}`), sentAt, time.Minute), publisher.ErrSignatureMismatch)
+		assert.ErrorIs(t, publisher.VerifySignature("secret", strconv.FormatInt(sentAt.Unix()+1, 10), signature, body, sentAt, time.Minute), publisher.ErrSignatureMismatch)
+	})
+
+	t.Run("should refuse a timestamp outside the tolerance", func(t *testing.T) {
+		signature := publisher.Signature([]string{"secret"}, sentAt, body)
+		assert.ErrorIs(t, publisher.VerifySignature("secret", timestamp, signature, body, sentAt.Add(6*time.Minute), 5*time.Minute), publisher.ErrTimestampTooOld)
+		assert.ErrorIs(t, publisher.VerifySignature("secret", "yesterday", signature, body, sentAt, 5*time.Minute), publisher.ErrTimestampTooOld)
+	})
+
+	t.Run("should carry one signature per secret while rotating", func(t *testing.T) {
+		signature := publisher.Signature([]string{"new", "old"}, sentAt, body)
+		assert.Len(t, strings.Split(signature, ","), 2)
+		assert.NoError(t, publisher.VerifySignature("new", timestamp, signature, body, sentAt, time.Minute))
+		assert.NoError(t, publisher.VerifySignature("old", timestamp, signature, body, sentAt, time.Minute))
+	})
+}
+
+func TestSignedWebhook(t *testing.T) {
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	webhook := publisher.Webhook{URL: server.URL, Secrets: []string{"secret"}, Client: server.Client()}
+	statusCode, err := webhook.Post(context.Background(), testEvent())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, statusCode)
+	if assert.NotNil(t, received) {
+		err = publisher.VerifySignature("secret", received.Header.Get(publisher.HeaderTimestamp), received.Header.Get(publisher.HeaderSignature), body, time.Now(), time.Minute)
+		assert.NoError(t, err)
+	}
+}
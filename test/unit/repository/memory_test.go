@@ -0,0 +1,126 @@
+package repository_test
+
+import (
+	"account-service/src/model"
+	"account-service/src/repository"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAccount(accountNumber string) *model.Account {
+	return &model.Account{
+		AccountNumber: accountNumber,
+		FullName:      "John Doe",
+		IDNumber:      "ID" + accountNumber,
+		PhoneNumber:   "08" + accountNumber,
+	}
+}
+
+func TestMemoryTransaction(t *testing.T) {
+	t.Run("should keep the writes of a committed transaction", func(t *testing.T) {
+		store := repository.NewMemory()
+		err := store.Transaction(context.Background(), func(tx repository.Repositories) error {
+			return tx.Accounts().Create(newAccount("1000000001"))
+		})
+		require.NoError(t, err)
+
+		account, err := store.Repositories(context.Background()).Accounts().FindByNumber("1000000001")
+		require.NoError(t, err)
+		assert.Equal(t, model.AccountStatusActive, account.Status)
+	})
+
+	t.Run("should undo the writes of a failed transaction", func(t *testing.T) {
+		store := repository.NewMemory()
+		failure := errors.New("failed")
+		err := store.Transaction(context.Background(), func(tx repository.Repositories) error {
+			if err := tx.Accounts().Create(newAccount("1000000001")); err != nil {
+				return err
+			}
+			return failure
+		})
+		assert.ErrorIs(t, err, failure)
+
+		_, err = store.Repositories(context.Background()).Accounts().FindByNumber("1000000001")
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+	})
+
+	t.Run("should undo the writes of a panicking transaction", func(t *testing.T) {
+		store := repository.NewMemory()
+		assert.Panics(t, func() {
+			store.Transaction(context.Background(), func(tx repository.Repositories) error {
+				tx.Accounts().Create(newAccount("1000000001"))
+				panic("failed")
+			})
+		})
+
+		_, err := store.Repositories(context.Background()).Accounts().FindByNumber("1000000001")
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+	})
+}
+
+func TestMemoryAccounts(t *testing.T) {
+	t.Run("should reject a duplicate account", func(t *testing.T) {
+		accounts := repository.NewMemory().Repositories(context.Background()).Accounts()
+		require.NoError(t, accounts.Create(newAccount("1000000001")))
+
+		duplicate := newAccount("1000000002")
+		duplicate.IDNumber = "ID1000000001"
+		assert.ErrorIs(t, accounts.Create(duplicate), repository.ErrDuplicate)
+	})
+
+	t.Run("should hand out copies", func(t *testing.T) {
+		accounts := repository.NewMemory().Repositories(context.Background()).Accounts()
+		require.NoError(t, accounts.Create(newAccount("1000000001")))
+
+		account, err := accounts.FindByNumber("1000000001")
+		require.NoError(t, err)
+		account.Balance = model.NewMoney(100)
+
+		stored, err := accounts.FindByNumber("1000000001")
+		require.NoError(t, err)
+		assert.True(t, stored.Balance.IsZero())
+
+		require.NoError(t, accounts.UpdateBalance(account))
+		stored, err = accounts.FindByNumber("1000000001")
+		require.NoError(t, err)
+		assert.Equal(t, model.NewMoney(100), stored.Balance)
+	})
+}
+
+func TestMemoryWithdrawalUsage(t *testing.T) {
+	repos := repository.NewMemory().Repositories(context.Background())
+	account := newAccount("1000000001")
+	require.NoError(t, repos.Accounts().Create(account))
+
+	reference := "transfer"
+	scheduleID := uint(1)
+	for _, activity := range []*model.CashActivity{
+		{Type: "debit", Nominal: model.NewMoney(100)},
+		{Type: "debit", Nominal: model.NewMoney(200)},
+		{Type: "credit", Nominal: model.NewMoney(400)},
+		{Type: "debit", Nominal: model.NewMoney(800), TransferReference: &reference},
+		{Type: "debit", Nominal: model.NewMoney(1600), FeeScheduleID: &scheduleID},
+	} {
+		activity.AccountID = account.ID
+		require.NoError(t, repos.CashActivities().Create(activity))
+	}
+	first, err := repos.CashActivities().Find(1)
+	require.NoError(t, err)
+	require.NoError(t, repos.CashActivities().MarkReversed(first, first.CreatedAt))
+
+	start := first.CreatedAt.AddDate(0, 0, -1)
+	total, count, err := repos.CashActivities().WithdrawalUsage(account.ID, start, start.AddDate(0, 0, 2))
+	require.NoError(t, err)
+	assert.Equal(t, model.NewMoney(200), total)
+	assert.Equal(t, 1, count)
+
+	// Outgoing transfers count towards the limits, not the free withdrawals.
+	total, count, err = repos.CashActivities().OutflowUsage(account.ID, start, start.AddDate(0, 0, 2))
+	require.NoError(t, err)
+	assert.Equal(t, model.NewMoney(1000), total)
+	assert.Equal(t, 2, count)
+}
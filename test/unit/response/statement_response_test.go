@@ -0,0 +1,198 @@
+package response_test
+
+import (
+	"account-service/src/model"
+	"account-service/src/response"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var jakarta = time.FixedZone("WIB", 7*60*60)
+
+func testStatement(lines int) (*model.Statement, []model.StatementLine) {
+	statement := &model.Statement{
+		AccountNumber:  "9876543210",
+		FullName:       "Statement Test User",
+		From:           time.Date(2025, time.January, 1, 0, 0, 0, 0, jakarta),
+		To:             time.Date(2025, time.February, 1, 0, 0, 0, 0, jakarta),
+		GeneratedAt:    time.Date(2025, time.February, 3, 9, 30, 0, 0, jakarta),
+		OpeningBalance: model.NewMoney(100000),
+	}
+
+	balance := statement.OpeningBalance
+	result := make([]model.StatementLine, lines)
+	for i := range result {
+		line := model.StatementLine{CashActivity: model.CashActivity{
+			ID:            uint(i + 1),
+			Type:          "credit",
+			Nominal:       model.NewMoney(1000),
+			BalanceBefore: balance,
+			Description:   "Deposit",
+			CreatedAt:     statement.From.Add(time.Duration(i) * time.Minute),
+		}}
+		if i%2 == 1 {
+			line.Type = "debit"
+			line.Nominal = model.NewMoney(500)
+			line.Description = "Withdrawal"
+			statement.DebitCount++
+			statement.DebitTotal = statement.DebitTotal.Add(line.Nominal)
+			balance = balance.Sub(line.Nominal)
+		} else {
+			statement.CreditCount++
+			statement.CreditTotal = statement.CreditTotal.Add(line.Nominal)
+			balance = balance.Add(line.Nominal)
+		}
+		line.BalanceAfter = balance
+		result[i] = line
+	}
+	statement.ClosingBalance = balance
+	return statement, result
+}
+
+func writeStatement(t *testing.T, format string, statement *model.Statement, lines []model.StatementLine) []byte {
+	var buf bytes.Buffer
+	writer, err := response.NewStatementWriter(format, &buf, statement)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	assert.NoError(t, writer.WriteHeader())
+	for i := range lines {
+		assert.NoError(t, writer.WriteLine(&lines[i]))
+	}
+	assert.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestStatementResponse(t *testing.T) {
+	t.Run("should refuse an unknown format", func(t *testing.T) {
+		statement, _ := testStatement(0)
+		_, err := response.NewStatementWriter("xlsx", &bytes.Buffer{}, statement)
+		assert.Error(t, err)
+	})
+
+	t.Run("should name the file after the account and period", func(t *testing.T) {
+		statement, _ := testStatement(0)
+		assert.Equal(t, "statement-9876543210-20250101-20250131.xml", response.StatementFileName(statement, model.StatementFormatCamt053))
+		assert.Equal(t, "application/pdf", response.StatementContentType(model.StatementFormatPDF))
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		statement, lines := testStatement(2)
+		lines[1].Description = "=HYPERLINK(\"http://example.com\")"
+
+		records, err := csv.NewReader(bytes.NewReader(writeStatement(t, model.StatementFormatCSV, statement, lines))).ReadAll()
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"date", "id", "kind", "description", "debit", "credit", "balance"},
+			{"2025-01-01", "", "", "Opening balance", "", "", "100000.00"},
+			{"2025-01-01 00:00:00", "1", "deposit", "Deposit", "", "1000.00", "101000.00"},
+			{"2025-01-01 00:01:00", "2", "withdrawal", "'=HYPERLINK(\"http://example.com\")", "500.00", "", "100500.00"},
+			{"2025-01-31", "", "", "Total", "500.00", "1000.00", ""},
+			{"2025-01-31", "", "", "Closing balance", "", "", "100500.00"},
+		}, records)
+	})
+
+	t.Run("camt.053", func(t *testing.T) {
+		statement, lines := testStatement(3)
+		lines[2].ReversalOfID = &lines[1].ID
+
+		var document struct {
+			XMLName xml.Name `xml:"urn:iso:std:iso:20022:tech:xsd:camt.053.001.02 Document"`
+			Stmt    struct {
+				ID       string `xml:"Id"`
+				Account  string `xml:"Acct>Id>Othr>Id"`
+				Balances []struct {
+					Code   string `xml:"Tp>CdOrPrtry>Cd"`
+					Amount struct {
+						Value    string `xml:",chardata"`
+						Currency string `xml:"Ccy,attr"`
+					} `xml:"Amt"`
+					Indicator string `xml:"CdtDbtInd"`
+					Date      string `xml:"Dt>Dt"`
+				} `xml:"Bal"`
+				Entries    int    `xml:"TxsSummry>TtlNtries>NbOfNtries"`
+				Net        string `xml:"TxsSummry>TtlNtries>TtlNetNtryAmt"`
+				CreditSum  string `xml:"TxsSummry>TtlCdtNtries>Sum"`
+				DebitSum   string `xml:"TxsSummry>TtlDbtNtries>Sum"`
+				EntryNodes []struct {
+					Reference string `xml:"NtryRef"`
+					Amount    string `xml:"Amt"`
+					Indicator string `xml:"CdtDbtInd"`
+					Reversal  bool   `xml:"RvslInd"`
+					Status    string `xml:"Sts"`
+					Domain    string `xml:"BkTxCd>Domn>Cd"`
+					Family    string `xml:"BkTxCd>Domn>Fmly>Cd"`
+					SubFamily string `xml:"BkTxCd>Domn>Fmly>SubFmlyCd"`
+				} `xml:"Ntry"`
+			} `xml:"BkToCstmrStmt>Stmt"`
+		}
+		err := xml.Unmarshal(writeStatement(t, model.StatementFormatCamt053, statement, lines), &document)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		stmt := document.Stmt
+		assert.LessOrEqual(t, len(stmt.ID), 35)
+		assert.Equal(t, "9876543210", stmt.Account)
+		if assert.Len(t, stmt.Balances, 2) {
+			assert.Equal(t, "OPBD", stmt.Balances[0].Code)
+			assert.Equal(t, "100000.00", stmt.Balances[0].Amount.Value)
+			assert.Equal(t, "IDR", stmt.Balances[0].Amount.Currency)
+			assert.Equal(t, "CRDT", stmt.Balances[0].Indicator)
+			assert.Equal(t, "2025-01-01", stmt.Balances[0].Date)
+			assert.Equal(t, "CLBD", stmt.Balances[1].Code)
+			assert.Equal(t, statement.ClosingBalance.String(), stmt.Balances[1].Amount.Value)
+			assert.Equal(t, "2025-01-31", stmt.Balances[1].Date)
+		}
+		assert.Equal(t, 3, stmt.Entries)
+		assert.Equal(t, "1500.00", stmt.Net)
+		assert.Equal(t, "2000.00", stmt.CreditSum)
+		assert.Equal(t, "500.00", stmt.DebitSum)
+
+		if assert.Len(t, stmt.EntryNodes, 3) {
+			deposit, withdrawal, reversal := stmt.EntryNodes[0], stmt.EntryNodes[1], stmt.EntryNodes[2]
+			assert.Equal(t, "1", deposit.Reference)
+			assert.Equal(t, "CRDT", deposit.Indicator)
+			assert.Equal(t, "BOOK", deposit.Status)
+			assert.Equal(t, []string{"PMNT", "CNTR", "CDPT"}, []string{deposit.Domain, deposit.Family, deposit.SubFamily})
+			assert.Equal(t, "DBIT", withdrawal.Indicator)
+			assert.Equal(t, "CWDL", withdrawal.SubFamily)
+			assert.False(t, withdrawal.Reversal)
+			assert.True(t, reversal.Reversal)
+		}
+	})
+
+	t.Run("PDF", func(t *testing.T) {
+		statement, lines := testStatement(response.StatementLinesPerPage*2 + 1)
+		lines[0].Description = "Reversal (wrong account) \\ café"
+		pdf := writeStatement(t, model.StatementFormatPDF, statement, lines)
+
+		assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")))
+		assert.True(t, bytes.HasSuffix(pdf, []byte("%%EOF\n")))
+		assert.Contains(t, string(pdf), "/Type /Pages /Kids [6 0 R 8 0 R 10 0 R] /Count 3")
+		assert.Contains(t, string(pdf), `(Reversal \(wrong account\) \\ caf\351)`)
+		assert.Equal(t, 3, bytes.Count(pdf, []byte("(Page total)")))
+		assert.Equal(t, 1, bytes.Count(pdf, []byte("(Closing balance)")))
+
+		// Every entry of the cross-reference table points at its object.
+		startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+		if !assert.NotNil(t, startxref) {
+			return
+		}
+		xrefOffset, _ := strconv.Atoi(string(startxref[1]))
+		entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf[xrefOffset:], -1)
+		assert.Len(t, entries, 10)
+		for i, entry := range entries {
+			offset, _ := strconv.Atoi(string(entry[1]))
+			assert.True(t, bytes.HasPrefix(pdf[offset:], []byte(fmt.Sprintf("%d 0 obj\n", i+1))), "object %d", i+1)
+		}
+	})
+}
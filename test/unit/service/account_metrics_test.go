@@ -0,0 +1,77 @@
+package service_test
+
+import (
+	"account-service/src/metrics"
+	"account-service/src/model"
+	"account-service/src/service"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// counterValue returns the value of the counter name with the label
+// outcome, or of the unlabelled counter name if outcome is empty.
+func counterValue(t *testing.T, name, outcome string) float64 {
+	t.Helper()
+	families, err := metrics.Registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.Metric {
+			if outcome == "" || (len(metric.Label) == 1 && metric.Label[0].GetValue() == outcome) {
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestAccountMetrics(t *testing.T) {
+	inner, _ := newAccountService(model.WithdrawalLimits{})
+	accounts := service.NewAccountMetrics(inner)
+	accountNumber := openAccount(t, accounts, 1, 0)
+
+	t.Run("should count a deposit and its amount", func(t *testing.T) {
+		count := counterValue(t, "account_deposits_total", metrics.OutcomeSuccess)
+		amount := counterValue(t, "account_deposited_amount_total", "")
+
+		require.Nil(t, accounts.Deposit(context.Background(), &model.DepositRequest{AccountNumber: accountNumber, Nominal: model.NewMoney(500)}))
+
+		assert.Equal(t, count+1, counterValue(t, "account_deposits_total", metrics.OutcomeSuccess))
+		assert.Equal(t, amount+500, counterValue(t, "account_deposited_amount_total", ""))
+	})
+
+	t.Run("should count a deposit to an unknown account as not found", func(t *testing.T) {
+		count := counterValue(t, "account_deposits_total", metrics.OutcomeNotFound)
+		amount := counterValue(t, "account_deposited_amount_total", "")
+
+		assert.NotNil(t, accounts.Deposit(context.Background(), &model.DepositRequest{AccountNumber: "0000000000", Nominal: model.NewMoney(500)}))
+
+		assert.Equal(t, count+1, counterValue(t, "account_deposits_total", metrics.OutcomeNotFound))
+		assert.Equal(t, amount, counterValue(t, "account_deposited_amount_total", ""))
+	})
+
+	t.Run("should count a withdrawal over the balance as insufficient", func(t *testing.T) {
+		count := counterValue(t, "account_withdrawals_total", metrics.OutcomeInsufficientBalance)
+		amount := counterValue(t, "account_withdrawn_amount_total", "")
+
+		assert.NotNil(t, accounts.Withdraw(context.Background(), &model.Withdrawal{AccountNumber: accountNumber, Nominal: model.NewMoney(10_000), PIN: testPIN}))
+
+		assert.Equal(t, count+1, counterValue(t, "account_withdrawals_total", metrics.OutcomeInsufficientBalance))
+		assert.Equal(t, amount, counterValue(t, "account_withdrawn_amount_total", ""))
+	})
+
+	t.Run("should count a withdrawal and its amount", func(t *testing.T) {
+		count := counterValue(t, "account_withdrawals_total", metrics.OutcomeSuccess)
+		amount := counterValue(t, "account_withdrawn_amount_total", "")
+
+		require.Nil(t, accounts.Withdraw(context.Background(), &model.Withdrawal{AccountNumber: accountNumber, Nominal: model.NewMoney(200), PIN: testPIN}))
+
+		assert.Equal(t, count+1, counterValue(t, "account_withdrawals_total", metrics.OutcomeSuccess))
+		assert.Equal(t, amount+200, counterValue(t, "account_withdrawn_amount_total", ""))
+	})
+}
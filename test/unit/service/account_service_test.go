@@ -0,0 +1,357 @@
+package service_test
+
+import (
+	"account-service/src/model"
+	"account-service/src/repository"
+	"account-service/src/service"
+	"account-service/src/utils"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testPIN = model.PIN("123456")
+
+var testPINPolicy = model.PINPolicy{MaxAttempts: 3, HashCost: bcrypt.MinCost}
+
+// newAccountService returns the account service over an empty in-memory
+// store, with the store to inspect.
+func newAccountService(limits model.WithdrawalLimits) (service.AccountServices, repository.UnitOfWork) {
+	store := repository.NewMemory()
+	return service.NewAccountService(store, service.NewLedger(), utils.Validator(), limits, testPINPolicy, time.Local), store
+}
+
+// openAccount registers the nth account and deposits balance into it,
+// returning its number.
+func openAccount(t *testing.T, accounts service.AccountServices, n int, balance int64) string {
+	t.Helper()
+	account, err := accounts.CreateAccount(context.Background(), &model.CreateAccount{
+		FullName:    fmt.Sprintf("Customer %d", n),
+		IDNumber:    fmt.Sprintf("%016d", n),
+		PhoneNumber: fmt.Sprintf("0812%08d", n),
+		PIN:         testPIN,
+	})
+	require.Nil(t, err)
+	if balance > 0 {
+		require.Nil(t, accounts.Deposit(context.Background(), &model.DepositRequest{AccountNumber: account.AccountNumber, Nominal: model.NewMoney(balance)}))
+	}
+	return account.AccountNumber
+}
+
+func balanceOf(t *testing.T, accounts service.AccountServices, accountNumber string) model.Money {
+	t.Helper()
+	account, err := accounts.GetBalance(context.Background(), accountNumber)
+	require.Nil(t, err)
+	return account.Balance
+}
+
+func TestCreateAccount(t *testing.T) {
+	t.Run("should open an active account on the default product", func(t *testing.T) {
+		accounts, _ := newAccountService(model.WithdrawalLimits{})
+		account, err := accounts.CreateAccount(context.Background(), &model.CreateAccount{
+			FullName: "John Doe", IDNumber: "1234567890123456", PhoneNumber: "081234567890", PIN: testPIN,
+		})
+		require.Nil(t, err)
+		assert.NotEmpty(t, account.AccountNumber)
+		assert.Equal(t, model.AccountStatusActive, account.Status)
+		assert.Equal(t, model.DefaultProductCode, account.ProductCode)
+		assert.True(t, account.Balance.IsZero())
+	})
+
+	t.Run("should reject a taken ID number or phone number", func(t *testing.T) {
+		accounts, _ := newAccountService(model.WithdrawalLimits{})
+		openAccount(t, accounts, 1, 0)
+
+		_, err := accounts.CreateAccount(context.Background(), &model.CreateAccount{
+			FullName: "Jane Doe", IDNumber: fmt.Sprintf("%016d", 1), PhoneNumber: "089999999999", PIN: testPIN,
+		})
+		if assert.NotNil(t, err) {
+			assert.Equal(t, http.StatusConflict, err.Code)
+			assert.Equal(t, service.ErrDuplicateIDNumber.Error(), err.Message)
+		}
+
+		_, err = accounts.CreateAccount(context.Background(), &model.CreateAccount{
+			FullName: "Jane Doe", IDNumber: "9999999999999999", PhoneNumber: fmt.Sprintf("0812%08d", 1), PIN: testPIN,
+		})
+		if assert.NotNil(t, err) {
+			assert.Equal(t, http.StatusConflict, err.Code)
+			assert.Equal(t, service.ErrDuplicatePhoneNumber.Error(), err.Message)
+		}
+	})
+
+	t.Run("should reject an unknown product", func(t *testing.T) {
+		accounts, _ := newAccountService(model.WithdrawalLimits{})
+		_, err := accounts.CreateAccount(context.Background(), &model.CreateAccount{
+			FullName: "John Doe", IDNumber: "1234567890123456", PhoneNumber: "081234567890", PIN: testPIN, ProductCode: "giro",
+		})
+		if assert.NotNil(t, err) {
+			assert.Equal(t, http.StatusBadRequest, err.Code)
+			assert.Equal(t, service.ErrProductNotFound.Error(), err.Message)
+		}
+	})
+}
+
+func TestDepositAndWithdraw(t *testing.T) {
+	t.Run("should move the balance and chain the activities", func(t *testing.T) {
+		accounts, _ := newAccountService(model.WithdrawalLimits{})
+		accountNumber := openAccount(t, accounts, 1, 100000)
+
+		err := accounts.Withdraw(context.Background(), &model.Withdrawal{AccountNumber: accountNumber, Nominal: model.NewMoney(30000), PIN: testPIN})
+		require.Nil(t, err)
+		assert.Equal(t, model.NewMoney(70000), balanceOf(t, accounts, accountNumber))
+
+		verification, err := accounts.VerifyLedger(context.Background(), accountNumber)
+		require.Nil(t, err)
+		assert.True(t, verification.Valid)
+		assert.Equal(t, 2, verification.CheckedEntries)
+	})
+
+	t.Run("should refuse to overdraw", func(t *testing.T) {
+		accounts, _ := newAccountService(model.WithdrawalLimits{})
+		accountNumber := openAccount(t, accounts, 1, 10000)
+
+		err := accounts.Withdraw(context.Background(), &model.Withdrawal{AccountNumber: accountNumber, Nominal: model.NewMoney(10001), PIN: testPIN})
+		if assert.NotNil(t, err) {
+			assert.Equal(t, http.StatusBadRequest, err.Code)
+			assert.Equal(t, service.ErrInsufficientBalance.Error(), err.Message)
+		}
+		assert.Equal(t, model.NewMoney(10000), balanceOf(t, accounts, accountNumber))
+	})
+
+	t.Run("should report an unknown account", func(t *testing.T) {
+		accounts, _ := newAccountService(model.WithdrawalLimits{})
+		err := accounts.Deposit(context.Background(), &model.DepositRequest{AccountNumber: "1234567890", Nominal: model.NewMoney(1)})
+		if assert.NotNil(t, err) {
+			assert.Equal(t, http.StatusNotFound, err.Code)
+		}
+	})
+}
+
+func TestWithdrawPIN(t *testing.T) {
+	accounts, _ := newAccountService(model.WithdrawalLimits{})
+	accountNumber := openAccount(t, accounts, 1, 100000)
+	withdrawal := &model.Withdrawal{AccountNumber: accountNumber, Nominal: model.NewMoney(1000), PIN: "000000"}
+
+	err := accounts.Withdraw(context.Background(), withdrawal)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, http.StatusForbidden, err.Code)
+		assert.Equal(t, service.ErrIncorrectPIN.Error()+": 2 attempts remaining", err.Message)
+	}
+	accounts.Withdraw(context.Background(), withdrawal)
+	err = accounts.Withdraw(context.Background(), withdrawal)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, http.StatusLocked, err.Code)
+	}
+
+	// Locked, so even the right PIN is refused until it is reset.
+	withdrawal.PIN = testPIN
+	err = accounts.Withdraw(context.Background(), withdrawal)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, service.ErrPINLocked.Error(), err.Message)
+	}
+	assert.Equal(t, model.NewMoney(100000), balanceOf(t, accounts, accountNumber))
+
+	require.Nil(t, accounts.ResetPIN(context.Background(), &model.PINResetRequest{AccountNumber: accountNumber, NewPIN: "654321"}))
+	withdrawal.PIN = "654321"
+	assert.Nil(t, accounts.Withdraw(context.Background(), withdrawal))
+	assert.Equal(t, model.NewMoney(99000), balanceOf(t, accounts, accountNumber))
+}
+
+func TestWithdrawalLimits(t *testing.T) {
+	accounts, _ := newAccountService(model.WithdrawalLimits{MaxPerTransaction: model.NewMoney(50000), MaxDailyCount: 2})
+	accountNumber := openAccount(t, accounts, 1, 500000)
+
+	err := accounts.Withdraw(context.Background(), &model.Withdrawal{AccountNumber: accountNumber, Nominal: model.NewMoney(50001), PIN: testPIN})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, http.StatusUnprocessableEntity, err.Code)
+		assert.True(t, strings.HasPrefix(err.Message, service.ErrPerTransactionLimit.Error()))
+	}
+
+	// An override raises the per-transaction limit of this account only.
+	maxPerTransaction := model.NewMoney(100000)
+	limits, err := accounts.SetWithdrawalLimits(context.Background(), accountNumber, &model.WithdrawalLimitRequest{MaxPerTransaction: &maxPerTransaction})
+	require.Nil(t, err)
+	assert.Equal(t, maxPerTransaction, limits.MaxPerTransaction)
+	assert.Equal(t, 2, limits.MaxDailyCount)
+
+	for i := 0; i < 2; i++ {
+		require.Nil(t, accounts.Withdraw(context.Background(), &model.Withdrawal{AccountNumber: accountNumber, Nominal: model.NewMoney(60000), PIN: testPIN}))
+	}
+	err = accounts.Withdraw(context.Background(), &model.Withdrawal{AccountNumber: accountNumber, Nominal: model.NewMoney(1000), PIN: testPIN})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, service.ErrDailyCountLimit.Error()+": 0 remaining", err.Message)
+	}
+
+	// Nor can the money leave the account by transfer.
+	to := openAccount(t, accounts, 2, 0)
+	_, err = accounts.Transfer(context.Background(), &model.TransferRequest{FromAccountNumber: accountNumber, ToAccountNumber: to, Nominal: model.NewMoney(1000), PIN: testPIN})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, service.ErrDailyCountLimit.Error()+": 0 remaining", err.Message)
+	}
+	assert.Equal(t, model.NewMoney(0), balanceOf(t, accounts, to))
+}
+
+func TestWithdrawalFee(t *testing.T) {
+	accounts, store := newAccountService(model.WithdrawalLimits{})
+	require.NoError(t, store.Repositories(context.Background()).FeeSchedules().Create(&model.FeeSchedule{
+		ProductCode:   model.DefaultProductCode,
+		Type:          model.FeeTypeWithdrawal,
+		Amount:        model.NewMoney(2500),
+		FreePerMonth:  1,
+		EffectiveFrom: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+	}))
+	accountNumber := openAccount(t, accounts, 1, 100000)
+
+	// The first withdrawal of the month is free, the second is charged.
+	for i := 0; i < 2; i++ {
+		require.Nil(t, accounts.Withdraw(context.Background(), &model.Withdrawal{AccountNumber: accountNumber, Nominal: model.NewMoney(10000), PIN: testPIN}))
+	}
+	assert.Equal(t, model.NewMoney(77500), balanceOf(t, accounts, accountNumber))
+
+	// Reversing the charged withdrawal refunds its fee.
+	mutations, _, err := accounts.GetMutations(context.Background(), &model.Mutation{AccountNumber: accountNumber, Month: int(time.Now().Month())})
+	require.Nil(t, err)
+	require.Len(t, mutations, 4)
+	fee := mutations[3]
+	assert.NotNil(t, fee.FeeScheduleID)
+	if assert.NotNil(t, fee.FeeOfID) {
+		assert.Equal(t, mutations[2].ID, *fee.FeeOfID)
+	}
+
+	reversal, err := accounts.Reverse(context.Background(), mutations[2].ID, &model.ReversalRequest{Reason: "Dispensed no cash"})
+	require.Nil(t, err)
+	assert.NotNil(t, reversal.FeeReversalID)
+	assert.Equal(t, model.NewMoney(90000), reversal.Balance)
+}
+
+func TestTransfer(t *testing.T) {
+	accounts, _ := newAccountService(model.WithdrawalLimits{})
+	from := openAccount(t, accounts, 1, 100000)
+	to := openAccount(t, accounts, 2, 0)
+
+	result, err := accounts.Transfer(context.Background(), &model.TransferRequest{FromAccountNumber: from, ToAccountNumber: to, Nominal: model.NewMoney(40000), PIN: testPIN})
+	require.Nil(t, err)
+	assert.Equal(t, model.NewMoney(60000), result.From.Balance)
+	assert.Equal(t, model.NewMoney(40000), result.To.Balance)
+
+	// Transfer legs cannot be reversed one at a time.
+	mutations, _, err := accounts.GetMutations(context.Background(), &model.Mutation{AccountNumber: to, Month: int(time.Now().Month())})
+	require.Nil(t, err)
+	require.Len(t, mutations, 1)
+	_, err = accounts.Reverse(context.Background(), mutations[0].ID, &model.ReversalRequest{Reason: "Wrong account"})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, service.ErrTransferReversal.Error(), err.Message)
+	}
+}
+
+func TestReverse(t *testing.T) {
+	accounts, _ := newAccountService(model.WithdrawalLimits{})
+	accountNumber := openAccount(t, accounts, 1, 100000)
+
+	mutations, _, err := accounts.GetMutations(context.Background(), &model.Mutation{AccountNumber: accountNumber, Month: int(time.Now().Month())})
+	require.Nil(t, err)
+	require.Len(t, mutations, 1)
+
+	reversal, err := accounts.Reverse(context.Background(), mutations[0].ID, &model.ReversalRequest{Reason: "Posted to the wrong account"})
+	require.Nil(t, err)
+	assert.True(t, reversal.Balance.IsZero())
+	assert.Nil(t, reversal.FeeReversalID)
+
+	_, err = accounts.Reverse(context.Background(), mutations[0].ID, &model.ReversalRequest{Reason: "Again"})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, http.StatusConflict, err.Code)
+	}
+	_, err = accounts.Reverse(context.Background(), reversal.ReversalID, &model.ReversalRequest{Reason: "Undo"})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, service.ErrReversalOfReversal.Error(), err.Message)
+	}
+
+	verification, err := accounts.VerifyLedger(context.Background(), accountNumber)
+	require.Nil(t, err)
+	assert.True(t, verification.Valid)
+}
+
+func TestAccountStatus(t *testing.T) {
+	accounts, _ := newAccountService(model.WithdrawalLimits{})
+	accountNumber := openAccount(t, accounts, 1, 1000)
+	req := &model.AccountStatusRequest{Actor: "admin", Reason: "Suspicious activity"}
+
+	account, err := accounts.Freeze(context.Background(), accountNumber, req)
+	require.Nil(t, err)
+	assert.Equal(t, model.AccountStatusFrozen, account.Status)
+
+	err = accounts.Deposit(context.Background(), &model.DepositRequest{AccountNumber: accountNumber, Nominal: model.NewMoney(1)})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, http.StatusLocked, err.Code)
+	}
+
+	_, err = accounts.Unfreeze(context.Background(), accountNumber, req)
+	require.Nil(t, err)
+
+	_, err = accounts.Close(context.Background(), accountNumber, req)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, service.ErrNonZeroBalance.Error(), err.Message)
+	}
+	require.Nil(t, accounts.Withdraw(context.Background(), &model.Withdrawal{AccountNumber: accountNumber, Nominal: model.NewMoney(1000), PIN: testPIN}))
+	_, err = accounts.Close(context.Background(), accountNumber, req)
+	require.Nil(t, err)
+
+	_, err = accounts.GetBalance(context.Background(), accountNumber)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, http.StatusGone, err.Code)
+	}
+}
+
+func TestGetMutationsPaginates(t *testing.T) {
+	accounts, _ := newAccountService(model.WithdrawalLimits{})
+	accountNumber := openAccount(t, accounts, 1, 0)
+	for i := 1; i <= 5; i++ {
+		require.Nil(t, accounts.Deposit(context.Background(), &model.DepositRequest{AccountNumber: accountNumber, Nominal: model.NewMoney(int64(i))}))
+	}
+
+	mutations, total, err := accounts.GetMutations(context.Background(), &model.Mutation{AccountNumber: accountNumber, Month: int(time.Now().Month()), Page: 2, Limit: 2})
+	require.Nil(t, err)
+	assert.Equal(t, int64(5), total)
+	if assert.Len(t, mutations, 2) {
+		assert.Equal(t, model.NewMoney(3), mutations[0].Nominal)
+		assert.Equal(t, model.NewMoney(4), mutations[1].Nominal)
+	}
+}
+
+func TestConcurrentWithdrawals(t *testing.T) {
+	accounts, _ := newAccountService(model.WithdrawalLimits{})
+	accountNumber := openAccount(t, accounts, 1, 10000)
+
+	// Twenty withdrawals of 1000 race for a balance of 10000: exactly ten
+	// succeed and the balance never goes negative.
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := accounts.Withdraw(context.Background(), &model.Withdrawal{AccountNumber: accountNumber, Nominal: model.NewMoney(1000), PIN: testPIN}); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 10, succeeded)
+	assert.True(t, balanceOf(t, accounts, accountNumber).IsZero())
+	verification, err := accounts.VerifyLedger(context.Background(), accountNumber)
+	require.Nil(t, err)
+	assert.True(t, verification.Valid)
+	assert.Equal(t, 11, verification.CheckedEntries)
+}
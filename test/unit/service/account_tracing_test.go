@@ -0,0 +1,63 @@
+package service_test
+
+import (
+	"account-service/src/model"
+	"account-service/src/service"
+	"account-service/src/tracing"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// recordSpans installs a tracer provider recording the spans that end.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestAccountTracing(t *testing.T) {
+	inner, _ := newAccountService(model.WithdrawalLimits{})
+	accountNumber := openAccount(t, inner, 1, 1000)
+	accounts := service.NewAccountTracing(inner)
+
+	t.Run("should trace each call in a span named after the method", func(t *testing.T) {
+		recorder := recordSpans(t)
+		ctx, parent := tracing.Tracer().Start(context.Background(), "POST /v1/tarik")
+
+		require.Nil(t, accounts.Withdraw(ctx, &model.Withdrawal{AccountNumber: accountNumber, Nominal: model.NewMoney(100), PIN: testPIN}))
+		_, err := accounts.GetBalance(ctx, accountNumber)
+		require.Nil(t, err)
+		parent.End()
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 3)
+		assert.Equal(t, "AccountService.Withdraw", spans[0].Name())
+		assert.Equal(t, "AccountService.GetBalance", spans[1].Name())
+		for _, span := range spans[:2] {
+			assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+			assert.Equal(t, codes.Unset, span.Status().Code)
+		}
+	})
+
+	t.Run("should record the code of a failed call", func(t *testing.T) {
+		recorder := recordSpans(t)
+
+		_, err := accounts.GetBalance(context.Background(), "0000000000")
+		require.NotNil(t, err)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		assert.Contains(t, spans[0].Attributes(), semconv.ErrorTypeKey.String("404"))
+	})
+}
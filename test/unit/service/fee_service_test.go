@@ -0,0 +1,91 @@
+package service_test
+
+import (
+	"account-service/src/model"
+	"account-service/src/repository"
+	"account-service/src/service"
+	"account-service/src/utils"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedAccount stores the nth account holding balance, opened at createdAt,
+// returning its number. Services that work on past business days need
+// accounts older than the account service opens them.
+func seedAccount(t *testing.T, store repository.UnitOfWork, n int, balance int64, createdAt time.Time) string {
+	t.Helper()
+	account := model.Account{
+		AccountNumber: fmt.Sprintf("%010d", n),
+		FullName:      fmt.Sprintf("Customer %d", n),
+		IDNumber:      fmt.Sprintf("%016d", n),
+		PhoneNumber:   fmt.Sprintf("0812%08d", n),
+		Balance:       model.NewMoney(balance),
+		CreatedAt:     createdAt,
+	}
+	require.NoError(t, store.Repositories(context.Background()).Accounts().Create(&account))
+	return account.AccountNumber
+}
+
+func storedBalance(t *testing.T, store repository.UnitOfWork, accountNumber string) model.Money {
+	t.Helper()
+	account, err := store.Repositories(context.Background()).Accounts().FindByNumber(accountNumber)
+	require.NoError(t, err)
+	return account.Balance
+}
+
+func TestChargeMonthlyFees(t *testing.T) {
+	now := time.Now().UTC()
+	lastMonth := time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, time.UTC)
+
+	store := repository.NewMemory()
+	require.NoError(t, store.Repositories(context.Background()).FeeSchedules().Create(&model.FeeSchedule{
+		ProductCode:   model.DefaultProductCode,
+		Type:          model.FeeTypeMonthlyAdmin,
+		Amount:        model.NewMoney(500),
+		EffectiveFrom: lastMonth,
+	}))
+	funded := seedAccount(t, store, 1, 10_000, lastMonth)
+	empty := seedAccount(t, store, 2, 0, lastMonth)
+	frozen := seedAccount(t, store, 3, 10_000, lastMonth)
+	frozenAccount, err := store.Repositories(context.Background()).Accounts().FindByNumber(frozen)
+	require.NoError(t, err)
+	frozenAccount.Status = model.AccountStatusFrozen
+	require.NoError(t, store.Repositories(context.Background()).Accounts().UpdateStatus(frozenAccount))
+	fees := service.NewFeeService(store, service.NewLedger(), utils.Validator(), time.UTC)
+
+	t.Run("should charge the accounts that can cover the fee", func(t *testing.T) {
+		result, err := fees.ChargeMonthlyFees(context.Background(), lastMonth)
+		require.Nil(t, err)
+		assert.Equal(t, 1, result.Charged)
+		assert.Equal(t, model.NewMoney(500), result.Total)
+		assert.Equal(t, 1, result.Skipped)
+		assert.Equal(t, model.NewMoney(9_500), storedBalance(t, store, funded))
+		assert.Equal(t, model.NewMoney(0), storedBalance(t, store, empty))
+		assert.Equal(t, model.NewMoney(10_000), storedBalance(t, store, frozen))
+
+		activity, findErr := store.Repositories(context.Background()).CashActivities().Latest(1)
+		require.NoError(t, findErr)
+		require.NotNil(t, activity.FeePeriod)
+		assert.True(t, activity.FeePeriod.Equal(lastMonth))
+	})
+
+	t.Run("should not charge an account twice for a month", func(t *testing.T) {
+		result, err := fees.ChargeMonthlyFees(context.Background(), lastMonth)
+		require.Nil(t, err)
+		assert.Equal(t, 0, result.Charged)
+		assert.Equal(t, 1, result.Skipped)
+		assert.Equal(t, model.NewMoney(9_500), storedBalance(t, store, funded))
+	})
+
+	t.Run("should refuse a month that has not ended", func(t *testing.T) {
+		_, err := fees.ChargeMonthlyFees(context.Background(), now)
+		require.NotNil(t, err)
+		assert.Equal(t, fiber.StatusUnprocessableEntity, err.Code)
+	})
+}
@@ -0,0 +1,62 @@
+package service_test
+
+import (
+	"account-service/src/model"
+	"account-service/src/repository"
+	"account-service/src/service"
+	"account-service/src/utils"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterestRun(t *testing.T) {
+	now := time.Now().UTC()
+	monthEnd := time.Date(now.Year(), now.Month(), 0, 0, 0, 0, 0, time.UTC)
+	dayBefore := monthEnd.AddDate(0, 0, -1)
+	rate := 1_000
+
+	store := repository.NewMemory()
+	accountNumber := seedAccount(t, store, 1, 3_650_000, dayBefore.AddDate(0, 0, -1))
+	interest := service.NewInterestService(store, service.NewLedger(), utils.Validator(), time.UTC)
+	_, err := interest.SetProductRate(context.Background(), model.DefaultProductCode, &model.ProductRateRequest{AnnualRateBPS: &rate})
+	require.Nil(t, err)
+	daily := model.DailyInterest(model.NewMoney(3_650_000), rate)
+
+	t.Run("should accrue a day of interest without crediting it", func(t *testing.T) {
+		run, err := interest.Run(context.Background(), dayBefore)
+		require.Nil(t, err)
+		assert.Equal(t, 1, run.AccruedAccounts)
+		assert.Equal(t, daily, run.TotalAccrued)
+		assert.Equal(t, 0, run.CapitalizedAccounts)
+
+		pending, err := interest.PendingInterest(context.Background(), accountNumber)
+		require.Nil(t, err)
+		assert.Equal(t, daily, pending.Pending)
+		assert.Len(t, pending.Accruals, 1)
+		assert.Equal(t, model.NewMoney(3_650_000), storedBalance(t, store, accountNumber))
+	})
+
+	t.Run("should credit the interest of the month on its last day", func(t *testing.T) {
+		run, err := interest.Run(context.Background(), monthEnd)
+		require.Nil(t, err)
+		assert.Equal(t, 1, run.CapitalizedAccounts)
+		assert.Equal(t, daily.Add(daily), run.TotalCapitalized)
+		assert.Equal(t, model.NewMoney(3_650_000).Add(daily).Add(daily), storedBalance(t, store, accountNumber))
+
+		pending, err := interest.PendingInterest(context.Background(), accountNumber)
+		require.Nil(t, err)
+		assert.True(t, pending.Pending.IsZero())
+		assert.Empty(t, pending.Accruals)
+	})
+
+	t.Run("should return the first run of a date run again", func(t *testing.T) {
+		run, err := interest.Run(context.Background(), monthEnd)
+		require.Nil(t, err)
+		assert.True(t, run.AlreadyCompleted)
+		assert.Equal(t, model.NewMoney(3_650_000).Add(daily).Add(daily), storedBalance(t, store, accountNumber))
+	})
+}
@@ -0,0 +1,118 @@
+package tracing_test
+
+import (
+	"account-service/src/tracing"
+	"context"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// recordSpans installs a tracer provider recording the spans that end.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+type widget struct {
+	ID   uint
+	Name string
+}
+
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	require.NoError(t, db.AutoMigrate(&widget{}))
+	require.NoError(t, db.Use(tracing.GORM()))
+	return db
+}
+
+func TestGORM(t *testing.T) {
+	db := openDB(t)
+
+	t.Run("should trace every query as a child of the span of its context", func(t *testing.T) {
+		recorder := recordSpans(t)
+		ctx, parent := tracing.Tracer().Start(context.Background(), "parent")
+
+		require.NoError(t, db.WithContext(ctx).Create(&widget{Name: "first"}).Error)
+		var found widget
+		require.NoError(t, db.WithContext(ctx).Where("name = ?", "first").First(&found).Error)
+		require.NoError(t, db.WithContext(ctx).Exec("UPDATE widgets SET name = ? WHERE id = ?", "second", found.ID).Error)
+		parent.End()
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 4)
+		assert.Equal(t, "INSERT widgets", spans[0].Name())
+		assert.Equal(t, "SELECT widgets", spans[1].Name())
+		assert.Equal(t, "UPDATE", spans[2].Name())
+		for _, span := range spans[:3] {
+			assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+			assert.Contains(t, span.Attributes(), semconv.DBSystemSqlite)
+		}
+		assert.Contains(t, spans[1].Attributes(), semconv.DBQueryText("SELECT * FROM `widgets` WHERE name = ? ORDER BY `widgets`.`id` LIMIT 1"))
+	})
+
+	t.Run("should mark a failed query but not a missing record", func(t *testing.T) {
+		recorder := recordSpans(t)
+		ctx, parent := tracing.Tracer().Start(context.Background(), "parent")
+		defer parent.End()
+
+		var found widget
+		assert.ErrorIs(t, db.WithContext(ctx).Where("name = ?", "missing").First(&found).Error, gorm.ErrRecordNotFound)
+		assert.Error(t, db.WithContext(ctx).Exec("SELECT * FROM gadgets").Error)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 2)
+		assert.Equal(t, codes.Unset, spans[0].Status().Code)
+		assert.Equal(t, codes.Error, spans[1].Status().Code)
+	})
+}
+
+func TestGORM_OutsideTrace(t *testing.T) {
+	db := openDB(t)
+
+	t.Run("should not trace a query outside a trace", func(t *testing.T) {
+		recorder := recordSpans(t)
+
+		require.NoError(t, db.Create(&widget{Name: "first"}).Error)
+
+		assert.Empty(t, recorder.Ended())
+	})
+}
+
+func TestEnd(t *testing.T) {
+	t.Run("should mark only server errors as failed", func(t *testing.T) {
+		recorder := recordSpans(t)
+
+		for _, err := range []*fiber.Error{nil, fiber.NewError(fiber.StatusNotFound, "account not found"), fiber.ErrInternalServerError} {
+			_, span := tracing.Tracer().Start(context.Background(), "call")
+			tracing.End(span, err)
+		}
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 3)
+		assert.Equal(t, codes.Unset, spans[0].Status().Code)
+		assert.Equal(t, codes.Unset, spans[1].Status().Code)
+		assert.Contains(t, spans[1].Attributes(), semconv.ErrorTypeKey.String("404"))
+		assert.Equal(t, codes.Error, spans[2].Status().Code)
+	})
+}
@@ -0,0 +1,151 @@
+package utils_test
+
+import (
+	"account-service/src/tracing"
+	"account-service/src/utils"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"gorm.io/gorm"
+)
+
+// captureLog sends utils.Log to a buffer as JSON at level until the test ends,
+// returning a function decoding the entries written so far.
+func captureLog(t *testing.T, level logrus.Level) func() []map[string]any {
+	t.Helper()
+	var buf bytes.Buffer
+	formatter, previousLevel := utils.Log.Formatter, utils.Log.GetLevel()
+	utils.Log.SetOutput(&buf)
+	utils.ConfigureLog(utils.LogFormatJSON, level)
+	t.Cleanup(func() {
+		utils.Log.SetOutput(os.Stdout)
+		utils.Log.SetFormatter(formatter)
+		utils.Log.SetLevel(previousLevel)
+	})
+
+	return func() []map[string]any {
+		var entries []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			entry := map[string]any{}
+			require.NoError(t, json.Unmarshal([]byte(line), &entry))
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+}
+
+func TestLog(t *testing.T) {
+	t.Run("should add the request ID and trace ID of the context", func(t *testing.T) {
+		entries := captureLog(t, logrus.InfoLevel)
+		previous := otel.GetTracerProvider()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider())
+		defer otel.SetTracerProvider(previous)
+
+		ctx, span := tracing.Tracer().Start(utils.WithRequestID(context.Background(), "req-1"), "request")
+		defer span.End()
+		utils.Log.WithContext(ctx).Info("inside")
+		utils.Log.Info("outside")
+
+		logged := entries()
+		require.Len(t, logged, 2)
+		assert.Equal(t, "inside", logged[0]["msg"])
+		assert.Equal(t, "req-1", logged[0]["request_id"])
+		assert.Equal(t, span.SpanContext().TraceID().String(), logged[0]["trace_id"])
+		assert.NotContains(t, logged[1], "request_id")
+	})
+
+	t.Run("should mask ID numbers and phone numbers", func(t *testing.T) {
+		entries := captureLog(t, logrus.InfoLevel)
+
+		utils.Log.WithFields(logrus.Fields{
+			"phone":   "081234567890",
+			"account": "1234567890",
+		}).WithError(errors.New("duplicate NIK 3201123456780001")).Info("Registering 3201123456780001")
+
+		logged := entries()
+		require.Len(t, logged, 1)
+		assert.Equal(t, "Registering 3201********0001", logged[0]["msg"])
+		assert.Equal(t, "0812****7890", logged[0]["phone"])
+		assert.Equal(t, "1234567890", logged[0]["account"])
+		assert.Equal(t, "duplicate NIK 3201********0001", logged[0]["error"])
+	})
+}
+
+type customer struct {
+	ID       uint
+	IDNumber string
+}
+
+func openDB(t *testing.T, slowThreshold time.Duration) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: utils.NewGormLogger(utils.Log, slowThreshold)})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	require.NoError(t, db.AutoMigrate(&customer{}))
+	return db
+}
+
+func TestGormLogger(t *testing.T) {
+	ctx := utils.WithRequestID(context.Background(), "req-2")
+
+	t.Run("should log queries at debug level with their parameters redacted", func(t *testing.T) {
+		db := openDB(t, 0)
+		entries := captureLog(t, logrus.DebugLevel)
+
+		require.NoError(t, db.WithContext(ctx).Create(&customer{IDNumber: "3171234567890001"}).Error)
+
+		logged := entries()
+		require.Len(t, logged, 1)
+		assert.Equal(t, "debug", logged[0]["level"])
+		assert.Equal(t, "req-2", logged[0]["request_id"])
+		assert.Contains(t, logged[0]["sql"], "INSERT INTO `customers`")
+		assert.Contains(t, logged[0]["sql"], "***")
+		assert.NotContains(t, logged[0]["sql"], "3171234567890001")
+	})
+
+	t.Run("should log only slow and failed queries at info level", func(t *testing.T) {
+		db := openDB(t, time.Nanosecond)
+		entries := captureLog(t, logrus.InfoLevel)
+
+		require.NoError(t, db.WithContext(ctx).Create(&customer{IDNumber: "3171234567890002"}).Error)
+		assert.Error(t, db.WithContext(ctx).Exec("SELECT * FROM missing WHERE id_number = ?", "3171234567890002").Error)
+
+		logged := entries()
+		require.Len(t, logged, 2)
+		assert.Equal(t, "warning", logged[0]["level"])
+		assert.Contains(t, logged[0]["msg"], "Slow query")
+		assert.Equal(t, "error", logged[1]["level"])
+		assert.Contains(t, logged[1], "error")
+		for _, entry := range logged {
+			assert.NotContains(t, entry["sql"], "3171234567890002")
+		}
+	})
+
+	t.Run("should not log a missing record as an error", func(t *testing.T) {
+		db := openDB(t, 0)
+		entries := captureLog(t, logrus.InfoLevel)
+
+		var found customer
+		assert.ErrorIs(t, db.WithContext(ctx).First(&found, 42).Error, gorm.ErrRecordNotFound)
+
+		assert.Empty(t, entries())
+	})
+}